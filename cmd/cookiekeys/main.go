@@ -0,0 +1,63 @@
+// Package main генерирует ключи подписи и шифрования пользовательских cookie.
+//
+// Без флагов выводит новую пару ключей, которую можно передать в COOKIE_KEYS.
+// С флагом -f добавляет новую пару в начало файла ключей для COOKIE_KEYS_FILE,
+// оставляя -keep предыдущих пар для проверки уже выданных cookie.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TimBerk/go-link-shortener/internal/pkg/cookies"
+)
+
+// rotateFile добавляет новую пару ключей в начало файла и оставляет keep предыдущих пар
+func rotateFile(path string, keep int) error {
+	var keys []cookies.KeyPair
+	data, err := os.ReadFile(path)
+	if err == nil {
+		if keys, err = cookies.ParseKeyPairs(string(data)); err != nil {
+			return err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if len(keys) > keep {
+		keys = keys[:keep]
+	}
+
+	lines := []string{
+		"# Cookie key pairs: the first pair signs new cookies, the others only verify them",
+		cookies.GenerateKeyPair().String(),
+	}
+	for _, key := range keys {
+		lines = append(lines, key.String())
+	}
+	return os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600)
+}
+
+func main() {
+	var path string
+	var keep int
+	flag.StringVar(&path, "f", "", "Path to cookie keys file to rotate, prints a new key pair when empty")
+	flag.IntVar(&keep, "keep", 1, "Number of previous key pairs kept in the file for verification")
+	flag.Parse()
+
+	if path == "" {
+		fmt.Fprintln(os.Stdout, cookies.GenerateKeyPair().String())
+		return
+	}
+	if keep < 0 {
+		logrus.Fatal("keep must not be negative")
+	}
+	if err := rotateFile(path, keep); err != nil {
+		logrus.Fatal("Rotate cookie keys: ", err)
+	}
+	fmt.Fprintf(os.Stdout, "New cookie key pair is written to %s\n", path)
+}
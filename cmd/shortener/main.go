@@ -5,23 +5,38 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgxpool"
 	"golang.org/x/crypto/acme/autocert"
+	"google.golang.org/grpc"
 
+	"github.com/TimBerk/go-link-shortener/internal/app/attempts"
 	"github.com/TimBerk/go-link-shortener/internal/app/config"
+	"github.com/TimBerk/go-link-shortener/internal/app/grpcserver"
+	"github.com/TimBerk/go-link-shortener/internal/app/handler"
 	"github.com/TimBerk/go-link-shortener/internal/app/middlewares/logger"
 	"github.com/TimBerk/go-link-shortener/internal/app/router"
 	"github.com/TimBerk/go-link-shortener/internal/app/store"
-	"github.com/TimBerk/go-link-shortener/internal/app/store/json"
-	"github.com/TimBerk/go-link-shortener/internal/app/store/local"
-	"github.com/TimBerk/go-link-shortener/internal/app/store/pg"
+	"github.com/TimBerk/go-link-shortener/internal/app/store/cache"
+	_ "github.com/TimBerk/go-link-shortener/internal/app/store/json"
+	_ "github.com/TimBerk/go-link-shortener/internal/app/store/local"
+	_ "github.com/TimBerk/go-link-shortener/internal/app/store/pg"
+	"github.com/TimBerk/go-link-shortener/internal/app/store/pg/migrate"
+	_ "github.com/TimBerk/go-link-shortener/internal/app/store/redis"
+	_ "github.com/TimBerk/go-link-shortener/internal/app/store/sqlite"
 	"github.com/TimBerk/go-link-shortener/internal/app/worker"
+	"github.com/TimBerk/go-link-shortener/internal/pkg/cookies"
+	"github.com/TimBerk/go-link-shortener/internal/pkg/jwtauth"
+	"github.com/TimBerk/go-link-shortener/internal/pkg/oidc"
 	_ "github.com/TimBerk/go-link-shortener/swagger"
 )
 
@@ -41,6 +56,54 @@ func printBuildInfo() {
 	fmt.Fprintf(os.Stdout, "Build commit: %s\n", buildCommit)
 }
 
+// runMigrate - применяет, откатывает или выводит состояние миграций PostgreSQL
+func runMigrate(ctx context.Context, cfg *config.Config) error {
+	if !strings.HasPrefix(cfg.StorageURL, "postgres://") && !strings.HasPrefix(cfg.StorageURL, "postgresql://") {
+		return errors.New("postgres storage url is required for migrations")
+	}
+
+	db, err := pgxpool.New(ctx, cfg.StorageURL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := migrate.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	switch cfg.Migrate {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, migration := range applied {
+			fmt.Fprintf(os.Stdout, "Applied: %d_%s\n", migration.Version, migration.Name)
+		}
+		return err
+	case "down":
+		reverted, err := migrator.Down(ctx, 1)
+		for _, migration := range reverted {
+			fmt.Fprintf(os.Stdout, "Reverted: %d_%s\n", migration.Version, migration.Name)
+		}
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err == nil && !slices.ContainsFunc(statuses, func(status migrate.Status) bool { return status.Applied }) {
+			fmt.Fprintln(os.Stdout, "No migrations applied")
+		}
+		for _, status := range statuses {
+			if status.Applied {
+				fmt.Fprintf(os.Stdout, "%d_%s: applied at %s\n", status.Version, status.Name, status.AppliedAt.Format(time.RFC3339))
+			} else {
+				fmt.Fprintf(os.Stdout, "%d_%s: pending\n", status.Version, status.Name)
+			}
+		}
+		return err
+	default:
+		return fmt.Errorf("unknown migrate command %q, expected up, down or status", cfg.Migrate)
+	}
+}
+
 // @Title Shortener API
 // @Description Сервис сокращения URL
 // @Version 1.0
@@ -60,29 +123,90 @@ func main() {
 		logger.Log.Fatal("Error initializing logs: ", errLogs)
 	}
 
-	generator := store.NewIDGenerator()
-	urlChan := make(chan store.URLPair, 1000)
+	cookieKeys, errKeys := cookies.LoadKeys(cfg.CookieKeys, cfg.CookieKeysFile)
+	if errKeys != nil {
+		logger.Log.Fatal("Load cookie keys: ", errKeys)
+	}
+	if len(cookieKeys) > 0 {
+		if err := cookies.SetKeys(cookieKeys); err != nil {
+			logger.Log.Fatal("Set cookie keys: ", err)
+		}
+	} else {
+		logger.Log.Warning("Cookie keys are not configured, user cookies will be invalid after restart")
+	}
 
-	var dataStore store.Store
-	var errStore error
+	if cfg.Migrate != "" {
+		if err := runMigrate(ctx, cfg); err != nil {
+			logger.Log.Fatal("Migrate: ", err)
+		}
+		return
+	}
 
-	if cfg.DatabaseDSN != "" {
-		dataStore, errStore = pg.NewPgStore(generator, cfg)
-	} else if cfg.UseLocalStore {
-		dataStore, errStore = local.NewURLStore(generator)
-	} else {
-		dataStore, errStore = json.NewJSONStore(cfg.FileStoragePath, generator)
+	generator, errGenerator := store.NewGenerator(cfg)
+	if errGenerator != nil {
+		logger.Log.Fatal("Create generator: ", errGenerator)
 	}
+	urlChan := make(chan store.URLPair, 1000)
+	clickChan := make(chan store.Click, 10000)
+
+	dataStore, errStore := store.Open(ctx, cfg.StorageURL, generator, cfg)
 	if errStore != nil {
 		logger.Log.Fatal("Read Store: ", errStore)
 	}
+	// Длина ссылок, выросшая из-за коллизий, восстанавливается по сохраненным ссылкам
+	if err := store.RestoreCodeLength(ctx, dataStore, generator); err != nil {
+		logger.Log.WithField("err", err).Error("Restore short link length")
+	}
+	if cfg.CacheSize > 0 {
+		dataStore = cache.NewCachedStore(dataStore, cfg.CacheSize, cfg.CacheTTL)
+	}
 
-	// Запускаем воркер
+	// Запускаем воркеры удаления, аналитики переходов и очистки истекших ссылок
 	var wg sync.WaitGroup
-	wg.Add(1)
+	wg.Add(2)
 	go worker.Worker(ctx, dataStore, urlChan, &wg)
+	go worker.ClickWorker(ctx, dataStore, clickChan, &wg)
+	if cfg.ReaperInterval > 0 {
+		wg.Add(1)
+		go worker.Reaper(ctx, dataStore, cfg.ReaperInterval, &wg)
+	}
+
+	var tokens *jwtauth.Manager
+	if jwtOptions := cfg.JWTOptions(); jwtOptions.Enabled() {
+		var errTokens error
+		if tokens, errTokens = jwtauth.NewManager(jwtOptions); errTokens != nil {
+			logger.Log.Fatal("Create JWT manager: ", errTokens)
+		}
+	}
+
+	var oidcProvider *oidc.Provider
+	if oidcOptions := cfg.OIDCOptions(); oidcOptions.Enabled() {
+		var errOIDC error
+		if oidcProvider, errOIDC = oidc.NewProvider(oidcOptions); errOIDC != nil {
+			logger.Log.Fatal("Create OIDC provider: ", errOIDC)
+		}
+	}
+
+	// Ограничитель неверных паролей общий для HTTP и gRPC, чтобы у ссылки был один лимит попыток
+	passwordAttempts := attempts.New(attempts.DefaultLimit, attempts.DefaultClientLimit, attempts.DefaultLockout)
+	router := router.RegisterRouters(dataStore, cfg, ctx, urlChan,
+		handler.WithClickChan(clickChan), handler.WithPasswordAttempts(passwordAttempts), handler.WithTokens(tokens), handler.WithOIDC(oidcProvider))
 
-	router := router.RegisterRouters(dataStore, cfg, ctx, urlChan)
+	var grpcServer *grpc.Server
+	if cfg.GRPCAddress != "" {
+		listener, err := net.Listen("tcp", cfg.GRPCAddress)
+		if err != nil {
+			logger.Log.Fatalf("gRPC listen error: %v", err)
+		}
+		grpcServer = grpcserver.New(dataStore, cfg, urlChan,
+			grpcserver.WithClickChan(clickChan), grpcserver.WithPasswordAttempts(passwordAttempts), grpcserver.WithTokens(tokens))
+		go func() {
+			logger.Log.WithField("address", cfg.GRPCAddress).Info("Starting gRPC server")
+			if err := grpcServer.Serve(listener); err != nil {
+				logger.Log.Fatalf("gRPC server error: %v", err)
+			}
+		}()
+	}
 
 	var server *http.Server // Объявляем переменную сервера на уровне функции
 	go func() {
@@ -126,8 +250,28 @@ func main() {
 	} else {
 		logger.Log.Info("Server stopped gracefully")
 	}
+	if grpcServer != nil {
+		// GracefulStop дожидается завершения запросов, которые еще могут отправлять ссылки в канал удаления
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+			logger.Log.Info("gRPC server stopped gracefully")
+		case <-shutdownCtx.Done():
+			grpcServer.Stop()
+			logger.Log.Errorf("gRPC server shutdown error: %v", shutdownCtx.Err())
+		}
+	}
 
 	close(urlChan)
+	close(clickChan)
 	wg.Wait()
+
+	if err := store.Close(dataStore); err != nil {
+		logger.Log.Errorf("Store close error: %v", err)
+	}
 	logger.Log.Info("Server shutdown completed")
 }
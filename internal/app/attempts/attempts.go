@@ -0,0 +1,137 @@
+// Package attempts ограничивает количество неверных попыток ввода пароля защищенных ссылок.
+// Один ограничитель используется HTTP- и gRPC-обработчиками, чтобы у ссылки был общий лимит попыток.
+package attempts
+
+import (
+	"sync"
+	"time"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/middlewares/auth"
+)
+
+const (
+	// DefaultLimit - количество неверных паролей клиента для одной ссылки, после которого ввод временно блокируется
+	DefaultLimit = 5
+	// DefaultClientLimit - количество неверных паролей клиента по всем ссылкам, после которого ввод временно блокируется
+	DefaultClientLimit = 20
+	// DefaultLockout - время блокировки ввода пароля
+	DefaultLockout = time.Minute
+	// maxTracked - количество счетчиков неверных паролей, после которого незаблокированные счетчики сбрасываются
+	maxTracked = 10000
+)
+
+// state описывает неверные попытки ввода пароля
+type state struct {
+	failures    int
+	lockedUntil time.Time
+}
+
+// pair - ключ попыток клиента для одной ссылки
+type pair struct {
+	key    string
+	client string
+}
+
+// Limiter ограничивает количество неверных попыток ввода пароля.
+// Попытки считаются для каждой пары ссылки и клиента, чтобы неверные пароли одного клиента
+// не блокировали ссылку для остальных, и отдельно для клиента по всем ссылкам, чтобы перебор
+// по разным ссылкам тоже замедлялся.
+type Limiter struct {
+	limit       int
+	clientLimit int
+	lockout     time.Duration
+	pairs       map[pair]state
+	clients     map[string]state
+	mutex       sync.Mutex
+	// NowFunc возвращает текущее время, заменяется в тестах
+	NowFunc func() time.Time
+}
+
+// New создает ограничитель, блокирующий клиенту ввод пароля на lockout после limit неверных паролей
+// для одной ссылки или после clientLimit неверных паролей по всем ссылкам
+func New(limit int, clientLimit int, lockout time.Duration) *Limiter {
+	return &Limiter{
+		limit:       limit,
+		clientLimit: clientLimit,
+		lockout:     lockout,
+		pairs:       make(map[pair]state),
+		clients:     make(map[string]state),
+		NowFunc:     time.Now,
+	}
+}
+
+// Client возвращает ключ клиента для подсчета попыток: ID пользователя, подтвержденного токеном,
+// ключом API или сессией, иначе адрес клиента. ID из cookie не используется, так как клиент
+// получает новый ID, просто не передав cookie.
+func Client(identity auth.Identity, ip string) string {
+	switch identity.Source {
+	case auth.SourceToken, auth.SourceAPIKey, auth.SourceSession:
+		return "user:" + identity.UserID
+	default:
+		return "ip:" + ip
+	}
+}
+
+// Blocked возвращает оставшееся время блокировки, если ввод пароля для ссылки key заблокирован клиенту
+func (l *Limiter) Blocked(key string, client string) (time.Duration, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.NowFunc()
+	left := max(lockedFor(l.pairs, pair{key: key, client: client}, now), lockedFor(l.clients, client, now))
+	return left, left > 0
+}
+
+// Fail учитывает неверный пароль клиента для ссылки key и блокирует ввод после исчерпания попыток.
+// При переполнении сбрасываются незаблокированные счетчики, чтобы память не росла без ограничений.
+func (l *Limiter) Fail(key string, client string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.NowFunc()
+	fail(l.pairs, pair{key: key, client: client}, l.limit, now.Add(l.lockout), now)
+	fail(l.clients, client, l.clientLimit, now.Add(l.lockout), now)
+}
+
+// Reset сбрасывает неверные попытки клиента для ссылки key после ввода верного пароля.
+// Попытки клиента по всем ссылкам не сбрасываются, иначе верный пароль своей ссылки снимал бы ограничение перебора.
+func (l *Limiter) Reset(key string, client string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	delete(l.pairs, pair{key: key, client: client})
+}
+
+// lockedFor возвращает оставшееся время блокировки по счетчику id и удаляет истекшую блокировку
+func lockedFor[K comparable](states map[K]state, id K, now time.Time) time.Duration {
+	st, ok := states[id]
+	if !ok || st.lockedUntil.IsZero() {
+		return 0
+	}
+
+	left := st.lockedUntil.Sub(now)
+	if left <= 0 {
+		delete(states, id)
+		return 0
+	}
+	return left
+}
+
+// fail увеличивает счетчик id и блокирует его до lockedUntil после limit неверных попыток
+func fail[K comparable](states map[K]state, id K, limit int, lockedUntil time.Time, now time.Time) {
+	if len(states) >= maxTracked {
+		for tracked, st := range states {
+			if !now.Before(st.lockedUntil) {
+				delete(states, tracked)
+			}
+		}
+	}
+
+	st := states[id]
+	st.failures++
+	if st.failures >= limit {
+		st.failures = 0
+		st.lockedUntil = lockedUntil
+	}
+	states[id] = st
+}
@@ -0,0 +1,71 @@
+package attempts
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/middlewares/auth"
+)
+
+func TestLimiter_PerClient(t *testing.T) {
+	now := time.Now()
+	limiter := New(3, 10, time.Minute)
+	limiter.NowFunc = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		limiter.Fail("abc123", "ip:198.51.100.1")
+	}
+
+	left, blocked := limiter.Blocked("abc123", "ip:198.51.100.1")
+	assert.True(t, blocked, "Клиент должен быть заблокирован после исчерпания попыток")
+	assert.Equal(t, time.Minute, left)
+
+	_, blocked = limiter.Blocked("abc123", "ip:203.0.113.2")
+	assert.False(t, blocked, "Неверные пароли другого клиента не должны блокировать ссылку")
+	_, blocked = limiter.Blocked("def456", "ip:198.51.100.1")
+	assert.False(t, blocked, "Блокировка одной ссылки не должна действовать на другие ссылки")
+
+	now = now.Add(time.Minute)
+	_, blocked = limiter.Blocked("abc123", "ip:198.51.100.1")
+	assert.False(t, blocked, "Блокировка должна сниматься по истечении времени")
+}
+
+func TestLimiter_ClientAcrossLinks(t *testing.T) {
+	now := time.Now()
+	limiter := New(3, 10, time.Minute)
+	limiter.NowFunc = func() time.Time { return now }
+
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("link-%d", i)
+		limiter.Fail(key, "ip:198.51.100.1")
+		limiter.Reset(key, "ip:198.51.100.1")
+	}
+
+	_, blocked := limiter.Blocked("other", "ip:198.51.100.1")
+	assert.True(t, blocked, "Перебор по разным ссылкам должен блокировать клиента, в том числе после верных паролей")
+	_, blocked = limiter.Blocked("other", "ip:203.0.113.2")
+	assert.False(t, blocked)
+}
+
+func TestClient(t *testing.T) {
+	tests := []struct {
+		name     string
+		identity auth.Identity
+		want     string
+	}{
+		{name: "Token user", identity: auth.Identity{UserID: "777", Source: auth.SourceToken}, want: "user:777"},
+		{name: "API key owner", identity: auth.Identity{UserID: "777", Source: auth.SourceAPIKey}, want: "user:777"},
+		{name: "Session account", identity: auth.Identity{UserID: "777", Source: auth.SourceSession}, want: "user:777"},
+		{name: "Cookie user", identity: auth.Identity{UserID: "777", Source: auth.SourceCookie}, want: "ip:198.51.100.1"},
+		{name: "New user", identity: auth.Identity{UserID: "777", Source: auth.SourceNew}, want: "ip:198.51.100.1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, Client(test.identity, "198.51.100.1"))
+		})
+	}
+}
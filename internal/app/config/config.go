@@ -9,30 +9,99 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/caarlos0/env/v11"
 	"github.com/sirupsen/logrus"
+
+	"github.com/TimBerk/go-link-shortener/internal/pkg/jwtauth"
+	"github.com/TimBerk/go-link-shortener/internal/pkg/oidc"
 )
 
 // JSONFile структура для хранения json-конфигурации
 type JSONFile struct {
-	ServerAddress   string `json:"server_address"`
-	BaseURL         string `json:"base_url"`
-	FileStoragePath string `json:"file_storage_path"`
-	DatabaseDSN     string `json:"database_dsn"`
-	EnableHTTPS     bool   `json:"enable_https"`
+	ServerAddress     string   `json:"server_address"`
+	GRPCAddress       string   `json:"grpc_address"`
+	BaseURL           string   `json:"base_url"`
+	FileStoragePath   string   `json:"file_storage_path"`
+	DatabaseDSN       string   `json:"database_dsn"`
+	SQLitePath        string   `json:"sqlite_path"`
+	RedisURL          string   `json:"redis_url"`
+	StorageURL        string   `json:"storage_url"`
+	Generator         string   `json:"generator"`
+	CounterPath       string   `json:"counter_path"`
+	CodeLength        int      `json:"code_length"`
+	CodeMaxLength     int      `json:"code_max_length"`
+	CodeAlphabet      string   `json:"code_alphabet"`
+	EnableHTTPS       bool     `json:"enable_https"`
+	TrustedSubnet     string   `json:"trusted_subnet"`
+	TrustedProxies    []string `json:"trusted_proxies"`
+	CookieKeys        []string `json:"cookie_keys"`
+	CookieKeysFile    string   `json:"cookie_keys_file"`
+	JWTAlgorithm      string   `json:"jwt_algorithm"`
+	JWTSecret         string   `json:"jwt_secret"`
+	JWTPrivateKeyFile string   `json:"jwt_private_key_file"`
+	JWTPublicKeyFile  string   `json:"jwt_public_key_file"`
+	JWTIssuer         string   `json:"jwt_issuer"`
+	JWTAudience       string   `json:"jwt_audience"`
+	JWTTTL            string   `json:"jwt_ttl"`
+	SessionTTL        string   `json:"session_ttl"`
+	OIDCIssuer        string   `json:"oidc_issuer"`
+	OIDCClientID      string   `json:"oidc_client_id"`
+	OIDCClientSecret  string   `json:"oidc_client_secret"`
+	OIDCRedirectURL   string   `json:"oidc_redirect_url"`
+	OIDCScopes        []string `json:"oidc_scopes"`
+	OIDCUserClaims    []string `json:"oidc_user_claims"`
+	OIDCUserPrefix    string   `json:"oidc_user_prefix"`
+	AdminUsers        []string `json:"admin_users"`
 }
 
 // Config задает основные переменные окружения
 type Config struct {
-	ServerAddress   string
-	BaseURL         string
-	LogLevel        string
-	FileStoragePath string
-	UseLocalStore   bool `envconfig:"USE_LOCAL_STORE" default:"false"`
-	DatabaseDSN     string
-	EnableHTTPS     bool   `envconfig:"ENABLE_HTTPS" default:"false"`
-	ConfigFile      string `envconfig:"CONFIG"`
+	ServerAddress       string
+	GRPCAddress         string
+	BaseURL             string
+	LogLevel            string
+	FileStoragePath     string
+	FileSyncPolicy      string
+	FileCompactInterval time.Duration
+	UseLocalStore       bool `envconfig:"USE_LOCAL_STORE" default:"false"`
+	DatabaseDSN         string
+	SQLitePath          string
+	RedisURL            string
+	StorageURL          string
+	Generator           string
+	CounterPath         string
+	CodeLength          int
+	CodeMaxLength       int
+	CodeAlphabet        string
+	CollisionThreshold  float64
+	CacheSize           int
+	CacheTTL            time.Duration
+	ReaperInterval      time.Duration
+	TrustedSubnet       string
+	TrustedProxies      string
+	CookieKeys          string
+	CookieKeysFile      string
+	JWTAlgorithm        string
+	JWTSecret           string
+	JWTPrivateKeyFile   string
+	JWTPublicKeyFile    string
+	JWTIssuer           string
+	JWTAudience         string
+	JWTTTL              time.Duration
+	SessionTTL          time.Duration
+	OIDCIssuer          string
+	OIDCClientID        string
+	OIDCClientSecret    string
+	OIDCRedirectURL     string
+	OIDCScopes          string
+	OIDCUserClaims      string
+	OIDCUserPrefix      string
+	AdminUsers          string
+	EnableHTTPS         bool   `envconfig:"ENABLE_HTTPS" default:"false"`
+	ConfigFile          string `envconfig:"CONFIG"`
+	Migrate             string `envconfig:"MIGRATE"`
 }
 
 // InitConfig Инициализирует и устанавливает значения для переменных окружения
@@ -43,22 +112,94 @@ func InitConfig() *Config {
 	}
 
 	envServerAddress := os.Getenv("SERVER_ADDRESS")
+	envGRPCAddress := os.Getenv("GRPC_ADDRESS")
 	envBaseURL := os.Getenv("BASE_URL")
 	envLogLevel := os.Getenv("LOGGING_LEVEL")
 	envFileStoragePath := os.Getenv("FILE_STORAGE_PATH")
+	envFileSyncPolicy := os.Getenv("FILE_SYNC_POLICY")
+	envFileCompactInterval := os.Getenv("FILE_COMPACT_INTERVAL")
 	envUseLocalStore := os.Getenv("USE_LOCAL_STORE")
 	envDatabaseDSN := os.Getenv("DATABASE_DSN")
+	envSQLitePath := os.Getenv("SQLITE_PATH")
+	envRedisURL := os.Getenv("REDIS_URL")
+	envStorageURL := os.Getenv("STORAGE_URL")
+	envGenerator := os.Getenv("GENERATOR")
+	envCounterPath := os.Getenv("COUNTER_PATH")
+	envCodeLength := os.Getenv("CODE_LENGTH")
+	envCodeMaxLength := os.Getenv("CODE_MAX_LENGTH")
+	envCodeAlphabet := os.Getenv("CODE_ALPHABET")
+	envCollisionThreshold := os.Getenv("COLLISION_THRESHOLD")
+	envCacheSize := os.Getenv("CACHE_SIZE")
+	envCacheTTL := os.Getenv("CACHE_TTL")
+	envReaperInterval := os.Getenv("REAPER_INTERVAL")
 	envEnableHTTPS := os.Getenv("ENABLE_HTTPS")
+	envTrustedSubnet := os.Getenv("TRUSTED_SUBNET")
+	envTrustedProxies := os.Getenv("TRUSTED_PROXIES")
+	envCookieKeys := os.Getenv("COOKIE_KEYS")
+	envCookieKeysFile := os.Getenv("COOKIE_KEYS_FILE")
+	envJWTAlgorithm := os.Getenv("JWT_ALGORITHM")
+	envJWTSecret := os.Getenv("JWT_SECRET")
+	envJWTPrivateKeyFile := os.Getenv("JWT_PRIVATE_KEY_FILE")
+	envJWTPublicKeyFile := os.Getenv("JWT_PUBLIC_KEY_FILE")
+	envJWTIssuer := os.Getenv("JWT_ISSUER")
+	envJWTAudience := os.Getenv("JWT_AUDIENCE")
+	envJWTTTL := os.Getenv("JWT_TTL")
+	envSessionTTL := os.Getenv("SESSION_TTL")
+	envOIDCIssuer := os.Getenv("OIDC_ISSUER")
+	envOIDCClientID := os.Getenv("OIDC_CLIENT_ID")
+	envOIDCClientSecret := os.Getenv("OIDC_CLIENT_SECRET")
+	envOIDCRedirectURL := os.Getenv("OIDC_REDIRECT_URL")
+	envOIDCScopes := os.Getenv("OIDC_SCOPES")
+	envOIDCUserClaims := os.Getenv("OIDC_USER_CLAIMS")
+	envOIDCUserPrefix := os.Getenv("OIDC_USER_PREFIX")
+	envAdminUsers := os.Getenv("ADMIN_USERS")
 	envConfigFile := os.Getenv("CONFIG")
+	envMigrate := os.Getenv("MIGRATE")
 
 	flag.StringVar(&cfg.ServerAddress, "a", "localhost:8080", "HTTP server address")
+	// gRPC-сервер работает без TLS, поэтому включается только явным адресом из -g, GRPC_ADDRESS или grpc_address
+	flag.StringVar(&cfg.GRPCAddress, "g", "", "gRPC server address without TLS, e.g. localhost:3200 (env GRPC_ADDRESS); empty disables gRPC server")
 	flag.StringVar(&cfg.BaseURL, "b", "http://localhost:8080", "Base URL for shortened links")
 	flag.StringVar(&cfg.LogLevel, "l", "info", "Logging level")
 	flag.StringVar(&cfg.FileStoragePath, "p", "files/data.json", "Path for files")
+	flag.StringVar(&cfg.FileSyncPolicy, "file-sync", "always", "Sync policy for file storage log: always, interval or never")
+	flag.DurationVar(&cfg.FileCompactInterval, "file-compact-interval", time.Minute, "Interval for file storage log compaction checks")
 	flag.BoolVar(&cfg.UseLocalStore, "local", false, "Use local store for url links")
 	flag.StringVar(&cfg.DatabaseDSN, "d", "", "Database DSN for PostgreSQL")
+	flag.StringVar(&cfg.SQLitePath, "sqlite", "", "Path to SQLite database file")
+	flag.StringVar(&cfg.RedisURL, "redis", "", "Redis URL for shared key-value store")
+	flag.StringVar(&cfg.StorageURL, "storage", "", "Storage URL: memory://, file:///path, sqlite:///path, postgres://..., redis://...")
+	flag.StringVar(&cfg.Generator, "generator", "random", "Short link generator: random, crypto, hash or sequence")
+	flag.StringVar(&cfg.CounterPath, "counter-path", "files/counter", "Path to persisted counter for sequence generator")
+	flag.IntVar(&cfg.CodeLength, "code-length", 6, "Initial length of generated short links")
+	flag.IntVar(&cfg.CodeMaxLength, "code-max-length", 12, "Max length of generated short links when growing on collisions")
+	flag.StringVar(&cfg.CodeAlphabet, "code-alphabet", "base62", "Short link alphabet: base62, unambiguous or custom symbols")
+	flag.Float64Var(&cfg.CollisionThreshold, "collision-threshold", 0.1, "Share of colliding links that grows link length, 0 disables growth")
+	// Кеш сбрасывается только на своем экземпляре, поэтому при общем хранилище удаленная на другом
+	// экземпляре ссылка продолжает открываться до cache-ttl. Кеш включается явно.
+	flag.IntVar(&cfg.CacheSize, "cache-size", 0, "Max number of cached redirects (env CACHE_SIZE), 0 disables cache")
+	flag.DurationVar(&cfg.CacheTTL, "cache-ttl", time.Minute, "Time to live for cached redirects (env CACHE_TTL); with a shared store deleted links keep redirecting on other instances for up to this time")
+	flag.DurationVar(&cfg.ReaperInterval, "reaper-interval", time.Minute, "Interval for deleting expired links")
 	flag.BoolVar(&cfg.EnableHTTPS, "s", false, "Enable HTTPS server")
+	flag.StringVar(&cfg.TrustedSubnet, "t", "", "Trusted subnet in CIDR notation for internal endpoints, empty denies access")
+	flag.StringVar(&cfg.TrustedProxies, "trusted-proxies", "", "Comma separated proxy subnets in CIDR notation whose X-Real-IP header is trusted")
+	flag.StringVar(&cfg.CookieKeysFile, "cookie-keys-file", "", "Path to file with cookie key pairs, the first pair signs new cookies")
+	flag.StringVar(&cfg.JWTAlgorithm, "jwt-algorithm", "HS256", "Bearer token signing algorithm: HS256 or RS256")
+	flag.StringVar(&cfg.JWTPrivateKeyFile, "jwt-private-key-file", "", "Path to PEM RSA private key for RS256 bearer tokens")
+	flag.StringVar(&cfg.JWTPublicKeyFile, "jwt-public-key-file", "", "Path to PEM RSA public key for verifying RS256 bearer tokens")
+	flag.StringVar(&cfg.JWTIssuer, "jwt-issuer", "", "Bearer token issuer, checked when not empty")
+	flag.StringVar(&cfg.JWTAudience, "jwt-audience", "", "Bearer token audience, checked when not empty")
+	flag.DurationVar(&cfg.JWTTTL, "jwt-ttl", 24*time.Hour, "Time to live for issued bearer tokens")
+	flag.DurationVar(&cfg.SessionTTL, "session-ttl", 30*24*time.Hour, "Time to live for account sessions")
+	flag.StringVar(&cfg.OIDCIssuer, "oidc-issuer", "", "OpenID Connect issuer URL, empty disables SSO login")
+	flag.StringVar(&cfg.OIDCClientID, "oidc-client-id", "", "OpenID Connect client id")
+	flag.StringVar(&cfg.OIDCRedirectURL, "oidc-redirect-url", "", "OpenID Connect redirect URL, defaults to base URL with /api/user/oidc/callback")
+	flag.StringVar(&cfg.OIDCScopes, "oidc-scopes", "openid,profile,email", "Comma separated OpenID Connect scopes")
+	flag.StringVar(&cfg.OIDCUserClaims, "oidc-user-claims", "sub", "Comma separated ID token claims for user ID, the first non-empty one is used")
+	flag.StringVar(&cfg.OIDCUserPrefix, "oidc-user-prefix", oidc.DefaultUserPrefix, "Prefix for user IDs of OpenID Connect users")
+	flag.StringVar(&cfg.AdminUsers, "admin-users", "", "Comma separated user IDs with admin role")
 	flag.StringVar(&cfg.ConfigFile, "c", "", "path to JSON config for server")
+	flag.StringVar(&cfg.Migrate, "migrate", "", "Run PostgreSQL migrations and exit: up, down or status")
 
 	flag.Parse()
 
@@ -83,9 +224,95 @@ func InitConfig() *Config {
 
 	cfg.LogLevel = cmp.Or(envLogLevel, cfg.LogLevel)
 	cfg.ServerAddress = cmp.Or(envServerAddress, cfgJSON.ServerAddress, cfg.ServerAddress)
+	cfg.GRPCAddress = cmp.Or(envGRPCAddress, cfgJSON.GRPCAddress, cfg.GRPCAddress)
 	cfg.BaseURL = cmp.Or(envBaseURL, cfgJSON.BaseURL, cfg.BaseURL)
 	cfg.FileStoragePath = cmp.Or(envFileStoragePath, cfgJSON.FileStoragePath, cfg.FileStoragePath)
+	cfg.FileSyncPolicy = cmp.Or(envFileSyncPolicy, cfg.FileSyncPolicy)
+	if envFileCompactInterval != "" {
+		compactInterval, err := time.ParseDuration(envFileCompactInterval)
+		if err != nil {
+			logrus.Warning("Couldn't parse FILE_COMPACT_INTERVAL", err)
+		} else {
+			cfg.FileCompactInterval = compactInterval
+		}
+	}
 	cfg.DatabaseDSN = cmp.Or(envDatabaseDSN, cfgJSON.DatabaseDSN, cfg.DatabaseDSN)
+	cfg.SQLitePath = cmp.Or(envSQLitePath, cfgJSON.SQLitePath, cfg.SQLitePath)
+	cfg.RedisURL = cmp.Or(envRedisURL, cfgJSON.RedisURL, cfg.RedisURL)
+	cfg.StorageURL = cmp.Or(envStorageURL, cfgJSON.StorageURL, cfg.StorageURL)
+	cfg.Generator = cmp.Or(envGenerator, cfgJSON.Generator, cfg.Generator)
+	cfg.CounterPath = cmp.Or(envCounterPath, cfgJSON.CounterPath, cfg.CounterPath)
+	cfg.CodeAlphabet = cmp.Or(envCodeAlphabet, cfgJSON.CodeAlphabet, cfg.CodeAlphabet)
+	cfg.CodeLength = cmp.Or(parseIntEnv("CODE_LENGTH", envCodeLength), cfgJSON.CodeLength, cfg.CodeLength)
+	cfg.CodeMaxLength = cmp.Or(parseIntEnv("CODE_MAX_LENGTH", envCodeMaxLength), cfgJSON.CodeMaxLength, cfg.CodeMaxLength)
+	if envCollisionThreshold != "" {
+		collisionThreshold, err := strconv.ParseFloat(envCollisionThreshold, 64)
+		if err != nil {
+			logrus.Warning("Couldn't parse COLLISION_THRESHOLD", err)
+		} else {
+			cfg.CollisionThreshold = collisionThreshold
+		}
+	}
+	cfg.Migrate = cmp.Or(envMigrate, cfg.Migrate)
+	cfg.TrustedSubnet = cmp.Or(envTrustedSubnet, cfgJSON.TrustedSubnet, cfg.TrustedSubnet)
+	cfg.TrustedProxies = cmp.Or(envTrustedProxies, strings.Join(cfgJSON.TrustedProxies, ","), cfg.TrustedProxies)
+	cfg.CookieKeys = cmp.Or(envCookieKeys, strings.Join(cfgJSON.CookieKeys, ","))
+	cfg.CookieKeysFile = cmp.Or(envCookieKeysFile, cfgJSON.CookieKeysFile, cfg.CookieKeysFile)
+	cfg.JWTAlgorithm = cmp.Or(envJWTAlgorithm, cfgJSON.JWTAlgorithm, cfg.JWTAlgorithm)
+	cfg.JWTSecret = cmp.Or(envJWTSecret, cfgJSON.JWTSecret)
+	cfg.JWTPrivateKeyFile = cmp.Or(envJWTPrivateKeyFile, cfgJSON.JWTPrivateKeyFile, cfg.JWTPrivateKeyFile)
+	cfg.JWTPublicKeyFile = cmp.Or(envJWTPublicKeyFile, cfgJSON.JWTPublicKeyFile, cfg.JWTPublicKeyFile)
+	cfg.JWTIssuer = cmp.Or(envJWTIssuer, cfgJSON.JWTIssuer, cfg.JWTIssuer)
+	cfg.JWTAudience = cmp.Or(envJWTAudience, cfgJSON.JWTAudience, cfg.JWTAudience)
+	if jwtTTL := cmp.Or(envJWTTTL, cfgJSON.JWTTTL); jwtTTL != "" {
+		ttl, err := time.ParseDuration(jwtTTL)
+		if err != nil {
+			logrus.Warning("Couldn't parse JWT_TTL", err)
+		} else {
+			cfg.JWTTTL = ttl
+		}
+	}
+	if sessionTTL := cmp.Or(envSessionTTL, cfgJSON.SessionTTL); sessionTTL != "" {
+		ttl, err := time.ParseDuration(sessionTTL)
+		if err != nil {
+			logrus.Warning("Couldn't parse SESSION_TTL", err)
+		} else {
+			cfg.SessionTTL = ttl
+		}
+	}
+	cfg.OIDCIssuer = cmp.Or(envOIDCIssuer, cfgJSON.OIDCIssuer, cfg.OIDCIssuer)
+	cfg.OIDCClientID = cmp.Or(envOIDCClientID, cfgJSON.OIDCClientID, cfg.OIDCClientID)
+	cfg.OIDCClientSecret = cmp.Or(envOIDCClientSecret, cfgJSON.OIDCClientSecret)
+	cfg.OIDCRedirectURL = cmp.Or(envOIDCRedirectURL, cfgJSON.OIDCRedirectURL, cfg.OIDCRedirectURL)
+	cfg.OIDCScopes = cmp.Or(envOIDCScopes, strings.Join(cfgJSON.OIDCScopes, ","), cfg.OIDCScopes)
+	cfg.OIDCUserClaims = cmp.Or(envOIDCUserClaims, strings.Join(cfgJSON.OIDCUserClaims, ","), cfg.OIDCUserClaims)
+	cfg.OIDCUserPrefix = cmp.Or(envOIDCUserPrefix, cfgJSON.OIDCUserPrefix, cfg.OIDCUserPrefix)
+	cfg.AdminUsers = cmp.Or(envAdminUsers, strings.Join(cfgJSON.AdminUsers, ","), cfg.AdminUsers)
+
+	if envCacheSize != "" {
+		cacheSize, err := strconv.Atoi(envCacheSize)
+		if err != nil {
+			logrus.Warning("Couldn't parse CACHE_SIZE", err)
+		} else {
+			cfg.CacheSize = cacheSize
+		}
+	}
+	if envCacheTTL != "" {
+		cacheTTL, err := time.ParseDuration(envCacheTTL)
+		if err != nil {
+			logrus.Warning("Couldn't parse CACHE_TTL", err)
+		} else {
+			cfg.CacheTTL = cacheTTL
+		}
+	}
+	if envReaperInterval != "" {
+		reaperInterval, err := time.ParseDuration(envReaperInterval)
+		if err != nil {
+			logrus.Warning("Couldn't parse REAPER_INTERVAL", err)
+		} else {
+			cfg.ReaperInterval = reaperInterval
+		}
+	}
 
 	boolLocalStore, err := strconv.ParseBool(strings.ToLower(envUseLocalStore))
 	if err != nil {
@@ -99,9 +326,94 @@ func InitConfig() *Config {
 	}
 	cfg.EnableHTTPS = cmp.Or(boolHTTPS, cfgJSON.EnableHTTPS, cfg.EnableHTTPS)
 
+	if cfg.StorageURL == "" {
+		cfg.StorageURL = cfg.legacyStorageURL()
+	}
+
 	return cfg
 }
 
+// parseIntEnv разбирает целочисленную переменную окружения, при ошибке возвращает 0
+func parseIntEnv(name string, value string) int {
+	if value == "" {
+		return 0
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		logrus.Warning("Couldn't parse "+name, err)
+		return 0
+	}
+	return parsed
+}
+
+// legacyStorageURL формирует URL хранилища из отдельных настроек хранилищ
+func (cfg *Config) legacyStorageURL() string {
+	switch {
+	case cfg.DatabaseDSN != "":
+		return cfg.DatabaseDSN
+	case cfg.RedisURL != "":
+		return cfg.RedisURL
+	case cfg.SQLitePath != "":
+		return "sqlite://" + cfg.SQLitePath
+	case cfg.UseLocalStore:
+		return "memory://"
+	default:
+		return "file://" + cfg.FileStoragePath
+	}
+}
+
+// JWTOptions возвращает параметры токенов Authorization: Bearer
+func (cfg *Config) JWTOptions() jwtauth.Options {
+	return jwtauth.Options{
+		Algorithm:      cfg.JWTAlgorithm,
+		Secret:         cfg.JWTSecret,
+		PrivateKeyFile: cfg.JWTPrivateKeyFile,
+		PublicKeyFile:  cfg.JWTPublicKeyFile,
+		Issuer:         cfg.JWTIssuer,
+		Audience:       cfg.JWTAudience,
+		TTL:            cfg.JWTTTL,
+	}
+}
+
+// OIDCCallbackPath - путь возврата от провайдера OpenID Connect
+const OIDCCallbackPath = "/api/user/oidc/callback"
+
+// OIDCOptions возвращает параметры входа через OpenID Connect.
+// Без явного адреса возврата используется OIDCCallbackPath относительно базового URL.
+func (cfg *Config) OIDCOptions() oidc.Options {
+	return oidc.Options{
+		Issuer:       cfg.OIDCIssuer,
+		ClientID:     cfg.OIDCClientID,
+		ClientSecret: cfg.OIDCClientSecret,
+		RedirectURL:  cmp.Or(cfg.OIDCRedirectURL, strings.TrimSuffix(cfg.BaseURL, "/")+OIDCCallbackPath),
+		Scopes:       splitList(cfg.OIDCScopes),
+		UserClaims:   splitList(cfg.OIDCUserClaims),
+		UserPrefix:   cfg.OIDCUserPrefix,
+	}
+}
+
+// Admins возвращает ID пользователей с ролью администратора
+func (cfg *Config) Admins() []string {
+	return splitList(cfg.AdminUsers)
+}
+
+// Proxies возвращает подсети доверенных прокси, заголовку X-Real-IP от которых можно верить
+func (cfg *Config) Proxies() []string {
+	return splitList(cfg.TrustedProxies)
+}
+
+// splitList разбирает список через запятую, пропуская пустые элементы
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // NewConfig Инициализирует минимальные настройки
 func NewConfig(serverAddress, baseURL string, useLocalStore bool) *Config {
 	return &Config{
@@ -109,5 +421,7 @@ func NewConfig(serverAddress, baseURL string, useLocalStore bool) *Config {
 		BaseURL:       baseURL,
 		UseLocalStore: useLocalStore,
 		EnableHTTPS:   false,
+		CodeLength:    6,
+		CodeAlphabet:  "base62",
 	}
 }
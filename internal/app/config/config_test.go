@@ -3,11 +3,13 @@ package config
 import (
 	"flag"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/TimBerk/go-link-shortener/internal/pkg/utils"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestInitConfig(t *testing.T) {
@@ -84,3 +86,107 @@ func TestInitConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestLegacyStorageURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      Config
+		expected string
+	}{
+		{
+			name:     "PostgreSQL DSN",
+			cfg:      Config{DatabaseDSN: "postgres://localhost/db", UseLocalStore: true, FileStoragePath: "files/data.json"},
+			expected: "postgres://localhost/db",
+		},
+		{
+			name:     "Redis URL",
+			cfg:      Config{RedisURL: "redis://localhost:6379/0", FileStoragePath: "files/data.json"},
+			expected: "redis://localhost:6379/0",
+		},
+		{
+			name:     "SQLite path",
+			cfg:      Config{SQLitePath: "files/data.db", FileStoragePath: "files/data.json"},
+			expected: "sqlite://files/data.db",
+		},
+		{
+			name:     "Local store",
+			cfg:      Config{UseLocalStore: true, FileStoragePath: "files/data.json"},
+			expected: "memory://",
+		},
+		{
+			name:     "File store",
+			cfg:      Config{FileStoragePath: "files/data.json"},
+			expected: "file://files/data.json",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, test.cfg.legacyStorageURL(), "Неверный URL хранилища")
+		})
+	}
+}
+
+func TestOIDCOptions(t *testing.T) {
+	tests := []struct {
+		name             string
+		cfg              Config
+		expectedRedirect string
+		expectedScopes   []string
+		expectedClaims   []string
+	}{
+		{
+			name:             "Redirect from base URL",
+			cfg:              Config{BaseURL: "https://short.example.com/", OIDCScopes: "openid, email", OIDCUserClaims: "email,sub"},
+			expectedRedirect: "https://short.example.com/api/user/oidc/callback",
+			expectedScopes:   []string{"openid", "email"},
+			expectedClaims:   []string{"email", "sub"},
+		},
+		{
+			name:             "Explicit redirect URL",
+			cfg:              Config{BaseURL: "https://short.example.com", OIDCRedirectURL: "https://sso.short.example.com/callback", OIDCUserClaims: ",sub,"},
+			expectedRedirect: "https://sso.short.example.com/callback",
+			expectedClaims:   []string{"sub"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts := test.cfg.OIDCOptions()
+			assert.Equal(t, test.expectedRedirect, opts.RedirectURL)
+			assert.Equal(t, test.expectedScopes, opts.Scopes)
+			assert.Equal(t, test.expectedClaims, opts.UserClaims)
+		})
+	}
+}
+
+func TestInitConfigTrustedSubnet(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(configPath, []byte(`{"trusted_subnet":"10.0.0.0/8"}`), 0644))
+
+	tests := []struct {
+		name     string
+		env      string
+		args     []string
+		expected string
+	}{
+		{name: "Disabled by default", expected: ""},
+		{name: "Flag", args: []string{"-t", "192.168.0.0/16"}, expected: "192.168.0.0/16"},
+		{name: "JSON file overrides flag", args: []string{"-t", "192.168.0.0/16", "-c", configPath}, expected: "10.0.0.0/8"},
+		{name: "Environment overrides JSON file", env: "172.16.0.0/12", args: []string{"-c", configPath}, expected: "172.16.0.0/12"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			flag.CommandLine = flag.NewFlagSet("", flag.ContinueOnError)
+			os.Args = append([]string{"cmd"}, test.args...)
+			if test.env != "" {
+				t.Setenv("TRUSTED_SUBNET", test.env)
+			}
+
+			cfg := InitConfig()
+
+			assert.Equal(t, test.expected, cfg.TrustedSubnet, "Неверная доверенная подсеть")
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package grpcserver
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/grpcserver/pb"
+	"github.com/TimBerk/go-link-shortener/internal/app/middlewares/auth"
+	"github.com/TimBerk/go-link-shortener/internal/pkg/cookies"
+)
+
+const (
+	// UserMetadataKey - ключ метаданных с закодированным ID пользователя, значение совпадает с cookie user HTTP API
+	UserMetadataKey = "user"
+	// authorizationMetadataKey - ключ метаданных с токеном в формате заголовка Authorization: Bearer
+	authorizationMetadataKey = "authorization"
+	// apiKeyMetadataKey - ключ метаданных с ключом API в формате заголовка X-API-Key
+	apiKeyMetadataKey = "x-api-key"
+	// sessionMetadataKey - ключ метаданных с токеном сессии, значение совпадает с cookie session HTTP API
+	sessionMetadataKey = "session"
+)
+
+// anonymousMethods - методы, которые не работают с пользователем
+var anonymousMethods = map[string]bool{
+	pb.Shortener_Ping_FullMethodName: true,
+}
+
+// authorizedMethods - методы, которые, как и их HTTP-аналоги, не создают нового пользователя
+var authorizedMethods = map[string]bool{
+	pb.Shortener_DeleteUserURLs_FullMethodName: true,
+}
+
+// firstMetadata возвращает первое значение метаданных запроса по ключу
+func firstMetadata(ctx context.Context, key string) string {
+	if values := metadata.ValueFromIncomingContext(ctx, key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// userInterceptor определяет пользователя по метаданным authorization, x-api-key, session и user
+// по тем же правилам, что и HTTP API.
+// Новый пользователь возвращается в заголовке ответа user, а для методов из authorizedMethods
+// запрос без пользователя отклоняется с кодом Unauthenticated.
+func (s *Server) userInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if anonymousMethods[info.FullMethod] {
+		return handler(ctx, req)
+	}
+
+	resolver := auth.Resolver{Tokens: s.tokens, Keys: s.store, Sessions: s.store, Admins: s.admins}
+	identity, err := resolver.Resolve(ctx, auth.Credentials{
+		Authorization: firstMetadata(ctx, authorizationMetadataKey),
+		APIKey:        firstMetadata(ctx, apiKeyMetadataKey),
+		Session:       firstMetadata(ctx, sessionMetadataKey),
+		Cookie:        firstMetadata(ctx, UserMetadataKey),
+	})
+	if err != nil && !auth.IsUnauthorized(err) {
+		logrus.WithField("err", err).Error("Error resolving request user")
+		return nil, status.Error(codes.Internal, "Error resolving user")
+	} else if err != nil {
+		logrus.WithField("err", err).Info("Invalid authorization")
+		return nil, status.Error(codes.Unauthenticated, "Unauthorized")
+	}
+
+	if identity.IsNew() {
+		if authorizedMethods[info.FullMethod] {
+			return nil, status.Error(codes.Unauthenticated, "Unauthorized")
+		}
+		encoded, err := cookies.GetEncodedValue(identity.UserID)
+		if err == nil {
+			err = grpc.SetHeader(ctx, metadata.Pairs(UserMetadataKey, encoded))
+		}
+		if err != nil {
+			logrus.WithField("err", err).Error("Failed to set user metadata")
+		}
+	}
+
+	return handler(auth.WithIdentity(ctx, identity), req)
+}
+
+// loggingInterceptor логирует входящие запросы и результат их обработки
+func loggingInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+
+	logrus.WithField("method", info.FullMethod).Info("Incoming gRPC request")
+
+	resp, err := handler(ctx, req)
+
+	logrus.WithFields(logrus.Fields{
+		"method":   info.FullMethod,
+		"code":     status.Code(err).String(),
+		"duration": time.Since(start).String(),
+	}).Info("Outgoing gRPC response")
+
+	return resp, err
+}
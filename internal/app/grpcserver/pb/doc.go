@@ -0,0 +1,4 @@
+// Package pb содержит сгенерированный код gRPC API сервиса сокращения URL.
+package pb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative shortener.proto
@@ -0,0 +1,346 @@
+// Package grpcserver предоставляет gRPC API сервиса сокращения URL.
+// Сервер использует то же хранилище, канал удаления и правила определения пользователя, что и HTTP-обработчики.
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/attempts"
+	"github.com/TimBerk/go-link-shortener/internal/app/config"
+	"github.com/TimBerk/go-link-shortener/internal/app/grpcserver/pb"
+	"github.com/TimBerk/go-link-shortener/internal/app/middlewares/auth"
+	"github.com/TimBerk/go-link-shortener/internal/app/models/batch"
+	"github.com/TimBerk/go-link-shortener/internal/app/store"
+	"github.com/TimBerk/go-link-shortener/internal/pkg/jwtauth"
+)
+
+// Server - реализация gRPC-сервиса Shortener
+type Server struct {
+	pb.UnimplementedShortenerServer
+
+	store     store.Store
+	cfg       *config.Config
+	urlChan   chan store.URLPair
+	clickChan chan<- store.Click
+	attempts  *attempts.Limiter
+	tokens    *jwtauth.Manager
+	admins    auth.Admins
+}
+
+// Option задает дополнительные параметры сервера
+type Option func(*Server)
+
+// WithClickChan задает канал, в который сервер отправляет переходы по ссылкам для аналитики
+func WithClickChan(clickChan chan<- store.Click) Option {
+	return func(s *Server) {
+		s.clickChan = clickChan
+	}
+}
+
+// WithPasswordAttempts задает ограничитель неверных паролей, общий с HTTP-обработчиками
+func WithPasswordAttempts(limiter *attempts.Limiter) Option {
+	return func(s *Server) {
+		s.attempts = limiter
+	}
+}
+
+// WithTokens задает менеджер токенов для метаданных authorization
+func WithTokens(tokens *jwtauth.Manager) Option {
+	return func(s *Server) {
+		s.tokens = tokens
+	}
+}
+
+// NewServer создает реализацию сервиса на основании переданных настроек
+func NewServer(store store.Store, cfg *config.Config, urlChan chan store.URLPair, opts ...Option) *Server {
+	s := &Server{
+		store:    store,
+		cfg:      cfg,
+		urlChan:  urlChan,
+		attempts: attempts.New(attempts.DefaultLimit, attempts.DefaultClientLimit, attempts.DefaultLockout),
+		admins:   auth.NewAdmins(cfg.Admins()...),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// New создает gRPC-сервер с зарегистрированным сервисом Shortener
+func New(store store.Store, cfg *config.Config, urlChan chan store.URLPair, opts ...Option) *grpc.Server {
+	shortener := NewServer(store, cfg, urlChan, opts...)
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(loggingInterceptor, shortener.userInterceptor))
+	pb.RegisterShortenerServer(server, shortener)
+	return server
+}
+
+// shortURL формирует короткую ссылку по коду так же, как HTTP API
+func (s *Server) shortURL(code string) string {
+	return fmt.Sprintf("http://%s/%s", s.cfg.ServerAddress, code)
+}
+
+// linkOptions проверяет параметры ссылки и возвращает их в формате хранилища
+func linkOptions(opts *pb.LinkOptions, now time.Time) (store.LinkOptions, error) {
+	if opts == nil {
+		return store.LinkOptions{}, nil
+	}
+
+	if opts.GetAlias() != "" {
+		if err := store.ValidateAlias(opts.GetAlias()); err != nil {
+			return store.LinkOptions{}, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	var expiresAt *time.Time
+	if opts.GetExpiresAt() != nil {
+		t := opts.GetExpiresAt().AsTime()
+		expiresAt = &t
+	}
+	resolved, err := store.ResolveExpiration(expiresAt, opts.GetTtl(), now)
+	if err != nil {
+		return store.LinkOptions{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	maxClicks := int(opts.GetMaxClicks())
+	if err := store.ValidateMaxClicks(maxClicks); err != nil {
+		return store.LinkOptions{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	var passwordHash string
+	if opts.GetPassword() != "" {
+		passwordHash, err = store.HashPassword(opts.GetPassword())
+		if errors.Is(err, store.ErrInvalidPassword) {
+			return store.LinkOptions{}, status.Error(codes.InvalidArgument, err.Error())
+		} else if err != nil {
+			return store.LinkOptions{}, status.Error(codes.Internal, "Error saving link")
+		}
+	}
+
+	return store.LinkOptions{
+		Alias:        opts.GetAlias(),
+		ExpiresAt:    resolved,
+		MaxClicks:    maxClicks,
+		PasswordHash: passwordHash,
+	}, nil
+}
+
+// Shorten сокращает URL, для URL без параметров возвращает уже существующую ссылку с признаком exists
+func (s *Server) Shorten(ctx context.Context, req *pb.ShortenRequest) (*pb.ShortenResponse, error) {
+	if req.GetUrl() == "" {
+		return nil, status.Error(codes.InvalidArgument, "Empty URL")
+	}
+
+	userID := auth.UserID(ctx)
+	opts, err := linkOptions(req.GetOptions(), time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if !opts.IsZero() {
+		code, err := s.store.AddLink(ctx, req.GetUrl(), opts, userID)
+		if errors.Is(err, store.ErrAliasTaken) {
+			return nil, status.Error(codes.AlreadyExists, "Alias is already taken")
+		} else if err != nil {
+			logrus.WithField("err", err).Error("Error saving link")
+			return nil, status.Error(codes.Internal, "Error saving link")
+		}
+		return &pb.ShortenResponse{Result: s.shortURL(code)}, nil
+	}
+
+	code, err := s.store.AddURL(ctx, req.GetUrl(), userID)
+	existLink := errors.Is(err, store.ErrLinkExist)
+	if err != nil && !existLink {
+		logrus.WithField("err", err).Error("Error getting url")
+		return nil, status.Error(codes.Internal, "Error getting url")
+	}
+	return &pb.ShortenResponse{Result: s.shortURL(code), Exists: existLink}, nil
+}
+
+// ShortenBatch сокращает несколько URL за один запрос
+func (s *Server) ShortenBatch(ctx context.Context, req *pb.ShortenBatchRequest) (*pb.ShortenBatchResponse, error) {
+	if len(req.GetItems()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Empty batch")
+	}
+
+	now := time.Now()
+	items := make(batch.BatchRequest, 0, len(req.GetItems()))
+	for _, item := range req.GetItems() {
+		opts, err := linkOptions(item.GetOptions(), now)
+		if err != nil {
+			return nil, err
+		}
+		batchItem := batch.ItemRequest{
+			CorrelationID: item.GetCorrelationId(),
+			OriginalURL:   item.GetOriginalUrl(),
+			Alias:         opts.Alias,
+			MaxClicks:     opts.MaxClicks,
+			PasswordHash:  opts.PasswordHash,
+		}
+		if !opts.ExpiresAt.IsZero() {
+			batchItem.ExpiresAt = &opts.ExpiresAt
+		}
+		items = append(items, batchItem)
+	}
+
+	results, err := s.store.AddURLs(ctx, items, auth.UserID(ctx))
+	if errors.Is(err, store.ErrAliasTaken) {
+		return nil, status.Error(codes.AlreadyExists, "Alias is already taken")
+	} else if err != nil {
+		logrus.WithField("err", err).Error("Error shortening URLs")
+		return nil, status.Errorf(codes.Internal, "Error shortening URLs: %v", err)
+	}
+
+	response := &pb.ShortenBatchResponse{Items: make([]*pb.ShortenBatchResult, 0, len(results))}
+	for _, result := range results {
+		response.Items = append(response.Items, &pb.ShortenBatchResult{
+			CorrelationId: result.CorrelationID,
+			ShortUrl:      s.shortURL(result.ShortURL),
+		})
+	}
+	return response, nil
+}
+
+// Expand возвращает оригинальный URL по короткой ссылке по тем же правилам, что и перенаправление HTTP API:
+// для защищенной ссылки проверяется пароль, переход уменьшает лимит переходов и учитывается в аналитике
+func (s *Server) Expand(ctx context.Context, req *pb.ExpandRequest) (*pb.ExpandResponse, error) {
+	code := req.GetShortUrl()
+	if i := strings.LastIndex(code, "/"); i >= 0 {
+		code = code[i+1:]
+	}
+	if code == "" {
+		return nil, status.Error(codes.InvalidArgument, "Empty short URL")
+	}
+
+	userID := auth.UserID(ctx)
+	originalURL, exists, isDeleted := s.store.GetOriginalURL(ctx, code, userID)
+	if !exists {
+		return nil, status.Error(codes.NotFound, "Short URL not found")
+	} else if isDeleted {
+		return nil, status.Error(codes.FailedPrecondition, "Short URL is deleted")
+	}
+
+	if err := s.unlockLink(ctx, code, req.GetPassword()); err != nil {
+		return nil, err
+	}
+
+	clickResult, err := s.store.UseClick(ctx, code)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "Failed to use short URL")
+	} else if clickResult == store.ClickExhausted {
+		return nil, status.Error(codes.FailedPrecondition, "Short URL click limit is exhausted")
+	}
+
+	s.recordClick(ctx, code, userID)
+
+	return &pb.ExpandResponse{OriginalUrl: originalURL}, nil
+}
+
+// unlockLink проверяет пароль защищенной ссылки с учетом ограничения неверных попыток
+func (s *Server) unlockLink(ctx context.Context, code string, password string) error {
+	passwordHash, err := s.store.GetPasswordHash(ctx, code)
+	if err != nil {
+		return status.Error(codes.Internal, "Failed to check short URL password")
+	}
+	if passwordHash == "" {
+		return nil
+	}
+
+	client := attempts.Client(auth.FromContext(ctx), peerIP(ctx))
+	if _, ok := s.attempts.Blocked(code, client); ok {
+		return status.Error(codes.ResourceExhausted, "Too many attempts, try again later")
+	}
+	if !store.CheckPassword(passwordHash, password) {
+		s.attempts.Fail(code, client)
+		logrus.WithField("shortUri", code).Info("Invalid short URL password")
+		return status.Error(codes.PermissionDenied, "Invalid password")
+	}
+
+	s.attempts.Reset(code, client)
+	return nil
+}
+
+// recordClick отправляет переход в канал аналитики, не блокируя запрос
+func (s *Server) recordClick(ctx context.Context, code string, visitorID string) {
+	if s.clickChan == nil {
+		return
+	}
+
+	click := store.Click{
+		ShortURL:  code,
+		VisitorID: visitorID,
+		ClickedAt: time.Now(),
+	}
+	if values := metadata.ValueFromIncomingContext(ctx, "user-agent"); len(values) > 0 {
+		click.UserAgent = values[0]
+	}
+	click.IP = peerIP(ctx)
+	select {
+	case s.clickChan <- click:
+	default:
+		logrus.WithField("shortUri", code).Warning("Click channel is full, click is dropped")
+	}
+}
+
+// peerIP возвращает адрес клиента из соединения gRPC
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+		return host
+	}
+	return p.Addr.String()
+}
+
+// ListUserURLs возвращает не удаленные ссылки пользователя
+func (s *Server) ListUserURLs(ctx context.Context, _ *pb.ListUserURLsRequest) (*pb.ListUserURLsResponse, error) {
+	urls, err := s.store.GetUserURLs(ctx, auth.UserID(ctx))
+	if err != nil {
+		logrus.WithField("err", err).Error("Failed to get user URLs")
+		return nil, status.Error(codes.Internal, "Failed to get user URLs")
+	}
+
+	response := &pb.ListUserURLsResponse{Urls: make([]*pb.UserURL, 0, len(urls))}
+	for _, url := range urls {
+		response.Urls = append(response.Urls, &pb.UserURL{
+			ShortUrl:    s.shortURL(url.ShortURL),
+			OriginalUrl: url.OriginalURL,
+		})
+	}
+	return response, nil
+}
+
+// DeleteUserURLs передает ссылки пользователя в канал удаления
+func (s *Server) DeleteUserURLs(ctx context.Context, req *pb.DeleteUserURLsRequest) (*pb.DeleteUserURLsResponse, error) {
+	userID := auth.UserID(ctx)
+	for _, shortURL := range req.GetShortUrls() {
+		logrus.WithFields(logrus.Fields{
+			"shortURL": shortURL,
+			"UserID":   userID,
+		}).Info("Deleted user link")
+		s.urlChan <- store.URLPair{ShortURL: shortURL, UserID: userID}
+	}
+	return &pb.DeleteUserURLsResponse{}, nil
+}
+
+// Ping проверяет подключение к хранилищу
+func (s *Server) Ping(ctx context.Context, _ *pb.PingRequest) (*pb.PingResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := s.store.Ping(ctx); err != nil {
+		logrus.WithField("err", err).Error("Check connection to DB")
+		return nil, status.Error(codes.Unavailable, "failed to check connection to DB")
+	}
+	return &pb.PingResponse{}, nil
+}
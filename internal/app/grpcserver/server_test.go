@@ -0,0 +1,246 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/config"
+	"github.com/TimBerk/go-link-shortener/internal/app/grpcserver/pb"
+	"github.com/TimBerk/go-link-shortener/internal/app/store"
+	"github.com/TimBerk/go-link-shortener/internal/app/store/local"
+	"github.com/TimBerk/go-link-shortener/internal/pkg/cookies"
+	"github.com/TimBerk/go-link-shortener/internal/pkg/jwtauth"
+)
+
+// newTestClient запускает сервер с хранилищем и возвращает клиента и канал удаления
+func newTestClient(t *testing.T, dataStore store.Store, opts ...Option) (pb.ShortenerClient, chan store.URLPair) {
+	t.Helper()
+
+	urlChan := make(chan store.URLPair, 10)
+	server := New(dataStore, config.NewConfig("localhost:8080", "http://localhost:8080", true), urlChan, opts...)
+
+	listener := bufconn.Listen(1024 * 1024)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return pb.NewShortenerClient(conn), urlChan
+}
+
+// newMemoryStore создает хранилище в памяти
+func newMemoryStore(t *testing.T) store.Store {
+	t.Helper()
+
+	dataStore, err := local.NewURLStore(store.NewIDGenerator())
+	require.NoError(t, err)
+	return dataStore
+}
+
+// userContext возвращает контекст с пользователем в метаданных
+func userContext(t *testing.T, userID string) context.Context {
+	t.Helper()
+
+	encoded, err := cookies.GetEncodedValue(userID)
+	require.NoError(t, err)
+	return metadata.AppendToOutgoingContext(context.Background(), UserMetadataKey, encoded)
+}
+
+func TestShorten(t *testing.T) {
+	client, _ := newTestClient(t, newMemoryStore(t))
+	ctx := userContext(t, "user-1")
+
+	first, err := client.Shorten(ctx, &pb.ShortenRequest{Url: "https://example.com"})
+	require.NoError(t, err)
+	assert.False(t, first.GetExists())
+	assert.Regexp(t, `^http://localhost:8080/\w+$`, first.GetResult())
+
+	second, err := client.Shorten(ctx, &pb.ShortenRequest{Url: "https://example.com"})
+	require.NoError(t, err)
+	assert.True(t, second.GetExists(), "Повторное сокращение должно вернуть существующую ссылку")
+	assert.Equal(t, first.GetResult(), second.GetResult())
+
+	tests := []struct {
+		name         string
+		request      *pb.ShortenRequest
+		expectedCode codes.Code
+		expectedURL  string
+	}{
+		{
+			name:         "Alias",
+			request:      &pb.ShortenRequest{Url: "https://example.com", Options: &pb.LinkOptions{Alias: "my-link"}},
+			expectedCode: codes.OK,
+			expectedURL:  "http://localhost:8080/my-link",
+		},
+		{
+			name:         "Taken alias",
+			request:      &pb.ShortenRequest{Url: "https://example.org", Options: &pb.LinkOptions{Alias: "my-link"}},
+			expectedCode: codes.AlreadyExists,
+		},
+		{
+			name:         "Invalid ttl",
+			request:      &pb.ShortenRequest{Url: "https://example.com", Options: &pb.LinkOptions{Ttl: "soon"}},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:         "Empty URL",
+			request:      &pb.ShortenRequest{},
+			expectedCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response, err := client.Shorten(ctx, test.request)
+
+			assert.Equal(t, test.expectedCode, status.Code(err))
+			assert.Equal(t, test.expectedURL, response.GetResult())
+		})
+	}
+}
+
+func TestShorten_NewUser(t *testing.T) {
+	client, _ := newTestClient(t, newMemoryStore(t))
+
+	var header metadata.MD
+	response, err := client.Shorten(context.Background(), &pb.ShortenRequest{Url: "https://example.com"}, grpc.Header(&header))
+	require.NoError(t, err)
+
+	values := header.Get(UserMetadataKey)
+	require.Len(t, values, 1, "Сервер должен вернуть нового пользователя в заголовке")
+	userID, err := cookies.DecodeUserID(values[0])
+	require.NoError(t, err)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), UserMetadataKey, values[0])
+	urls, err := client.ListUserURLs(ctx, &pb.ListUserURLsRequest{})
+	require.NoError(t, err)
+	require.Len(t, urls.GetUrls(), 1, "Ссылка должна принадлежать пользователю %s", userID)
+	assert.Equal(t, response.GetResult(), urls.GetUrls()[0].GetShortUrl())
+	assert.Equal(t, "https://example.com", urls.GetUrls()[0].GetOriginalUrl())
+}
+
+func TestShortenBatch(t *testing.T) {
+	client, _ := newTestClient(t, newMemoryStore(t))
+	ctx := userContext(t, "user-1")
+
+	response, err := client.ShortenBatch(ctx, &pb.ShortenBatchRequest{Items: []*pb.ShortenBatchItem{
+		{CorrelationId: "1", OriginalUrl: "https://example.com"},
+		{CorrelationId: "2", OriginalUrl: "https://example.org", Options: &pb.LinkOptions{Alias: "org"}},
+	}})
+	require.NoError(t, err)
+	require.Len(t, response.GetItems(), 2)
+	assert.Equal(t, "1", response.GetItems()[0].GetCorrelationId())
+	assert.Equal(t, "2", response.GetItems()[1].GetCorrelationId())
+	assert.Equal(t, "http://localhost:8080/org", response.GetItems()[1].GetShortUrl())
+
+	_, err = client.ShortenBatch(ctx, &pb.ShortenBatchRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestExpand(t *testing.T) {
+	client, _ := newTestClient(t, newMemoryStore(t))
+	ctx := userContext(t, "user-1")
+
+	_, err := client.Shorten(ctx, &pb.ShortenRequest{Url: "https://example.com", Options: &pb.LinkOptions{Alias: "plain"}})
+	require.NoError(t, err)
+	_, err = client.Shorten(ctx, &pb.ShortenRequest{Url: "https://example.org", Options: &pb.LinkOptions{Alias: "secret", Password: "s3cret"}})
+	require.NoError(t, err)
+	_, err = client.Shorten(ctx, &pb.ShortenRequest{Url: "https://example.net", Options: &pb.LinkOptions{Alias: "once", MaxClicks: 1}})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		request      *pb.ExpandRequest
+		expectedCode codes.Code
+		expectedURL  string
+	}{
+		{name: "Code", request: &pb.ExpandRequest{ShortUrl: "plain"}, expectedCode: codes.OK, expectedURL: "https://example.com"},
+		{name: "Full short URL", request: &pb.ExpandRequest{ShortUrl: "http://localhost:8080/plain"}, expectedCode: codes.OK, expectedURL: "https://example.com"},
+		{name: "Not found", request: &pb.ExpandRequest{ShortUrl: "missing"}, expectedCode: codes.NotFound},
+		{name: "Wrong password", request: &pb.ExpandRequest{ShortUrl: "secret", Password: "secret"}, expectedCode: codes.PermissionDenied},
+		{name: "Valid password", request: &pb.ExpandRequest{ShortUrl: "secret", Password: "s3cret"}, expectedCode: codes.OK, expectedURL: "https://example.org"},
+		{name: "One-time link", request: &pb.ExpandRequest{ShortUrl: "once"}, expectedCode: codes.OK, expectedURL: "https://example.net"},
+		{name: "Exhausted one-time link", request: &pb.ExpandRequest{ShortUrl: "once"}, expectedCode: codes.FailedPrecondition},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response, err := client.Expand(ctx, test.request)
+
+			assert.Equal(t, test.expectedCode, status.Code(err))
+			assert.Equal(t, test.expectedURL, response.GetOriginalUrl())
+		})
+	}
+}
+
+func TestDeleteUserURLs(t *testing.T) {
+	client, urlChan := newTestClient(t, newMemoryStore(t))
+
+	_, err := client.DeleteUserURLs(context.Background(), &pb.DeleteUserURLsRequest{ShortUrls: []string{"abc123"}})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "Удаление без пользователя должно отклоняться")
+
+	_, err = client.DeleteUserURLs(userContext(t, "user-1"), &pb.DeleteUserURLsRequest{ShortUrls: []string{"abc123"}})
+	require.NoError(t, err)
+	assert.Equal(t, store.URLPair{ShortURL: "abc123", UserID: "user-1"}, <-urlChan)
+}
+
+func TestPing(t *testing.T) {
+	client, _ := newTestClient(t, newMemoryStore(t))
+
+	_, err := client.Ping(context.Background(), &pb.PingRequest{})
+
+	assert.NoError(t, err)
+}
+
+func TestBearerToken(t *testing.T) {
+	tokens, err := jwtauth.NewManager(jwtauth.Options{Secret: "0123456789abcdef0123456789abcdef"})
+	require.NoError(t, err)
+	token, _, err := tokens.Issue("user-1")
+	require.NoError(t, err)
+
+	dataStore, err := local.NewURLStore(store.NewIDGenerator())
+	require.NoError(t, err)
+	_, err = dataStore.AddURL(context.Background(), "https://example.com", "user-1")
+	require.NoError(t, err)
+	client, _ := newTestClient(t, dataStore, WithTokens(tokens))
+
+	tests := []struct {
+		name          string
+		authorization string
+		expectedCode  codes.Code
+		expectedURLs  int
+	}{
+		{name: "Valid token", authorization: "Bearer " + token, expectedCode: codes.OK, expectedURLs: 1},
+		{name: "Invalid token", authorization: "Bearer broken", expectedCode: codes.Unauthenticated},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", test.authorization)
+
+			response, err := client.ListUserURLs(ctx, &pb.ListUserURLsRequest{})
+
+			assert.Equal(t, test.expectedCode, status.Code(err))
+			assert.Len(t, response.GetUrls(), test.expectedURLs)
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/middlewares/auth"
+	"github.com/TimBerk/go-link-shortener/internal/app/store"
+	"github.com/TimBerk/go-link-shortener/internal/pkg/cookies"
+	"github.com/TimBerk/go-link-shortener/internal/pkg/utils"
+)
+
+// unknownAccountHash - bcrypt-хеш, с которым сравнивается пароль при входе в отсутствующую учетную запись,
+// чтобы время ответа не выдавало существование логина
+const unknownAccountHash = "$2a$10$s5rGmupIIPZaZu6U0gw38.M5ze/KJIV41spHvaw6yHx.DBdv7Syv2"
+
+// CredentialsRequest запрос на регистрацию или вход
+// swagger:model
+type CredentialsRequest struct {
+	Login    string `json:"login" example:"alice"`
+	Password string `json:"password" example:"correct horse battery staple"`
+}
+
+// ClaimResponse результат передачи ссылок анонимного пользователя учетной записи
+// swagger:model
+type ClaimResponse struct {
+	Claimed int `json:"claimed" example:"3"`
+}
+
+// startSession создает сессию пользователя и устанавливает cookie session
+func (h *Handler) startSession(w http.ResponseWriter, userID string) error {
+	session, token, err := store.NewSession(userID, time.Now(), cmp.Or(h.cfg.SessionTTL, store.DefaultSessionTTL))
+	if err != nil {
+		return err
+	}
+	if err := h.store.AddSession(h.ctx, session); err != nil {
+		return err
+	}
+	cookies.SetSessionCookie(w, token, session.ExpiresAt, h.cfg.EnableHTTPS)
+	return nil
+}
+
+// writeAccount отправляет учетную запись без хеша пароля
+func writeAccount(w http.ResponseWriter, account store.Account, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if errResponse := json.NewEncoder(w).Encode(account); errResponse != nil {
+		logrus.WithField("err", errResponse).Error("Failed to response account")
+	}
+}
+
+// RegisterHandler создает учетную запись и открывает для нее сессию
+// @Summary Зарегистрироваться
+// @Description Создает учетную запись с новым ID пользователя и устанавливает cookie session.
+// @Description Ссылки анонимного пользователя передаются учетной записи отдельным запросом /api/user/claim.
+// @Accept  json
+// @Produce json
+// @Param   request body CredentialsRequest true "Логин и пароль"
+// @Success 201 {object} store.Account "Созданная учетная запись"
+// @Failure 400 {object} ErrorResponse "Неверный логин или пароль"
+// @Failure 409 {object} ErrorResponse "Логин занят"
+// @Failure 500 {object} ErrorResponse "Ошибка создания учетной записи"
+// @Router /api/user/register [post]
+func (h *Handler) RegisterHandler(w http.ResponseWriter, r *http.Request) {
+	var request CredentialsRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		utils.WriteJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	account, err := store.NewAccount(request.Login, request.Password, time.Now())
+	if errors.Is(err, store.ErrInvalidLogin) || errors.Is(err, store.ErrInvalidPassword) {
+		utils.WriteJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		logrus.WithField("err", err).Error("Failed to create account")
+		utils.WriteJSONError(w, "Failed to create account", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.store.CreateAccount(h.ctx, account)
+	if errors.Is(err, store.ErrAccountExists) {
+		utils.WriteJSONError(w, "Login is already taken", http.StatusConflict)
+		return
+	} else if err != nil {
+		logrus.WithField("err", err).Error("Failed to save account")
+		utils.WriteJSONError(w, "Failed to create account", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.startSession(w, account.ID); err != nil {
+		logrus.WithField("err", err).Error("Failed to start session")
+		utils.WriteJSONError(w, "Failed to start session", http.StatusInternalServerError)
+		return
+	}
+
+	logrus.WithField("UserID", account.ID).Info("Registered account")
+	writeAccount(w, account, http.StatusCreated)
+}
+
+// LoginHandler проверяет логин и пароль и открывает сессию учетной записи
+// @Summary Войти
+// @Description Проверяет логин и пароль и устанавливает cookie session.
+// @Description После нескольких неверных паролей вход в учетную запись временно блокируется.
+// @Accept  json
+// @Produce json
+// @Param   request body CredentialsRequest true "Логин и пароль"
+// @Success 200 {object} store.Account "Учетная запись"
+// @Failure 400 {object} ErrorResponse "Неверный запрос"
+// @Failure 401 {object} ErrorResponse "Неверный логин или пароль"
+// @Failure 429 {object} ErrorResponse "Слишком много неверных попыток"
+// @Failure 500 {object} ErrorResponse "Ошибка входа"
+// @Router /api/user/login [post]
+func (h *Handler) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	var request CredentialsRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		utils.WriteJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	login := store.NormalizeLogin(request.Login)
+	client := h.attemptsClient(r)
+	if left, ok := h.loginAttempts.Blocked(login, client); ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int((left+time.Second-1)/time.Second)))
+		utils.WriteJSONError(w, "Too many attempts, try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	account, err := h.store.GetAccount(h.ctx, login)
+	if err != nil && !errors.Is(err, store.ErrAccountNotFound) {
+		logrus.WithField("err", err).Error("Failed to get account")
+		utils.WriteJSONError(w, "Failed to log in", http.StatusInternalServerError)
+		return
+	}
+	passwordHash := account.PasswordHash
+	if err != nil {
+		passwordHash = unknownAccountHash
+	}
+	if !store.CheckPassword(passwordHash, request.Password) || err != nil {
+		h.loginAttempts.Fail(login, client)
+		logrus.WithField("login", login).Info("Invalid account credentials")
+		utils.WriteJSONError(w, "Invalid login or password", http.StatusUnauthorized)
+		return
+	}
+	h.loginAttempts.Reset(login, client)
+
+	if err := h.startSession(w, account.ID); err != nil {
+		logrus.WithField("err", err).Error("Failed to start session")
+		utils.WriteJSONError(w, "Failed to start session", http.StatusInternalServerError)
+		return
+	}
+
+	logrus.WithField("UserID", account.ID).Info("Logged in account")
+	writeAccount(w, account, http.StatusOK)
+}
+
+// LogoutHandler завершает сессию учетной записи
+// @Summary Выйти
+// @Description Удаляет сессию из cookie session на сервере и в браузере
+// @Success 204 "Сессия завершена"
+// @Failure 500 {object} ErrorResponse "Ошибка завершения сессии"
+// @Router /api/user/logout [post]
+func (h *Handler) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(cookies.SessionCookieName); err == nil && cookie.Value != "" {
+		if err := h.store.DeleteSession(h.ctx, store.HashSessionToken(cookie.Value)); err != nil {
+			logrus.WithField("err", err).Error("Failed to delete session")
+			utils.WriteJSONError(w, "Failed to log out", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	cookies.ClearSessionCookie(w, h.cfg.EnableHTTPS)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ClaimURLsHandler передает учетной записи ссылки анонимного пользователя из cookie user
+// @Summary Забрать анонимные ссылки
+// @Description Передает учетной записи текущей сессии все не удаленные ссылки пользователя из cookie user
+// @Produce json
+// @Success 200 {object} ClaimResponse "Количество переданных ссылок"
+// @Failure 400 {object} ErrorResponse "Нет cookie анонимного пользователя"
+// @Failure 401 {string} string "Нет сессии учетной записи"
+// @Failure 500 {object} ErrorResponse "Ошибка передачи ссылок"
+// @Router /api/user/claim [post]
+func (h *Handler) ClaimURLsHandler(w http.ResponseWriter, r *http.Request) {
+	identity := auth.FromContext(r.Context())
+	if identity.Source != auth.SourceSession {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	anonymousID, err := cookies.GetUserID(r)
+	if err != nil {
+		utils.WriteJSONError(w, "No anonymous user to claim", http.StatusBadRequest)
+		return
+	}
+
+	claimed, err := h.store.ClaimURLs(h.ctx, anonymousID, identity.UserID)
+	if err != nil {
+		logrus.WithField("err", err).Error("Failed to claim URLs")
+		utils.WriteJSONError(w, "Failed to claim URLs", http.StatusInternalServerError)
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"anonymousID": anonymousID,
+		"UserID":      identity.UserID,
+		"claimed":     claimed,
+	}).Info("Claimed anonymous user links")
+
+	w.Header().Set("Content-Type", "application/json")
+	if errResponse := json.NewEncoder(w).Encode(ClaimResponse{Claimed: claimed}); errResponse != nil {
+		logrus.WithField("err", errResponse).Error("Failed to response claimed URLs")
+	}
+}
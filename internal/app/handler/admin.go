@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/middlewares/auth"
+	"github.com/TimBerk/go-link-shortener/internal/app/store"
+	"github.com/TimBerk/go-link-shortener/internal/pkg/utils"
+)
+
+var (
+	// errInvalidLimit ошибка о недопустимом размере страницы поиска
+	errInvalidLimit = fmt.Errorf("limit must be from 1 to %d", store.MaxSearchLimit)
+	// errInvalidOffset ошибка о недопустимом смещении поиска
+	errInvalidOffset = errors.New("offset must be a non-negative number")
+)
+
+// parseLinkSearch разбирает параметры поиска ссылок query, limit и offset.
+// Без limit возвращается страница из store.DefaultSearchLimit ссылок.
+func parseLinkSearch(values url.Values) (store.LinkSearch, error) {
+	search := store.LinkSearch{Query: values.Get("query"), Limit: store.DefaultSearchLimit}
+	if value := values.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > store.MaxSearchLimit {
+			return store.LinkSearch{}, errInvalidLimit
+		}
+		search.Limit = limit
+	}
+	if value := values.Get("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return store.LinkSearch{}, errInvalidOffset
+		}
+		search.Offset = offset
+	}
+	return search, nil
+}
+
+// AdminURLsHandler ищет ссылки всех пользователей
+// @Summary Найти ссылки всех пользователей
+// @Description Доступно только администраторам. Возвращает ссылки, короткий идентификатор или оригинальный URL
+// @Description которых содержит query без учета регистра, включая удаленные. Ссылки упорядочены по короткому идентификатору.
+// @Produce json
+// @Param   query query string false "Подстрока короткого идентификатора или оригинального URL"
+// @Param   limit query int false "Размер страницы, от 1 до 1000, по умолчанию 100"
+// @Param   offset query int false "Количество пропускаемых ссылок"
+// @Success 200 {array} store.Link "Страница найденных ссылок"
+// @Failure 400 {object} ErrorResponse "Неверные параметры поиска"
+// @Failure 401 {string} string "Пользователь не авторизован"
+// @Failure 403 {string} string "Пользователь не администратор"
+// @Failure 500 {object} ErrorResponse "Ошибка поиска ссылок"
+// @Router /api/admin/urls [get]
+func (h *Handler) AdminURLsHandler(w http.ResponseWriter, r *http.Request) {
+	search, err := parseLinkSearch(r.URL.Query())
+	if err != nil {
+		utils.WriteJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	links, err := h.store.SearchURLs(h.ctx, search)
+	if err != nil {
+		logrus.WithField("err", err).Error("Failed to search URLs")
+		utils.WriteJSONError(w, "Failed to search URLs", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if errResponse := json.NewEncoder(w).Encode(links); errResponse != nil {
+		logrus.WithField("err", errResponse).Error("Failed to response URLs search")
+	}
+}
+
+// AdminDisableURLHandler отключает ссылку любого пользователя
+// @Summary Отключить ссылку
+// @Description Доступно только администраторам. Помечает ссылку как удаленную независимо от владельца,
+// @Description после чего переход по ней возвращает 410.
+// @Param   id path string true "Короткий идентификатор URL"
+// @Success 204 "Ссылка отключена"
+// @Failure 401 {string} string "Пользователь не авторизован"
+// @Failure 403 {string} string "Пользователь не администратор"
+// @Failure 404 {object} ErrorResponse "Ссылка не найдена"
+// @Failure 500 {object} ErrorResponse "Ошибка отключения ссылки"
+// @Router /api/admin/urls/{id} [delete]
+func (h *Handler) AdminDisableURLHandler(w http.ResponseWriter, r *http.Request) {
+	shortURL := chi.URLParam(r, "id")
+	err := h.store.DisableURL(h.ctx, shortURL)
+	if errors.Is(err, store.ErrLinkNotFound) {
+		utils.WriteJSONError(w, "Short URL not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		logrus.WithField("err", err).Error("Failed to disable URL")
+		utils.WriteJSONError(w, "Failed to disable URL", http.StatusInternalServerError)
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"shortURL": shortURL,
+		"AdminID":  auth.UserID(r.Context()),
+	}).Info("Disabled link by admin")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AdminUserURLsHandler возвращает ссылки выбранного пользователя
+// @Summary Получить URL пользователя для администратора
+// @Description Доступно только администраторам. Возвращает сокращенные URL пользователя userID в том же формате,
+// @Description что и /api/user/urls для самого пользователя.
+// @Produce json
+// @Param   userID path string true "ID пользователя"
+// @Success 200 {array} store.UserURL "Массив URL пользователя"
+// @Success 204 "Нет сохраненных URL"
+// @Failure 401 {string} string "Пользователь не авторизован"
+// @Failure 403 {string} string "Пользователь не администратор"
+// @Failure 500 {object} ErrorResponse "Ошибка получения URL"
+// @Router /api/admin/users/{userID}/urls [get]
+func (h *Handler) AdminUserURLsHandler(w http.ResponseWriter, r *http.Request) {
+	urls, err := h.store.GetUserURLs(h.ctx, chi.URLParam(r, "userID"))
+	if err != nil {
+		logrus.WithField("err", err).Error("Failed to get user URLs")
+		utils.WriteJSONError(w, "Failed to get user URLs", http.StatusInternalServerError)
+		return
+	}
+	if len(urls) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	for i := range urls {
+		urls[i].ShortURL = fmt.Sprintf("http://%s/%s", h.cfg.ServerAddress, urls[i].ShortURL)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if errResponse := json.NewEncoder(w).Encode(urls); errResponse != nil {
+		logrus.WithField("err", errResponse).Error("Failed to response user URLs")
+	}
+}
@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/middlewares/auth"
+	"github.com/TimBerk/go-link-shortener/internal/app/store"
+	"github.com/TimBerk/go-link-shortener/internal/pkg/utils"
+)
+
+// APIKeyRequest запрос на создание ключа API
+// swagger:model
+type APIKeyRequest struct {
+	Name string `json:"name" example:"ci"`
+}
+
+// APIKeyResponse созданный ключ API. Значение Key возвращается только при создании.
+// swagger:model
+type APIKeyResponse struct {
+	store.APIKey
+	Key string `json:"key" example:"sk_Zm9vYmFyYmF6..."`
+}
+
+// CreateAPIKeyHandler создает ключ API пользователя
+// @Summary Создать ключ API
+// @Description Создает именованный ключ API для заголовка X-API-Key. Значение ключа возвращается только в этом ответе.
+// @Accept  json
+// @Produce json
+// @Param   request body APIKeyRequest true "Название ключа"
+// @Success 201 {object} APIKeyResponse "Созданный ключ API"
+// @Failure 400 {object} ErrorResponse "Неверный запрос"
+// @Failure 401 {string} string "Пользователь не авторизован"
+// @Failure 500 {object} ErrorResponse "Ошибка создания ключа"
+// @Router /api/user/keys [post]
+func (h *Handler) CreateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.Authenticated(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var request APIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		utils.WriteJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	key, value, err := store.NewAPIKey(userID, request.Name, time.Now())
+	if errors.Is(err, store.ErrInvalidAPIKeyName) {
+		utils.WriteJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		logrus.WithField("err", err).Error("Failed to generate API key")
+		utils.WriteJSONError(w, "Failed to create API key", http.StatusInternalServerError)
+		return
+	}
+	if err := h.store.AddAPIKey(h.ctx, key); err != nil {
+		logrus.WithField("err", err).Error("Failed to save API key")
+		utils.WriteJSONError(w, "Failed to create API key", http.StatusInternalServerError)
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"keyID":  key.ID,
+		"UserID": userID,
+	}).Info("Created user API key")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	if errResponse := json.NewEncoder(w).Encode(APIKeyResponse{APIKey: key, Key: value}); errResponse != nil {
+		logrus.WithField("err", errResponse).Error("Failed to response API key")
+	}
+}
+
+// APIKeysHandler возвращает ключи API пользователя
+// @Summary Получить ключи API
+// @Description Возвращает ключи API текущего пользователя в порядке создания без их значений
+// @Produce json
+// @Success 200 {array} store.APIKey "Ключи API пользователя"
+// @Success 204 "Нет ключей API"
+// @Failure 401 {string} string "Пользователь не авторизован"
+// @Failure 500 {object} ErrorResponse "Ошибка получения ключей"
+// @Router /api/user/keys [get]
+func (h *Handler) APIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.Authenticated(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	keys, err := h.store.GetAPIKeys(h.ctx, userID)
+	if err != nil {
+		logrus.WithField("err", err).Error("Failed to get API keys")
+		utils.WriteJSONError(w, "Failed to get API keys", http.StatusInternalServerError)
+		return
+	}
+	if len(keys) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if errResponse := json.NewEncoder(w).Encode(keys); errResponse != nil {
+		logrus.WithField("err", errResponse).Error("Failed to response API keys")
+	}
+}
+
+// RevokeAPIKeyHandler отзывает ключ API пользователя
+// @Summary Отозвать ключ API
+// @Description Удаляет ключ API текущего пользователя, после чего запросы с ним отклоняются
+// @Param   id path string true "Идентификатор ключа API"
+// @Success 204 "Ключ отозван"
+// @Failure 401 {string} string "Пользователь не авторизован"
+// @Failure 404 {object} ErrorResponse "Ключ API не найден"
+// @Failure 500 {object} ErrorResponse "Ошибка отзыва ключа"
+// @Router /api/user/keys/{id} [delete]
+func (h *Handler) RevokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.Authenticated(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	keyID := chi.URLParam(r, "id")
+	err := h.store.RevokeAPIKey(h.ctx, userID, keyID)
+	if errors.Is(err, store.ErrAPIKeyNotFound) {
+		utils.WriteJSONError(w, "API key not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		logrus.WithField("err", err).Error("Failed to revoke API key")
+		utils.WriteJSONError(w, "Failed to revoke API key", http.StatusInternalServerError)
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"keyID":  keyID,
+		"UserID": userID,
+	}).Info("Revoked user API key")
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/attempts"
+	"github.com/TimBerk/go-link-shortener/internal/app/store"
+	"github.com/TimBerk/go-link-shortener/internal/pkg/jwtauth"
+	"github.com/TimBerk/go-link-shortener/internal/pkg/oidc"
+)
+
+// Option задает дополнительные параметры обработчика
+type Option func(*Handler)
+
+// WithClickChan задает канал, в который обработчик отправляет переходы по ссылкам для аналитики
+func WithClickChan(clickChan chan<- store.Click) Option {
+	return func(h *Handler) {
+		h.clickChan = clickChan
+	}
+}
+
+// WithPasswordAttempts задает ограничитель неверных паролей, общий с другими обработчиками ссылок
+func WithPasswordAttempts(limiter *attempts.Limiter) Option {
+	return func(h *Handler) {
+		h.attempts = limiter
+	}
+}
+
+// WithTokens задает менеджер токенов для заголовка Authorization: Bearer и выпуска токенов
+func WithTokens(tokens *jwtauth.Manager) Option {
+	return func(h *Handler) {
+		h.tokens = tokens
+	}
+}
+
+// WithOIDC задает провайдера OpenID Connect для входа через SSO
+func WithOIDC(provider *oidc.Provider) Option {
+	return func(h *Handler) {
+		h.oidc = provider
+	}
+}
+
+// recordClick отправляет переход по ссылке в канал аналитики, не блокируя перенаправление.
+// Если канал не задан или заполнен, переход не учитывается.
+func (h *Handler) recordClick(r *http.Request, shortURL string, visitorID string) {
+	if h.clickChan == nil {
+		return
+	}
+
+	click := store.Click{
+		ShortURL:  shortURL,
+		VisitorID: visitorID,
+		ClickedAt: time.Now(),
+		Referrer:  r.Referer(),
+		UserAgent: r.UserAgent(),
+		IP:        h.proxies.ClientIP(r),
+	}
+	select {
+	case h.clickChan <- click:
+	default:
+		logrus.WithField("shortUri", shortURL).Warning("Click channel is full, click is dropped")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"net/http/httptest"
 
 	"github.com/stretchr/testify/mock"
@@ -34,7 +35,7 @@ func ExampleHandler_ShortenURL() {
 	rec := httptest.NewRecorder()
 
 	// Вызов обработчика
-	h.ShortenURL(rec, req)
+	h.Identity(http.HandlerFunc(h.ShortenURL)).ServeHTTP(rec, req)
 
 	// Проверка ответа
 	fmt.Printf("Status: %d\n", rec.Code)
@@ -57,7 +58,7 @@ func ExampleHandler_ShortenJSONURL() {
 	rec := httptest.NewRecorder()
 
 	// Вызов обработчика
-	h.ShortenJSONURL(rec, req)
+	h.Identity(http.HandlerFunc(h.ShortenJSONURL)).ServeHTTP(rec, req)
 
 	// Проверка ответа
 	fmt.Printf("Status: %d\n", rec.Code)
@@ -98,7 +99,7 @@ func ExampleHandler_ShortenBatch() {
 	req.AddCookie(testCookie)
 	rec := httptest.NewRecorder()
 
-	h.ShortenBatch(rec, req)
+	h.Identity(http.HandlerFunc(h.ShortenBatch)).ServeHTTP(rec, req)
 
 	fmt.Printf("Status: %d\n", rec.Code)
 	fmt.Printf("Content-Type: %s\n", rec.Header().Get("Content-Type"))
@@ -110,19 +111,22 @@ func ExampleHandler_ShortenBatch() {
 
 func ExampleHandler_UserURLsHandler() {
 	// Добавление тестовых данных
-	addShortURL(exampleUserID, shortURL, originalURL)
+	mockStore.On("GetUserURLs", mock.Anything, exampleUserID).
+		Return([]store.UserURL{{ShortURL: shortCode, OriginalURL: originalURL}}, nil)
 
 	// Подготовка запроса
 	req := httptest.NewRequest("GET", "/api/user/urls", nil)
 	req.AddCookie(testCookie)
 	rec := httptest.NewRecorder()
 
-	h.UserURLsHandler(rec, req)
+	h.Identity(http.HandlerFunc(h.UserURLsHandler)).ServeHTTP(rec, req)
 
 	fmt.Printf("Status: %d\n", rec.Code)
 	fmt.Printf("Content-Type: %s\n", rec.Header().Get("Content-Type"))
+	fmt.Printf("Body: %s", rec.Body.String())
 
 	// Output:
 	// Status: 200
 	// Content-Type: application/json
+	// Body: [{"short_url":"http://localhost:8090/abc123","original_url":"https://example.com/original"}]
 }
@@ -14,36 +14,65 @@ import (
 	"github.com/mailru/easyjson"
 	"github.com/sirupsen/logrus"
 
+	"github.com/TimBerk/go-link-shortener/internal/app/attempts"
 	"github.com/TimBerk/go-link-shortener/internal/app/config"
+	"github.com/TimBerk/go-link-shortener/internal/app/middlewares/auth"
+	"github.com/TimBerk/go-link-shortener/internal/app/middlewares/subnet"
 	"github.com/TimBerk/go-link-shortener/internal/app/models/batch"
 	"github.com/TimBerk/go-link-shortener/internal/app/models/simple"
 	"github.com/TimBerk/go-link-shortener/internal/app/store"
-	"github.com/TimBerk/go-link-shortener/internal/pkg/cookies"
+	"github.com/TimBerk/go-link-shortener/internal/pkg/jwtauth"
+	"github.com/TimBerk/go-link-shortener/internal/pkg/oidc"
 	"github.com/TimBerk/go-link-shortener/internal/pkg/utils"
 )
 
-// userURLs - локальный store для быстрого нахождения ссылок пользователя
-var userURLs = make(map[string][]map[string]string)
-
 // Handler - структура для хранения настроек и обработчиков данных
 type Handler struct {
-	store   store.Store
-	cfg     *config.Config
-	ctx     context.Context
-	urlChan chan store.URLPair
+	store     store.Store
+	cfg       *config.Config
+	ctx       context.Context
+	urlChan   chan store.URLPair
+	clickChan chan<- store.Click
+	attempts  *attempts.Limiter
+	tokens    *jwtauth.Manager
+	// loginAttempts ограничивает неверные пароли при входе в учетную запись по логину
+	loginAttempts *attempts.Limiter
+	// oidc - провайдер входа через OpenID Connect, nil - вход через SSO не настроен
+	oidc *oidc.Provider
+	// admins - пользователи с ролью администратора
+	admins auth.Admins
+	// proxies - доверенные прокси, от которых адрес клиента берется из заголовка X-Real-IP
+	proxies subnet.Proxies
 }
 
-// addShortURL - обработчик локального хранилища ссылок пользователя
-func addShortURL(userID, shortURL, originalURL string) {
-	userURLs[userID] = append(userURLs[userID], map[string]string{
-		"short_url":    shortURL,
-		"original_url": originalURL,
-	})
+// NewHandler - инициализация нового обработчика на основании переаданных настроек
+func NewHandler(store store.Store, cfg *config.Config, ctx context.Context, urlChan chan store.URLPair, opts ...Option) *Handler {
+	h := &Handler{
+		store:         store,
+		cfg:           cfg,
+		ctx:           ctx,
+		urlChan:       urlChan,
+		attempts:      attempts.New(attempts.DefaultLimit, attempts.DefaultClientLimit, attempts.DefaultLockout),
+		loginAttempts: attempts.New(attempts.DefaultLimit, attempts.DefaultClientLimit, attempts.DefaultLockout),
+		admins:        auth.NewAdmins(cfg.Admins()...),
+		proxies:       subnet.NewProxies(cfg.Proxies()...),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
-// NewHandler - инициализация нового обработчика на основании переаданных настроек
-func NewHandler(store store.Store, cfg *config.Config, ctx context.Context, urlChan chan store.URLPair) *Handler {
-	return &Handler{store: store, cfg: cfg, ctx: ctx, urlChan: urlChan}
+// Identity - middleware, определяющий пользователя запроса по токену Authorization: Bearer,
+// ключу API X-API-Key, сессии учетной записи или cookie user и назначающий ему роль
+func (h *Handler) Identity(next http.Handler) http.Handler {
+	return auth.Middleware(auth.Resolver{Tokens: h.tokens, Keys: h.store, Sessions: h.store, Admins: h.admins})(next)
+}
+
+// LenientIdentity - middleware публичных путей, определяющий пользователя как Identity,
+// но без проверки ключа API и без отказа при недействительном токене
+func (h *Handler) LenientIdentity(next http.Handler) http.Handler {
+	return auth.LenientMiddleware(auth.Resolver{Tokens: h.tokens, Keys: h.store, Sessions: h.store, Admins: h.admins})(next)
 }
 
 // ErrorResponse стандартный формат ошибки API
@@ -52,6 +81,14 @@ type ErrorResponse struct {
 	Error string `json:"error" example:"error message"`
 }
 
+// TokenResponse токен для заголовка Authorization: Bearer
+// swagger:model
+type TokenResponse struct {
+	Token     string    `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	TokenType string    `json:"token_type" example:"Bearer"`
+	ExpiresAt time.Time `json:"expires_at" example:"2025-01-02T15:04:05Z"`
+}
+
 // RequestJSON запрос на сокращение URL
 // swagger:model
 type RequestJSON struct {
@@ -81,11 +118,7 @@ func (h *Handler) ShortenURL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID, err := cookies.GetUserID(r)
-	if err != nil {
-		userID = cookies.GenerateUserID()
-		cookies.SetUserCookie(w, userID)
-	}
+	userID := auth.UserID(r.Context())
 
 	shortURL, err := h.store.AddURL(h.ctx, originalURL, userID)
 	existLink := errors.Is(err, store.ErrLinkExist)
@@ -95,7 +128,6 @@ func (h *Handler) ShortenURL(w http.ResponseWriter, r *http.Request) {
 	}
 
 	fullShortURL := fmt.Sprintf("http://%s/%s", h.cfg.ServerAddress, shortURL)
-	addShortURL(userID, fullShortURL, originalURL)
 
 	if !existLink {
 		w.WriteHeader(http.StatusCreated)
@@ -118,6 +150,7 @@ func (h *Handler) ShortenURL(w http.ResponseWriter, r *http.Request) {
 // @Success 201 {object} simple.ResponseJSON
 // @Success 409 {object} simple.ResponseJSON
 // @Failure 400 {object} ErrorResponse "Неверный запрос"
+// @Failure 409 {object} ErrorResponse "Пользовательская ссылка занята"
 // @Router /api/shorten [post]
 func (h *Handler) ShortenJSONURL(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -137,22 +170,63 @@ func (h *Handler) ShortenJSONURL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID, err := cookies.GetUserID(r)
+	userID := auth.UserID(r.Context())
+
+	if jsonBody.Alias != "" {
+		if err := store.ValidateAlias(jsonBody.Alias); err != nil {
+			utils.WriteJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	expiresAt, err := store.ResolveExpiration(jsonBody.ExpiresAt, jsonBody.TTL, time.Now())
 	if err != nil {
-		userID = cookies.GenerateUserID()
-		cookies.SetUserCookie(w, userID)
+		utils.WriteJSONError(w, err.Error(), http.StatusBadRequest)
+		return
 	}
-
-	shortURL, err := h.store.AddURL(h.ctx, jsonBody.URL, userID)
-	existLink := errors.Is(err, store.ErrLinkExist)
-	if err != nil && !existLink {
-		utils.WriteJSONError(w, "Error getting url", http.StatusBadRequest)
+	if err := store.ValidateMaxClicks(jsonBody.MaxClicks); err != nil {
+		utils.WriteJSONError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	var passwordHash string
+	if jsonBody.Password != "" {
+		passwordHash, err = store.HashPassword(jsonBody.Password)
+		if errors.Is(err, store.ErrInvalidPassword) {
+			utils.WriteJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if err != nil {
+			utils.WriteJSONError(w, "Error saving link", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	var shortURL string
+	var existLink bool
+	opts := store.LinkOptions{
+		Alias:        jsonBody.Alias,
+		ExpiresAt:    expiresAt,
+		MaxClicks:    jsonBody.MaxClicks,
+		PasswordHash: passwordHash,
+	}
+	if !opts.IsZero() {
+		shortURL, err = h.store.AddLink(h.ctx, jsonBody.URL, opts, userID)
+		if errors.Is(err, store.ErrAliasTaken) {
+			utils.WriteJSONError(w, "Alias is already taken", http.StatusConflict)
+			return
+		} else if err != nil {
+			utils.WriteJSONError(w, "Error saving link", http.StatusInternalServerError)
+			return
+		}
+	} else {
+		shortURL, err = h.store.AddURL(h.ctx, jsonBody.URL, userID)
+		existLink = errors.Is(err, store.ErrLinkExist)
+		if err != nil && !existLink {
+			utils.WriteJSONError(w, "Error getting url", http.StatusBadRequest)
+			return
+		}
+	}
 
 	fullShortURL := fmt.Sprintf("http://%s/%s", h.cfg.ServerAddress, shortURL)
 	responseJSON := simple.ResponseJSON{Result: fullShortURL}
-	addShortURL(userID, fullShortURL, jsonBody.URL)
 
 	response, err := easyjson.Marshal(responseJSON)
 	if err != nil {
@@ -171,20 +245,24 @@ func (h *Handler) ShortenJSONURL(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// Redirect выполняет перенаправление по короткому URL
+// Redirect выполняет перенаправление по короткому URL.
+// Для ссылки с паролем вместо перенаправления отдается форма ввода пароля, которая отправляется POST-запросом.
 // @Summary Перенаправить по короткому URL
 // @Description Перенаправляет на оригинальный URL по короткому идентификатору
+// @Accept  x-www-form-urlencoded
 // @Param   id path string true "Короткий идентификатор URL"
+// @Param   password formData string false "Пароль защищенной ссылки"
+// @Success 200 {string} string "Форма ввода пароля"
+// @Success 303 "Перенаправление на оригинальный URL после ввода пароля"
 // @Success 307 "Перенаправление на оригинальный URL"
+// @Failure 401 {string} string "Неверный пароль"
 // @Failure 404 {string} string "URL не найден"
-// @Failure 410 {string} string "URL удален"
+// @Failure 410 {string} string "URL удален, истек или исчерпал лимит переходов"
+// @Failure 429 {string} string "Превышено количество попыток ввода пароля"
 // @Router /{id} [get]
+// @Router /{id} [post]
 func (h *Handler) Redirect(w http.ResponseWriter, r *http.Request) {
-	userID, err := cookies.GetUserID(r)
-	if err != nil {
-		userID = cookies.GenerateUserID()
-		cookies.SetUserCookie(w, userID)
-	}
+	userID := auth.UserID(r.Context())
 
 	shortURL := chi.URLParam(r, "id")
 	originalURL, exists, isDeleted := h.store.GetOriginalURL(h.ctx, shortURL, userID)
@@ -204,7 +282,28 @@ func (h *Handler) Redirect(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.unlockLink(w, r, shortURL) {
+		return
+	}
+
+	clickResult, err := h.store.UseClick(h.ctx, shortURL)
+	if err != nil {
+		http.Error(w, "Failed to use short URL", http.StatusInternalServerError)
+		return
+	} else if clickResult == store.ClickExhausted {
+		logrus.WithField("shortUri", shortURL).Info("Short URL click limit is exhausted")
+		http.Error(w, "Short URL click limit is exhausted", http.StatusGone)
+		return
+	}
+
+	h.recordClick(r, shortURL, userID)
+
 	w.Header().Set("Location", originalURL)
+	if r.Method == http.MethodPost {
+		// после отправки формы браузер должен перейти по ссылке GET-запросом, не повторяя пароль
+		w.WriteHeader(http.StatusSeeOther)
+		return
+	}
 	w.WriteHeader(http.StatusTemporaryRedirect)
 }
 
@@ -236,16 +335,13 @@ func (h *Handler) Ping(w http.ResponseWriter, r *http.Request) {
 // @Param   urls body []batch.BatchRequest true "Массив URL для сокращения"
 // @Success 201 {array} batch.BatchResponse
 // @Failure 400 {object} ErrorResponse "Неверный запрос"
+// @Failure 409 {object} ErrorResponse "Пользовательская ссылка занята"
 // @Failure 500 {object} ErrorResponse "Ошибка сервера"
 // @Router /api/shorten/batch [post]
 func (h *Handler) ShortenBatch(w http.ResponseWriter, r *http.Request) {
 	var batchRequests batch.BatchRequest
 
-	userID, err := cookies.GetUserID(r)
-	if err != nil {
-		userID = cookies.GenerateUserID()
-		cookies.SetUserCookie(w, userID)
-	}
+	userID := auth.UserID(r.Context())
 
 	if err := easyjson.UnmarshalFromReader(r.Body, &batchRequests); err != nil {
 		logrus.WithField("err", err).Error("Invalid request body")
@@ -259,12 +355,53 @@ func (h *Handler) ShortenBatch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	now := time.Now()
+	for i, item := range batchRequests {
+		if item.Alias != "" {
+			if err := store.ValidateAlias(item.Alias); err != nil {
+				utils.WriteJSONError(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		expiresAt, err := store.ResolveExpiration(item.ExpiresAt, item.TTL, now)
+		if err != nil {
+			utils.WriteJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := store.ValidateMaxClicks(item.MaxClicks); err != nil {
+			utils.WriteJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if item.Password != "" {
+			passwordHash, err := store.HashPassword(item.Password)
+			if errors.Is(err, store.ErrInvalidPassword) {
+				utils.WriteJSONError(w, err.Error(), http.StatusBadRequest)
+				return
+			} else if err != nil {
+				utils.WriteJSONError(w, "Error saving link", http.StatusInternalServerError)
+				return
+			}
+			batchRequests[i].Password, batchRequests[i].PasswordHash = "", passwordHash
+		}
+		batchRequests[i].ExpiresAt, batchRequests[i].TTL = nil, ""
+		if !expiresAt.IsZero() {
+			batchRequests[i].ExpiresAt = &expiresAt
+		}
+	}
+
 	batchResponses, err := h.store.AddURLs(h.ctx, batchRequests, userID)
-	if err != nil {
+	if errors.Is(err, store.ErrAliasTaken) {
+		utils.WriteJSONError(w, "Alias is already taken", http.StatusConflict)
+		return
+	} else if err != nil {
 		logrus.WithField("err", err).Error("Error shortening URLs")
 		http.Error(w, fmt.Sprintf("Error shortening URLs: %v", err), http.StatusInternalServerError)
 		return
 	}
+	for i := range batchResponses {
+		batchResponses[i].ShortURL = fmt.Sprintf("http://%s/%s", h.cfg.ServerAddress, batchResponses[i].ShortURL)
+	}
 
 	response, err := easyjson.Marshal(batchResponses)
 	if err != nil {
@@ -283,24 +420,31 @@ func (h *Handler) ShortenBatch(w http.ResponseWriter, r *http.Request) {
 
 // UserURLsHandler возвращает все URL пользователя
 // @Summary Получить URL пользователя
-// @Description Возвращает все сокращенные URL текущего пользователя
+// @Description Возвращает созданные текущим пользователем сокращенные URL в порядке добавления.
+// @Description Ссылка, уже сокращенная другим пользователем, возвращается с кодом 409 и в список не попадает.
 // @Produce json
-// @Success 200 {array} map[string]string "Массив URL пользователя"
+// @Success 200 {array} store.UserURL "Массив URL пользователя"
 // @Success 204 "Нет сохраненных URL"
+// @Failure 500 {string} string "Ошибка получения URL"
 // @Router /api/user/urls [get]
 func (h *Handler) UserURLsHandler(w http.ResponseWriter, r *http.Request) {
-	userID, err := cookies.GetUserID(r)
+	userID := auth.UserID(r.Context())
+
+	urls, err := h.store.GetUserURLs(h.ctx, userID)
 	if err != nil {
-		userID = cookies.GenerateUserID()
-		cookies.SetUserCookie(w, userID)
+		logrus.WithField("err", err).Error("Failed to get user URLs")
+		http.Error(w, "Failed to get user URLs", http.StatusInternalServerError)
+		return
 	}
-
-	urls, exists := userURLs[userID]
-	if !exists || len(urls) == 0 {
+	if len(urls) == 0 {
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
 
+	for i := range urls {
+		urls[i].ShortURL = fmt.Sprintf("http://%s/%s", h.cfg.ServerAddress, urls[i].ShortURL)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	errResponse := json.NewEncoder(w).Encode(urls)
 	if errResponse != nil {
@@ -308,6 +452,72 @@ func (h *Handler) UserURLsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// UserURLStatsHandler возвращает статистику переходов по ссылке пользователя
+// @Summary Получить статистику ссылки пользователя
+// @Description Возвращает количество переходов, уникальных посетителей, переходы по дням и часам в UTC,
+// @Description топ источников перехода и клиентов за период
+// @Produce json
+// @Param   id path string true "Короткий идентификатор URL"
+// @Param   from query string false "Начало периода включительно, RFC 3339 или дата 2006-01-02"
+// @Param   to query string false "Конец периода не включительно, RFC 3339 или дата 2006-01-02"
+// @Success 200 {object} store.LinkStats "Статистика ссылки"
+// @Failure 400 {object} ErrorResponse "Неверный период"
+// @Failure 401 {string} string "Пользователь не авторизован"
+// @Failure 404 {object} ErrorResponse "Ссылка пользователя не найдена"
+// @Failure 500 {object} ErrorResponse "Ошибка получения статистики"
+// @Router /api/user/urls/{id}/stats [get]
+func (h *Handler) UserURLStatsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.Authenticated(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query, err := store.ParseStatsQuery(r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+	if err != nil {
+		utils.WriteJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	link := store.URLPair{ShortURL: chi.URLParam(r, "id"), UserID: userID}
+	stats, err := h.store.GetLinkStats(h.ctx, link, query)
+	if errors.Is(err, store.ErrStatsNotFound) {
+		utils.WriteJSONError(w, "Short URL not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		logrus.WithField("err", err).Error("Failed to get link stats")
+		utils.WriteJSONError(w, "Failed to get link stats", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if errResponse := json.NewEncoder(w).Encode(stats); errResponse != nil {
+		logrus.WithField("err", errResponse).Error("Failed to response link stats")
+	}
+}
+
+// InternalStatsHandler возвращает количество сокращенных URL и пользователей сервиса
+// @Summary Получить статистику сервиса
+// @Description Доступно только из доверенной подсети trusted_subnet
+// @Produce json
+// @Success 200 {object} store.ServiceStats "Статистика сервиса"
+// @Failure 403 {string} string "Адрес клиента не входит в доверенную подсеть"
+// @Failure 500 {object} ErrorResponse "Ошибка получения статистики"
+// @Router /api/internal/stats [get]
+func (h *Handler) InternalStatsHandler(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.store.GetServiceStats(h.ctx)
+	if err != nil {
+		logrus.WithField("err", err).Error("Failed to get service stats")
+		utils.WriteJSONError(w, "Failed to get service stats", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if errResponse := json.NewEncoder(w).Encode(stats); errResponse != nil {
+		logrus.WithField("err", errResponse).Error("Failed to response service stats")
+	}
+}
+
 // DeleteURLsHandler помечает URL как удаленные
 // @Summary Удалить URL пользователя
 // @Description Помечает указанные URL как удаленные (асинхронно)
@@ -319,8 +529,8 @@ func (h *Handler) UserURLsHandler(w http.ResponseWriter, r *http.Request) {
 // @Failure 401 {string} string "Пользователь не авторизован"
 // @Router /api/user/urls [delete]
 func (h *Handler) DeleteURLsHandler(w http.ResponseWriter, r *http.Request) {
-	userID, err := cookies.GetUserID(r)
-	if err != nil {
+	userID, ok := auth.Authenticated(r.Context())
+	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
@@ -342,3 +552,37 @@ func (h *Handler) DeleteURLsHandler(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusAccepted)
 }
+
+// IssueTokenHandler выпускает токен для текущего пользователя.
+// Пользователь определяется по cookie user или действующему токену, без них создается новый пользователь.
+// @Summary Получить токен пользователя
+// @Description Выпускает JWT для заголовка Authorization: Bearer, идентифицирующий того же пользователя, что и cookie user
+// @Produce json
+// @Success 200 {object} TokenResponse "Токен пользователя"
+// @Failure 401 {string} string "Недействительный токен"
+// @Failure 500 {object} ErrorResponse "Ошибка выпуска токена"
+// @Failure 501 {object} ErrorResponse "Выпуск токенов не настроен"
+// @Router /api/user/token [post]
+func (h *Handler) IssueTokenHandler(w http.ResponseWriter, r *http.Request) {
+	if h.tokens == nil {
+		utils.WriteJSONError(w, "Bearer tokens are not enabled", http.StatusNotImplemented)
+		return
+	}
+
+	token, expiresAt, err := h.tokens.Issue(auth.UserID(r.Context()))
+	if errors.Is(err, jwtauth.ErrIssueDisabled) {
+		utils.WriteJSONError(w, "Token issuing is not enabled", http.StatusNotImplemented)
+		return
+	} else if err != nil {
+		logrus.WithField("err", err).Error("Failed to issue token")
+		utils.WriteJSONError(w, "Failed to issue token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	response := TokenResponse{Token: token, TokenType: "Bearer", ExpiresAt: expiresAt}
+	if errResponse := json.NewEncoder(w).Encode(response); errResponse != nil {
+		logrus.WithField("err", errResponse).Error("Failed to response token")
+	}
+}
@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/config"
+	"github.com/TimBerk/go-link-shortener/internal/app/store"
+	"github.com/TimBerk/go-link-shortener/internal/app/store/local"
+	"github.com/TimBerk/go-link-shortener/internal/pkg/cookies"
+)
+
+// newAccountsRouter возвращает обработчик с локальным стором и роутер с путями учетных записей
+func newAccountsRouter(t *testing.T) (*Handler, http.Handler) {
+	testStore, err := local.NewURLStore(store.NewIDGenerator())
+	require.NoError(t, err)
+	h := NewHandler(testStore, config.NewConfig("localhost:8021", "http://base.loc", true), context.Background(), make(chan store.URLPair, 1))
+
+	router := chi.NewRouter()
+	router.Use(h.Identity)
+	router.Post("/api/user/register", h.RegisterHandler)
+	router.Post("/api/user/login", h.LoginHandler)
+	router.Post("/api/user/logout", h.LogoutHandler)
+	router.Post("/api/user/claim", h.ClaimURLsHandler)
+	router.Get("/api/user/urls", h.UserURLsHandler)
+	router.Post("/", h.ShortenURL)
+	return h, router
+}
+
+// postCredentials отправляет логин и пароль на путь target
+func postCredentials(router http.Handler, target string, login string, password string, requestCookies ...*http.Cookie) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(CredentialsRequest{Login: login, Password: password})
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(string(body)))
+	for _, cookie := range requestCookies {
+		req.AddCookie(cookie)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+// findCookie возвращает cookie ответа по имени
+func findCookie(recorder *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, cookie := range recorder.Result().Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
+
+func TestRegisterHandler(t *testing.T) {
+	_, router := newAccountsRouter(t)
+	require.Equal(t, http.StatusCreated, postCredentials(router, "/api/user/register", "taken", "password-1").Code)
+
+	tests := []struct {
+		name             string
+		login            string
+		password         string
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{name: "Register", login: " Alice ", password: "password-1", expectedStatus: http.StatusCreated},
+		{name: "Taken login", login: "TAKEN", password: "password-2", expectedStatus: http.StatusConflict, expectedResponse: `{"error":"Login is already taken"}`},
+		{name: "Short login", login: "al", password: "password-1", expectedStatus: http.StatusBadRequest, expectedResponse: `{"error":"invalid login: login must be from 3 to 64 characters"}`},
+		{name: "Login with spaces", login: "al ice", password: "password-1", expectedStatus: http.StatusBadRequest, expectedResponse: `{"error":"invalid login: login must not contain spaces"}`},
+		{name: "Short password", login: "bob", password: "short", expectedStatus: http.StatusBadRequest, expectedResponse: `{"error":"invalid password: password must be at least 8 bytes"}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := postCredentials(router, "/api/user/register", test.login, test.password)
+
+			assert.Equal(t, test.expectedStatus, recorder.Code)
+			if test.expectedStatus != http.StatusCreated {
+				assert.Equal(t, test.expectedResponse, strings.TrimSuffix(recorder.Body.String(), "\n"))
+				assert.Nil(t, findCookie(recorder, cookies.SessionCookieName))
+				return
+			}
+
+			var account map[string]any
+			require.NoError(t, json.NewDecoder(recorder.Body).Decode(&account))
+			assert.Equal(t, "alice", account["login"], "Логин должен нормализоваться")
+			assert.NotEmpty(t, account["id"])
+			assert.NotContains(t, account, "password_hash")
+			session := findCookie(recorder, cookies.SessionCookieName)
+			require.NotNil(t, session, "После регистрации должна открываться сессия")
+			assert.True(t, session.HttpOnly)
+		})
+	}
+}
+
+func TestLoginHandler(t *testing.T) {
+	_, router := newAccountsRouter(t)
+	require.Equal(t, http.StatusCreated, postCredentials(router, "/api/user/register", "alice", "password-1").Code)
+
+	tests := []struct {
+		name             string
+		login            string
+		password         string
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{name: "Login", login: "Alice", password: "password-1", expectedStatus: http.StatusOK},
+		{name: "Wrong password", login: "alice", password: "password-2", expectedStatus: http.StatusUnauthorized, expectedResponse: `{"error":"Invalid login or password"}`},
+		{name: "Unknown login", login: "bob", password: "password-1", expectedStatus: http.StatusUnauthorized, expectedResponse: `{"error":"Invalid login or password"}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := postCredentials(router, "/api/user/login", test.login, test.password)
+
+			assert.Equal(t, test.expectedStatus, recorder.Code)
+			if test.expectedStatus != http.StatusOK {
+				assert.Equal(t, test.expectedResponse, strings.TrimSuffix(recorder.Body.String(), "\n"))
+				return
+			}
+			assert.NotNil(t, findCookie(recorder, cookies.SessionCookieName), "После входа должна открываться сессия")
+		})
+	}
+}
+
+func TestLoginHandler_Attempts(t *testing.T) {
+	_, router := newAccountsRouter(t)
+	require.Equal(t, http.StatusCreated, postCredentials(router, "/api/user/register", "alice", "password-1").Code)
+
+	for i := 0; i < 5; i++ {
+		require.Equal(t, http.StatusUnauthorized, postCredentials(router, "/api/user/login", "alice", "wrong-password").Code)
+	}
+
+	recorder := postCredentials(router, "/api/user/login", "alice", "password-1")
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code, "После неверных паролей вход должен блокироваться")
+	assert.NotEmpty(t, recorder.Header().Get("Retry-After"))
+}
+
+func TestAccountClaimFlow(t *testing.T) {
+	_, router := newAccountsRouter(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("https://example.com/anonymous"))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusCreated, recorder.Code)
+	anonymous := findCookie(recorder, "user")
+	require.NotNil(t, anonymous, "Анонимный пользователь должен получать cookie user")
+
+	recorder = postCredentials(router, "/api/user/register", "alice", "password-1", anonymous)
+	require.Equal(t, http.StatusCreated, recorder.Code)
+	session := findCookie(recorder, cookies.SessionCookieName)
+	require.NotNil(t, session)
+
+	claim := func(requestCookies ...*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/user/claim", nil)
+		for _, cookie := range requestCookies {
+			req.AddCookie(cookie)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, claim(anonymous).Code, "Без сессии ссылки не должны передаваться")
+	assert.Equal(t, http.StatusBadRequest, claim(session).Code, "Без cookie user нечего передавать")
+
+	recorder = claim(session, anonymous)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"claimed":1}`, recorder.Body.String())
+
+	req = httptest.NewRequest(http.MethodGet, "/api/user/urls", nil)
+	req.AddCookie(session)
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "https://example.com/anonymous", "Переданная ссылка должна принадлежать учетной записи")
+
+	req = httptest.NewRequest(http.MethodPost, "/api/user/logout", nil)
+	req.AddCookie(session)
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusNoContent, recorder.Code)
+	cleared := findCookie(recorder, cookies.SessionCookieName)
+	require.NotNil(t, cleared)
+	assert.Negative(t, cleared.MaxAge, "Cookie session должна удаляться")
+
+	assert.Equal(t, http.StatusUnauthorized, claim(session, anonymous).Code, "Завершенная сессия не должна приниматься")
+}
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/config"
+	"github.com/TimBerk/go-link-shortener/internal/app/store"
+)
+
+// newAdminRouter возвращает роутер с путями модерации без проверки роли, которую выполняет пакет router
+func newAdminRouter(h *Handler) http.Handler {
+	router := chi.NewRouter()
+	router.Get("/api/admin/urls", h.AdminURLsHandler)
+	router.Delete("/api/admin/urls/{id}", h.AdminDisableURLHandler)
+	router.Get("/api/admin/users/{userID}/urls", h.AdminUserURLsHandler)
+	return router
+}
+
+func TestAdminURLsHandler(t *testing.T) {
+	links := []store.Link{
+		{ShortURL: "abc123", OriginalURL: "https://example.com", UserID: "777"},
+		{ShortURL: "def456", OriginalURL: "https://example.org", UserID: "888", IsDeleted: true},
+	}
+
+	tests := []struct {
+		name             string
+		query            string
+		expectedSearch   *store.LinkSearch
+		storeErr         error
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			name:             "Default page",
+			expectedSearch:   &store.LinkSearch{Limit: store.DefaultSearchLimit},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `[{"short_url":"abc123","original_url":"https://example.com","user_id":"777","is_deleted":false},{"short_url":"def456","original_url":"https://example.org","user_id":"888","is_deleted":true}]`,
+		},
+		{
+			name:             "Query with page",
+			query:            "?query=Example&limit=2&offset=4",
+			expectedSearch:   &store.LinkSearch{Query: "Example", Limit: 2, Offset: 4},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `[{"short_url":"abc123","original_url":"https://example.com","user_id":"777","is_deleted":false},{"short_url":"def456","original_url":"https://example.org","user_id":"888","is_deleted":true}]`,
+		},
+		{name: "Zero limit", query: "?limit=0", expectedStatus: http.StatusBadRequest, expectedResponse: `{"error":"limit must be from 1 to 1000"}`},
+		{name: "Too large limit", query: "?limit=1001", expectedStatus: http.StatusBadRequest, expectedResponse: `{"error":"limit must be from 1 to 1000"}`},
+		{name: "Negative offset", query: "?offset=-1", expectedStatus: http.StatusBadRequest, expectedResponse: `{"error":"offset must be a non-negative number"}`},
+		{name: "Invalid offset", query: "?offset=first", expectedStatus: http.StatusBadRequest, expectedResponse: `{"error":"offset must be a non-negative number"}`},
+		{
+			name:             "Store error",
+			expectedSearch:   &store.LinkSearch{Limit: store.DefaultSearchLimit},
+			storeErr:         errors.New("store error"),
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: `{"error":"Failed to search URLs"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockStore := new(MockURLStore)
+			if test.expectedSearch != nil {
+				mockStore.On("SearchURLs", mock.Anything, *test.expectedSearch).Return(links, test.storeErr)
+			}
+			testHandler := NewHandler(mockStore, config.NewConfig("localhost:8021", "http://base.loc", true), context.Background(), make(chan store.URLPair, 1))
+
+			recorder := httptest.NewRecorder()
+			newAdminRouter(testHandler).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/admin/urls"+test.query, nil))
+
+			assert.Equal(t, test.expectedStatus, recorder.Code)
+			assert.Equal(t, test.expectedResponse, strings.TrimSuffix(recorder.Body.String(), "\n"))
+			mockStore.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAdminDisableURLHandler(t *testing.T) {
+	tests := []struct {
+		name             string
+		storeErr         error
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{name: "Disable link", expectedStatus: http.StatusNoContent},
+		{name: "Unknown link", storeErr: store.ErrLinkNotFound, expectedStatus: http.StatusNotFound, expectedResponse: `{"error":"Short URL not found"}`},
+		{name: "Store error", storeErr: errors.New("store error"), expectedStatus: http.StatusInternalServerError, expectedResponse: `{"error":"Failed to disable URL"}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockStore := new(MockURLStore)
+			mockStore.On("DisableURL", mock.Anything, "abc123").Return(test.storeErr)
+			testHandler := NewHandler(mockStore, config.NewConfig("localhost:8021", "http://base.loc", true), context.Background(), make(chan store.URLPair, 1))
+
+			recorder := httptest.NewRecorder()
+			newAdminRouter(testHandler).ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, "/api/admin/urls/abc123", nil))
+
+			assert.Equal(t, test.expectedStatus, recorder.Code)
+			assert.Equal(t, test.expectedResponse, strings.TrimSuffix(recorder.Body.String(), "\n"))
+			mockStore.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAdminUserURLsHandler(t *testing.T) {
+	tests := []struct {
+		name             string
+		urls             []store.UserURL
+		storeErr         error
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			name:             "User links",
+			urls:             []store.UserURL{{ShortURL: "abc123", OriginalURL: "https://example.com"}},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `[{"short_url":"http://localhost:8021/abc123","original_url":"https://example.com"}]`,
+		},
+		{name: "Without links", urls: []store.UserURL{}, expectedStatus: http.StatusNoContent},
+		{name: "Store error", urls: []store.UserURL{}, storeErr: errors.New("store error"), expectedStatus: http.StatusInternalServerError, expectedResponse: `{"error":"Failed to get user URLs"}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockStore := new(MockURLStore)
+			mockStore.On("GetUserURLs", mock.Anything, "888").Return(test.urls, test.storeErr)
+			testHandler := NewHandler(mockStore, config.NewConfig("localhost:8021", "http://base.loc", true), context.Background(), make(chan store.URLPair, 1))
+
+			recorder := httptest.NewRecorder()
+			newAdminRouter(testHandler).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/admin/users/888/urls", nil))
+
+			assert.Equal(t, test.expectedStatus, recorder.Code)
+			assert.Equal(t, test.expectedResponse, strings.TrimSuffix(recorder.Body.String(), "\n"))
+			mockStore.AssertExpectations(t)
+		})
+	}
+}
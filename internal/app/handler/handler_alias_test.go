@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/config"
+	"github.com/TimBerk/go-link-shortener/internal/app/models/batch"
+	"github.com/TimBerk/go-link-shortener/internal/app/store"
+)
+
+func TestShortenJSONURLHandler_Alias(t *testing.T) {
+	tests := []struct {
+		name             string
+		body             string
+		alias            string
+		mockErr          error
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			name:             "Free alias",
+			body:             `{"url":"https://example.com","alias":"spring-sale"}`,
+			alias:            "spring-sale",
+			expectedStatus:   http.StatusCreated,
+			expectedResponse: `{"result":"http://localhost:8021/spring-sale"}`,
+		},
+		{
+			name:             "Taken alias",
+			body:             `{"url":"https://example.com","alias":"spring-sale"}`,
+			alias:            "spring-sale",
+			mockErr:          store.ErrAliasTaken,
+			expectedStatus:   http.StatusConflict,
+			expectedResponse: `{"error":"Alias is already taken"}`,
+		},
+		{
+			name:             "Reserved alias",
+			body:             `{"url":"https://example.com","alias":"Swagger"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"error":"invalid alias: \"Swagger\" is reserved"}`,
+		},
+		{
+			name:             "Alias with invalid symbols",
+			body:             `{"url":"https://example.com","alias":"spring/sale"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"error":"invalid alias: only latin letters, digits, '-' and '_' are allowed"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockStore := new(MockURLStore)
+			testHandler := NewHandler(mockStore, config.NewConfig("localhost:8021", "http://base.loc", true), context.Background(), make(chan store.URLPair, 1))
+			if test.alias != "" {
+				shortURL := test.alias
+				if test.mockErr != nil {
+					shortURL = ""
+				}
+				opts := store.LinkOptions{Alias: test.alias}
+				mockStore.On("AddLink", mock.Anything, "https://example.com", opts, userID).Return(shortURL, test.mockErr)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/api/shorten", bytes.NewBufferString(test.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.AddCookie(mockCookie(userID))
+			recorder := httptest.NewRecorder()
+
+			serveWithIdentity(testHandler, testHandler.ShortenJSONURL, recorder, req)
+
+			assert.Equal(t, test.expectedStatus, recorder.Code)
+			assert.Equal(t, test.expectedResponse, strings.TrimSuffix(recorder.Body.String(), "\n"))
+			mockStore.AssertExpectations(t)
+		})
+	}
+}
+
+func TestShortenBatch_Alias(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		mockErr        error
+		callStore      bool
+		expectedStatus int
+	}{
+		{
+			name:           "Free aliases",
+			body:           `[{"correlation_id":"1","original_url":"https://example.com","alias":"spring-sale"}]`,
+			callStore:      true,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "Taken alias",
+			body:           `[{"correlation_id":"1","original_url":"https://example.com","alias":"spring-sale"}]`,
+			mockErr:        store.ErrAliasTaken,
+			callStore:      true,
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "Reserved alias",
+			body:           `[{"correlation_id":"1","original_url":"https://example.com","alias":"api"}]`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockStore := new(MockURLStore)
+			testHandler := NewHandler(mockStore, config.NewConfig("localhost:8021", "http://base.loc", true), context.Background(), make(chan store.URLPair, 1))
+			if test.callStore {
+				responses := batch.BatchResponse{{CorrelationID: "1", ShortURL: "spring-sale"}}
+				if test.mockErr != nil {
+					responses = nil
+				}
+				mockStore.On("AddURLs", mock.Anything, mock.Anything, userID).Return(responses, test.mockErr)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/api/shorten/batch", bytes.NewBufferString(test.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.AddCookie(mockCookie(userID))
+			recorder := httptest.NewRecorder()
+
+			serveWithIdentity(testHandler, testHandler.ShortenBatch, recorder, req)
+
+			assert.Equal(t, test.expectedStatus, recorder.Code)
+			mockStore.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/config"
+	"github.com/TimBerk/go-link-shortener/internal/app/store"
+)
+
+// newAPIKeysRouter возвращает роутер с путями ключей API за middleware определения пользователя
+func newAPIKeysRouter(h *Handler) http.Handler {
+	router := chi.NewRouter()
+	router.Use(h.Identity)
+	router.Post("/api/user/keys", h.CreateAPIKeyHandler)
+	router.Get("/api/user/keys", h.APIKeysHandler)
+	router.Delete("/api/user/keys/{id}", h.RevokeAPIKeyHandler)
+	router.Get("/api/user/urls", h.UserURLsHandler)
+	return router
+}
+
+func TestCreateAPIKeyHandler(t *testing.T) {
+	tests := []struct {
+		name             string
+		body             string
+		withCookie       bool
+		storeErr         error
+		expectStore      bool
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{name: "Create key", body: `{"name":" ci "}`, withCookie: true, expectStore: true, expectedStatus: http.StatusCreated},
+		{name: "Empty name", body: `{"name":"  "}`, withCookie: true, expectedStatus: http.StatusBadRequest, expectedResponse: `{"error":"invalid api key name: name is required"}`},
+		{name: "Invalid body", body: `{"name":`, withCookie: true, expectedStatus: http.StatusBadRequest, expectedResponse: `{"error":"Invalid request body"}`},
+		{name: "Store error", body: `{"name":"ci"}`, withCookie: true, storeErr: errors.New("store error"), expectStore: true, expectedStatus: http.StatusInternalServerError, expectedResponse: `{"error":"Failed to create API key"}`},
+		{name: "Without cookie", body: `{"name":"ci"}`, expectedStatus: http.StatusUnauthorized, expectedResponse: "Unauthorized"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockStore := new(MockURLStore)
+			if test.expectStore {
+				mockStore.On("AddAPIKey", mock.Anything, mock.MatchedBy(func(key store.APIKey) bool {
+					return key.UserID == userID && key.Name == "ci"
+				})).Return(test.storeErr)
+			}
+			testHandler := NewHandler(mockStore, config.NewConfig("localhost:8021", "http://base.loc", true), context.Background(), make(chan store.URLPair, 1))
+			req := httptest.NewRequest(http.MethodPost, "/api/user/keys", strings.NewReader(test.body))
+			if test.withCookie {
+				req.AddCookie(mockCookie(userID))
+			}
+			recorder := httptest.NewRecorder()
+
+			newAPIKeysRouter(testHandler).ServeHTTP(recorder, req)
+
+			assert.Equal(t, test.expectedStatus, recorder.Code)
+			mockStore.AssertExpectations(t)
+			if test.expectedStatus != http.StatusCreated {
+				assert.Equal(t, test.expectedResponse, strings.TrimSuffix(recorder.Body.String(), "\n"))
+				return
+			}
+
+			var response map[string]any
+			require.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+			assert.Equal(t, "ci", response["name"])
+			assert.NotEmpty(t, response["id"])
+			assert.NotContains(t, response, "user_id")
+			value, _ := response["key"].(string)
+			assert.True(t, strings.HasPrefix(value, "sk_"), "Значение ключа должно возвращаться при создании")
+			assert.Equal(t, value[:len(response["prefix"].(string))], response["prefix"])
+			assert.Equal(t, "no-store", recorder.Header().Get("Cache-Control"))
+		})
+	}
+}
+
+func TestAPIKeysHandler(t *testing.T) {
+	createdAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	keys := []store.APIKey{{ID: "key-1", UserID: userID, Name: "ci", Prefix: "sk_abcdefg", Hash: "secret-hash", CreatedAt: createdAt}}
+
+	tests := []struct {
+		name             string
+		storeKeys        []store.APIKey
+		storeErr         error
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			name:             "List keys",
+			storeKeys:        keys,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `[{"id":"key-1","name":"ci","prefix":"sk_abcdefg","created_at":"2024-05-01T10:00:00Z"}]`,
+		},
+		{name: "No keys", storeKeys: []store.APIKey{}, expectedStatus: http.StatusNoContent},
+		{name: "Store error", storeKeys: []store.APIKey{}, storeErr: errors.New("store error"), expectedStatus: http.StatusInternalServerError, expectedResponse: `{"error":"Failed to get API keys"}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockStore := new(MockURLStore)
+			mockStore.On("GetAPIKeys", mock.Anything, userID).Return(test.storeKeys, test.storeErr)
+			testHandler := NewHandler(mockStore, config.NewConfig("localhost:8021", "http://base.loc", true), context.Background(), make(chan store.URLPair, 1))
+			req := httptest.NewRequest(http.MethodGet, "/api/user/keys", nil)
+			req.AddCookie(mockCookie(userID))
+			recorder := httptest.NewRecorder()
+
+			newAPIKeysRouter(testHandler).ServeHTTP(recorder, req)
+
+			assert.Equal(t, test.expectedStatus, recorder.Code)
+			assert.Equal(t, test.expectedResponse, strings.TrimSuffix(recorder.Body.String(), "\n"))
+			mockStore.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRevokeAPIKeyHandler(t *testing.T) {
+	tests := []struct {
+		name             string
+		withCookie       bool
+		storeErr         error
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{name: "Revoke own key", withCookie: true, expectedStatus: http.StatusNoContent},
+		{name: "Key of another user", withCookie: true, storeErr: store.ErrAPIKeyNotFound, expectedStatus: http.StatusNotFound, expectedResponse: `{"error":"API key not found"}`},
+		{name: "Without cookie", expectedStatus: http.StatusUnauthorized, expectedResponse: "Unauthorized"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockStore := new(MockURLStore)
+			if test.withCookie {
+				mockStore.On("RevokeAPIKey", mock.Anything, userID, "key-1").Return(test.storeErr)
+			}
+			testHandler := NewHandler(mockStore, config.NewConfig("localhost:8021", "http://base.loc", true), context.Background(), make(chan store.URLPair, 1))
+			req := httptest.NewRequest(http.MethodDelete, "/api/user/keys/key-1", nil)
+			if test.withCookie {
+				req.AddCookie(mockCookie(userID))
+			}
+			recorder := httptest.NewRecorder()
+
+			newAPIKeysRouter(testHandler).ServeHTTP(recorder, req)
+
+			assert.Equal(t, test.expectedStatus, recorder.Code)
+			assert.Equal(t, test.expectedResponse, strings.TrimSuffix(recorder.Body.String(), "\n"))
+			mockStore.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAPIKeyIdentity(t *testing.T) {
+	tests := []struct {
+		name           string
+		storeUserID    string
+		storeErr       error
+		expectedStatus int
+	}{
+		{name: "Known key", storeUserID: userID, expectedStatus: http.StatusOK},
+		{name: "Revoked key", storeErr: store.ErrAPIKeyNotFound, expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockStore := new(MockURLStore)
+			mockStore.On("UseAPIKey", mock.Anything, store.HashAPIKey("sk_test"), mock.Anything).Return(test.storeUserID, test.storeErr)
+			if test.expectedStatus == http.StatusOK {
+				mockStore.On("GetUserURLs", mock.Anything, userID).Return([]store.UserURL{{ShortURL: "short1", OriginalURL: "https://example.com"}}, nil)
+			}
+			testHandler := NewHandler(mockStore, config.NewConfig("localhost:8021", "http://base.loc", true), context.Background(), make(chan store.URLPair, 1))
+			req := httptest.NewRequest(http.MethodGet, "/api/user/urls", nil)
+			req.Header.Set("X-API-Key", "sk_test")
+			recorder := httptest.NewRecorder()
+
+			newAPIKeysRouter(testHandler).ServeHTTP(recorder, req)
+
+			assert.Equal(t, test.expectedStatus, recorder.Code)
+			assert.Empty(t, recorder.Result().Cookies(), "Запрос с ключом API не должен получать cookie")
+			mockStore.AssertExpectations(t)
+		})
+	}
+}
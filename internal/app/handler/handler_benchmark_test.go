@@ -38,7 +38,7 @@ func BenchmarkShortenURL(b *testing.B) {
 		w := httptest.NewRecorder()
 		r := httptest.NewRequest("POST", "/", body)
 		r.AddCookie(testCookie)
-		h.ShortenURL(w, r)
+		serveWithIdentity(h, h.ShortenURL, w, r)
 		body.Reset()
 	}
 
@@ -61,7 +61,7 @@ func BenchmarkShortenJSONURL(b *testing.B) {
 		r := httptest.NewRequest("POST", "/api/shorten", bytes.NewReader(body))
 		r.Header.Set("Content-Type", "application/json")
 		r.AddCookie(testCookie)
-		h.ShortenJSONURL(w, r)
+		serveWithIdentity(h, h.ShortenJSONURL, w, r)
 	}
 
 	mockStore.AssertExpectations(b)
@@ -107,7 +107,7 @@ func BenchmarkShortenBatch(b *testing.B) {
 		r := httptest.NewRequest("POST", "/api/shorten/batch", bytes.NewReader(body))
 		r.Header.Set("Content-Type", "application/json")
 		r.AddCookie(testCookie)
-		h.ShortenBatch(w, r)
+		serveWithIdentity(h, h.ShortenBatch, w, r)
 	}
 
 	mockStore.AssertExpectations(b)
@@ -118,14 +118,16 @@ func BenchmarkUserURLsHandler(b *testing.B) {
 	mockStore := new(MockURLStore)
 	h := setupTestHandler(mockStore)
 
-	addShortURL(userID, "http://short/1", "http://original/1")
-	addShortURL(userID, "http://short/2", "http://original/2")
+	mockStore.On("GetUserURLs", mock.Anything, userID).Return([]store.UserURL{
+		{ShortURL: "short1", OriginalURL: "http://original/1"},
+		{ShortURL: "short2", OriginalURL: "http://original/2"},
+	}, nil)
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		w := httptest.NewRecorder()
 		r := httptest.NewRequest("GET", "/api/user/urls", nil)
 		r.AddCookie(testCookie)
-		h.UserURLsHandler(w, r)
+		serveWithIdentity(h, h.UserURLsHandler, w, r)
 	}
 }
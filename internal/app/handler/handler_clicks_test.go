@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/config"
+	"github.com/TimBerk/go-link-shortener/internal/app/store"
+)
+
+func TestRedirect_ClickLimit(t *testing.T) {
+	tests := []struct {
+		name           string
+		clickResult    store.ClickResult
+		expectedStatus int
+	}{
+		{name: "Unlimited link", clickResult: store.ClickUnlimited, expectedStatus: http.StatusTemporaryRedirect},
+		{name: "Click within limit", clickResult: store.ClickAllowed, expectedStatus: http.StatusTemporaryRedirect},
+		{name: "Exhausted limit", clickResult: store.ClickExhausted, expectedStatus: http.StatusGone},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockStore := &MockStore{
+				originalURL: "https://example.com",
+				exists:      true,
+				clickResult: test.clickResult,
+			}
+			testHandler := NewHandler(mockStore, config.NewConfig("localhost:8021", "http://base.loc", true), context.Background(), make(chan store.URLPair, 1))
+			req := httptest.NewRequest(http.MethodGet, "/abc123", nil)
+			recorder := httptest.NewRecorder()
+
+			serveWithIdentity(testHandler, testHandler.Redirect, recorder, req)
+
+			assert.Equal(t, test.expectedStatus, recorder.Code)
+		})
+	}
+}
+
+func TestShortenJSONURLHandler_MaxClicks(t *testing.T) {
+	tests := []struct {
+		name             string
+		body             string
+		maxClicks        int
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			name:             "One-time link",
+			body:             `{"url":"https://example.com","max_clicks":1}`,
+			maxClicks:        1,
+			expectedStatus:   http.StatusCreated,
+			expectedResponse: `{"result":"http://localhost:8021/abc123"}`,
+		},
+		{
+			name:             "Negative limit",
+			body:             `{"url":"https://example.com","max_clicks":-1}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"error":"invalid max clicks: max_clicks must be positive"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockStore := new(MockURLStore)
+			testHandler := NewHandler(mockStore, config.NewConfig("localhost:8021", "http://base.loc", true), context.Background(), make(chan store.URLPair, 1))
+			if test.maxClicks != 0 {
+				opts := store.LinkOptions{MaxClicks: test.maxClicks}
+				mockStore.On("AddLink", mock.Anything, "https://example.com", opts, userID).Return("abc123", nil)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/api/shorten", bytes.NewBufferString(test.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.AddCookie(mockCookie(userID))
+			recorder := httptest.NewRecorder()
+
+			serveWithIdentity(testHandler, testHandler.ShortenJSONURL, recorder, req)
+
+			assert.Equal(t, test.expectedStatus, recorder.Code)
+			assert.Equal(t, test.expectedResponse, strings.TrimSuffix(recorder.Body.String(), "\n"))
+			mockStore.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRedirect_RecordsClick(t *testing.T) {
+	tests := []struct {
+		name       string
+		realIP     string
+		proxies    string
+		clickLimit store.ClickResult
+		expectedIP string
+		wantClick  bool
+	}{
+		{name: "Click from connection address", clickLimit: store.ClickUnlimited, expectedIP: "192.0.2.1", wantClick: true},
+		{name: "Click from trusted proxy header", realIP: "198.51.100.7", proxies: "192.0.2.0/24", clickLimit: store.ClickAllowed, expectedIP: "198.51.100.7", wantClick: true},
+		{name: "Forged header is ignored", realIP: "198.51.100.7", clickLimit: store.ClickUnlimited, expectedIP: "192.0.2.1", wantClick: true},
+		{name: "Exhausted link is not recorded", clickLimit: store.ClickExhausted},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockStore := &MockStore{originalURL: "https://example.com", exists: true, clickResult: test.clickLimit}
+			clickChan := make(chan store.Click, 1)
+			cfg := config.NewConfig("localhost:8021", "http://base.loc", true)
+			cfg.TrustedProxies = test.proxies
+			testHandler := NewHandler(mockStore, cfg, context.Background(), make(chan store.URLPair, 1), WithClickChan(clickChan))
+			req := httptest.NewRequest(http.MethodGet, "/abc123", nil)
+			req.RemoteAddr = "192.0.2.1:54321"
+			req.Header.Set("Referer", "https://ref.example.com")
+			req.Header.Set("User-Agent", "test-agent")
+			if test.realIP != "" {
+				req.Header.Set("X-Real-IP", test.realIP)
+			}
+			req.AddCookie(mockCookie(userID))
+			recorder := httptest.NewRecorder()
+
+			serveWithIdentity(testHandler, testHandler.Redirect, recorder, req)
+
+			if !test.wantClick {
+				assert.Empty(t, clickChan)
+				return
+			}
+			require.Len(t, clickChan, 1)
+			click := <-clickChan
+			assert.Equal(t, userID, click.VisitorID)
+			assert.Equal(t, "https://ref.example.com", click.Referrer)
+			assert.Equal(t, "test-agent", click.UserAgent)
+			assert.Equal(t, test.expectedIP, click.IP)
+			assert.False(t, click.ClickedAt.IsZero())
+		})
+	}
+}
+
+func TestRedirect_FullClickChanDoesNotBlock(t *testing.T) {
+	mockStore := &MockStore{originalURL: "https://example.com", exists: true}
+	clickChan := make(chan store.Click)
+	testHandler := NewHandler(mockStore, config.NewConfig("localhost:8021", "http://base.loc", true), context.Background(),
+		make(chan store.URLPair, 1), WithClickChan(clickChan))
+	recorder := httptest.NewRecorder()
+
+	serveWithIdentity(testHandler, testHandler.Redirect, recorder, httptest.NewRequest(http.MethodGet, "/abc123", nil))
+
+	assert.Equal(t, http.StatusTemporaryRedirect, recorder.Code, "Переполненный канал аналитики не должен мешать перенаправлению")
+}
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/config"
+	"github.com/TimBerk/go-link-shortener/internal/app/models/batch"
+	"github.com/TimBerk/go-link-shortener/internal/app/store"
+)
+
+func TestShortenJSONURLHandler_Expiration(t *testing.T) {
+	tests := []struct {
+		name             string
+		body             string
+		wantTTL          time.Duration
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			name:             "TTL",
+			body:             `{"url":"https://example.com","ttl":"24h"}`,
+			wantTTL:          24 * time.Hour,
+			expectedStatus:   http.StatusCreated,
+			expectedResponse: `{"result":"http://localhost:8021/abc123"}`,
+		},
+		{
+			name:             "Expires at",
+			body:             `{"url":"https://example.com","expires_at":"` + time.Now().Add(time.Hour).Format(time.RFC3339) + `"}`,
+			wantTTL:          time.Hour,
+			expectedStatus:   http.StatusCreated,
+			expectedResponse: `{"result":"http://localhost:8021/abc123"}`,
+		},
+		{
+			name:             "Both TTL and expires at",
+			body:             `{"url":"https://example.com","ttl":"1h","expires_at":"2100-01-01T00:00:00Z"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"error":"invalid expiration: only one of expires_at and ttl is allowed"}`,
+		},
+		{
+			name:             "Non-positive TTL",
+			body:             `{"url":"https://example.com","ttl":"0s"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"error":"invalid expiration: ttl must be positive"}`,
+		},
+		{
+			name:             "Expires at in the past",
+			body:             `{"url":"https://example.com","expires_at":"2000-01-01T00:00:00Z"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"error":"invalid expiration: expires_at must be in the future"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockStore := new(MockURLStore)
+			testHandler := NewHandler(mockStore, config.NewConfig("localhost:8021", "http://base.loc", true), context.Background(), make(chan store.URLPair, 1))
+			if test.wantTTL != 0 {
+				withinTTL := mock.MatchedBy(func(opts store.LinkOptions) bool {
+					left := time.Until(opts.ExpiresAt)
+					return opts.Alias == "" && left > test.wantTTL-time.Minute && left <= test.wantTTL
+				})
+				mockStore.On("AddLink", mock.Anything, "https://example.com", withinTTL, userID).Return("abc123", nil)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/api/shorten", bytes.NewBufferString(test.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.AddCookie(mockCookie(userID))
+			recorder := httptest.NewRecorder()
+
+			serveWithIdentity(testHandler, testHandler.ShortenJSONURL, recorder, req)
+
+			assert.Equal(t, test.expectedStatus, recorder.Code)
+			assert.Equal(t, test.expectedResponse, strings.TrimSuffix(recorder.Body.String(), "\n"))
+			mockStore.AssertExpectations(t)
+		})
+	}
+}
+
+func TestShortenBatch_Expiration(t *testing.T) {
+	mockStore := new(MockURLStore)
+	testHandler := NewHandler(mockStore, config.NewConfig("localhost:8021", "http://base.loc", true), context.Background(), make(chan store.URLPair, 1))
+	resolved := mock.MatchedBy(func(urls batch.BatchRequest) bool {
+		return len(urls) == 2 &&
+			urls[0].TTL == "" && urls[0].ExpiresAt != nil && time.Until(*urls[0].ExpiresAt) > 59*time.Minute &&
+			urls[1].ExpiresAt == nil
+	})
+	mockStore.On("AddURLs", mock.Anything, resolved, userID).
+		Return(batch.BatchResponse{{CorrelationID: "1", ShortURL: "abc123"}, {CorrelationID: "2", ShortURL: "def456"}}, nil)
+
+	body := `[{"correlation_id":"1","original_url":"https://example.com","ttl":"1h"},` +
+		`{"correlation_id":"2","original_url":"https://example.org"}]`
+	req := httptest.NewRequest(http.MethodPost, "/api/shorten/batch", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(mockCookie(userID))
+	recorder := httptest.NewRecorder()
+
+	serveWithIdentity(testHandler, testHandler.ShortenBatch, recorder, req)
+
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	mockStore.AssertExpectations(t)
+}
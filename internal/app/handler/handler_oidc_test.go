@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/config"
+	"github.com/TimBerk/go-link-shortener/internal/app/store"
+	"github.com/TimBerk/go-link-shortener/internal/app/store/local"
+	"github.com/TimBerk/go-link-shortener/internal/pkg/cookies"
+	"github.com/TimBerk/go-link-shortener/internal/pkg/oidc"
+	"github.com/TimBerk/go-link-shortener/internal/pkg/oidc/oidctest"
+)
+
+// newOIDCRouter возвращает роутер с путями входа через SSO у локального провайдера idp.
+// Без idp вход через SSO не настроен.
+func newOIDCRouter(t *testing.T, idp *oidctest.Server) http.Handler {
+	testStore, err := local.NewURLStore(store.NewIDGenerator())
+	require.NoError(t, err)
+	cfg := config.NewConfig("localhost:8021", "http://localhost:8021", true)
+
+	var opts []Option
+	if idp != nil {
+		cfg.OIDCIssuer = idp.URL
+		cfg.OIDCClientID = idp.ClientID
+		cfg.OIDCClientSecret = idp.ClientSecret
+		cfg.OIDCUserPrefix = oidc.DefaultUserPrefix
+		provider, err := oidc.NewProvider(cfg.OIDCOptions())
+		require.NoError(t, err)
+		opts = append(opts, WithOIDC(provider))
+	}
+	h := NewHandler(testStore, cfg, context.Background(), make(chan store.URLPair, 1), opts...)
+
+	router := chi.NewRouter()
+	router.Use(h.Identity)
+	router.Get("/api/user/oidc/login", h.OIDCLoginHandler)
+	router.Get(config.OIDCCallbackPath, h.OIDCCallbackHandler)
+	router.Get("/api/user/urls", h.UserURLsHandler)
+	router.Post("/", h.ShortenURL)
+	return router
+}
+
+// startOIDCLogin начинает вход через SSO и возвращает адрес провайдера и cookie oidc
+func startOIDCLogin(t *testing.T, router http.Handler) (string, *http.Cookie) {
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/user/oidc/login", nil))
+	require.Equal(t, http.StatusFound, recorder.Code)
+
+	state := findCookie(recorder, cookies.OIDCCookieName)
+	require.NotNil(t, state, "State входа должен сохраняться в cookie")
+	assert.True(t, state.HttpOnly)
+	return recorder.Header().Get("Location"), state
+}
+
+// oidcCallback выполняет возврат от провайдера на адрес callback
+func oidcCallback(router http.Handler, callback *url.URL, requestCookies ...*http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, callback.RequestURI(), nil)
+	for _, cookie := range requestCookies {
+		req.AddCookie(cookie)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestOIDCLoginFlow(t *testing.T) {
+	idp := oidctest.NewServer(t, "shortener", "secret")
+	idp.Subject = "248289761001"
+	router := newOIDCRouter(t, idp)
+
+	authURL, state := startOIDCLogin(t, router)
+	require.True(t, strings.HasPrefix(authURL, idp.URL+"/authorize?"), "Пользователь должен перенаправляться к провайдеру")
+	callback := idp.Authorize(t, authURL)
+	assert.Equal(t, config.OIDCCallbackPath, callback.Path)
+
+	recorder := oidcCallback(router, callback, state)
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	assert.JSONEq(t, `{"user_id":"oidc:248289761001"}`, recorder.Body.String())
+	cleared := findCookie(recorder, cookies.OIDCCookieName)
+	require.NotNil(t, cleared)
+	assert.Negative(t, cleared.MaxAge, "Cookie oidc должна удаляться после возврата")
+	session := findCookie(recorder, cookies.SessionCookieName)
+	require.NotNil(t, session, "После входа через SSO должна открываться сессия")
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("https://example.com/sso"))
+	req.AddCookie(session)
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusCreated, recorder.Code)
+	assert.Nil(t, findCookie(recorder, "user"), "Пользователь сессии не должен получать cookie user")
+
+	req = httptest.NewRequest(http.MethodGet, "/api/user/urls", nil)
+	req.AddCookie(session)
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "https://example.com/sso")
+
+	assert.Equal(t, http.StatusUnauthorized, oidcCallback(router, callback, state).Code, "Код авторизации нельзя использовать повторно")
+}
+
+func TestOIDCCallbackHandler(t *testing.T) {
+	tests := []struct {
+		name             string
+		callback         func(callback *url.URL)
+		withoutState     bool
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			name:             "Without state cookie",
+			withoutState:     true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"error":"OIDC login has expired, start again"}`,
+		},
+		{
+			name: "Another state",
+			callback: func(callback *url.URL) {
+				query := callback.Query()
+				query.Set("state", "forged")
+				callback.RawQuery = query.Encode()
+			},
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"error":"Invalid OIDC state"}`,
+		},
+		{
+			name: "Rejected by provider",
+			callback: func(callback *url.URL) {
+				query := callback.Query()
+				query.Del("code")
+				query.Set("error", "access_denied")
+				callback.RawQuery = query.Encode()
+			},
+			expectedStatus:   http.StatusUnauthorized,
+			expectedResponse: `{"error":"Login was rejected by identity provider"}`,
+		},
+		{
+			name: "Unknown code",
+			callback: func(callback *url.URL) {
+				query := callback.Query()
+				query.Set("code", "forged")
+				callback.RawQuery = query.Encode()
+			},
+			expectedStatus:   http.StatusUnauthorized,
+			expectedResponse: `{"error":"OIDC login failed"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			idp := oidctest.NewServer(t, "shortener", "")
+			router := newOIDCRouter(t, idp)
+			authURL, state := startOIDCLogin(t, router)
+			callback := idp.Authorize(t, authURL)
+			if test.callback != nil {
+				test.callback(callback)
+			}
+
+			var recorder *httptest.ResponseRecorder
+			if test.withoutState {
+				recorder = oidcCallback(router, callback)
+			} else {
+				recorder = oidcCallback(router, callback, state)
+			}
+
+			assert.Equal(t, test.expectedStatus, recorder.Code)
+			assert.Equal(t, test.expectedResponse, strings.TrimSuffix(recorder.Body.String(), "\n"))
+			assert.Nil(t, findCookie(recorder, cookies.SessionCookieName), "Сессия не должна открываться при ошибке входа")
+		})
+	}
+}
+
+func TestOIDCLoginHandler_NotConfigured(t *testing.T) {
+	router := newOIDCRouter(t, nil)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/user/oidc/login", nil))
+
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Equal(t, `{"error":"OIDC login is not configured"}`, strings.TrimSuffix(recorder.Body.String(), "\n"))
+}
@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/attempts"
+	"github.com/TimBerk/go-link-shortener/internal/app/config"
+	"github.com/TimBerk/go-link-shortener/internal/app/store"
+)
+
+func newPasswordRequest(password string) *http.Request {
+	form := url.Values{"password": {password}}
+	req := httptest.NewRequest(http.MethodPost, "/abc123", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+func TestRedirect_Password(t *testing.T) {
+	passwordHash, err := store.HashPassword("s3cret")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name             string
+		request          *http.Request
+		expectedStatus   int
+		expectedLocation string
+		expectedBody     string
+	}{
+		{
+			name:           "Form for protected link",
+			request:        httptest.NewRequest(http.MethodGet, "/abc123", nil),
+			expectedStatus: http.StatusOK,
+			expectedBody:   `<input type="password" name="password"`,
+		},
+		{
+			name:           "Wrong password",
+			request:        newPasswordRequest("secret"),
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   "Invalid password",
+		},
+		{
+			name:             "Valid password",
+			request:          newPasswordRequest("s3cret"),
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "https://example.com",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockStore := &MockStore{
+				originalURL:  "https://example.com",
+				exists:       true,
+				passwordHash: passwordHash,
+			}
+			testHandler := NewHandler(mockStore, config.NewConfig("localhost:8021", "http://base.loc", true), context.Background(), make(chan store.URLPair, 1))
+			recorder := httptest.NewRecorder()
+
+			serveWithIdentity(testHandler, testHandler.Redirect, recorder, test.request)
+
+			assert.Equal(t, test.expectedStatus, recorder.Code)
+			assert.Equal(t, test.expectedLocation, recorder.Header().Get("Location"))
+			assert.Contains(t, recorder.Body.String(), test.expectedBody)
+		})
+	}
+}
+
+func TestRedirect_PasswordThrottling(t *testing.T) {
+	passwordHash, err := store.HashPassword("s3cret")
+	require.NoError(t, err)
+	mockStore := &MockStore{
+		originalURL:  "https://example.com",
+		exists:       true,
+		passwordHash: passwordHash,
+	}
+	testHandler := NewHandler(mockStore, config.NewConfig("localhost:8021", "http://base.loc", true), context.Background(), make(chan store.URLPair, 1))
+	now := time.Now()
+	testHandler.attempts.NowFunc = func() time.Time { return now }
+
+	for i := 0; i < attempts.DefaultLimit; i++ {
+		recorder := httptest.NewRecorder()
+		serveWithIdentity(testHandler, testHandler.Redirect, recorder, newPasswordRequest("wrong"))
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code, "Попытка %d", i+1)
+	}
+
+	recorder := httptest.NewRecorder()
+	serveWithIdentity(testHandler, testHandler.Redirect, recorder, newPasswordRequest("s3cret"))
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code, "Верный пароль не должен приниматься во время блокировки")
+	assert.Equal(t, "60", recorder.Header().Get("Retry-After"))
+
+	now = now.Add(attempts.DefaultLockout)
+	recorder = httptest.NewRecorder()
+	serveWithIdentity(testHandler, testHandler.Redirect, recorder, newPasswordRequest("s3cret"))
+	assert.Equal(t, http.StatusSeeOther, recorder.Code, "После блокировки ссылка должна открываться")
+}
+
+func TestRedirect_PasswordThrottlingPerClient(t *testing.T) {
+	passwordHash, err := store.HashPassword("s3cret")
+	require.NoError(t, err)
+	mockStore := &MockStore{
+		originalURL:  "https://example.com",
+		exists:       true,
+		passwordHash: passwordHash,
+	}
+	testHandler := NewHandler(mockStore, config.NewConfig("localhost:8021", "http://base.loc", true), context.Background(), make(chan store.URLPair, 1))
+
+	for i := 0; i < attempts.DefaultLimit; i++ {
+		req := newPasswordRequest("wrong")
+		req.RemoteAddr = "198.51.100.1:1234"
+		recorder := httptest.NewRecorder()
+		serveWithIdentity(testHandler, testHandler.Redirect, recorder, req)
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code, "Попытка %d", i+1)
+	}
+
+	req := newPasswordRequest("s3cret")
+	req.RemoteAddr = "198.51.100.1:1234"
+	recorder := httptest.NewRecorder()
+	serveWithIdentity(testHandler, testHandler.Redirect, recorder, req)
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code, "Клиент A должен быть заблокирован")
+
+	req = newPasswordRequest("s3cret")
+	req.RemoteAddr = "203.0.113.2:1234"
+	recorder = httptest.NewRecorder()
+	serveWithIdentity(testHandler, testHandler.Redirect, recorder, req)
+	assert.Equal(t, http.StatusSeeOther, recorder.Code, "Неверные пароли клиента A не должны блокировать ссылку для клиента B")
+}
+
+func TestShortenJSONURLHandler_Password(t *testing.T) {
+	tests := []struct {
+		name             string
+		body             string
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			name:             "Protected link",
+			body:             `{"url":"https://example.com","password":"s3cret"}`,
+			expectedStatus:   http.StatusCreated,
+			expectedResponse: `{"result":"http://localhost:8021/abc123"}`,
+		},
+		{
+			name:             "Too long password",
+			body:             `{"url":"https://example.com","password":"` + strings.Repeat("a", 73) + `"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"error":"invalid password: password must be at most 72 bytes"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockStore := new(MockURLStore)
+			testHandler := NewHandler(mockStore, config.NewConfig("localhost:8021", "http://base.loc", true), context.Background(), make(chan store.URLPair, 1))
+			if test.expectedStatus == http.StatusCreated {
+				hashed := mock.MatchedBy(func(opts store.LinkOptions) bool {
+					return store.CheckPassword(opts.PasswordHash, "s3cret")
+				})
+				mockStore.On("AddLink", mock.Anything, "https://example.com", hashed, userID).Return("abc123", nil)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/api/shorten", bytes.NewBufferString(test.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.AddCookie(mockCookie(userID))
+			recorder := httptest.NewRecorder()
+
+			serveWithIdentity(testHandler, testHandler.ShortenJSONURL, recorder, req)
+
+			assert.Equal(t, test.expectedStatus, recorder.Code)
+			assert.Equal(t, test.expectedResponse, strings.TrimSuffix(recorder.Body.String(), "\n"))
+			mockStore.AssertExpectations(t)
+		})
+	}
+}
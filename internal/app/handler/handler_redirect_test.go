@@ -6,6 +6,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -15,10 +16,12 @@ import (
 )
 
 type MockStore struct {
-	originalURL string
-	exists      bool
-	addedURL    string
-	addedURLs   batch.BatchRequest
+	originalURL  string
+	exists       bool
+	addedURL     string
+	addedURLs    batch.BatchRequest
+	clickResult  store.ClickResult
+	passwordHash string
 }
 
 func (m *MockStore) GetOriginalURL(ctx context.Context, shortURL string, userID string) (string, bool, bool) {
@@ -33,6 +36,14 @@ func (m *MockStore) AddURLs(ctx context.Context, urls batch.BatchRequest, userID
 	return responses, nil
 }
 
+func (m *MockStore) AddLink(ctx context.Context, originalURL string, opts store.LinkOptions, userID string) (string, error) {
+	m.addedURL = originalURL
+	if opts.Alias != "" {
+		return opts.Alias, nil
+	}
+	return "abc123", nil
+}
+
 func (m *MockStore) AddURL(ctx context.Context, url string, userID string) (string, error) {
 	m.addedURL = url
 	return "abc123", nil
@@ -46,6 +57,86 @@ func (m *MockStore) DeleteURL(ctx context.Context, batch []store.URLPair) error
 	return nil
 }
 
+func (m *MockStore) GetUserURLs(ctx context.Context, userID string) ([]store.UserURL, error) {
+	return nil, nil
+}
+
+func (m *MockStore) UseClick(ctx context.Context, shortURL string) (store.ClickResult, error) {
+	return m.clickResult, nil
+}
+
+func (m *MockStore) GetPasswordHash(ctx context.Context, shortURL string) (string, error) {
+	return m.passwordHash, nil
+}
+
+func (m *MockStore) GetLinkExpiry(ctx context.Context, shortURL string) (time.Time, error) {
+	return time.Time{}, nil
+}
+
+func (m *MockStore) AddClicks(ctx context.Context, clicks []store.Click) error {
+	return nil
+}
+
+func (m *MockStore) GetLinkStats(ctx context.Context, link store.URLPair, query store.StatsQuery) (store.LinkStats, error) {
+	return store.LinkStats{}, nil
+}
+
+func (m *MockStore) GetServiceStats(ctx context.Context) (store.ServiceStats, error) {
+	return store.ServiceStats{}, nil
+}
+
+func (m *MockStore) DeleteExpiredURLs(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	return nil, nil
+}
+
+func (m *MockStore) AddAPIKey(ctx context.Context, key store.APIKey) error {
+	return nil
+}
+
+func (m *MockStore) GetAPIKeys(ctx context.Context, userID string) ([]store.APIKey, error) {
+	return nil, nil
+}
+
+func (m *MockStore) RevokeAPIKey(ctx context.Context, userID string, keyID string) error {
+	return nil
+}
+
+func (m *MockStore) UseAPIKey(ctx context.Context, keyHash string, usedAt time.Time) (string, error) {
+	return "", store.ErrAPIKeyNotFound
+}
+
+func (m *MockStore) CreateAccount(ctx context.Context, account store.Account) error {
+	return nil
+}
+
+func (m *MockStore) GetAccount(ctx context.Context, login string) (store.Account, error) {
+	return store.Account{}, store.ErrAccountNotFound
+}
+
+func (m *MockStore) AddSession(ctx context.Context, session store.Session) error {
+	return nil
+}
+
+func (m *MockStore) GetSession(ctx context.Context, tokenHash string, now time.Time) (store.Session, error) {
+	return store.Session{}, store.ErrSessionNotFound
+}
+
+func (m *MockStore) DeleteSession(ctx context.Context, tokenHash string) error {
+	return nil
+}
+
+func (m *MockStore) ClaimURLs(ctx context.Context, fromUserID string, toUserID string) (int, error) {
+	return 0, nil
+}
+
+func (m *MockStore) SearchURLs(ctx context.Context, search store.LinkSearch) ([]store.Link, error) {
+	return nil, nil
+}
+
+func (m *MockStore) DisableURL(ctx context.Context, shortURL string) error {
+	return nil
+}
+
 func TestShortenURL_Success(t *testing.T) {
 	ctx := context.Background()
 	urlChan := make(chan store.URLPair, 1000)
@@ -57,7 +148,7 @@ func TestShortenURL_Success(t *testing.T) {
 	req.Header.Set("Content-Type", "text/plain")
 	w := httptest.NewRecorder()
 
-	handler.ShortenURL(w, req)
+	serveWithIdentity(handler, handler.ShortenURL, w, req)
 
 	assert.Equal(t, http.StatusCreated, w.Code)
 	expectedResponse := "http://localhost:8021/abc123"
@@ -76,8 +167,26 @@ func TestRedirect_Success(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/abc123", nil)
 	w := httptest.NewRecorder()
 
-	handler.Redirect(w, req)
+	serveWithIdentity(handler, handler.Redirect, w, req)
 
 	assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
 	assert.Equal(t, "https://example.com", w.Header().Get("Location"))
 }
+
+func TestShortenBatch_Success(t *testing.T) {
+	ctx := context.Background()
+	urlChan := make(chan store.URLPair, 1000)
+	mockConfig := config.NewConfig("localhost:8021", "http://base.url", true)
+	mockStore := &MockStore{}
+	handler := NewHandler(mockStore, mockConfig, ctx, urlChan)
+	body := strings.NewReader(`[{"correlation_id":"test_1","original_url":"https://example.com"}]`)
+	req := httptest.NewRequest(http.MethodPost, "/api/shorten/batch", body)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	serveWithIdentity(handler, handler.ShortenBatch, w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	expectedResponse := `[{"correlation_id":"test_1","short_url":"http://localhost:8021/abc123"}]`
+	assert.JSONEq(t, expectedResponse, w.Body.String())
+}
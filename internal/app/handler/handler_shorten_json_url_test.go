@@ -70,7 +70,7 @@ func TestShortenJsonURLHandler(t *testing.T) {
 			req.AddCookie(testCookie)
 			recorder := httptest.NewRecorder()
 
-			testHandler.ShortenJSONURL(recorder, req)
+			serveWithIdentity(testHandler, testHandler.ShortenJSONURL, recorder, req)
 
 			assert.Equal(t, test.expectedStatus, recorder.Code, "Неверный статус код для теста: %s", test.name)
 			assert.Equal(t, test.expectedResponse, strings.TrimSuffix(recorder.Body.String(), "\n"), "Неверное тело ответа для теста: %s", test.name)
@@ -65,7 +65,7 @@ func TestShortenURL(t *testing.T) {
 			req.AddCookie(testCookie)
 			recorder := httptest.NewRecorder()
 
-			testHandler.ShortenURL(recorder, req)
+			serveWithIdentity(testHandler, testHandler.ShortenURL, recorder, req)
 
 			assert.Equal(t, test.expectedStatus, recorder.Code, "Неверный статус код для теста: %s", test.name)
 			assert.Equal(t, test.expectedResponse, recorder.Body.String(), "Неверное тело ответа для теста: %s", test.name)
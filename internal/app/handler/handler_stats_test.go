@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/config"
+	"github.com/TimBerk/go-link-shortener/internal/app/store"
+)
+
+func TestUserURLStatsHandler(t *testing.T) {
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	stats := store.LinkStats{
+		TotalClicks:    2,
+		UniqueVisitors: 1,
+		ByDay:          []store.PeriodCount{{Period: day, Clicks: 2}},
+		ByHour:         []store.PeriodCount{{Period: day.Add(10 * time.Hour), Clicks: 2}},
+		TopReferrers:   []store.ValueCount{{Value: "https://ref.example.com", Clicks: 2}},
+		TopUserAgents:  []store.ValueCount{},
+	}
+
+	tests := []struct {
+		name             string
+		target           string
+		withCookie       bool
+		query            store.StatsQuery
+		storeStats       store.LinkStats
+		storeErr         error
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			name:           "Stats of own link",
+			target:         "/api/user/urls/abc123/stats?from=2024-05-01",
+			withCookie:     true,
+			query:          store.StatsQuery{From: day},
+			storeStats:     stats,
+			expectedStatus: http.StatusOK,
+			expectedResponse: `{"total_clicks":2,"unique_visitors":1,` +
+				`"by_day":[{"period":"2024-05-01T00:00:00Z","clicks":2}],` +
+				`"by_hour":[{"period":"2024-05-01T10:00:00Z","clicks":2}],` +
+				`"top_referrers":[{"value":"https://ref.example.com","clicks":2}],"top_user_agents":[]}`,
+		},
+		{
+			name:             "Link of another user",
+			target:           "/api/user/urls/abc123/stats",
+			withCookie:       true,
+			storeErr:         store.ErrStatsNotFound,
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: `{"error":"Short URL not found"}`,
+		},
+		{
+			name:             "Store error",
+			target:           "/api/user/urls/abc123/stats",
+			withCookie:       true,
+			storeErr:         errors.New("store error"),
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: `{"error":"Failed to get link stats"}`,
+		},
+		{
+			name:             "Invalid range",
+			target:           "/api/user/urls/abc123/stats?from=yesterday",
+			withCookie:       true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"error":"invalid stats range: from must be a RFC 3339 time or a date like 2006-01-02"}`,
+		},
+		{
+			name:             "Without cookie",
+			target:           "/api/user/urls/abc123/stats",
+			expectedStatus:   http.StatusUnauthorized,
+			expectedResponse: "Unauthorized",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockStore := new(MockURLStore)
+			testHandler := NewHandler(mockStore, config.NewConfig("localhost:8021", "http://base.loc", true), context.Background(), make(chan store.URLPair, 1))
+			if test.storeErr != nil || test.expectedStatus == http.StatusOK {
+				link := store.URLPair{ShortURL: "abc123", UserID: userID}
+				mockStore.On("GetLinkStats", mock.Anything, link, test.query).Return(test.storeStats, test.storeErr)
+			}
+			router := chi.NewRouter()
+			router.Use(testHandler.Identity)
+			router.Get("/api/user/urls/{id}/stats", testHandler.UserURLStatsHandler)
+			req := httptest.NewRequest(http.MethodGet, test.target, nil)
+			if test.withCookie {
+				req.AddCookie(mockCookie(userID))
+			}
+			recorder := httptest.NewRecorder()
+
+			router.ServeHTTP(recorder, req)
+
+			assert.Equal(t, test.expectedStatus, recorder.Code)
+			assert.Equal(t, test.expectedResponse, strings.TrimSuffix(recorder.Body.String(), "\n"))
+			mockStore.AssertExpectations(t)
+		})
+	}
+}
+
+func TestInternalStatsHandler(t *testing.T) {
+	tests := []struct {
+		name             string
+		storeStats       store.ServiceStats
+		storeErr         error
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			name:             "Service stats",
+			storeStats:       store.ServiceStats{URLs: 10, Users: 3},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"urls":10,"users":3}`,
+		},
+		{
+			name:             "Store error",
+			storeErr:         errors.New("store error"),
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: `{"error":"Failed to get service stats"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockStore := new(MockURLStore)
+			testHandler := NewHandler(mockStore, config.NewConfig("localhost:8021", "http://base.loc", true), context.Background(), make(chan store.URLPair, 1))
+			mockStore.On("GetServiceStats", mock.Anything).Return(test.storeStats, test.storeErr)
+			recorder := httptest.NewRecorder()
+
+			testHandler.InternalStatsHandler(recorder, httptest.NewRequest(http.MethodGet, "/api/internal/stats", nil))
+
+			assert.Equal(t, test.expectedStatus, recorder.Code)
+			assert.Equal(t, test.expectedResponse, strings.TrimSuffix(recorder.Body.String(), "\n"))
+			mockStore.AssertExpectations(t)
+		})
+	}
+}
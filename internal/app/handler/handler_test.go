@@ -27,6 +27,11 @@ func mockCookie(userID string) *http.Cookie {
 	}
 }
 
+// serveWithIdentity вызывает обработчик через middleware определения пользователя, как это делает роутер
+func serveWithIdentity(h *Handler, handlerFunc http.HandlerFunc, w http.ResponseWriter, r *http.Request) {
+	h.Identity(handlerFunc).ServeHTTP(w, r)
+}
+
 type MockURLStore struct {
 	mock.Mock
 }
@@ -36,6 +41,11 @@ func (m *MockURLStore) AddURL(ctx context.Context, originalURL string, userID st
 	return args.String(0), nil
 }
 
+func (m *MockURLStore) AddLink(ctx context.Context, originalURL string, opts store.LinkOptions, userID string) (string, error) {
+	args := m.Called(ctx, originalURL, opts, userID)
+	return args.String(0), args.Error(1)
+}
+
 func (m *MockURLStore) AddURLs(ctx context.Context, urls batch.BatchRequest, userID string) (batch.BatchResponse, error) {
 	args := m.Called(ctx, urls, userID)
 	return args.Get(0).(batch.BatchResponse), args.Error(1)
@@ -55,3 +65,103 @@ func (m *MockURLStore) DeleteURL(ctx context.Context, batch []store.URLPair) err
 	args := m.Called(ctx, batch)
 	return args.Error(0)
 }
+
+func (m *MockURLStore) GetUserURLs(ctx context.Context, userID string) ([]store.UserURL, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]store.UserURL), args.Error(1)
+}
+
+func (m *MockURLStore) UseClick(ctx context.Context, shortURL string) (store.ClickResult, error) {
+	args := m.Called(ctx, shortURL)
+	return args.Get(0).(store.ClickResult), args.Error(1)
+}
+
+func (m *MockURLStore) GetLinkExpiry(ctx context.Context, shortURL string) (time.Time, error) {
+	args := m.Called(ctx, shortURL)
+	return args.Get(0).(time.Time), args.Error(1)
+}
+
+func (m *MockURLStore) GetPasswordHash(ctx context.Context, shortURL string) (string, error) {
+	args := m.Called(ctx, shortURL)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockURLStore) AddClicks(ctx context.Context, clicks []store.Click) error {
+	args := m.Called(ctx, clicks)
+	return args.Error(0)
+}
+
+func (m *MockURLStore) GetLinkStats(ctx context.Context, link store.URLPair, query store.StatsQuery) (store.LinkStats, error) {
+	args := m.Called(ctx, link, query)
+	return args.Get(0).(store.LinkStats), args.Error(1)
+}
+
+func (m *MockURLStore) GetServiceStats(ctx context.Context) (store.ServiceStats, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(store.ServiceStats), args.Error(1)
+}
+
+func (m *MockURLStore) AddAPIKey(ctx context.Context, key store.APIKey) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+func (m *MockURLStore) GetAPIKeys(ctx context.Context, userID string) ([]store.APIKey, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]store.APIKey), args.Error(1)
+}
+
+func (m *MockURLStore) RevokeAPIKey(ctx context.Context, userID string, keyID string) error {
+	args := m.Called(ctx, userID, keyID)
+	return args.Error(0)
+}
+
+func (m *MockURLStore) UseAPIKey(ctx context.Context, keyHash string, usedAt time.Time) (string, error) {
+	args := m.Called(ctx, keyHash, usedAt)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockURLStore) CreateAccount(ctx context.Context, account store.Account) error {
+	args := m.Called(ctx, account)
+	return args.Error(0)
+}
+
+func (m *MockURLStore) GetAccount(ctx context.Context, login string) (store.Account, error) {
+	args := m.Called(ctx, login)
+	return args.Get(0).(store.Account), args.Error(1)
+}
+
+func (m *MockURLStore) AddSession(ctx context.Context, session store.Session) error {
+	args := m.Called(ctx, session)
+	return args.Error(0)
+}
+
+func (m *MockURLStore) GetSession(ctx context.Context, tokenHash string, now time.Time) (store.Session, error) {
+	args := m.Called(ctx, tokenHash, now)
+	return args.Get(0).(store.Session), args.Error(1)
+}
+
+func (m *MockURLStore) DeleteSession(ctx context.Context, tokenHash string) error {
+	args := m.Called(ctx, tokenHash)
+	return args.Error(0)
+}
+
+func (m *MockURLStore) ClaimURLs(ctx context.Context, fromUserID string, toUserID string) (int, error) {
+	args := m.Called(ctx, fromUserID, toUserID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockURLStore) SearchURLs(ctx context.Context, search store.LinkSearch) ([]store.Link, error) {
+	args := m.Called(ctx, search)
+	return args.Get(0).([]store.Link), args.Error(1)
+}
+
+func (m *MockURLStore) DisableURL(ctx context.Context, shortURL string) error {
+	args := m.Called(ctx, shortURL)
+	return args.Error(0)
+}
+
+func (m *MockURLStore) DeleteExpiredURLs(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	args := m.Called(ctx, now, limit)
+	return args.Get(0).([]string), args.Error(1)
+}
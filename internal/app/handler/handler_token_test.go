@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/config"
+	"github.com/TimBerk/go-link-shortener/internal/app/store"
+	"github.com/TimBerk/go-link-shortener/internal/pkg/jwtauth"
+)
+
+func TestIssueTokenHandler(t *testing.T) {
+	tokens, err := jwtauth.NewManager(jwtauth.Options{Secret: "0123456789abcdef0123456789abcdef"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		opts           []Option
+		expectedStatus int
+	}{
+		{name: "Token for cookie user", opts: []Option{WithTokens(tokens)}, expectedStatus: http.StatusOK},
+		{name: "Tokens are not enabled", expectedStatus: http.StatusNotImplemented},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testHandler := NewHandler(new(MockURLStore), config.NewConfig("localhost:8021", "http://base.loc", true), context.Background(), make(chan store.URLPair, 1), test.opts...)
+			req := httptest.NewRequest(http.MethodPost, "/api/user/token", nil)
+			req.AddCookie(mockCookie(userID))
+			recorder := httptest.NewRecorder()
+
+			serveWithIdentity(testHandler, testHandler.IssueTokenHandler, recorder, req)
+
+			assert.Equal(t, test.expectedStatus, recorder.Code)
+			if test.expectedStatus != http.StatusOK {
+				return
+			}
+			var response TokenResponse
+			require.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+			assert.Equal(t, "Bearer", response.TokenType)
+			tokenUserID, err := tokens.Parse(response.Token)
+			require.NoError(t, err)
+			assert.Equal(t, userID, tokenUserID, "Токен должен идентифицировать пользователя cookie")
+		})
+	}
+}
+
+func TestBearerTokenIdentity(t *testing.T) {
+	tokens, err := jwtauth.NewManager(jwtauth.Options{Secret: "0123456789abcdef0123456789abcdef"})
+	require.NoError(t, err)
+	token, _, err := tokens.Issue(userID)
+	require.NoError(t, err)
+
+	mockStore := new(MockURLStore)
+	mockStore.On("GetUserURLs", mock.Anything, userID).Return([]store.UserURL{{ShortURL: "short1", OriginalURL: "https://example.com"}}, nil)
+	testHandler := NewHandler(mockStore, config.NewConfig("localhost:8021", "http://base.loc", true), context.Background(), make(chan store.URLPair, 1), WithTokens(tokens))
+	req := httptest.NewRequest(http.MethodGet, "/api/user/urls", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	recorder := httptest.NewRecorder()
+
+	serveWithIdentity(testHandler, testHandler.UserURLsHandler, recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Empty(t, recorder.Result().Cookies(), "Запрос с токеном не должен получать cookie")
+	mockStore.AssertExpectations(t)
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/config"
+	"github.com/TimBerk/go-link-shortener/internal/app/models/batch"
+	"github.com/TimBerk/go-link-shortener/internal/app/store"
+	"github.com/TimBerk/go-link-shortener/internal/app/store/local"
+)
+
+func TestUserURLsHandler(t *testing.T) {
+	ctx := context.Background()
+	urlChan := make(chan store.URLPair, 1000)
+	mockConfig := config.NewConfig("localhost:8021", "http://base.loc", true)
+
+	tests := []struct {
+		name             string
+		mockURLs         []store.UserURL
+		mockErr          error
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			name:             "User with links",
+			mockURLs:         []store.UserURL{{ShortURL: "short1", OriginalURL: "https://example.com"}},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `[{"short_url":"http://localhost:8021/short1","original_url":"https://example.com"}]` + "\n",
+		},
+		{
+			name:             "User without links",
+			mockURLs:         []store.UserURL{},
+			expectedStatus:   http.StatusNoContent,
+			expectedResponse: "",
+		},
+		{
+			name:             "Store error",
+			mockURLs:         []store.UserURL{},
+			mockErr:          errors.New("store error"),
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: "Failed to get user URLs\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockStore := new(MockURLStore)
+			testHandler := NewHandler(mockStore, mockConfig, ctx, urlChan)
+			mockStore.On("GetUserURLs", mock.Anything, userID).Return(test.mockURLs, test.mockErr)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/user/urls", nil)
+			req.AddCookie(mockCookie(userID))
+			recorder := httptest.NewRecorder()
+
+			serveWithIdentity(testHandler, testHandler.UserURLsHandler, recorder, req)
+
+			assert.Equal(t, test.expectedStatus, recorder.Code, "Неверный статус код для теста: %s", test.name)
+			assert.Equal(t, test.expectedResponse, recorder.Body.String(), "Неверное тело ответа для теста: %s", test.name)
+			mockStore.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserURLsHandler_BatchLinks(t *testing.T) {
+	ctx := context.Background()
+	urlChan := make(chan store.URLPair, 1000)
+	mockConfig := config.NewConfig("localhost:8021", "http://base.loc", true)
+	testStore, _ := local.NewURLStore(store.NewIDGenerator())
+	testHandler := NewHandler(testStore, mockConfig, ctx, urlChan)
+
+	_, err := testStore.AddURLs(ctx, batch.BatchRequest{{CorrelationID: "1", OriginalURL: "https://example.com"}}, userID)
+	assert.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/user/urls", nil)
+	req.AddCookie(mockCookie(userID))
+	recorder := httptest.NewRecorder()
+
+	serveWithIdentity(testHandler, testHandler.UserURLsHandler, recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"original_url":"https://example.com"`)
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TimBerk/go-link-shortener/internal/pkg/cookies"
+	"github.com/TimBerk/go-link-shortener/internal/pkg/oidc"
+	"github.com/TimBerk/go-link-shortener/internal/pkg/utils"
+)
+
+// OIDCLoginResponse результат входа через OpenID Connect
+// swagger:model
+type OIDCLoginResponse struct {
+	UserID string `json:"user_id" example:"oidc:248289761001"`
+}
+
+// OIDCLoginHandler начинает вход через OpenID Connect
+// @Summary Войти через SSO
+// @Description Перенаправляет к провайдеру OpenID Connect с кодом авторизации и PKCE.
+// @Description State, nonce и code verifier сохраняются в подписанной cookie oidc до возврата на /api/user/oidc/callback.
+// @Success 302 "Перенаправление к провайдеру"
+// @Failure 404 {object} ErrorResponse "Вход через SSO не настроен"
+// @Failure 502 {object} ErrorResponse "Провайдер недоступен"
+// @Router /api/user/oidc/login [get]
+func (h *Handler) OIDCLoginHandler(w http.ResponseWriter, r *http.Request) {
+	if h.oidc == nil {
+		utils.WriteJSONError(w, "OIDC login is not configured", http.StatusNotFound)
+		return
+	}
+
+	authRequest, err := oidc.NewAuthRequest()
+	if err != nil {
+		logrus.WithField("err", err).Error("Failed to generate OIDC request")
+		utils.WriteJSONError(w, "Failed to start OIDC login", http.StatusInternalServerError)
+		return
+	}
+	authURL, err := h.oidc.AuthCodeURL(r.Context(), authRequest)
+	if err != nil {
+		logrus.WithField("err", err).Error("Failed to discover OIDC provider")
+		utils.WriteJSONError(w, "Identity provider is unavailable", http.StatusBadGateway)
+		return
+	}
+	if err := cookies.SetOIDCCookie(w, authRequest, h.cfg.EnableHTTPS); err != nil {
+		logrus.WithField("err", err).Error("Failed to encode OIDC cookie")
+		utils.WriteJSONError(w, "Failed to start OIDC login", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// OIDCCallbackHandler завершает вход через OpenID Connect и открывает сессию
+// @Summary Возврат от SSO
+// @Description Проверяет state, обменивает код авторизации на ID token, проверяет его и устанавливает cookie session
+// @Description для пользователя из настроенных claims.
+// @Produce json
+// @Param   code query string true "Код авторизации"
+// @Param   state query string true "State запроса авторизации"
+// @Success 200 {object} OIDCLoginResponse "Пользователь сессии"
+// @Failure 400 {object} ErrorResponse "Нет запроса авторизации или state не совпадает"
+// @Failure 401 {object} ErrorResponse "Провайдер отклонил вход или ID token недействителен"
+// @Failure 404 {object} ErrorResponse "Вход через SSO не настроен"
+// @Failure 502 {object} ErrorResponse "Провайдер недоступен"
+// @Router /api/user/oidc/callback [get]
+func (h *Handler) OIDCCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	if h.oidc == nil {
+		utils.WriteJSONError(w, "OIDC login is not configured", http.StatusNotFound)
+		return
+	}
+
+	authRequest, err := cookies.GetOIDCCookie(r)
+	if err != nil {
+		utils.WriteJSONError(w, "OIDC login has expired, start again", http.StatusBadRequest)
+		return
+	}
+	cookies.ClearOIDCCookie(w, h.cfg.EnableHTTPS)
+
+	query := r.URL.Query()
+	if subtle.ConstantTimeCompare([]byte(query.Get("state")), []byte(authRequest.State)) != 1 {
+		utils.WriteJSONError(w, "Invalid OIDC state", http.StatusBadRequest)
+		return
+	}
+	if providerErr := query.Get("error"); providerErr != "" {
+		logrus.WithField("error", providerErr).Info("OIDC login rejected by provider")
+		utils.WriteJSONError(w, "Login was rejected by identity provider", http.StatusUnauthorized)
+		return
+	}
+
+	userID, err := h.oidc.Authenticate(r.Context(), query.Get("code"), authRequest)
+	if errors.Is(err, oidc.ErrDiscovery) {
+		logrus.WithField("err", err).Error("Failed to discover OIDC provider")
+		utils.WriteJSONError(w, "Identity provider is unavailable", http.StatusBadGateway)
+		return
+	} else if err != nil {
+		logrus.WithField("err", err).Info("Invalid OIDC login")
+		utils.WriteJSONError(w, "OIDC login failed", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.startSession(w, userID); err != nil {
+		logrus.WithField("err", err).Error("Failed to start session")
+		utils.WriteJSONError(w, "Failed to start session", http.StatusInternalServerError)
+		return
+	}
+
+	logrus.WithField("UserID", userID).Info("Logged in with OIDC")
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if errResponse := json.NewEncoder(w).Encode(OIDCLoginResponse{UserID: userID}); errResponse != nil {
+		logrus.WithField("err", errResponse).Error("Failed to response OIDC login")
+	}
+}
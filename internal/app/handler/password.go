@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"html/template"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/attempts"
+	"github.com/TimBerk/go-link-shortener/internal/app/middlewares/auth"
+	"github.com/TimBerk/go-link-shortener/internal/app/store"
+)
+
+// passwordForm - страница ввода пароля для защищенной ссылки
+var passwordForm = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Protected link</title>
+</head>
+<body>
+<form method="post">
+<p>This link is protected by a password.</p>
+{{if .}}<p>{{.}}</p>
+{{end}}<input type="password" name="password" autofocus required>
+<button type="submit">Open</button>
+</form>
+</body>
+</html>
+`))
+
+// writePasswordForm отдает страницу ввода пароля с сообщением об ошибке
+func writePasswordForm(w http.ResponseWriter, message string, status int) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := passwordForm.Execute(w, message); err != nil {
+		logrus.WithField("err", err).Error("Failed to response password form")
+	}
+}
+
+// unlockLink проверяет пароль защищенной ссылки и сообщает, можно ли выполнить переход.
+// Для защищенной ссылки GET-запрос получает страницу ввода пароля, а POST-запрос с полем password
+// проверяется с учетом ограничения неверных попыток. Если переход невозможен, ответ уже записан.
+func (h *Handler) unlockLink(w http.ResponseWriter, r *http.Request, shortURL string) bool {
+	passwordHash, err := h.store.GetPasswordHash(h.ctx, shortURL)
+	if err != nil {
+		http.Error(w, "Failed to check short URL password", http.StatusInternalServerError)
+		return false
+	}
+	if passwordHash == "" {
+		return true
+	}
+
+	if r.Method != http.MethodPost {
+		writePasswordForm(w, "", http.StatusOK)
+		return false
+	}
+
+	client := h.attemptsClient(r)
+	if left, ok := h.attempts.Blocked(shortURL, client); ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int((left+time.Second-1)/time.Second)))
+		writePasswordForm(w, "Too many attempts, try again later", http.StatusTooManyRequests)
+		return false
+	}
+
+	if !store.CheckPassword(passwordHash, r.PostFormValue("password")) {
+		h.attempts.Fail(shortURL, client)
+		logrus.WithField("shortUri", shortURL).Info("Invalid short URL password")
+		writePasswordForm(w, "Invalid password", http.StatusUnauthorized)
+		return false
+	}
+
+	h.attempts.Reset(shortURL, client)
+	return true
+}
+
+// attemptsClient возвращает клиента запроса для подсчета неверных паролей
+func (h *Handler) attemptsClient(r *http.Request) string {
+	return attempts.Client(auth.FromContext(r.Context()), h.proxies.ClientIP(r))
+}
@@ -0,0 +1,294 @@
+// Package auth определяет пользователя запроса.
+// Пользователь берется из токена Authorization: Bearer, затем из ключа API в заголовке X-API-Key,
+// затем из сессии учетной записи в cookie session, затем из cookie user,
+// а при их отсутствии создается новый пользователь, которому выдается cookie.
+// Пользователю назначается роль: администраторы задаются списком ID, остальные получают роль user.
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/store"
+	"github.com/TimBerk/go-link-shortener/internal/pkg/cookies"
+	"github.com/TimBerk/go-link-shortener/internal/pkg/jwtauth"
+)
+
+// Source - источник пользователя запроса
+type Source int
+
+const (
+	// SourceNew - пользователь создан для запроса
+	SourceNew Source = iota
+	// SourceCookie - пользователь из cookie user
+	SourceCookie
+	// SourceToken - пользователь из токена Authorization: Bearer
+	SourceToken
+	// SourceAPIKey - владелец ключа API из заголовка X-API-Key
+	SourceAPIKey
+	// SourceSession - учетная запись из сессии в cookie session
+	SourceSession
+)
+
+// Role - роль пользователя запроса
+type Role string
+
+const (
+	// RoleUser - обычный пользователь, которому доступны только свои ссылки
+	RoleUser Role = "user"
+	// RoleAdmin - администратор, которому доступны ссылки всех пользователей
+	RoleAdmin Role = "admin"
+)
+
+// Admins - множество ID пользователей с ролью администратора
+type Admins map[string]struct{}
+
+// NewAdmins возвращает множество администраторов из списка ID
+func NewAdmins(userIDs ...string) Admins {
+	admins := make(Admins, len(userIDs))
+	for _, userID := range userIDs {
+		admins[userID] = struct{}{}
+	}
+	return admins
+}
+
+// Role возвращает роль пользователя userID
+func (a Admins) Role(userID string) Role {
+	if _, ok := a[userID]; ok && userID != "" {
+		return RoleAdmin
+	}
+	return RoleUser
+}
+
+const (
+	// bearerPrefix - префикс заголовка Authorization с токеном
+	bearerPrefix = "Bearer "
+	// APIKeyHeader - заголовок с ключом API
+	APIKeyHeader = "X-API-Key"
+)
+
+var (
+	// ErrInvalidAuthorization ошибка о недопустимом заголовке Authorization
+	ErrInvalidAuthorization = errors.New("invalid authorization")
+	// ErrTokensDisabled ошибка о передаче токена, когда токены не настроены
+	ErrTokensDisabled = errors.New("bearer tokens are not enabled")
+	// ErrInvalidAPIKey ошибка о неизвестном или отозванном ключе API
+	ErrInvalidAPIKey = errors.New("invalid api key")
+)
+
+// KeyStore находит владельца ключа API по хешу значения
+type KeyStore interface {
+	UseAPIKey(ctx context.Context, keyHash string, usedAt time.Time) (string, error)
+}
+
+// SessionStore находит активную сессию по хешу токена
+type SessionStore interface {
+	GetSession(ctx context.Context, tokenHash string, now time.Time) (store.Session, error)
+}
+
+// Credentials - данные клиента, по которым определяется пользователь
+type Credentials struct {
+	// Authorization - значение заголовка Authorization
+	Authorization string
+	// APIKey - значение ключа API
+	APIKey string
+	// Session - токен сессии из cookie session
+	Session string
+	// Cookie - закодированное значение cookie user
+	Cookie string
+}
+
+// Resolver определяет пользователя запроса.
+// Без Tokens токены отклоняются, без Keys отклоняются ключи API, без Sessions сессии не учитываются.
+// Без Admins все пользователи получают роль user.
+type Resolver struct {
+	Tokens   *jwtauth.Manager
+	Keys     KeyStore
+	Sessions SessionStore
+	Admins   Admins
+}
+
+// Identity - пользователь запроса
+type Identity struct {
+	UserID string
+	Source Source
+	Role   Role
+}
+
+// IsNew сообщает, что пользователь создан для запроса и не передан клиентом
+func (i Identity) IsNew() bool {
+	return i.Source == SourceNew
+}
+
+// HasRole сообщает, что пользователю доступно действие роли role.
+// Администратору доступны действия всех ролей, новому пользователю - только действия роли user.
+func (i Identity) HasRole(role Role) bool {
+	if i.IsNew() {
+		return role == RoleUser
+	}
+	return i.Role == RoleAdmin || i.Role == role
+}
+
+// identityKey - ключ контекста с пользователем запроса
+type identityKey struct{}
+
+// WithIdentity возвращает контекст с пользователем запроса
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// FromContext возвращает пользователя запроса, определенного Middleware
+func FromContext(ctx context.Context) Identity {
+	identity, _ := ctx.Value(identityKey{}).(Identity)
+	return identity
+}
+
+// UserID возвращает ID пользователя запроса
+func UserID(ctx context.Context) string {
+	return FromContext(ctx).UserID
+}
+
+// Authenticated возвращает ID пользователя, если клиент передал его в cookie или токене
+func Authenticated(ctx context.Context) (string, bool) {
+	identity := FromContext(ctx)
+	return identity.UserID, identity.UserID != "" && !identity.IsNew()
+}
+
+// Resolve определяет пользователя по данным клиента и назначает ему роль.
+// Недействительные токен или ключ API не заменяются cookie: клиент, передавший их, получает ошибку.
+// Истекшая или завершенная сессия, как и недействительная cookie user, пропускается.
+// Если не переданы ни токен, ни ключ API, ни действительные сессия и cookie, создается новый пользователь.
+// Новый пользователь всегда получает роль user.
+func (res Resolver) Resolve(ctx context.Context, credentials Credentials) (Identity, error) {
+	identity, err := res.resolve(ctx, credentials)
+	if err != nil {
+		return Identity{}, err
+	}
+	identity.Role = RoleUser
+	if !identity.IsNew() {
+		identity.Role = res.Admins.Role(identity.UserID)
+	}
+	return identity, nil
+}
+
+// resolve определяет пользователя по данным клиента без роли
+func (res Resolver) resolve(ctx context.Context, credentials Credentials) (Identity, error) {
+	if credentials.Authorization != "" {
+		token, ok := strings.CutPrefix(credentials.Authorization, bearerPrefix)
+		if !ok || token == "" {
+			return Identity{}, ErrInvalidAuthorization
+		}
+		if res.Tokens == nil {
+			return Identity{}, ErrTokensDisabled
+		}
+		userID, err := res.Tokens.Parse(token)
+		if err != nil {
+			return Identity{}, err
+		}
+		return Identity{UserID: userID, Source: SourceToken}, nil
+	}
+
+	if credentials.APIKey != "" {
+		if res.Keys == nil {
+			return Identity{}, ErrInvalidAPIKey
+		}
+		userID, err := res.Keys.UseAPIKey(ctx, store.HashAPIKey(credentials.APIKey), time.Now().UTC())
+		if errors.Is(err, store.ErrAPIKeyNotFound) {
+			return Identity{}, ErrInvalidAPIKey
+		} else if err != nil {
+			return Identity{}, err
+		}
+		return Identity{UserID: userID, Source: SourceAPIKey}, nil
+	}
+
+	if credentials.Session != "" && res.Sessions != nil {
+		session, err := res.Sessions.GetSession(ctx, store.HashSessionToken(credentials.Session), time.Now())
+		if err == nil {
+			return Identity{UserID: session.UserID, Source: SourceSession}, nil
+		} else if !errors.Is(err, store.ErrSessionNotFound) {
+			return Identity{}, err
+		}
+	}
+
+	if credentials.Cookie != "" {
+		if userID, err := cookies.DecodeUserID(credentials.Cookie); err == nil {
+			return Identity{UserID: userID, Source: SourceCookie}, nil
+		}
+	}
+	return Identity{UserID: cookies.GenerateUserID(), Source: SourceNew}, nil
+}
+
+// ResolveLenient определяет пользователя для публичных путей, доступных без авторизации.
+// Ключ API не проверяется, чтобы не обновлять время его использования при каждом запросе,
+// а недействительный токен не отклоняет запрос: пользователь определяется по сессии или cookie,
+// как для клиента без токена.
+func (res Resolver) ResolveLenient(ctx context.Context, credentials Credentials) (Identity, error) {
+	credentials.APIKey = ""
+	identity, err := res.Resolve(ctx, credentials)
+	if IsUnauthorized(err) {
+		credentials.Authorization = ""
+		return res.Resolve(ctx, credentials)
+	}
+	return identity, err
+}
+
+// IsUnauthorized сообщает, что ошибка Resolve вызвана недействительными данными клиента, а не сбоем хранилища
+func IsUnauthorized(err error) bool {
+	return errors.Is(err, ErrInvalidAuthorization) || errors.Is(err, ErrTokensDisabled) ||
+		errors.Is(err, ErrInvalidAPIKey) || errors.Is(err, jwtauth.ErrInvalidToken)
+}
+
+// Middleware определяет пользователя запроса для всех обработчиков.
+// Новому пользователю устанавливается cookie user, при недействительных токене или ключе API
+// запрос отклоняется с кодом 401.
+func Middleware(resolver Resolver) func(http.Handler) http.Handler {
+	return middleware(resolver.Resolve)
+}
+
+// LenientMiddleware определяет пользователя запроса для публичных путей через Resolver.ResolveLenient.
+// Запрос не отклоняется из-за недействительных токена или ключа API.
+func LenientMiddleware(resolver Resolver) func(http.Handler) http.Handler {
+	return middleware(resolver.ResolveLenient)
+}
+
+// middleware определяет пользователя запроса функцией resolve и устанавливает cookie user новому пользователю
+func middleware(resolve func(ctx context.Context, credentials Credentials) (Identity, error)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var encodedCookie, sessionToken string
+			if cookie, err := r.Cookie("user"); err == nil {
+				encodedCookie = cookie.Value
+			}
+			if cookie, err := r.Cookie(cookies.SessionCookieName); err == nil {
+				sessionToken = cookie.Value
+			}
+
+			identity, err := resolve(r.Context(), Credentials{
+				Authorization: r.Header.Get("Authorization"),
+				APIKey:        r.Header.Get(APIKeyHeader),
+				Session:       sessionToken,
+				Cookie:        encodedCookie,
+			})
+			if err != nil && !IsUnauthorized(err) {
+				logrus.WithField("err", err).Error("Error resolving request user")
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			} else if err != nil {
+				logrus.WithField("err", err).Info("Invalid authorization")
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			if identity.IsNew() {
+				cookies.SetUserCookie(w, identity.UserID)
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), identity)))
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/store"
+	"github.com/TimBerk/go-link-shortener/internal/pkg/cookies"
+	"github.com/TimBerk/go-link-shortener/internal/pkg/jwtauth"
+)
+
+// stubKeys находит владельца ключа API по хешу значения
+type stubKeys struct {
+	owners map[string]string
+	err    error
+}
+
+func (s stubKeys) UseAPIKey(ctx context.Context, keyHash string, usedAt time.Time) (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+	userID, exists := s.owners[keyHash]
+	if !exists {
+		return "", store.ErrAPIKeyNotFound
+	}
+	return userID, nil
+}
+
+// stubSessions находит владельца сессии по хешу токена
+type stubSessions struct {
+	owners map[string]string
+	err    error
+}
+
+func (s stubSessions) GetSession(ctx context.Context, tokenHash string, now time.Time) (store.Session, error) {
+	if s.err != nil {
+		return store.Session{}, s.err
+	}
+	userID, exists := s.owners[tokenHash]
+	if !exists {
+		return store.Session{}, store.ErrSessionNotFound
+	}
+	return store.Session{TokenHash: tokenHash, UserID: userID, ExpiresAt: now.Add(time.Hour)}, nil
+}
+
+func TestMiddleware(t *testing.T) {
+	tokens, err := jwtauth.NewManager(jwtauth.Options{Secret: "0123456789abcdef0123456789abcdef"})
+	require.NoError(t, err)
+	token, _, err := tokens.Issue("token-user")
+	require.NoError(t, err)
+	cookieValue, err := cookies.GetEncodedValue("cookie-user")
+	require.NoError(t, err)
+	keys := stubKeys{owners: map[string]string{store.HashAPIKey("sk_valid"): "key-user"}}
+	sessions := stubSessions{owners: map[string]string{store.HashSessionToken("session-valid"): "account-user"}}
+
+	tests := []struct {
+		name           string
+		tokens         *jwtauth.Manager
+		keys           KeyStore
+		sessions       SessionStore
+		authorization  string
+		apiKey         string
+		session        string
+		cookie         string
+		expectedStatus int
+		expectedUserID string
+		expectedSource Source
+		expectCookie   bool
+	}{
+		{name: "Bearer token", tokens: tokens, authorization: "Bearer " + token, expectedStatus: http.StatusOK, expectedUserID: "token-user", expectedSource: SourceToken},
+		{name: "Token overrides cookie", tokens: tokens, authorization: "Bearer " + token, cookie: cookieValue, expectedStatus: http.StatusOK, expectedUserID: "token-user", expectedSource: SourceToken},
+		{name: "Cookie", tokens: tokens, cookie: cookieValue, expectedStatus: http.StatusOK, expectedUserID: "cookie-user", expectedSource: SourceCookie},
+		{name: "New user", tokens: tokens, expectedStatus: http.StatusOK, expectedSource: SourceNew, expectCookie: true},
+		{name: "Invalid cookie", cookie: "broken", expectedStatus: http.StatusOK, expectedSource: SourceNew, expectCookie: true},
+		{name: "Invalid token", tokens: tokens, authorization: "Bearer broken", cookie: cookieValue, expectedStatus: http.StatusUnauthorized},
+		{name: "Not a bearer token", tokens: tokens, authorization: "Basic dXNlcjpwYXNz", expectedStatus: http.StatusUnauthorized},
+		{name: "Tokens are not enabled", authorization: "Bearer " + token, expectedStatus: http.StatusUnauthorized},
+		{name: "API key", keys: keys, apiKey: "sk_valid", cookie: cookieValue, expectedStatus: http.StatusOK, expectedUserID: "key-user", expectedSource: SourceAPIKey},
+		{name: "Token overrides API key", tokens: tokens, keys: keys, authorization: "Bearer " + token, apiKey: "sk_valid", expectedStatus: http.StatusOK, expectedUserID: "token-user", expectedSource: SourceToken},
+		{name: "Unknown API key", keys: keys, apiKey: "sk_unknown", cookie: cookieValue, expectedStatus: http.StatusUnauthorized},
+		{name: "API keys are not enabled", apiKey: "sk_valid", expectedStatus: http.StatusUnauthorized},
+		{name: "API key store error", keys: stubKeys{err: errors.New("connection refused")}, apiKey: "sk_valid", expectedStatus: http.StatusInternalServerError},
+		{name: "Session", sessions: sessions, session: "session-valid", cookie: cookieValue, expectedStatus: http.StatusOK, expectedUserID: "account-user", expectedSource: SourceSession},
+		{name: "API key overrides session", keys: keys, sessions: sessions, apiKey: "sk_valid", session: "session-valid", expectedStatus: http.StatusOK, expectedUserID: "key-user", expectedSource: SourceAPIKey},
+		{name: "Expired session falls back to cookie", sessions: sessions, session: "session-expired", cookie: cookieValue, expectedStatus: http.StatusOK, expectedUserID: "cookie-user", expectedSource: SourceCookie},
+		{name: "Sessions are not enabled", session: "session-valid", cookie: cookieValue, expectedStatus: http.StatusOK, expectedUserID: "cookie-user", expectedSource: SourceCookie},
+		{name: "Session store error", sessions: stubSessions{err: errors.New("connection refused")}, session: "session-valid", expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var identity Identity
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				identity = FromContext(r.Context())
+			})
+			req := httptest.NewRequest(http.MethodGet, "/api/user/urls", nil)
+			if test.authorization != "" {
+				req.Header.Set("Authorization", test.authorization)
+			}
+			if test.apiKey != "" {
+				req.Header.Set(APIKeyHeader, test.apiKey)
+			}
+			if test.session != "" {
+				req.AddCookie(&http.Cookie{Name: cookies.SessionCookieName, Value: test.session})
+			}
+			if test.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "user", Value: test.cookie})
+			}
+			recorder := httptest.NewRecorder()
+
+			Middleware(Resolver{Tokens: test.tokens, Keys: test.keys, Sessions: test.sessions})(next).ServeHTTP(recorder, req)
+
+			assert.Equal(t, test.expectedStatus, recorder.Code)
+			if test.expectedStatus == http.StatusInternalServerError {
+				assert.Empty(t, recorder.Header().Get("WWW-Authenticate"))
+				return
+			}
+			if test.expectedStatus != http.StatusOK {
+				assert.Equal(t, `Bearer error="invalid_token"`, recorder.Header().Get("WWW-Authenticate"))
+				return
+			}
+			assert.Equal(t, test.expectedSource, identity.Source)
+			assert.NotEmpty(t, identity.UserID)
+			if test.expectedUserID != "" {
+				assert.Equal(t, test.expectedUserID, identity.UserID)
+			}
+			if test.expectCookie {
+				require.Len(t, recorder.Result().Cookies(), 1, "Новому пользователю должна устанавливаться cookie")
+				userID, err := cookies.DecodeUserID(recorder.Result().Cookies()[0].Value)
+				require.NoError(t, err)
+				assert.Equal(t, identity.UserID, userID)
+			} else {
+				assert.Empty(t, recorder.Result().Cookies())
+			}
+		})
+	}
+}
+
+func TestResolver_Role(t *testing.T) {
+	cookieValue, err := cookies.GetEncodedValue("admin-user")
+	require.NoError(t, err)
+	keys := stubKeys{owners: map[string]string{
+		store.HashAPIKey("sk_admin"): "admin-user",
+		store.HashAPIKey("sk_user"):  "key-user",
+	}}
+	admins := NewAdmins("admin-user", "oidc:248289761001")
+
+	tests := []struct {
+		name         string
+		admins       Admins
+		credentials  Credentials
+		expectedRole Role
+		canModerate  bool
+	}{
+		{name: "Admin by API key", admins: admins, credentials: Credentials{APIKey: "sk_admin"}, expectedRole: RoleAdmin, canModerate: true},
+		{name: "Admin by cookie", admins: admins, credentials: Credentials{Cookie: cookieValue}, expectedRole: RoleAdmin, canModerate: true},
+		{name: "User by API key", admins: admins, credentials: Credentials{APIKey: "sk_user"}, expectedRole: RoleUser},
+		{name: "New user", admins: admins, expectedRole: RoleUser},
+		{name: "Admins are not configured", credentials: Credentials{APIKey: "sk_admin"}, expectedRole: RoleUser},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			identity, err := Resolver{Keys: keys, Admins: test.admins}.Resolve(context.Background(), test.credentials)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expectedRole, identity.Role)
+			assert.True(t, identity.HasRole(RoleUser), "Роль user должна быть доступна всем пользователям")
+			assert.Equal(t, test.canModerate, identity.HasRole(RoleAdmin))
+		})
+	}
+}
+
+// usedKeys запоминает, что ключ API был проверен
+type usedKeys struct {
+	used bool
+}
+
+func (s *usedKeys) UseAPIKey(ctx context.Context, keyHash string, usedAt time.Time) (string, error) {
+	s.used = true
+	return "key-user", nil
+}
+
+func TestResolver_ResolveLenient(t *testing.T) {
+	tokens, err := jwtauth.NewManager(jwtauth.Options{Secret: "0123456789abcdef0123456789abcdef"})
+	require.NoError(t, err)
+	token, _, err := tokens.Issue("token-user")
+	require.NoError(t, err)
+	cookieValue, err := cookies.GetEncodedValue("cookie-user")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		credentials    Credentials
+		expectedUserID string
+		expectedSource Source
+	}{
+		{name: "Bearer token", credentials: Credentials{Authorization: "Bearer " + token, Cookie: cookieValue}, expectedUserID: "token-user", expectedSource: SourceToken},
+		{name: "Invalid token falls back to cookie", credentials: Credentials{Authorization: "Bearer broken", Cookie: cookieValue}, expectedUserID: "cookie-user", expectedSource: SourceCookie},
+		{name: "Invalid token without cookie", credentials: Credentials{Authorization: "Basic dXNlcjpwYXNz"}, expectedSource: SourceNew},
+		{name: "API key is ignored", credentials: Credentials{APIKey: "sk_valid", Cookie: cookieValue}, expectedUserID: "cookie-user", expectedSource: SourceCookie},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			keys := &usedKeys{}
+			identity, err := Resolver{Tokens: tokens, Keys: keys}.ResolveLenient(context.Background(), test.credentials)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expectedSource, identity.Source)
+			if test.expectedUserID != "" {
+				assert.Equal(t, test.expectedUserID, identity.UserID)
+			}
+			assert.False(t, keys.used, "Ключ API не должен проверяться и обновляться на публичных путях")
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package subnet
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Proxies - подсети доверенных прокси. Заголовку X-Real-IP верят только в запросах,
+// пришедших с адреса из этих подсетей, иначе клиент мог бы подставить в него любой адрес.
+type Proxies []netip.Prefix
+
+// NewProxies разбирает подсети доверенных прокси в нотации CIDR, пропуская некорректные
+func NewProxies(cidrs ...string) Proxies {
+	proxies := make(Proxies, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"err":    err,
+				"subnet": cidr,
+			}).Error("Invalid trusted proxy subnet, it is ignored")
+			continue
+		}
+		proxies = append(proxies, prefix.Masked())
+	}
+	return proxies
+}
+
+// ClientIP возвращает адрес клиента: из заголовка X-Real-IP для запросов от доверенного прокси,
+// в остальных случаях - адрес соединения
+func (p Proxies) ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	value := r.Header.Get("X-Real-IP")
+	if value == "" || !p.contains(host) {
+		return host
+	}
+	if addr, err := netip.ParseAddr(value); err == nil {
+		return addr.Unmap().String()
+	}
+	return host
+}
+
+// contains сообщает, что адрес соединения входит в одну из подсетей доверенных прокси
+func (p Proxies) contains(host string) bool {
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range p {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
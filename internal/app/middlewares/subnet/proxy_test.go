@@ -0,0 +1,39 @@
+package subnet
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProxies_ClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		proxies    []string
+		realIP     string
+		remoteAddr string
+		expectedIP string
+	}{
+		{name: "Without proxies", realIP: "198.51.100.7", remoteAddr: "192.0.2.1:1234", expectedIP: "192.0.2.1"},
+		{name: "Untrusted proxy", proxies: []string{"10.0.0.0/8"}, realIP: "198.51.100.7", remoteAddr: "192.0.2.1:1234", expectedIP: "192.0.2.1"},
+		{name: "Trusted proxy", proxies: []string{"10.0.0.0/8"}, realIP: "198.51.100.7", remoteAddr: "10.1.2.3:1234", expectedIP: "198.51.100.7"},
+		{name: "Trusted proxy without header", proxies: []string{"10.0.0.0/8"}, remoteAddr: "10.1.2.3:1234", expectedIP: "10.1.2.3"},
+		{name: "Trusted proxy with invalid header", proxies: []string{"10.0.0.0/8"}, realIP: "not-an-ip", remoteAddr: "10.1.2.3:1234", expectedIP: "10.1.2.3"},
+		{name: "IPv6 proxy", proxies: []string{"2001:db8::/32"}, realIP: "198.51.100.7", remoteAddr: "[2001:db8::1]:1234", expectedIP: "198.51.100.7"},
+		{name: "Invalid subnet is ignored", proxies: []string{"10.0.0.0"}, realIP: "198.51.100.7", remoteAddr: "10.0.0.0:1234", expectedIP: "10.0.0.0"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/abc123", nil)
+			req.RemoteAddr = test.remoteAddr
+			if test.realIP != "" {
+				req.Header.Set("X-Real-IP", test.realIP)
+			}
+
+			assert.Equal(t, test.expectedIP, NewProxies(test.proxies...).ClientIP(req))
+		})
+	}
+}
@@ -0,0 +1,54 @@
+// Package subnet ограничивает доступ к обработчикам доверенной подсетью
+package subnet
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+
+	"github.com/sirupsen/logrus"
+)
+
+// TrustedSubnet пропускает только запросы, адрес клиента которых входит в подсеть cidr.
+// Адрес берется из заголовка X-Real-IP, а при его отсутствии - из адреса соединения.
+// При пустой или некорректной подсети доступ запрещен для всех запросов.
+func TrustedSubnet(cidr string) func(http.Handler) http.Handler {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil && cidr != "" {
+		logrus.WithFields(logrus.Fields{
+			"err":    err,
+			"subnet": cidr,
+		}).Error("Invalid trusted subnet, internal endpoints are disabled")
+	}
+	trusted := err == nil
+	prefix = prefix.Masked()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			addr, ok := clientAddr(r)
+			if !trusted || !ok || !prefix.Contains(addr) {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientAddr возвращает адрес клиента из заголовка X-Real-IP или адреса соединения
+func clientAddr(r *http.Request) (netip.Addr, bool) {
+	value := r.Header.Get("X-Real-IP")
+	if value == "" {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		value = host
+	}
+
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
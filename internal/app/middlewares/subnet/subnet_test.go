@@ -0,0 +1,47 @@
+package subnet
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrustedSubnet(t *testing.T) {
+	tests := []struct {
+		name           string
+		subnet         string
+		realIP         string
+		remoteAddr     string
+		expectedStatus int
+	}{
+		{name: "Header in subnet", subnet: "192.168.1.0/24", realIP: "192.168.1.10", remoteAddr: "10.0.0.1:1234", expectedStatus: http.StatusOK},
+		{name: "Header outside subnet", subnet: "192.168.1.0/24", realIP: "192.168.2.10", remoteAddr: "192.168.1.10:1234", expectedStatus: http.StatusForbidden},
+		{name: "Connection address in subnet", subnet: "10.0.0.0/8", remoteAddr: "10.1.2.3:1234", expectedStatus: http.StatusOK},
+		{name: "IPv6 subnet", subnet: "2001:db8::/32", realIP: "2001:db8::1", expectedStatus: http.StatusOK},
+		{name: "Invalid header", subnet: "192.168.1.0/24", realIP: "not-an-ip", expectedStatus: http.StatusForbidden},
+		{name: "Empty subnet", realIP: "192.168.1.10", expectedStatus: http.StatusForbidden},
+		{name: "Invalid subnet", subnet: "192.168.1.0", realIP: "192.168.1.0", expectedStatus: http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+			req := httptest.NewRequest(http.MethodGet, "/api/internal/stats", nil)
+			if test.remoteAddr != "" {
+				req.RemoteAddr = test.remoteAddr
+			}
+			if test.realIP != "" {
+				req.Header.Set("X-Real-IP", test.realIP)
+			}
+			recorder := httptest.NewRecorder()
+
+			TrustedSubnet(test.subnet)(next).ServeHTTP(recorder, req)
+
+			assert.Equal(t, test.expectedStatus, recorder.Code)
+		})
+	}
+}
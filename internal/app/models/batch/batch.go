@@ -3,10 +3,22 @@ package batch
 
 //go:generate easyjson -all -snake_case batch.go
 
-// ItemRequest - параметры записи запроса с идентификатором и ссылкой
+import "time"
+
+// ItemRequest - параметры записи запроса с идентификатором и ссылкой.
+// Время жизни ссылки задается моментом истечения ExpiresAt или длительностью TTL, например "24h".
+// MaxClicks ограничивает количество переходов по ссылке, 1 - одноразовая ссылка.
+// Password задает пароль, который нужно ввести перед переходом по ссылке,
+// обработчик заменяет его хешем PasswordHash перед сохранением.
 type ItemRequest struct {
-	CorrelationID string `json:"correlation_id"`
-	OriginalURL   string `json:"original_url"`
+	CorrelationID string     `json:"correlation_id"`
+	OriginalURL   string     `json:"original_url"`
+	Alias         string     `json:"alias,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	TTL           string     `json:"ttl,omitempty"`
+	MaxClicks     int        `json:"max_clicks,omitempty"`
+	Password      string     `json:"password,omitempty"`
+	PasswordHash  string     `json:"-"`
 }
 
 //easyjson:json
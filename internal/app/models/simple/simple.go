@@ -3,9 +3,19 @@ package simple
 
 //go:generate easyjson -all -snake_case simple.go
 
-// RequestJSON описывает параметры запроса
+import "time"
+
+// RequestJSON описывает параметры запроса.
+// Время жизни ссылки задается моментом истечения ExpiresAt или длительностью TTL, например "24h".
+// MaxClicks ограничивает количество переходов по ссылке, 1 - одноразовая ссылка.
+// Password задает пароль, который нужно ввести перед переходом по ссылке.
 type RequestJSON struct {
-	URL string `json:"url"`
+	URL       string     `json:"url"`
+	Alias     string     `json:"alias,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	TTL       string     `json:"ttl,omitempty"`
+	MaxClicks int        `json:"max_clicks,omitempty"`
+	Password  string     `json:"password,omitempty"`
 }
 
 // ResponseJSON описывает параметры ответа
@@ -8,6 +8,7 @@ import (
 	easyjson "github.com/mailru/easyjson"
 	jlexer "github.com/mailru/easyjson/jlexer"
 	jwriter "github.com/mailru/easyjson/jwriter"
+	time "time"
 )
 
 // suppress unused package warning
@@ -105,6 +106,26 @@ func DecodeModels1(in *jlexer.Lexer, out *RequestJSON) {
 		switch key {
 		case "url":
 			out.URL = string(in.String())
+		case "alias":
+			out.Alias = string(in.String())
+		case "expires_at":
+			if in.IsNull() {
+				in.Skip()
+				out.ExpiresAt = nil
+			} else {
+				if out.ExpiresAt == nil {
+					out.ExpiresAt = new(time.Time)
+				}
+				if data := in.Raw(); in.Ok() {
+					in.AddError((*out.ExpiresAt).UnmarshalJSON(data))
+				}
+			}
+		case "ttl":
+			out.TTL = string(in.String())
+		case "max_clicks":
+			out.MaxClicks = int(in.Int())
+		case "password":
+			out.Password = string(in.String())
 		default:
 			in.SkipRecursive()
 		}
@@ -124,6 +145,31 @@ func EncodeModels1(out *jwriter.Writer, in RequestJSON) {
 		out.RawString(prefix[1:])
 		out.String(string(in.URL))
 	}
+	if in.Alias != "" {
+		const prefix string = ",\"alias\":"
+		out.RawString(prefix)
+		out.String(string(in.Alias))
+	}
+	if in.ExpiresAt != nil {
+		const prefix string = ",\"expires_at\":"
+		out.RawString(prefix)
+		out.Raw((*in.ExpiresAt).MarshalJSON())
+	}
+	if in.TTL != "" {
+		const prefix string = ",\"ttl\":"
+		out.RawString(prefix)
+		out.String(string(in.TTL))
+	}
+	if in.MaxClicks != 0 {
+		const prefix string = ",\"max_clicks\":"
+		out.RawString(prefix)
+		out.Int(int(in.MaxClicks))
+	}
+	if in.Password != "" {
+		const prefix string = ",\"password\":"
+		out.RawString(prefix)
+		out.String(string(in.Password))
+	}
 	out.RawByte('}')
 }
 
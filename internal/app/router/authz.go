@@ -0,0 +1,34 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/middlewares/auth"
+)
+
+// RequireRole пропускает только запросы пользователей с ролью role.
+// Пользователь берется из контекста, поэтому middleware подключается после Handler.Identity.
+// Клиент без токена, ключа API, сессии или cookie получает 401, пользователь без роли - 403.
+func RequireRole(role auth.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity := auth.FromContext(r.Context())
+			if identity.UserID == "" || identity.IsNew() {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			if !identity.HasRole(role) {
+				logrus.WithFields(logrus.Fields{
+					"UserID": identity.UserID,
+					"role":   role,
+					"path":   r.URL.Path,
+				}).Info("Access denied")
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/config"
+	"github.com/TimBerk/go-link-shortener/internal/app/middlewares/auth"
+	"github.com/TimBerk/go-link-shortener/internal/app/store"
+	"github.com/TimBerk/go-link-shortener/internal/app/store/local"
+	"github.com/TimBerk/go-link-shortener/internal/pkg/cookies"
+)
+
+func TestRequireRole(t *testing.T) {
+	tests := []struct {
+		name           string
+		identity       auth.Identity
+		role           auth.Role
+		expectedStatus int
+	}{
+		{name: "Admin", identity: auth.Identity{UserID: "admin-1", Source: auth.SourceSession, Role: auth.RoleAdmin}, role: auth.RoleAdmin, expectedStatus: http.StatusOK},
+		{name: "Admin has user role", identity: auth.Identity{UserID: "admin-1", Source: auth.SourceAPIKey, Role: auth.RoleAdmin}, role: auth.RoleUser, expectedStatus: http.StatusOK},
+		{name: "User", identity: auth.Identity{UserID: "777", Source: auth.SourceCookie, Role: auth.RoleUser}, role: auth.RoleAdmin, expectedStatus: http.StatusForbidden},
+		{name: "New user", identity: auth.Identity{UserID: "777", Source: auth.SourceNew, Role: auth.RoleUser}, role: auth.RoleUser, expectedStatus: http.StatusUnauthorized},
+		{name: "Without identity", role: auth.RoleUser, expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+			req := httptest.NewRequest(http.MethodGet, "/api/admin/urls", nil)
+			req = req.WithContext(auth.WithIdentity(req.Context(), test.identity))
+			recorder := httptest.NewRecorder()
+
+			RequireRole(test.role)(next).ServeHTTP(recorder, req)
+
+			assert.Equal(t, test.expectedStatus, recorder.Code)
+		})
+	}
+}
+
+// request выполняет запрос от пользователя userID, пустой userID соответствует клиенту без cookie
+func request(t *testing.T, router http.Handler, method string, target string, body string, userID string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if userID != "" {
+		value, err := cookies.GetEncodedValue(userID)
+		require.NoError(t, err)
+		req.AddCookie(&http.Cookie{Name: "user", Value: value})
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestRegisterRouters_Admin(t *testing.T) {
+	dataStore, err := local.NewURLStore(store.NewIDGenerator())
+	require.NoError(t, err)
+	cfg := config.NewConfig("localhost:8021", "http://localhost:8021", true)
+	cfg.AdminUsers = "admin-1"
+	router := RegisterRouters(dataStore, cfg, context.Background(), make(chan store.URLPair, 1))
+
+	recorder := request(t, router, http.MethodPost, "/", "https://example.com/spam", "777")
+	require.Equal(t, http.StatusCreated, recorder.Code)
+	shortURL := path.Base(recorder.Body.String())
+
+	assert.Equal(t, http.StatusUnauthorized, request(t, router, http.MethodGet, "/api/admin/urls", "", "").Code)
+	assert.Equal(t, http.StatusForbidden, request(t, router, http.MethodGet, "/api/admin/urls", "", "777").Code)
+	assert.Equal(t, http.StatusForbidden, request(t, router, http.MethodDelete, "/api/admin/urls/"+shortURL, "", "777").Code)
+	assert.Equal(t, http.StatusForbidden, request(t, router, http.MethodGet, "/api/admin/users/888/urls", "", "777").Code)
+
+	recorder = request(t, router, http.MethodGet, "/api/admin/urls?query=SPAM", "", "admin-1")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var links []store.Link
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&links))
+	assert.Equal(t, []store.Link{{ShortURL: shortURL, OriginalURL: "https://example.com/spam", UserID: "777"}}, links)
+
+	recorder = request(t, router, http.MethodGet, "/api/admin/users/777/urls", "", "admin-1")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "https://example.com/spam")
+
+	assert.Equal(t, http.StatusNoContent, request(t, router, http.MethodDelete, "/api/admin/urls/"+shortURL, "", "admin-1").Code)
+	assert.Equal(t, http.StatusNotFound, request(t, router, http.MethodDelete, "/api/admin/urls/unknown", "", "admin-1").Code)
+	assert.Equal(t, http.StatusGone, request(t, router, http.MethodGet, "/"+shortURL, "", "777").Code, "Отключенная ссылка должна перестать работать")
+}
+
+func TestRegisterRouters_RedirectIgnoresInvalidAuthorization(t *testing.T) {
+	dataStore, err := local.NewURLStore(store.NewIDGenerator())
+	require.NoError(t, err)
+	cfg := config.NewConfig("localhost:8021", "http://localhost:8021", true)
+	router := RegisterRouters(dataStore, cfg, context.Background(), make(chan store.URLPair, 1))
+
+	recorder := request(t, router, http.MethodPost, "/", "https://example.com/public", "777")
+	require.Equal(t, http.StatusCreated, recorder.Code)
+	shortURL := path.Base(recorder.Body.String())
+
+	headers := map[string]string{
+		"Authorization": "Bearer broken",
+		"X-API-Key":     "sk_unknown",
+	}
+	for name, value := range headers {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/"+shortURL, nil)
+			req.Header.Set(name, value)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusTemporaryRedirect, recorder.Code, "Недействительная авторизация не должна мешать переходу")
+			assert.Equal(t, "https://example.com/public", recorder.Header().Get("Location"))
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/user/urls", nil)
+	req.Header.Set("Authorization", "Bearer broken")
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code, "Пользовательские пути должны по-прежнему отклонять недействительный токен")
+}
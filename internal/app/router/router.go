@@ -10,8 +10,10 @@ import (
 
 	"github.com/TimBerk/go-link-shortener/internal/app/config"
 	"github.com/TimBerk/go-link-shortener/internal/app/handler"
+	"github.com/TimBerk/go-link-shortener/internal/app/middlewares/auth"
 	"github.com/TimBerk/go-link-shortener/internal/app/middlewares/compress"
 	"github.com/TimBerk/go-link-shortener/internal/app/middlewares/logger"
+	"github.com/TimBerk/go-link-shortener/internal/app/middlewares/subnet"
 	"github.com/TimBerk/go-link-shortener/internal/app/store"
 )
 
@@ -30,8 +32,8 @@ func addPprof(router chi.Router) {
 }
 
 // RegisterRouters - регистрирует пути приложения
-func RegisterRouters(dataStore store.Store, cfg *config.Config, ctx context.Context, urlChan chan store.URLPair) chi.Router {
-	h := handler.NewHandler(dataStore, cfg, ctx, urlChan)
+func RegisterRouters(dataStore store.Store, cfg *config.Config, ctx context.Context, urlChan chan store.URLPair, opts ...handler.Option) chi.Router {
+	h := handler.NewHandler(dataStore, cfg, ctx, urlChan, opts...)
 
 	router := chi.NewRouter()
 	router.Use(logger.RequestLogger)
@@ -40,12 +42,47 @@ func RegisterRouters(dataStore store.Store, cfg *config.Config, ctx context.Cont
 	addPprof(router)
 
 	router.Get("/ping", h.Ping)
-	router.Get("/api/user/urls", h.UserURLsHandler)
-	router.Delete("/api/user/urls", h.DeleteURLsHandler)
-	router.Post("/api/shorten/batch", h.ShortenBatch)
-	router.Post("/api/shorten", h.ShortenJSONURL)
-	router.Get("/{id}", h.Redirect)
-	router.Post("/", h.ShortenURL)
+	router.With(subnet.TrustedSubnet(cfg.TrustedSubnet)).Get("/api/internal/stats", h.InternalStatsHandler)
+
+	// Пользовательские пути получают пользователя запроса из токена, ключа API, сессии или cookie
+	router.Group(func(router chi.Router) {
+		router.Use(h.Identity)
+
+		router.Post("/api/user/register", h.RegisterHandler)
+		router.Post("/api/user/login", h.LoginHandler)
+		router.Post("/api/user/logout", h.LogoutHandler)
+		router.Post("/api/user/claim", h.ClaimURLsHandler)
+		router.Get("/api/user/oidc/login", h.OIDCLoginHandler)
+		router.Get(config.OIDCCallbackPath, h.OIDCCallbackHandler)
+		router.Post("/api/user/token", h.IssueTokenHandler)
+		router.Get("/api/user/urls", h.UserURLsHandler)
+		router.Delete("/api/user/urls", h.DeleteURLsHandler)
+		router.Get("/api/user/urls/{id}/stats", h.UserURLStatsHandler)
+		router.Post("/api/user/keys", h.CreateAPIKeyHandler)
+		router.Get("/api/user/keys", h.APIKeysHandler)
+		router.Delete("/api/user/keys/{id}", h.RevokeAPIKeyHandler)
+		router.Post("/api/shorten/batch", h.ShortenBatch)
+		router.Post("/api/shorten", h.ShortenJSONURL)
+		router.Post("/", h.ShortenURL)
+	})
+
+	// Перенаправление публично: недействительные токен или ключ API не мешают переходу по ссылке
+	router.Group(func(router chi.Router) {
+		router.Use(h.LenientIdentity)
+
+		router.Get("/{id}", h.Redirect)
+		router.Post("/{id}", h.Redirect)
+	})
+
+	// Пути модерации доступны только пользователям с ролью администратора
+	router.Group(func(router chi.Router) {
+		router.Use(h.Identity)
+		router.Use(RequireRole(auth.RoleAdmin))
+
+		router.Get("/api/admin/urls", h.AdminURLsHandler)
+		router.Delete("/api/admin/urls/{id}", h.AdminDisableURLHandler)
+		router.Get("/api/admin/users/{userID}/urls", h.AdminUserURLsHandler)
+	})
 
 	// Swagger documentation route
 	router.Get("/swagger/*", httpSwagger.Handler(
@@ -0,0 +1,127 @@
+package store
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// minLoginLength - минимальная длина логина в символах
+	minLoginLength = 3
+	// maxLoginLength - максимальная длина логина в символах
+	maxLoginLength = 64
+	// minAccountPasswordLength - минимальная длина пароля учетной записи в байтах
+	minAccountPasswordLength = 8
+	// accountIDBytes - количество случайных байт в ID учетной записи, как у ID пользователя из cookie
+	accountIDBytes = 16
+	// sessionTokenBytes - количество случайных байт в токене сессии
+	sessionTokenBytes = 32
+	// DefaultSessionTTL - время жизни сессии по умолчанию
+	DefaultSessionTTL = 30 * 24 * time.Hour
+)
+
+var (
+	// ErrAccountExists ошибка о занятом логине
+	ErrAccountExists = errors.New("account already exists")
+	// ErrAccountNotFound ошибка об отсутствии учетной записи
+	ErrAccountNotFound = errors.New("account not found")
+	// ErrInvalidLogin ошибка о недопустимом логине
+	ErrInvalidLogin = errors.New("invalid login")
+	// ErrSessionNotFound ошибка об отсутствующей или истекшей сессии
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+// Account учетная запись пользователя. ID совпадает с ID пользователя, которому принадлежат ссылки.
+type Account struct {
+	ID           string    `json:"id"`
+	Login        string    `json:"login"`
+	PasswordHash string    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Session серверная сессия учетной записи. Токен сессии не хранится, вместо него сохраняется хеш TokenHash.
+type Session struct {
+	TokenHash string
+	UserID    string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// NormalizeLogin приводит логин к виду, в котором он хранится и ищется
+func NormalizeLogin(login string) string {
+	return strings.ToLower(strings.TrimSpace(login))
+}
+
+// validateLogin проверяет нормализованный логин
+func validateLogin(login string) error {
+	length := utf8.RuneCountInString(login)
+	if length < minLoginLength || length > maxLoginLength {
+		return fmt.Errorf("%w: login must be from %d to %d characters", ErrInvalidLogin, minLoginLength, maxLoginLength)
+	}
+	for _, r := range login {
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			return fmt.Errorf("%w: login must not contain spaces", ErrInvalidLogin)
+		}
+	}
+	return nil
+}
+
+// NewAccount проверяет логин и пароль и создает учетную запись с новым ID пользователя
+func NewAccount(login string, password string, now time.Time) (Account, error) {
+	login = NormalizeLogin(login)
+	if err := validateLogin(login); err != nil {
+		return Account{}, err
+	}
+	if len(password) < minAccountPasswordLength {
+		return Account{}, fmt.Errorf("%w: password must be at least %d bytes", ErrInvalidPassword, minAccountPasswordLength)
+	}
+	passwordHash, err := HashPassword(password)
+	if err != nil {
+		return Account{}, err
+	}
+
+	id := make([]byte, accountIDBytes)
+	if _, err := rand.Read(id); err != nil {
+		return Account{}, err
+	}
+
+	return Account{
+		ID:           base64.URLEncoding.EncodeToString(id),
+		Login:        login,
+		PasswordHash: passwordHash,
+		CreatedAt:    now.UTC().Truncate(time.Microsecond),
+	}, nil
+}
+
+// NewSession создает сессию пользователя со временем жизни ttl и возвращает ее вместе с токеном для cookie
+func NewSession(userID string, now time.Time, ttl time.Duration) (Session, string, error) {
+	secret := make([]byte, sessionTokenBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return Session{}, "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(secret)
+
+	now = now.UTC().Truncate(time.Microsecond)
+	return Session{
+		TokenHash: HashSessionToken(token),
+		UserID:    userID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}, token, nil
+}
+
+// HashSessionToken возвращает хеш токена сессии для поиска в хранилище
+func HashSessionToken(token string) string {
+	return hashSecret(token)
+}
+
+// IsActive сообщает, что сессия не истекла к моменту now
+func (s Session) IsActive(now time.Time) bool {
+	return now.Before(s.ExpiresAt)
+}
@@ -0,0 +1,58 @@
+package store
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	// DefaultSearchLimit - размер страницы поиска ссылок по умолчанию
+	DefaultSearchLimit = 100
+	// MaxSearchLimit - максимальный размер страницы поиска ссылок
+	MaxSearchLimit = 1000
+)
+
+// ErrLinkNotFound ошибка об отсутствии ссылки
+var ErrLinkNotFound = errors.New("link not found")
+
+// LinkSearch параметры поиска среди ссылок всех пользователей
+type LinkSearch struct {
+	// Query - подстрока короткой или оригинальной ссылки без учета регистра, пустая строка отбирает все ссылки
+	Query string
+	// Limit - размер страницы
+	Limit int
+	// Offset - количество пропускаемых ссылок
+	Offset int
+}
+
+// Link ссылка в списке всех ссылок сервиса
+type Link struct {
+	ShortURL    string `json:"short_url"`
+	OriginalURL string `json:"original_url"`
+	UserID      string `json:"user_id"`
+	IsDeleted   bool   `json:"is_deleted"`
+}
+
+// Needle возвращает поисковый запрос в нижнем регистре, с которым сравниваются ссылки в нижнем регистре
+func (s LinkSearch) Needle() string {
+	return strings.ToLower(strings.TrimSpace(s.Query))
+}
+
+// Matches сообщает, что ссылка подходит под поисковый запрос из Needle
+func (l Link) Matches(query string) bool {
+	return query == "" ||
+		strings.Contains(strings.ToLower(l.ShortURL), query) ||
+		strings.Contains(strings.ToLower(l.OriginalURL), query)
+}
+
+// Page возвращает страницу поиска из ссылок, упорядоченных по короткой ссылке
+func (s LinkSearch) Page(links []Link) []Link {
+	if s.Offset >= len(links) {
+		return []Link{}
+	}
+	links = links[s.Offset:]
+	if len(links) > s.Limit {
+		links = links[:s.Limit]
+	}
+	return links
+}
@@ -0,0 +1,48 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// minAliasLength - наименьшая длина пользовательской ссылки
+	minAliasLength = 3
+	// maxAliasLength - наибольшая длина пользовательской ссылки, совпадает с длиной колонки short_url
+	maxAliasLength = maxCodeLength
+)
+
+var (
+	// ErrInvalidAlias ошибка о недопустимой пользовательской ссылке
+	ErrInvalidAlias = errors.New("invalid alias")
+	// ErrAliasTaken ошибка о занятой пользовательской ссылке
+	ErrAliasTaken = errors.New("alias is already taken")
+)
+
+// aliasPattern - допустимые символы пользовательской ссылки
+var aliasPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// reservedAliases - пути сервиса, которые не могут быть пользовательскими ссылками
+var reservedAliases = map[string]struct{}{
+	"api":     {},
+	"ping":    {},
+	"swagger": {},
+	"debug":   {},
+}
+
+// ValidateAlias проверяет символы, длину пользовательской ссылки и отсутствие ее среди путей сервиса
+func ValidateAlias(alias string) error {
+	if len(alias) < minAliasLength || len(alias) > maxAliasLength {
+		return fmt.Errorf("%w: length must be between %d and %d", ErrInvalidAlias, minAliasLength, maxAliasLength)
+	}
+	if !aliasPattern.MatchString(alias) {
+		return fmt.Errorf("%w: only latin letters, digits, '-' and '_' are allowed", ErrInvalidAlias)
+	}
+	if _, reserved := reservedAliases[strings.ToLower(alias)]; reserved {
+		return fmt.Errorf("%w: %q is reserved", ErrInvalidAlias, alias)
+	}
+
+	return nil
+}
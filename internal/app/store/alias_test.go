@@ -0,0 +1,40 @@
+package store_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/store"
+)
+
+func TestValidateAlias(t *testing.T) {
+	tests := []struct {
+		name    string
+		alias   string
+		wantErr bool
+	}{
+		{name: "Slug", alias: "spring-sale"},
+		{name: "Letters, digits and underscore", alias: "Sale_2024"},
+		{name: "Too short", alias: "ab", wantErr: true},
+		{name: "Too long", alias: strings.Repeat("a", 33), wantErr: true},
+		{name: "Slash", alias: "spring/sale", wantErr: true},
+		{name: "Non-latin letters", alias: "распродажа", wantErr: true},
+		{name: "Reserved api", alias: "api", wantErr: true},
+		{name: "Reserved ping in upper case", alias: "PING", wantErr: true},
+		{name: "Reserved swagger", alias: "swagger", wantErr: true},
+		{name: "Reserved debug", alias: "debug", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := store.ValidateAlias(test.alias)
+			if test.wantErr {
+				assert.ErrorIs(t, err, store.ErrInvalidAlias)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
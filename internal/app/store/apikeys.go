@@ -0,0 +1,83 @@
+package store
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// apiKeyPrefix - префикс значений ключей API, по которому их легко найти в логах и конфигурации
+	apiKeyPrefix = "sk_"
+	// apiKeyBytes - количество случайных байт в значении ключа API
+	apiKeyBytes = 32
+	// apiKeyVisiblePrefix - длина начала ключа, которое сохраняется для отображения в списке ключей
+	apiKeyVisiblePrefix = 10
+	// maxAPIKeyNameLength - максимальная длина названия ключа API в символах
+	maxAPIKeyNameLength = 64
+)
+
+var (
+	// ErrAPIKeyNotFound ошибка об отсутствии ключа API
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	// ErrInvalidAPIKeyName ошибка о недопустимом названии ключа API
+	ErrInvalidAPIKeyName = errors.New("invalid api key name")
+)
+
+// APIKey ключ API пользователя. Значение ключа не хранится, вместо него сохраняется хеш Hash.
+type APIKey struct {
+	ID     string `json:"id"`
+	UserID string `json:"-"`
+	Name   string `json:"name"`
+	// Prefix - начало значения ключа, по которому пользователь отличает ключи
+	Prefix     string     `json:"prefix"`
+	Hash       string     `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// NewAPIKey создает ключ API пользователя и возвращает его вместе со значением, которое показывается один раз
+func NewAPIKey(userID string, name string, now time.Time) (APIKey, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return APIKey{}, "", fmt.Errorf("%w: name is required", ErrInvalidAPIKeyName)
+	}
+	if utf8.RuneCountInString(name) > maxAPIKeyNameLength {
+		return APIKey{}, "", fmt.Errorf("%w: name must be at most %d characters", ErrInvalidAPIKeyName, maxAPIKeyNameLength)
+	}
+
+	secret := make([]byte, apiKeyBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return APIKey{}, "", err
+	}
+	value := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	return APIKey{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Name:      name,
+		Prefix:    value[:apiKeyVisiblePrefix],
+		Hash:      HashAPIKey(value),
+		CreatedAt: now.UTC().Truncate(time.Microsecond),
+	}, value, nil
+}
+
+// HashAPIKey возвращает хеш значения ключа API для поиска в хранилище.
+// Значение ключа случайное и длинное, поэтому достаточно SHA-256 без соли в отличие от паролей.
+func HashAPIKey(value string) string {
+	return hashSecret(value)
+}
+
+// hashSecret возвращает SHA-256 случайного секрета в виде hex-строки
+func hashSecret(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
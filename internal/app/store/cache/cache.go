@@ -0,0 +1,329 @@
+// Package cache предназначен для кеширования переходов по коротким ссылкам.
+// CachedStore оборачивает любой store.Store и хранит результаты GetOriginalURL
+// в ограниченном LRU-кеше с временем жизни записей, в том числе для несуществующих ссылок.
+// Запись истекающей ссылки живет не дольше самой ссылки.
+//
+// Изменения ссылок сбрасывают записи только в кеше своего экземпляра. Если несколько экземпляров
+// работают с общим хранилищем, удаление, отключение или очистка ссылки на одном экземпляре
+// видны на остальных не позже чем через время жизни записи.
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	models "github.com/TimBerk/go-link-shortener/internal/app/models/batch"
+	"github.com/TimBerk/go-link-shortener/internal/app/store"
+)
+
+// entry описывает закешированный результат поиска оригинальной ссылки
+type entry struct {
+	shortURL    string
+	originalURL string
+	exists      bool
+	isDeleted   bool
+	unlimited   bool
+	// hasPassword - хеш пароля получен из оборачиваемого стора и сохранен в passwordHash
+	hasPassword  bool
+	passwordHash string
+	// linkExpiresAt - момент истечения ссылки, нулевое значение у бессрочных ссылок
+	linkExpiresAt time.Time
+	expiresAt     time.Time
+}
+
+// refill описывает незавершенные чтения ссылки из оборачиваемого стора.
+// generation увеличивается при сбросе записи, пока чтение не завершилось.
+type refill struct {
+	readers    int
+	generation uint64
+}
+
+// Stats описывает статистику использования кеша
+type Stats struct {
+	Hits   int64
+	Misses int64
+	Size   int
+}
+
+// CachedStore описывает стор с кешем переходов
+type CachedStore struct {
+	store.Store
+	size    int
+	ttl     time.Duration
+	items   map[string]*list.Element
+	order   *list.List
+	refills map[string]*refill
+	mutex   sync.Mutex
+	hits    atomic.Int64
+	misses  atomic.Int64
+	nowFunc func() time.Time
+}
+
+// NewCachedStore оборачивает стор кешем на size записей с временем жизни ttl
+func NewCachedStore(base store.Store, size int, ttl time.Duration) *CachedStore {
+	return &CachedStore{
+		Store:   base,
+		size:    size,
+		ttl:     ttl,
+		items:   make(map[string]*list.Element, size),
+		order:   list.New(),
+		refills: make(map[string]*refill),
+		nowFunc: time.Now,
+	}
+}
+
+// GetOriginalURL возвращает оригинальную ссылку из кеша, а при его отсутствии - из оборачиваемого стора.
+// Если запись была сброшена, пока ссылка читалась из стора, прочитанное значение могло устареть и не кешируется.
+func (s *CachedStore) GetOriginalURL(ctx context.Context, shortURL string, userID string) (string, bool, bool) {
+	if cached, ok := s.get(shortURL); ok {
+		s.hits.Add(1)
+		return cached.originalURL, cached.exists, cached.isDeleted
+	}
+
+	s.misses.Add(1)
+	generation := s.beginRefill(shortURL)
+	originalURL, exists, isDeleted := s.Store.GetOriginalURL(ctx, shortURL, userID)
+	cached := entry{
+		shortURL:    shortURL,
+		originalURL: originalURL,
+		exists:      exists,
+		isDeleted:   isDeleted,
+	}
+	if exists && !isDeleted {
+		linkExpiresAt, err := s.Store.GetLinkExpiry(ctx, shortURL)
+		if err != nil {
+			s.finishRefill(shortURL, generation, nil)
+			return originalURL, exists, isDeleted
+		}
+		cached.linkExpiresAt = linkExpiresAt
+	}
+	s.finishRefill(shortURL, generation, &cached)
+
+	return originalURL, exists, isDeleted
+}
+
+// AddURL добавляет ссылку и сбрасывает закешированное отсутствие ее короткой ссылки
+func (s *CachedStore) AddURL(ctx context.Context, originalURL string, userID string) (string, error) {
+	shortURL, err := s.Store.AddURL(ctx, originalURL, userID)
+	if shortURL != "" {
+		s.invalidate(shortURL)
+	}
+	return shortURL, err
+}
+
+// AddLink добавляет ссылку с параметрами и сбрасывает закешированное отсутствие ее короткой ссылки
+func (s *CachedStore) AddLink(ctx context.Context, originalURL string, opts store.LinkOptions, userID string) (string, error) {
+	shortURL, err := s.Store.AddLink(ctx, originalURL, opts, userID)
+	if opts.Alias != "" {
+		s.invalidate(opts.Alias)
+	} else if shortURL != "" {
+		s.invalidate(shortURL)
+	}
+	return shortURL, err
+}
+
+// AddURLs добавляет ссылки и сбрасывает закешированное отсутствие их коротких ссылок
+func (s *CachedStore) AddURLs(ctx context.Context, urls models.BatchRequest, userID string) (models.BatchResponse, error) {
+	responses, err := s.Store.AddURLs(ctx, urls, userID)
+	for _, response := range responses {
+		s.invalidate(response.ShortURL)
+	}
+	return responses, err
+}
+
+// UseClick учитывает переход по ссылке. Ссылки без лимита переходов отмечаются в кеше,
+// чтобы повторные переходы не обращались к оборачиваемому стору. Запись ссылки с лимитом сбрасывается
+// после каждого перехода, так как ее состояние могло измениться.
+func (s *CachedStore) UseClick(ctx context.Context, shortURL string) (store.ClickResult, error) {
+	if cached, ok := s.get(shortURL); ok && cached.unlimited {
+		return store.ClickUnlimited, nil
+	}
+
+	result, err := s.Store.UseClick(ctx, shortURL)
+	if err != nil {
+		return result, err
+	}
+	switch result {
+	case store.ClickUnlimited:
+		s.markUnlimited(shortURL)
+	case store.ClickAllowed, store.ClickExhausted:
+		s.invalidate(shortURL)
+	}
+	return result, nil
+}
+
+// GetPasswordHash возвращает хеш пароля ссылки. Пароль ссылки не меняется после ее создания,
+// поэтому полученный хеш сохраняется в закешированной записи ссылки.
+func (s *CachedStore) GetPasswordHash(ctx context.Context, shortURL string) (string, error) {
+	if cached, ok := s.get(shortURL); ok && cached.hasPassword {
+		return cached.passwordHash, nil
+	}
+
+	passwordHash, err := s.Store.GetPasswordHash(ctx, shortURL)
+	if err != nil {
+		return "", err
+	}
+	s.rememberPassword(shortURL, passwordHash)
+	return passwordHash, nil
+}
+
+// DeleteURL удаляет ссылки и сбрасывает их записи в кеше
+func (s *CachedStore) DeleteURL(ctx context.Context, batch []store.URLPair) error {
+	err := s.Store.DeleteURL(ctx, batch)
+	for _, pair := range batch {
+		s.invalidate(pair.ShortURL)
+	}
+	return err
+}
+
+// DeleteExpiredURLs удаляет истекшие ссылки и сбрасывает их записи в кеше
+func (s *CachedStore) DeleteExpiredURLs(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	deleted, err := s.Store.DeleteExpiredURLs(ctx, now, limit)
+	for _, shortURL := range deleted {
+		s.invalidate(shortURL)
+	}
+	return deleted, err
+}
+
+// DisableURL отключает ссылку в оборачиваемом сторе и убирает ее из кеша
+func (s *CachedStore) DisableURL(ctx context.Context, shortURL string) error {
+	err := s.Store.DisableURL(ctx, shortURL)
+	s.invalidate(shortURL)
+	return err
+}
+
+// Close закрывает оборачиваемый стор, если он это поддерживает
+func (s *CachedStore) Close() error {
+	return store.Close(s.Store)
+}
+
+// Stats возвращает количество попаданий, промахов и текущий размер кеша
+func (s *CachedStore) Stats() Stats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return Stats{
+		Hits:   s.hits.Load(),
+		Misses: s.misses.Load(),
+		Size:   s.order.Len(),
+	}
+}
+
+// get возвращает не устаревшую запись кеша и отмечает ее как недавно использованную
+func (s *CachedStore) get(shortURL string) (entry, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	element, ok := s.items[shortURL]
+	if !ok {
+		return entry{}, false
+	}
+
+	cached := element.Value.(entry)
+	if !s.nowFunc().Before(cached.expiresAt) {
+		s.order.Remove(element)
+		delete(s.items, shortURL)
+		return entry{}, false
+	}
+
+	s.order.MoveToFront(element)
+	return cached, true
+}
+
+// beginRefill отмечает начало чтения ссылки из оборачиваемого стора и возвращает поколение ее записи
+func (s *CachedStore) beginRefill(shortURL string) uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	r, ok := s.refills[shortURL]
+	if !ok {
+		r = &refill{}
+		s.refills[shortURL] = r
+	}
+	r.readers++
+	return r.generation
+}
+
+// finishRefill завершает чтение ссылки и сохраняет запись cached, если с начала чтения
+// запись не сбрасывалась. Пустая cached только завершает чтение.
+func (s *CachedStore) finishRefill(shortURL string, generation uint64, cached *entry) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	r := s.refills[shortURL]
+	r.readers--
+	if r.readers == 0 {
+		delete(s.refills, shortURL)
+	}
+	if cached != nil && r.generation == generation {
+		s.setLocked(*cached)
+	}
+}
+
+// setLocked сохраняет запись в кеше, вытесняя давно не использованные при переполнении.
+// Запись устаревает через ttl или в момент истечения ссылки, если он наступает раньше.
+// Вызывается под блокировкой mutex.
+func (s *CachedStore) setLocked(cached entry) {
+	if s.size <= 0 {
+		return
+	}
+
+	cached.expiresAt = s.nowFunc().Add(s.ttl)
+	if !cached.linkExpiresAt.IsZero() && cached.linkExpiresAt.Before(cached.expiresAt) {
+		cached.expiresAt = cached.linkExpiresAt
+	}
+	if element, ok := s.items[cached.shortURL]; ok {
+		element.Value = cached
+		s.order.MoveToFront(element)
+		return
+	}
+
+	s.items[cached.shortURL] = s.order.PushFront(cached)
+	for s.order.Len() > s.size {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.items, oldest.Value.(entry).shortURL)
+	}
+}
+
+// markUnlimited отмечает закешированную ссылку как не имеющую лимита переходов
+func (s *CachedStore) markUnlimited(shortURL string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if element, ok := s.items[shortURL]; ok {
+		cached := element.Value.(entry)
+		cached.unlimited = true
+		element.Value = cached
+	}
+}
+
+// rememberPassword сохраняет хеш пароля в закешированной записи ссылки
+func (s *CachedStore) rememberPassword(shortURL string, passwordHash string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if element, ok := s.items[shortURL]; ok {
+		cached := element.Value.(entry)
+		cached.hasPassword = true
+		cached.passwordHash = passwordHash
+		element.Value = cached
+	}
+}
+
+// invalidate удаляет запись из кеша и отменяет сохранение значений, читаемых из стора в этот момент
+func (s *CachedStore) invalidate(shortURL string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if r, ok := s.refills[shortURL]; ok {
+		r.generation++
+	}
+	if element, ok := s.items[shortURL]; ok {
+		s.order.Remove(element)
+		delete(s.items, shortURL)
+	}
+}
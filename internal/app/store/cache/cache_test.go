@@ -0,0 +1,220 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/store"
+	"github.com/TimBerk/go-link-shortener/internal/app/store/local"
+)
+
+type countingStore struct {
+	store.Store
+	calls      int
+	clickCalls int
+}
+
+func (s *countingStore) GetOriginalURL(ctx context.Context, shortURL string, userID string) (string, bool, bool) {
+	s.calls++
+	return s.Store.GetOriginalURL(ctx, shortURL, userID)
+}
+
+func (s *countingStore) UseClick(ctx context.Context, shortURL string) (store.ClickResult, error) {
+	s.clickCalls++
+	return s.Store.UseClick(ctx, shortURL)
+}
+
+func newTestStore(t *testing.T, size int, ttl time.Duration) (*CachedStore, *countingStore) {
+	localStore, err := local.NewURLStore(store.NewIDGenerator())
+	require.NoError(t, err)
+	base := &countingStore{Store: localStore}
+	return NewCachedStore(base, size, ttl), base
+}
+
+func TestGetOriginalURL(t *testing.T) {
+	ctx := context.Background()
+	cachedStore, base := newTestStore(t, 10, time.Minute)
+
+	shortURL, err := cachedStore.AddURL(ctx, "https://example.com", "test")
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		originalURL, exists, isDeleted := cachedStore.GetOriginalURL(ctx, shortURL, "test")
+		assert.Equal(t, "https://example.com", originalURL)
+		assert.True(t, exists)
+		assert.False(t, isDeleted)
+	}
+
+	assert.Equal(t, 1, base.calls, "Повторные переходы должны обслуживаться из кеша")
+	assert.Equal(t, Stats{Hits: 2, Misses: 1, Size: 1}, cachedStore.Stats())
+}
+
+func TestNegativeCache(t *testing.T) {
+	ctx := context.Background()
+	cachedStore, base := newTestStore(t, 10, time.Minute)
+
+	_, exists, _ := cachedStore.GetOriginalURL(ctx, "unknown", "test")
+	assert.False(t, exists)
+	_, exists, _ = cachedStore.GetOriginalURL(ctx, "unknown", "test")
+	assert.False(t, exists)
+
+	assert.Equal(t, 1, base.calls, "Отсутствие ссылки должно кешироваться")
+}
+
+func TestDeleteURLInvalidates(t *testing.T) {
+	ctx := context.Background()
+	cachedStore, base := newTestStore(t, 10, time.Minute)
+
+	shortURL, err := cachedStore.AddURL(ctx, "https://example.com", "test")
+	require.NoError(t, err)
+	_, _, isDeleted := cachedStore.GetOriginalURL(ctx, shortURL, "test")
+	assert.False(t, isDeleted)
+
+	require.NoError(t, cachedStore.DeleteURL(ctx, []store.URLPair{{ShortURL: shortURL, UserID: "test"}}))
+
+	_, _, isDeleted = cachedStore.GetOriginalURL(ctx, shortURL, "test")
+	assert.True(t, isDeleted, "Удаление должно сбрасывать запись кеша")
+	assert.Equal(t, 2, base.calls)
+}
+
+func TestEviction(t *testing.T) {
+	ctx := context.Background()
+	cachedStore, base := newTestStore(t, 2, time.Minute)
+
+	cachedStore.GetOriginalURL(ctx, "first", "test")
+	cachedStore.GetOriginalURL(ctx, "second", "test")
+	cachedStore.GetOriginalURL(ctx, "first", "test")
+	cachedStore.GetOriginalURL(ctx, "third", "test")
+	assert.Equal(t, 3, base.calls)
+	assert.Equal(t, 2, cachedStore.Stats().Size)
+
+	cachedStore.GetOriginalURL(ctx, "first", "test")
+	assert.Equal(t, 3, base.calls, "Недавно использованная запись не должна вытесняться")
+	cachedStore.GetOriginalURL(ctx, "second", "test")
+	assert.Equal(t, 4, base.calls, "Давно не использованная запись должна вытесняться")
+}
+
+func TestExpiration(t *testing.T) {
+	ctx := context.Background()
+	cachedStore, base := newTestStore(t, 10, time.Minute)
+	now := time.Now()
+	cachedStore.nowFunc = func() time.Time { return now }
+
+	cachedStore.GetOriginalURL(ctx, "unknown", "test")
+	now = now.Add(2 * time.Minute)
+	cachedStore.GetOriginalURL(ctx, "unknown", "test")
+
+	assert.Equal(t, 2, base.calls, "Устаревшая запись должна запрашиваться заново")
+}
+
+// expiringStore возвращает ссылку, истекающую в expiresAt по часам now
+type expiringStore struct {
+	store.Store
+	expiresAt time.Time
+	now       *time.Time
+	calls     int
+}
+
+func (s *expiringStore) GetOriginalURL(ctx context.Context, shortURL string, userID string) (string, bool, bool) {
+	s.calls++
+	return "https://example.com", true, store.IsExpired(s.expiresAt, *s.now)
+}
+
+func (s *expiringStore) GetLinkExpiry(ctx context.Context, shortURL string) (time.Time, error) {
+	return s.expiresAt, nil
+}
+
+func TestLinkExpiration(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	base := &expiringStore{expiresAt: now.Add(10 * time.Second), now: &now}
+	cachedStore := NewCachedStore(base, 10, time.Minute)
+	cachedStore.nowFunc = func() time.Time { return now }
+
+	_, _, isDeleted := cachedStore.GetOriginalURL(ctx, "abc123", "test")
+	assert.False(t, isDeleted)
+	now = now.Add(5 * time.Second)
+	_, _, isDeleted = cachedStore.GetOriginalURL(ctx, "abc123", "test")
+	assert.False(t, isDeleted)
+	assert.Equal(t, 1, base.calls, "До истечения ссылки переход должен обслуживаться из кеша")
+
+	now = now.Add(5 * time.Second)
+	_, _, isDeleted = cachedStore.GetOriginalURL(ctx, "abc123", "test")
+	assert.True(t, isDeleted, "Истекшая ссылка не должна переходить из кеша")
+	assert.Equal(t, 2, base.calls)
+}
+
+func TestUseClick(t *testing.T) {
+	ctx := context.Background()
+	cachedStore, base := newTestStore(t, 10, time.Minute)
+
+	unlimitedURL, err := cachedStore.AddURL(ctx, "https://example.com", "test")
+	require.NoError(t, err)
+	cachedStore.GetOriginalURL(ctx, unlimitedURL, "test")
+	for i := 0; i < 3; i++ {
+		result, err := cachedStore.UseClick(ctx, unlimitedURL)
+		require.NoError(t, err)
+		assert.Equal(t, store.ClickUnlimited, result)
+	}
+	assert.Equal(t, 1, base.clickCalls, "Ссылка без лимита должна проверяться в сторе один раз")
+
+	limitedURL, err := cachedStore.AddLink(ctx, "https://example.com/secret", store.LinkOptions{MaxClicks: 1}, "test")
+	require.NoError(t, err)
+	_, _, isDeleted := cachedStore.GetOriginalURL(ctx, limitedURL, "test")
+	assert.False(t, isDeleted)
+
+	result, err := cachedStore.UseClick(ctx, limitedURL)
+	require.NoError(t, err)
+	assert.Equal(t, store.ClickAllowed, result)
+	result, err = cachedStore.UseClick(ctx, limitedURL)
+	require.NoError(t, err)
+	assert.Equal(t, store.ClickExhausted, result)
+
+	_, _, isDeleted = cachedStore.GetOriginalURL(ctx, limitedURL, "test")
+	assert.True(t, isDeleted, "После исчерпания лимита запись кеша должна сбрасываться")
+}
+
+// racingStore вызывает onRead после чтения ссылки, имитируя изменение, пришедшее во время промаха кеша
+type racingStore struct {
+	store.Store
+	onRead func()
+}
+
+func (s *racingStore) GetOriginalURL(ctx context.Context, shortURL string, userID string) (string, bool, bool) {
+	originalURL, exists, isDeleted := s.Store.GetOriginalURL(ctx, shortURL, userID)
+	if s.onRead != nil {
+		onRead := s.onRead
+		s.onRead = nil
+		onRead()
+	}
+	return originalURL, exists, isDeleted
+}
+
+func TestInvalidateDuringMiss(t *testing.T) {
+	ctx := context.Background()
+	localStore, err := local.NewURLStore(store.NewIDGenerator())
+	require.NoError(t, err)
+	base := &racingStore{Store: localStore}
+	cachedStore := NewCachedStore(base, 10, time.Minute)
+
+	shortURL, err := cachedStore.AddURL(ctx, "https://example.com", "test")
+	require.NoError(t, err)
+	base.onRead = func() {
+		require.NoError(t, cachedStore.DisableURL(ctx, shortURL))
+	}
+
+	_, _, isDeleted := cachedStore.GetOriginalURL(ctx, shortURL, "test")
+	assert.False(t, isDeleted, "Промах возвращает значение, прочитанное до отключения")
+
+	_, _, isDeleted = cachedStore.GetOriginalURL(ctx, shortURL, "test")
+	assert.True(t, isDeleted, "Сброс во время промаха не должен перезаписываться устаревшей записью")
+	assert.Empty(t, cachedStore.refills, "Завершенные чтения не должны оставаться в кеше")
+
+	_, _, isDeleted = cachedStore.GetOriginalURL(ctx, shortURL, "test")
+	assert.True(t, isDeleted)
+	assert.Equal(t, Stats{Hits: 1, Misses: 2, Size: 1}, cachedStore.Stats(), "Запись после сброса должна снова кешироваться")
+}
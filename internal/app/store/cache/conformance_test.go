@@ -0,0 +1,16 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/store"
+	"github.com/TimBerk/go-link-shortener/internal/app/store/storetest"
+)
+
+func TestConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		cachedStore, _ := newTestStore(t, 100, time.Minute)
+		return cachedStore
+	})
+}
@@ -0,0 +1,42 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ClickResult результат учета перехода по ссылке
+type ClickResult int
+
+const (
+	// ClickUnlimited - количество переходов по ссылке не ограничено
+	ClickUnlimited ClickResult = iota
+	// ClickAllowed - переход учтен, лимит переходов уменьшен
+	ClickAllowed
+	// ClickExhausted - лимит переходов по ссылке исчерпан
+	ClickExhausted
+)
+
+// Click описывает переход по короткой ссылке для аналитики.
+// OwnerID при сохранении перехода заполняется хранилищем по владельцу ссылки.
+type Click struct {
+	ShortURL  string
+	OwnerID   string
+	VisitorID string
+	ClickedAt time.Time
+	Referrer  string
+	UserAgent string
+	IP        string
+}
+
+// ErrInvalidMaxClicks ошибка о недопустимом лимите переходов по ссылке
+var ErrInvalidMaxClicks = errors.New("invalid max clicks")
+
+// ValidateMaxClicks проверяет лимит переходов по ссылке, нулевое значение соответствует отсутствию лимита
+func ValidateMaxClicks(maxClicks int) error {
+	if maxClicks < 0 {
+		return fmt.Errorf("%w: max_clicks must be positive", ErrInvalidMaxClicks)
+	}
+	return nil
+}
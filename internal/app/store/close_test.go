@@ -0,0 +1,46 @@
+package store_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/store"
+)
+
+// closerStore закрывается с заданной ошибкой
+type closerStore struct {
+	store.Store
+	err    error
+	closed bool
+}
+
+func (s *closerStore) Close() error {
+	s.closed = true
+	return s.err
+}
+
+// poolStore закрывается без ошибки, как пул соединений PostgreSQL
+type poolStore struct {
+	store.Store
+	closed bool
+}
+
+func (s *poolStore) Close() {
+	s.closed = true
+}
+
+func TestClose(t *testing.T) {
+	errClose := errors.New("close error")
+
+	withError := &closerStore{err: errClose}
+	assert.ErrorIs(t, store.Close(withError), errClose)
+	assert.True(t, withError.closed)
+
+	withoutError := &poolStore{}
+	assert.NoError(t, store.Close(withoutError))
+	assert.True(t, withoutError.closed, "Стор без ошибки закрытия тоже должен закрываться")
+
+	assert.NoError(t, store.Close(struct{ store.Store }{}), "Стор без Close не требует закрытия")
+}
@@ -0,0 +1,36 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidExpiration ошибка о недопустимом сроке действия ссылки
+var ErrInvalidExpiration = errors.New("invalid expiration")
+
+// ResolveExpiration возвращает момент истечения ссылки по абсолютному времени expiresAt
+// или времени жизни ttl в формате time.ParseDuration. Нулевое значение соответствует бессрочной ссылке.
+// Одновременно можно задать только один из параметров, момент истечения должен быть позже now.
+func ResolveExpiration(expiresAt *time.Time, ttl string, now time.Time) (time.Time, error) {
+	switch {
+	case expiresAt != nil && ttl != "":
+		return time.Time{}, fmt.Errorf("%w: only one of expires_at and ttl is allowed", ErrInvalidExpiration)
+	case ttl != "":
+		duration, err := time.ParseDuration(ttl)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%w: ttl must be a duration like 90s, 15m or 24h", ErrInvalidExpiration)
+		}
+		if duration <= 0 {
+			return time.Time{}, fmt.Errorf("%w: ttl must be positive", ErrInvalidExpiration)
+		}
+		return now.Add(duration), nil
+	case expiresAt != nil:
+		if !expiresAt.After(now) {
+			return time.Time{}, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidExpiration)
+		}
+		return *expiresAt, nil
+	default:
+		return time.Time{}, nil
+	}
+}
@@ -0,0 +1,44 @@
+package store_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/store"
+)
+
+func TestResolveExpiration(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	future := now.Add(48 * time.Hour)
+	past := now.Add(-time.Minute)
+
+	tests := []struct {
+		name      string
+		expiresAt *time.Time
+		ttl       string
+		want      time.Time
+		wantErr   bool
+	}{
+		{name: "Without expiration"},
+		{name: "TTL", ttl: "90m", want: now.Add(90 * time.Minute)},
+		{name: "Expires at", expiresAt: &future, want: future},
+		{name: "Both", expiresAt: &future, ttl: "1h", wantErr: true},
+		{name: "Invalid TTL", ttl: "week", wantErr: true},
+		{name: "Negative TTL", ttl: "-1h", wantErr: true},
+		{name: "Expires at in the past", expiresAt: &past, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := store.ResolveExpiration(test.expiresAt, test.ttl, now)
+			if test.wantErr {
+				assert.ErrorIs(t, err, store.ErrInvalidExpiration)
+				return
+			}
+			assert.NoError(t, err)
+			assert.True(t, test.want.Equal(got), "got %s, want %s", got, test.want)
+		})
+	}
+}
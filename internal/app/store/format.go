@@ -0,0 +1,232 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// возмозжные символы для генерации ссылки
+	chars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+	// символы без легко путаемых 0/O, 1/l/I
+	unambiguousChars = "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz23456789"
+	// символы, допустимые в алфавите ссылки без экранирования в пути URL
+	urlSafeChars = chars + "-._~"
+	// длина ссылки
+	length = 6
+	// maxCodeLength - наибольшая допустимая длина ссылки
+	maxCodeLength = 32
+	// collisionWindow - количество новых ссылок, по которому оценивается доля коллизий
+	collisionWindow = 100
+)
+
+// Алфавиты ссылок, выбираемые в настройках по имени
+const (
+	// AlphabetBase62 - латинские буквы и цифры
+	AlphabetBase62 = "base62"
+	// AlphabetUnambiguous - латинские буквы и цифры без 0, O, 1, l и I
+	AlphabetUnambiguous = "unambiguous"
+)
+
+// ErrInvalidCodeFormat ошибка о недопустимом алфавите или длине ссылки
+var ErrInvalidCodeFormat = errors.New("invalid short link format")
+
+// GeneratorOption задает необязательные параметры генератора ссылок
+type GeneratorOption func(*codeFormat)
+
+// WithAlphabet задает символы, из которых составляется ссылка
+func WithAlphabet(alphabet string) GeneratorOption {
+	return func(f *codeFormat) {
+		f.alphabet = alphabet
+	}
+}
+
+// WithLength задает начальную длину ссылки
+func WithLength(length int) GeneratorOption {
+	return func(f *codeFormat) {
+		f.length.Store(int64(length))
+	}
+}
+
+// WithAutoGrow включает увеличение длины ссылки до maxLength,
+// когда доля новых ссылок, потребовавших повторной генерации, превышает threshold
+func WithAutoGrow(maxLength int, threshold float64) GeneratorOption {
+	return func(f *codeFormat) {
+		f.maxLength = maxLength
+		f.threshold = threshold
+	}
+}
+
+// ResolveAlphabet возвращает символы алфавита по имени или сам переданный алфавит
+func ResolveAlphabet(alphabet string) string {
+	switch alphabet {
+	case "", AlphabetBase62:
+		return chars
+	case AlphabetUnambiguous:
+		return unambiguousChars
+	default:
+		return alphabet
+	}
+}
+
+// ValidateCodeFormat проверяет алфавит и длины ссылки
+func ValidateCodeFormat(alphabet string, length int, maxLength int) error {
+	if len(alphabet) < 2 {
+		return fmt.Errorf("%w: alphabet must contain at least 2 symbols", ErrInvalidCodeFormat)
+	}
+	seen := make(map[rune]struct{}, len(alphabet))
+	for _, symbol := range alphabet {
+		if !strings.ContainsRune(urlSafeChars, symbol) {
+			return fmt.Errorf("%w: alphabet symbol %q is not allowed in URL path", ErrInvalidCodeFormat, symbol)
+		}
+		if _, exists := seen[symbol]; exists {
+			return fmt.Errorf("%w: alphabet symbol %q is repeated", ErrInvalidCodeFormat, symbol)
+		}
+		seen[symbol] = struct{}{}
+	}
+	if length < 1 || length > maxCodeLength {
+		return fmt.Errorf("%w: length must be between 1 and %d", ErrInvalidCodeFormat, maxCodeLength)
+	}
+	if maxLength != 0 && (maxLength < length || maxLength > maxCodeLength) {
+		return fmt.Errorf("%w: max length must be between %d and %d", ErrInvalidCodeFormat, length, maxCodeLength)
+	}
+
+	return nil
+}
+
+// CodeLengthStore интерфейс для сторов, сохраняющих ссылки между перезапусками
+type CodeLengthStore interface {
+	// MaxCodeLength возвращает наибольшую длину сгенерированных ссылок без учета пользовательских
+	MaxCodeLength(ctx context.Context) (int, error)
+}
+
+// lengthRestorer интерфейс для генераторов, длина ссылок которых восстанавливается по стору
+type lengthRestorer interface {
+	restoreLength(length int)
+}
+
+// RestoreCodeLength продолжает увеличение длины ссылок с длины, достигнутой до перезапуска
+// или на других экземплярах с тем же хранилищем: генератор с автоувеличением длины получает
+// наибольшую длину сгенерированных ссылок стора, но не больше наибольшей длины автоувеличения.
+// Длина, увеличенная экземпляром во время работы, не передается уже запущенным экземплярам
+// и применяется ими при следующем запуске.
+func RestoreCodeLength(ctx context.Context, s Store, gen Generator) error {
+	lengthStore, ok := s.(CodeLengthStore)
+	if !ok {
+		return nil
+	}
+	restorer, ok := gen.(lengthRestorer)
+	if !ok {
+		return nil
+	}
+
+	length, err := lengthStore.MaxCodeLength(ctx)
+	if err != nil {
+		return err
+	}
+	restorer.restoreLength(length)
+	return nil
+}
+
+// codeFormat описывает алфавит и текущую длину ссылок.
+// Длина увеличивается, если в окне из collisionWindow новых ссылок доля коллизий превысила порог.
+// Нулевой указатель соответствует алфавиту base62 и длине 6 без увеличения.
+type codeFormat struct {
+	alphabet   string
+	length     atomic.Int64
+	maxLength  int
+	threshold  float64
+	mutex      sync.Mutex
+	generated  int
+	collisions int
+	// collided - оригинальные ссылки, коллизии которых уже учтены в текущем окне
+	collided map[string]struct{}
+}
+
+// newCodeFormat создает формат ссылок с переданными параметрами
+func newCodeFormat(opts ...GeneratorOption) *codeFormat {
+	format := &codeFormat{alphabet: chars}
+	format.length.Store(length)
+	for _, opt := range opts {
+		opt(format)
+	}
+	return format
+}
+
+// symbols возвращает алфавит ссылок
+func (f *codeFormat) symbols() string {
+	if f == nil {
+		return chars
+	}
+	return f.alphabet
+}
+
+// currentLength возвращает текущую длину ссылок
+func (f *codeFormat) currentLength() int {
+	if f == nil {
+		return length
+	}
+	return int(f.length.Load())
+}
+
+// restore увеличивает текущую длину ссылок до length, не превышая наибольшую длину автоувеличения.
+// Без автоувеличения длина не меняется.
+func (f *codeFormat) restore(length int) {
+	if f == nil || f.threshold <= 0 {
+		return
+	}
+
+	length = min(length, f.maxLength)
+	if length > f.currentLength() {
+		f.length.Store(int64(length))
+		logrus.WithField("length", length).Info("Short link length restored from store")
+	}
+}
+
+// observe учитывает попытку генерации: нулевая попытка - новая ссылка, остальные - повтор после коллизии.
+// Непустой key задают генераторы, которые для одной оригинальной ссылки всегда повторяют одни и те же коды.
+// Для них в окне учитывается не больше одной коллизии на ссылку, иначе повторное сокращение
+// уже сохраненной ссылки считалось бы новой коллизией и увеличивало длину ссылок.
+func (f *codeFormat) observe(key string, attempt int) {
+	if f == nil || f.threshold <= 0 {
+		return
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	switch {
+	case attempt == 0:
+		f.generated++
+	case key == "":
+		f.collisions++
+	default:
+		if _, counted := f.collided[key]; !counted {
+			if f.collided == nil {
+				f.collided = make(map[string]struct{})
+			}
+			f.collided[key] = struct{}{}
+			f.collisions++
+		}
+	}
+	if f.generated < collisionWindow {
+		return
+	}
+
+	rate := float64(f.collisions) / float64(f.generated)
+	if rate > f.threshold && f.currentLength() < f.maxLength {
+		newLength := f.length.Add(1)
+		logrus.WithFields(logrus.Fields{
+			"rate":   rate,
+			"length": newLength,
+		}).Warning("Short link collision rate exceeded threshold, increasing link length")
+	}
+	f.generated, f.collisions = 0, 0
+	clear(f.collided)
+}
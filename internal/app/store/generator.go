@@ -0,0 +1,323 @@
+package store
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/config"
+	"github.com/TimBerk/go-link-shortener/internal/pkg/utils"
+)
+
+// Виды генераторов ссылок, выбираемые в настройках
+const (
+	// GeneratorRandom - случайные ссылки на основе math/rand
+	GeneratorRandom = "random"
+	// GeneratorCrypto - случайные ссылки на основе crypto/rand
+	GeneratorCrypto = "crypto"
+	// GeneratorHash - ссылки, вычисляемые по хешу оригинальной ссылки
+	GeneratorHash = "hash"
+	// GeneratorSequence - последовательные ссылки на основе счетчика
+	GeneratorSequence = "sequence"
+)
+
+// counterBlockSize - количество значений счетчика, резервируемых в файле за одну запись
+const counterBlockSize = 1000
+
+// ErrUnknownGenerator ошибка о неизвестном виде генератора ссылок
+var ErrUnknownGenerator = errors.New("unknown link generator")
+
+// URLGenerator интерфейс для генераторов, вычисляющих ссылку по оригинальной ссылке
+type URLGenerator interface {
+	Generator
+	// NextFor - получает ссылку для оригинальной ссылки, attempt - номер попытки после коллизий
+	NextFor(originalURL string, attempt int) string
+}
+
+// Counter интерфейс для источника неповторяющихся значений
+type Counter interface {
+	// Next - получает следующее значение счетчика
+	Next() (uint64, error)
+}
+
+// collisionObserver интерфейс для генераторов, подбирающих длину ссылки по доле коллизий
+type collisionObserver interface {
+	observe(originalURL string, attempt int)
+}
+
+// NextFor получает ссылку для оригинальной ссылки.
+// Стор вызывает его с увеличивающимся номером попытки, пока не найдет свободную ссылку,
+// что позволяет генератору учитывать коллизии. Ссылки, совпадающие с путями сервиса, пропускаются.
+func NextFor(gen Generator, originalURL string, attempt int) string {
+	if observer, ok := gen.(collisionObserver); ok {
+		observer.observe(originalURL, attempt)
+	}
+
+	for {
+		var shortURL string
+		if urlGen, ok := gen.(URLGenerator); ok {
+			shortURL = urlGen.NextFor(originalURL, attempt)
+		} else {
+			shortURL = gen.Next()
+		}
+		if _, reserved := reservedAliases[strings.ToLower(shortURL)]; !reserved {
+			return shortURL
+		}
+		attempt++
+	}
+}
+
+// NewGenerator создает генератор ссылок по виду из настроек.
+// Для последовательного генератора счетчик сохраняется в файле cfg.CounterPath,
+// сторы с собственным счетчиком (PostgreSQL, Redis) заменяют его своим.
+func NewGenerator(cfg *config.Config) (Generator, error) {
+	if err := ValidateCodeFormat(ResolveAlphabet(cfg.CodeAlphabet), cfg.CodeLength, cfg.CodeMaxLength); err != nil {
+		return nil, err
+	}
+	opts := FormatOptions(cfg)
+
+	switch cfg.Generator {
+	case "", GeneratorRandom:
+		return NewIDGenerator(opts...), nil
+	case GeneratorCrypto:
+		return NewCryptoGenerator(opts...), nil
+	case GeneratorHash:
+		return NewHashGenerator(opts...), nil
+	case GeneratorSequence:
+		return NewSequenceGenerator(NewFileCounter(cfg.CounterPath), opts...), nil
+	default:
+		return nil, fmt.Errorf("%w %q, available generators: %s", ErrUnknownGenerator, cfg.Generator,
+			strings.Join([]string{GeneratorRandom, GeneratorCrypto, GeneratorHash, GeneratorSequence}, ", "))
+	}
+}
+
+// FormatOptions возвращает параметры формата ссылок из настроек.
+// Сторы с собственным счетчиком создают с ними последовательный генератор, чтобы сохранить алфавит и длину ссылок.
+func FormatOptions(cfg *config.Config) []GeneratorOption {
+	return []GeneratorOption{
+		WithAlphabet(ResolveAlphabet(cfg.CodeAlphabet)),
+		WithLength(cfg.CodeLength),
+		WithAutoGrow(cfg.CodeMaxLength, cfg.CollisionThreshold),
+	}
+}
+
+// CryptoGenerator генератор случайных ссылок на основе криптографически стойкого источника
+type CryptoGenerator struct {
+	format *codeFormat
+}
+
+// NewCryptoGenerator возвращает новый генератор случайных ссылок
+func NewCryptoGenerator(opts ...GeneratorOption) Generator {
+	return &CryptoGenerator{format: newCodeFormat(opts...)}
+}
+
+// Next генерирует ссылку.
+// Байты за пределами наибольшего кратного длине алфавита значения отбрасываются, чтобы символы были равновероятны.
+func (g *CryptoGenerator) Next() string {
+	symbols := g.format.symbols()
+	size := g.format.currentLength()
+	limit := 256 - 256%len(symbols)
+
+	id := make([]byte, 0, size)
+	buf := make([]byte, size*2)
+	for len(id) < size {
+		if _, err := rand.Read(buf); err != nil {
+			logrus.WithField("err", err).Error("Failed to read random bytes, using math/rand")
+			return (&IDGenerator{format: g.format}).Next()
+		}
+		for _, b := range buf {
+			if int(b) < limit && len(id) < size {
+				id = append(id, symbols[int(b)%len(symbols)])
+			}
+		}
+	}
+
+	return string(id)
+}
+
+// observe учитывает попытку генерации ссылки для подбора ее длины
+func (g *CryptoGenerator) observe(_ string, attempt int) {
+	g.format.observe("", attempt)
+}
+
+// restoreLength восстанавливает длину ссылок, увеличенную до перезапуска
+func (g *CryptoGenerator) restoreLength(length int) {
+	g.format.restore(length)
+}
+
+// HashGenerator генератор ссылок по хешу оригинальной ссылки.
+// Одна и та же ссылка всегда получает один и тот же код, при коллизии хешируется ссылка с номером попытки.
+type HashGenerator struct {
+	format *codeFormat
+}
+
+// NewHashGenerator возвращает новый генератор ссылок по хешу
+func NewHashGenerator(opts ...GeneratorOption) Generator {
+	return &HashGenerator{format: newCodeFormat(opts...)}
+}
+
+// Next генерирует случайную ссылку, когда оригинальная ссылка неизвестна
+func (g *HashGenerator) Next() string {
+	return (&CryptoGenerator{format: g.format}).Next()
+}
+
+// observe учитывает попытку генерации ссылки для подбора ее длины.
+// Коды по хешу повторяются для одной ссылки, поэтому коллизии учитываются по оригинальной ссылке.
+func (g *HashGenerator) observe(originalURL string, attempt int) {
+	g.format.observe(originalURL, attempt)
+}
+
+// restoreLength восстанавливает длину ссылок, увеличенную до перезапуска
+func (g *HashGenerator) restoreLength(length int) {
+	g.format.restore(length)
+}
+
+// NextFor вычисляет ссылку по хешу SHA-256 оригинальной ссылки
+func (g *HashGenerator) NextFor(originalURL string, attempt int) string {
+	data := originalURL
+	if attempt > 0 {
+		data += "#" + strconv.Itoa(attempt)
+	}
+	sum := sha256.Sum256([]byte(data))
+
+	symbols := g.format.symbols()
+	value := new(big.Int).SetBytes(sum[:])
+	base := big.NewInt(int64(len(symbols)))
+	remainder := new(big.Int)
+
+	id := make([]byte, g.format.currentLength())
+	for i := range id {
+		value.DivMod(value, base, remainder)
+		id[i] = symbols[remainder.Int64()]
+	}
+
+	return string(id)
+}
+
+// SequenceGenerator генератор последовательных ссылок.
+// Значения счетчика не повторяются, поэтому ссылки не конфликтуют между собой и длина ссылок
+// по коллизиям не увеличивается. Ссылка дополняется слева первым символом алфавита до заданной длины,
+// чтобы первые ссылки не были короткими и легко перебираемыми, и становится длиннее,
+// когда значение счетчика перестает в нее помещаться. Стор повторяет генерацию, только если код
+// уже занят пользовательской ссылкой или случайной ссылкой, выданной при недоступности счетчика.
+type SequenceGenerator struct {
+	counter Counter
+	format  *codeFormat
+}
+
+// NewSequenceGenerator возвращает новый генератор ссылок на основе счетчика
+func NewSequenceGenerator(counter Counter, opts ...GeneratorOption) Generator {
+	return &SequenceGenerator{counter: counter, format: newCodeFormat(opts...)}
+}
+
+// Next генерирует ссылку из следующего значения счетчика в кодировке алфавита ссылок.
+// При недоступности счетчика возвращает случайную ссылку.
+func (g *SequenceGenerator) Next() string {
+	value, err := g.counter.Next()
+	if err != nil {
+		logrus.WithField("err", err).Error("Failed to get next counter value, using random link")
+		return (&CryptoGenerator{format: g.format}).Next()
+	}
+
+	return encodeNumber(value, g.format.symbols(), g.format.currentLength())
+}
+
+// encodeNumber кодирует число символами переданного алфавита, дополняя код первым символом до длины size
+func encodeNumber(value uint64, symbols string, size int) string {
+	var id []byte
+	for value > 0 || len(id) < max(size, 1) {
+		id = append(id, symbols[value%uint64(len(symbols))])
+		value /= uint64(len(symbols))
+	}
+	for i, j := 0, len(id)-1; i < j; i, j = i+1, j-1 {
+		id[i], id[j] = id[j], id[i]
+	}
+
+	return string(id)
+}
+
+// FileCounter счетчик, сохраняемый в файле.
+// Значения резервируются блоками: в файл записывается граница блока,
+// поэтому после перезапуска счетчик продолжает со следующего блока и значения не повторяются.
+type FileCounter struct {
+	path  string
+	next  uint64
+	limit uint64
+	mutex sync.Mutex
+}
+
+// NewFileCounter возвращает счетчик, сохраняемый в файле по переданному пути.
+// Файл читается при первом обращении к счетчику.
+func NewFileCounter(path string) *FileCounter {
+	return &FileCounter{path: path}
+}
+
+// Next возвращает следующее значение счетчика, при исчерпании блока резервирует новый
+func (c *FileCounter) Next() (uint64, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.next >= c.limit {
+		if err := c.reserve(); err != nil {
+			return 0, err
+		}
+	}
+
+	value := c.next
+	c.next++
+	return value, nil
+}
+
+// reserve читает границу из файла и атомарно записывает границу следующего блока
+func (c *FileCounter) reserve() error {
+	start := uint64(1)
+	content, err := os.ReadFile(c.path)
+	if err == nil {
+		start, err = strconv.ParseUint(strings.TrimSpace(string(content)), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid counter file %s: %w", c.path, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	limit := start + counterBlockSize
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	tmpPath := c.path + ".tmp"
+	if err := writeCounter(tmpPath, limit); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, c.path); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	c.next, c.limit = start, limit
+	return nil
+}
+
+// writeCounter записывает значение счетчика в файл и сбрасывает его на диск
+func writeCounter(path string, value uint64) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer utils.CloseWithLog(file, "Error closing counter file")
+
+	if _, err := file.WriteString(strconv.FormatUint(value, 10)); err != nil {
+		return err
+	}
+	return file.Sync()
+}
@@ -0,0 +1,287 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/config"
+	"github.com/TimBerk/go-link-shortener/internal/app/store"
+)
+
+type sliceCounter struct {
+	values []uint64
+	err    error
+}
+
+func (c *sliceCounter) Next() (uint64, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	value := c.values[0]
+	c.values = c.values[1:]
+	return value, nil
+}
+
+func TestNewGenerator(t *testing.T) {
+	tests := []struct {
+		name      string
+		generator string
+		want      store.Generator
+		wantErr   error
+	}{
+		{name: "Default", generator: "", want: &store.IDGenerator{}},
+		{name: "Random", generator: store.GeneratorRandom, want: &store.IDGenerator{}},
+		{name: "Crypto", generator: store.GeneratorCrypto, want: &store.CryptoGenerator{}},
+		{name: "Hash", generator: store.GeneratorHash, want: &store.HashGenerator{}},
+		{name: "Sequence", generator: store.GeneratorSequence, want: &store.SequenceGenerator{}},
+		{name: "Unknown", generator: "uuid", wantErr: store.ErrUnknownGenerator},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := config.NewConfig("localhost:8080", "http://localhost:8080", true)
+			cfg.Generator = test.generator
+			cfg.CounterPath = filepath.Join(t.TempDir(), "counter")
+
+			gen, err := store.NewGenerator(cfg)
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.IsType(t, test.want, gen)
+		})
+	}
+}
+
+func TestCryptoGenerator(t *testing.T) {
+	gen := store.NewCryptoGenerator()
+	pattern := regexp.MustCompile(`^[A-Za-z0-9]{6}$`)
+
+	seen := make(map[string]struct{})
+	for i := 0; i < 1000; i++ {
+		shortURL := gen.Next()
+		assert.Regexp(t, pattern, shortURL)
+		seen[shortURL] = struct{}{}
+	}
+	assert.Len(t, seen, 1000, "Случайные ссылки не должны повторяться")
+}
+
+func TestHashGenerator(t *testing.T) {
+	gen := store.NewHashGenerator()
+
+	first := store.NextFor(gen, "https://example.com", 0)
+	assert.Len(t, first, 6)
+	assert.Equal(t, first, store.NextFor(gen, "https://example.com", 0), "Ссылка должна зависеть только от адреса")
+	assert.NotEqual(t, first, store.NextFor(gen, "https://example.com", 1), "При коллизии должна выдаваться другая ссылка")
+	assert.NotEqual(t, first, store.NextFor(gen, "https://example.org", 0))
+}
+
+func TestSequenceGenerator(t *testing.T) {
+	tests := []struct {
+		name    string
+		counter *sliceCounter
+		opts    []store.GeneratorOption
+		want    []string
+	}{
+		{
+			name:    "Base62 encoding",
+			counter: &sliceCounter{values: []uint64{0, 1, 61, 62, 3843, 3844}},
+			opts:    []store.GeneratorOption{store.WithLength(1)},
+			want:    []string{"A", "B", "9", "BA", "99", "BAA"},
+		},
+		{
+			name:    "Padding to link length",
+			counter: &sliceCounter{values: []uint64{0, 1, 62, 56800235583, 56800235584}},
+			want:    []string{"AAAAAA", "AAAAAB", "AAAABA", "999999", "BAAAAAA"},
+		},
+		{
+			name:    "Custom alphabet",
+			counter: &sliceCounter{values: []uint64{1, 5}},
+			opts:    []store.GeneratorOption{store.WithAlphabet("xyz"), store.WithLength(3)},
+			want:    []string{"xxy", "xyz"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gen := store.NewSequenceGenerator(test.counter, test.opts...)
+			for _, want := range test.want {
+				assert.Equal(t, want, gen.Next())
+			}
+		})
+	}
+
+	t.Run("Counter error falls back to random link", func(t *testing.T) {
+		gen := store.NewSequenceGenerator(&sliceCounter{err: errors.New("counter unavailable")})
+		assert.Len(t, gen.Next(), 6)
+	})
+}
+
+func TestFileCounter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "counter")
+
+	first := store.NewFileCounter(path)
+	seen := make(map[uint64]struct{})
+	for i := 0; i < 1500; i++ {
+		value, err := first.Next()
+		require.NoError(t, err)
+		seen[value] = struct{}{}
+	}
+	assert.Len(t, seen, 1500)
+
+	restarted := store.NewFileCounter(path)
+	value, err := restarted.Next()
+	require.NoError(t, err)
+	assert.NotContains(t, seen, value, "После перезапуска значения не должны повторяться")
+}
+
+func TestCodeFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		gen     store.Generator
+		pattern string
+	}{
+		{
+			name:    "Custom length",
+			gen:     store.NewCryptoGenerator(store.WithLength(10)),
+			pattern: `^[A-Za-z0-9]{10}$`,
+		},
+		{
+			name:    "Unambiguous alphabet",
+			gen:     store.NewIDGenerator(store.WithAlphabet(store.ResolveAlphabet(store.AlphabetUnambiguous))),
+			pattern: `^[^0O1lI]{6}$`,
+		},
+		{
+			name:    "Hash with custom alphabet and length",
+			gen:     store.NewHashGenerator(store.WithAlphabet("abc"), store.WithLength(20)),
+			pattern: `^[abc]{20}$`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				assert.Regexp(t, test.pattern, store.NextFor(test.gen, "https://example.com/"+strconv.Itoa(i), 0))
+			}
+		})
+	}
+}
+
+func TestValidateCodeFormat(t *testing.T) {
+	tests := []struct {
+		name      string
+		alphabet  string
+		length    int
+		maxLength int
+		wantErr   bool
+	}{
+		{name: "Base62", alphabet: store.ResolveAlphabet(store.AlphabetBase62), length: 6, maxLength: 12},
+		{name: "Without growth", alphabet: "ab", length: 32},
+		{name: "Single symbol", alphabet: "a", length: 6, wantErr: true},
+		{name: "Repeated symbol", alphabet: "abca", length: 6, wantErr: true},
+		{name: "Symbol not allowed in path", alphabet: "ab/", length: 6, wantErr: true},
+		{name: "Zero length", alphabet: "ab", length: 0, wantErr: true},
+		{name: "Max length less than length", alphabet: "ab", length: 8, maxLength: 6, wantErr: true},
+		{name: "Max length too long", alphabet: "ab", length: 6, maxLength: 64, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := store.ValidateCodeFormat(test.alphabet, test.length, test.maxLength)
+			if test.wantErr {
+				assert.ErrorIs(t, err, store.ErrInvalidCodeFormat)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestAutoGrow(t *testing.T) {
+	gen := store.NewIDGenerator(store.WithAutoGrow(7, 0.2))
+
+	// Доля коллизий ниже порога не меняет длину
+	for i := 0; i < 100; i++ {
+		store.NextFor(gen, "", 0)
+		if i%10 == 0 {
+			store.NextFor(gen, "", 1)
+		}
+	}
+	assert.Len(t, gen.Next(), 6)
+
+	// Доля коллизий выше порога увеличивает длину, но не больше максимальной
+	for window := 0; window < 3; window++ {
+		for i := 0; i < 100; i++ {
+			store.NextFor(gen, "", 0)
+			store.NextFor(gen, "", 1)
+		}
+	}
+	assert.Len(t, gen.Next(), 7)
+}
+
+func TestAutoGrow_Hash(t *testing.T) {
+	gen := store.NewHashGenerator(store.WithAutoGrow(7, 0.2))
+
+	// Повторное сокращение одной ссылки проходит те же коды и учитывается как одна коллизия
+	for i := 0; i < 100; i++ {
+		store.NextFor(gen, "https://example.com/popular", 0)
+		for attempt := 1; attempt <= i%5+1; attempt++ {
+			store.NextFor(gen, "https://example.com/popular", attempt)
+		}
+	}
+	assert.Len(t, gen.Next(), 6)
+
+	// Коллизии разных ссылок увеличивают длину
+	for i := 0; i < 100; i++ {
+		originalURL := "https://example.com/" + strconv.Itoa(i)
+		store.NextFor(gen, originalURL, 0)
+		store.NextFor(gen, originalURL, 1)
+	}
+	assert.Len(t, gen.Next(), 7)
+}
+
+// lengthStore возвращает заданную длину сохраненных ссылок
+type lengthStore struct {
+	store.Store
+	length int
+	err    error
+}
+
+func (s *lengthStore) MaxCodeLength(context.Context) (int, error) {
+	return s.length, s.err
+}
+
+func TestRestoreCodeLength(t *testing.T) {
+	errStore := errors.New("store error")
+
+	tests := []struct {
+		name           string
+		gen            store.Generator
+		store          store.Store
+		expectedLength int
+		expectedErr    error
+	}{
+		{name: "Grown length is restored", gen: store.NewIDGenerator(store.WithAutoGrow(8, 0.2)), store: &lengthStore{length: 7}, expectedLength: 7},
+		{name: "Length is limited by max length", gen: store.NewCryptoGenerator(store.WithAutoGrow(8, 0.2)), store: &lengthStore{length: 12}, expectedLength: 8},
+		{name: "Shorter links keep length", gen: store.NewHashGenerator(store.WithAutoGrow(8, 0.2)), store: &lengthStore{length: 3}, expectedLength: 6},
+		{name: "Without auto grow", gen: store.NewIDGenerator(), store: &lengthStore{length: 7}, expectedLength: 6},
+		{name: "Store without links length", gen: store.NewIDGenerator(store.WithAutoGrow(8, 0.2)), store: struct{ store.Store }{}, expectedLength: 6},
+		{name: "Store error", gen: store.NewIDGenerator(store.WithAutoGrow(8, 0.2)), store: &lengthStore{err: errStore}, expectedLength: 6, expectedErr: errStore},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := store.RestoreCodeLength(context.Background(), test.store, test.gen)
+			assert.ErrorIs(t, err, test.expectedErr)
+			assert.Len(t, test.gen.Next(), test.expectedLength)
+		})
+	}
+}
@@ -0,0 +1,15 @@
+package json
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/store"
+	"github.com/TimBerk/go-link-shortener/internal/app/store/storetest"
+)
+
+func TestConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		return newTestStore(t, filepath.Join(t.TempDir(), "data.json"))
+	})
+}
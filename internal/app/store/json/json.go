@@ -1,57 +1,314 @@
-// Package json предназначен для организации хранения данных в JSON-файле
+// Package json предназначен для организации хранения данных в JSON-файле.
+//
+// Файл хранилища является журналом: каждое изменение дописывается в конец файла
+// отдельной JSON-строкой, при загрузке журнал воспроизводится целиком.
+// В фоне журнал периодически сжимается в снимок текущего состояния,
+// который атомарно заменяет исходный файл.
+//
+// Переходы по ссылкам дописываются в отдельный файл рядом с журналом с суффиксом .clicks,
+// который не участвует в сжатии. При загрузке по файлу переходов строится индекс смещений строк
+// каждой ссылки, поэтому статистика ссылки читает только ее переходы. Ключи API, учетные записи и сессии хранятся снимками в файлах
+// с суффиксами .keys, .accounts и .sessions, которые атомарно перезаписываются при каждом изменении.
 package json
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"slices"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/TimBerk/go-link-shortener/internal/pkg/utils"
 
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 
+	"github.com/TimBerk/go-link-shortener/internal/app/config"
 	models "github.com/TimBerk/go-link-shortener/internal/app/models/batch"
 	"github.com/TimBerk/go-link-shortener/internal/app/store"
 )
 
+// SyncPolicy определяет, когда записи журнала сбрасываются на диск
+type SyncPolicy string
+
+const (
+	// SyncAlways - сброс на диск после каждой записи
+	SyncAlways SyncPolicy = "always"
+	// SyncInterval - сброс на диск в фоне с заданным интервалом
+	SyncInterval SyncPolicy = "interval"
+	// SyncNever - сброс на диск остается на усмотрение операционной системы
+	SyncNever SyncPolicy = "never"
+)
+
+const (
+	// opAdd - операция добавления записи
+	opAdd = "add"
+	// opUpdate - операция замены записи
+	opUpdate = "update"
+	// opDelete - операция пометки записи удаленной
+	opDelete = "delete"
+
+	// defaultSyncInterval - интервал сброса журнала на диск для SyncInterval
+	defaultSyncInterval = time.Second
+	// defaultCompactInterval - интервал проверки необходимости сжатия журнала
+	defaultCompactInterval = time.Minute
+	// defaultCompactThreshold - количество устаревших записей журнала, после которого он сжимается
+	defaultCompactThreshold = 1000
+	// clicksSuffix - суффикс файла переходов по ссылкам
+	clicksSuffix = ".clicks"
+	// apiKeysSuffix - суффикс файла ключей API
+	apiKeysSuffix = ".keys"
+	// accountsSuffix - суффикс файла учетных записей
+	accountsSuffix = ".accounts"
+	// sessionsSuffix - суффикс файла сессий
+	sessionsSuffix = ".sessions"
+	// apiKeyTouchInterval - минимальный интервал между сохранениями момента использования ключа API на диск
+	apiKeyTouchInterval = time.Minute
+)
+
+// ErrUnknownSyncPolicy ошибка о неизвестной политике сброса журнала
+var ErrUnknownSyncPolicy = errors.New("unknown sync policy")
+
 // JSONRecord описывает структуру JSON-записи
 type JSONRecord struct {
 	UUID        string `json:"uuid"`
 	ShortURL    string `json:"short_url"`
 	OriginalURL string `json:"original_url"`
 	UserID      string `json:"user_id"`
+	IsDeleted   bool   `json:"is_deleted"`
+	IsAlias     bool   `json:"is_alias,omitempty"`
+	// ExpiresAt - момент истечения ссылки, у бессрочных ссылок не задан
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// ClicksLeft - оставшееся количество переходов, у ссылок без лимита не задано
+	ClicksLeft *int `json:"clicks_left,omitempty"`
+	// PasswordHash - хеш пароля ссылки, у ссылок без пароля не задан
+	PasswordHash string `json:"password_hash,omitempty"`
+}
+
+// isCustom сообщает, что запись не участвует в поиске по оригинальной ссылке
+func (r JSONRecord) isCustom() bool {
+	return r.IsAlias || r.ExpiresAt != nil || r.ClicksLeft != nil || r.PasswordHash != ""
+}
+
+// isExpired сообщает, что ссылка истекла к моменту now
+func (r JSONRecord) isExpired(now time.Time) bool {
+	return r.ExpiresAt != nil && store.IsExpired(*r.ExpiresAt, now)
+}
+
+// isInactive сообщает, что ссылка удалена, истекла или исчерпала лимит переходов к моменту now
+func (r JSONRecord) isInactive(now time.Time) bool {
+	return r.IsDeleted || r.isExpired(now) || (r.ClicksLeft != nil && *r.ClicksLeft <= 0)
+}
+
+// ClickRecord описывает JSON-запись перехода по ссылке
+type ClickRecord struct {
+	ShortURL  string    `json:"short_url"`
+	OwnerID   string    `json:"owner_id"`
+	VisitorID string    `json:"visitor_id,omitempty"`
+	ClickedAt time.Time `json:"clicked_at"`
+	Referrer  string    `json:"referrer,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	IP        string    `json:"ip,omitempty"`
+}
+
+// clickSpan описывает положение строки перехода в файле переходов
+type clickSpan struct {
+	offset int64
+	length int
+}
+
+// APIKeyRecord описывает JSON-запись ключа API
+type APIKeyRecord struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"key_hash"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// AccountRecord описывает JSON-запись учетной записи
+type AccountRecord struct {
+	ID           string    `json:"id"`
+	Login        string    `json:"login"`
+	PasswordHash string    `json:"password_hash"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// SessionRecord описывает JSON-запись сессии
+type SessionRecord struct {
+	TokenHash string    `json:"token_hash"`
+	UserID    string    `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// logEntry описывает строку журнала. Строки без операции относятся к снимку и добавляют запись.
+type logEntry struct {
+	Op string `json:"op,omitempty"`
+	JSONRecord
+}
+
+// Option задает дополнительные параметры JSON-стора
+type Option func(*JSONStore)
+
+// WithSyncPolicy задает политику сброса журнала на диск
+func WithSyncPolicy(policy SyncPolicy) Option {
+	return func(s *JSONStore) {
+		if policy != "" {
+			s.syncPolicy = policy
+		}
+	}
+}
+
+// WithSyncInterval задает интервал сброса журнала на диск для SyncInterval
+func WithSyncInterval(interval time.Duration) Option {
+	return func(s *JSONStore) {
+		if interval > 0 {
+			s.syncInterval = interval
+		}
+	}
+}
+
+// WithCompactInterval задает интервал проверки необходимости сжатия журнала
+func WithCompactInterval(interval time.Duration) Option {
+	return func(s *JSONStore) {
+		if interval > 0 {
+			s.compactInterval = interval
+		}
+	}
+}
+
+// WithCompactThreshold задает количество устаревших записей журнала, после которого он сжимается
+func WithCompactThreshold(threshold int) Option {
+	return func(s *JSONStore) {
+		if threshold > 0 {
+			s.compactThreshold = threshold
+		}
+	}
 }
 
 // JSONStore описывает структуру JSON-стора
 type JSONStore struct {
 	storage     map[string]JSONRecord
 	fullStorage map[string]JSONRecord
+	userStorage map[string][]string
+	apiKeys     map[string]store.APIKey
+	accounts    map[string]store.Account
+	sessions    map[string]store.Session
 	filePath    string
 	gen         store.Generator
 	mutex       sync.Mutex
+
+	file             *os.File
+	clicksFile       *os.File
+	clickIndex       map[string][]clickSpan
+	clicksSize       int64
+	syncPolicy       SyncPolicy
+	syncInterval     time.Duration
+	compactInterval  time.Duration
+	compactThreshold int
+	logEntries       int
+	dirty            bool
+	missingNewline   bool
+	compacting       bool
+	pending          []logEntry
+	closed           bool
+	done             chan struct{}
+	wg               sync.WaitGroup
 }
 
-// NewJSONStore на основании переданного пути и генератора создает новый JSON-стор
-func NewJSONStore(filePath string, gen store.Generator) (*JSONStore, error) {
+// NewJSONStore на основании переданного пути и генератора создает новый JSON-стор.
+// Журнал из файла воспроизводится, после чего запускается фоновый сброс и сжатие журнала.
+func NewJSONStore(filePath string, gen store.Generator, opts ...Option) (*JSONStore, error) {
 	store := &JSONStore{
-		storage:     make(map[string]JSONRecord),
-		fullStorage: make(map[string]JSONRecord),
-		filePath:    filePath,
-		gen:         gen,
+		storage:          make(map[string]JSONRecord),
+		fullStorage:      make(map[string]JSONRecord),
+		userStorage:      make(map[string][]string),
+		apiKeys:          make(map[string]store.APIKey),
+		accounts:         make(map[string]store.Account),
+		sessions:         make(map[string]store.Session),
+		clickIndex:       make(map[string][]clickSpan),
+		filePath:         filePath,
+		gen:              gen,
+		syncPolicy:       SyncAlways,
+		syncInterval:     defaultSyncInterval,
+		compactInterval:  defaultCompactInterval,
+		compactThreshold: defaultCompactThreshold,
+		done:             make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(store)
+	}
+
+	switch store.syncPolicy {
+	case SyncAlways, SyncInterval, SyncNever:
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSyncPolicy, store.syncPolicy)
 	}
 
 	err := store.loadStorage()
 	if err != nil {
 		return nil, fmt.Errorf("error loading json store: %s", err)
 	}
+	if err := store.loadAPIKeys(); err != nil {
+		return nil, fmt.Errorf("error loading json api keys: %s", err)
+	}
+	if err := store.loadAccounts(); err != nil {
+		return nil, fmt.Errorf("error loading json accounts: %s", err)
+	}
+	if err := store.loadClicks(); err != nil {
+		return nil, fmt.Errorf("error loading json clicks: %s", err)
+	}
+
+	if err := store.openLog(); err != nil {
+		return nil, fmt.Errorf("error opening json store: %s", err)
+	}
+
+	store.clicksFile, err = os.OpenFile(filePath+clicksSuffix, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		utils.CloseWithLog(store.file, "Error closing JSON-file")
+		return nil, fmt.Errorf("error opening json clicks file: %s", err)
+	}
+
+	store.wg.Add(1)
+	go store.run()
 
 	return store, nil
 }
 
-// loadStorage осуществляет загрузку и декодирование записей из файла
+// init регистрирует стор для схемы file://
+func init() {
+	store.Register("file", func(ctx context.Context, storageURL string, gen store.Generator, cfg *config.Config) (store.Store, error) {
+		filePath, err := store.PathFromURL(storageURL)
+		if err != nil {
+			return nil, err
+		}
+
+		jsonStore, err := NewJSONStore(
+			filePath,
+			gen,
+			WithSyncPolicy(SyncPolicy(cfg.FileSyncPolicy)),
+			WithCompactInterval(cfg.FileCompactInterval),
+		)
+		if err != nil {
+			return nil, err
+		}
+		return jsonStore, nil
+	})
+}
+
+// loadStorage осуществляет загрузку журнала из файла и воспроизводит его записи.
+// Неполная последняя строка, оставшаяся после аварийного завершения, отбрасывается.
 func (s *JSONStore) loadStorage() error {
 	file, err := os.Open(s.filePath)
 	if err != nil {
@@ -62,127 +319,1188 @@ func (s *JSONStore) loadStorage() error {
 	}
 	defer utils.CloseWithLog(file, "Error closing JSON-file")
 
-	decoder := json.NewDecoder(file)
-	for decoder.More() {
-		var entry JSONRecord
-		if err := decoder.Decode(&entry); err != nil {
-			return err
+	reader := bufio.NewReader(file)
+	var offset int64
+	for {
+		line, errRead := reader.ReadBytes('\n')
+		if errRead != nil && !errors.Is(errRead, io.EOF) {
+			return errRead
+		}
+
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+			var entry logEntry
+			if errDecode := json.Unmarshal(trimmed, &entry); errDecode != nil {
+				if errRead == nil {
+					return errDecode
+				}
+
+				logrus.WithFields(logrus.Fields{
+					"err":    errDecode,
+					"offset": offset,
+				}).Warning("Truncating incomplete JSON store record")
+				return os.Truncate(s.filePath, offset)
+			}
+
+			s.apply(entry)
+			s.logEntries++
+			s.missingNewline = errRead != nil
+		}
+		offset += int64(len(line))
+
+		if errRead != nil {
+			return nil
+		}
+	}
+}
+
+// loadClicks строит индекс смещений строк переходов по ссылкам.
+// Неполная последняя строка, оставшаяся после аварийного завершения, отбрасывается.
+func (s *JSONStore) loadClicks() error {
+	path := s.filePath + clicksSuffix
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer utils.CloseWithLog(file, "Error closing JSON clicks file")
+
+	reader := bufio.NewReader(file)
+	for {
+		line, errRead := reader.ReadBytes('\n')
+		if errRead != nil && !errors.Is(errRead, io.EOF) {
+			return errRead
+		}
+		if errors.Is(errRead, io.EOF) {
+			if len(line) == 0 {
+				return nil
+			}
+			logrus.WithField("offset", s.clicksSize).Warning("Truncating incomplete JSON click record")
+			return os.Truncate(path, s.clicksSize)
+		}
+
+		var click ClickRecord
+		if err := json.Unmarshal(line, &click); err == nil {
+			s.clickIndex[click.ShortURL] = append(s.clickIndex[click.ShortURL], clickSpan{offset: s.clicksSize, length: len(line)})
+		}
+		s.clicksSize += int64(len(line))
+	}
+}
+
+// loadAPIKeys загружает ключи API из файла ключей
+func (s *JSONStore) loadAPIKeys() error {
+	var records []APIKeyRecord
+	if err := readSnapshotFile(s.filePath+apiKeysSuffix, &records); err != nil {
+		return err
+	}
+	for _, record := range records {
+		s.apiKeys[record.ID] = store.APIKey{
+			ID:         record.ID,
+			UserID:     record.UserID,
+			Name:       record.Name,
+			Prefix:     record.Prefix,
+			Hash:       record.KeyHash,
+			CreatedAt:  record.CreatedAt,
+			LastUsedAt: record.LastUsedAt,
+		}
+	}
+	return nil
+}
+
+// saveAPIKeys атомарно перезаписывает файл ключей API текущим состоянием
+func (s *JSONStore) saveAPIKeys() error {
+	records := make([]APIKeyRecord, 0, len(s.apiKeys))
+	for _, key := range s.apiKeys {
+		records = append(records, APIKeyRecord{
+			ID:         key.ID,
+			UserID:     key.UserID,
+			Name:       key.Name,
+			Prefix:     key.Prefix,
+			KeyHash:    key.Hash,
+			CreatedAt:  key.CreatedAt,
+			LastUsedAt: key.LastUsedAt,
+		})
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].CreatedAt.Before(records[j].CreatedAt)
+	})
+	return writeSnapshotFile(s.filePath+apiKeysSuffix, records)
+}
+
+// loadAccounts загружает учетные записи и не истекшие сессии из их файлов
+func (s *JSONStore) loadAccounts() error {
+	var accounts []AccountRecord
+	if err := readSnapshotFile(s.filePath+accountsSuffix, &accounts); err != nil {
+		return err
+	}
+	for _, record := range accounts {
+		s.accounts[record.Login] = store.Account{
+			ID:           record.ID,
+			Login:        record.Login,
+			PasswordHash: record.PasswordHash,
+			CreatedAt:    record.CreatedAt,
+		}
+	}
+
+	var sessions []SessionRecord
+	if err := readSnapshotFile(s.filePath+sessionsSuffix, &sessions); err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, record := range sessions {
+		session := store.Session{
+			TokenHash: record.TokenHash,
+			UserID:    record.UserID,
+			CreatedAt: record.CreatedAt,
+			ExpiresAt: record.ExpiresAt,
+		}
+		if session.IsActive(now) {
+			s.sessions[session.TokenHash] = session
 		}
-		s.storage[entry.ShortURL] = entry
 	}
 	return nil
 }
 
-// saveStorage осуществляет сохранение записей в файл
-func (s *JSONStore) saveStorage() error {
-	file, err := os.Create(s.filePath)
+// saveAccounts атомарно перезаписывает файл учетных записей текущим состоянием
+func (s *JSONStore) saveAccounts() error {
+	records := make([]AccountRecord, 0, len(s.accounts))
+	for _, account := range s.accounts {
+		records = append(records, AccountRecord{
+			ID:           account.ID,
+			Login:        account.Login,
+			PasswordHash: account.PasswordHash,
+			CreatedAt:    account.CreatedAt,
+		})
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].CreatedAt.Before(records[j].CreatedAt)
+	})
+	return writeSnapshotFile(s.filePath+accountsSuffix, records)
+}
+
+// saveSessions атомарно перезаписывает файл сессий текущим состоянием
+func (s *JSONStore) saveSessions() error {
+	records := make([]SessionRecord, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		records = append(records, SessionRecord{
+			TokenHash: session.TokenHash,
+			UserID:    session.UserID,
+			CreatedAt: session.CreatedAt,
+			ExpiresAt: session.ExpiresAt,
+		})
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].CreatedAt.Before(records[j].CreatedAt)
+	})
+	return writeSnapshotFile(s.filePath+sessionsSuffix, records)
+}
+
+// readSnapshotFile читает JSON-снимок из файла, отсутствие файла не считается ошибкой
+func readSnapshotFile(path string, v any) error {
+	data, err := os.ReadFile(path)
 	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
 		return err
 	}
-	defer utils.CloseWithLog(file, "Error closing JSON-file")
+	return json.Unmarshal(data, v)
+}
 
-	encoder := json.NewEncoder(file)
-	for _, entry := range s.storage {
-		if err := encoder.Encode(entry); err != nil {
+// writeSnapshotFile атомарно заменяет файл JSON-снимком v через временный файл
+func writeSnapshotFile(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	tmpPath := path + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		utils.CloseWithLog(file, "Error closing JSON snapshot file")
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		utils.CloseWithLog(file, "Error closing JSON snapshot file")
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := syncDir(filepath.Dir(path)); err != nil {
+		logrus.WithField("err", err).Warning("Error syncing json store directory")
+	}
+	return nil
+}
+
+// openLog открывает файл журнала для дозаписи
+func (s *JSONStore) openLog() error {
+	file, err := os.OpenFile(s.filePath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	if s.missingNewline {
+		if _, err := file.Write([]byte("\n")); err != nil {
+			utils.CloseWithLog(file, "Error closing JSON-file")
 			return err
 		}
+		s.missingNewline = false
 	}
+
+	s.file = file
 	return nil
 }
 
-// AddURL осуществляет добавление с генерацией короткой ссылки для пользователя
-func (s *JSONStore) AddURL(ctx context.Context, originalURL string, userID string) (string, error) {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
+// apply применяет запись журнала к состоянию стора
+func (s *JSONStore) apply(entry logEntry) {
+	switch entry.Op {
+	case opDelete:
+		record, exists := s.storage[entry.ShortURL]
+		if exists && record.UserID == entry.UserID {
+			record.IsDeleted = true
+			s.putRecord(record)
+		}
+	case "", opAdd, opUpdate:
+		s.putRecord(entry.JSONRecord)
+	default:
+		logrus.WithField("op", entry.Op).Warning("Skipping unknown JSON store operation")
+	}
+}
 
-	record, exists := s.fullStorage[originalURL]
-	if exists && record.UserID == userID {
-		return record.ShortURL, store.ErrLinkExist
+// putRecord сохраняет запись в индексах стора.
+// Ссылки с параметрами не участвуют в поиске по оригинальной ссылке.
+func (s *JSONStore) putRecord(record JSONRecord) {
+	if current, exists := s.storage[record.ShortURL]; !exists {
+		s.userStorage[record.UserID] = append(s.userStorage[record.UserID], record.ShortURL)
+		if !record.isCustom() {
+			s.fullStorage[record.OriginalURL] = record
+		}
+	} else {
+		if current.UserID != record.UserID {
+			s.moveUserLink(record.ShortURL, current.UserID, record.UserID)
+		}
+		if indexed, ok := s.fullStorage[record.OriginalURL]; ok && indexed.ShortURL == record.ShortURL {
+			s.fullStorage[record.OriginalURL] = record
+		}
+	}
+	s.storage[record.ShortURL] = record
+}
+
+// moveUserLink переносит ссылку из списка ссылок одного пользователя в конец списка другого
+func (s *JSONStore) moveUserLink(shortURL string, fromUserID string, toUserID string) {
+	links := s.userStorage[fromUserID]
+	for i, link := range links {
+		if link == shortURL {
+			links = append(links[:i], links[i+1:]...)
+			break
+		}
 	}
+	if len(links) == 0 {
+		delete(s.userStorage, fromUserID)
+	} else {
+		s.userStorage[fromUserID] = links
+	}
+	s.userStorage[toUserID] = append(s.userStorage[toUserID], shortURL)
+}
 
-	shortURL := s.gen.Next()
+// appendEntries дописывает записи в журнал согласно политике сброса на диск
+func (s *JSONStore) appendEntries(entries ...logEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
 
-	record, exists = s.storage[shortURL]
-	if exists && record.UserID == userID {
-		return s.AddURL(ctx, originalURL, userID)
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return err
+		}
 	}
 
-	record = JSONRecord{
-		ShortURL:    shortURL,
-		OriginalURL: originalURL,
-		UUID:        uuid.New().String(),
-		UserID:      userID,
+	if _, err := s.file.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	s.logEntries += len(entries)
+	if s.compacting {
+		s.pending = append(s.pending, entries...)
 	}
 
-	s.storage[shortURL] = record
-	s.fullStorage[originalURL] = record
+	switch s.syncPolicy {
+	case SyncAlways:
+		return s.file.Sync()
+	case SyncInterval:
+		s.dirty = true
+	}
+	return nil
+}
 
-	err := s.saveStorage()
-	if err != nil {
-		logrus.WithField("err", err).Error("Error saving json store")
-		return "", err
+// nextShortURL генерирует для оригинальной ссылки короткую, отсутствующую в сторе и в списке зарезервированных
+func (s *JSONStore) nextShortURL(originalURL string, reserved map[string]struct{}) string {
+	for attempt := 0; ; attempt++ {
+		shortURL := store.NextFor(s.gen, originalURL, attempt)
+		if _, exists := s.storage[shortURL]; exists {
+			continue
+		}
+		if _, exists := reserved[shortURL]; exists {
+			continue
+		}
+		return shortURL
 	}
-	return shortURL, nil
 }
 
-// AddURLs осуществляет добавление с генерацией коротких ссылок для пользователя
-func (s *JSONStore) AddURLs(ctx context.Context, urls models.BatchRequest, userID string) (models.BatchResponse, error) {
-	var responses models.BatchResponse
+// run выполняет фоновый сброс журнала на диск и его сжатие
+func (s *JSONStore) run() {
+	defer s.wg.Done()
+
+	syncTicker := time.NewTicker(s.syncInterval)
+	defer syncTicker.Stop()
+	compactTicker := time.NewTicker(s.compactInterval)
+	defer compactTicker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-syncTicker.C:
+			s.syncLog()
+		case <-compactTicker.C:
+			if !s.needsCompaction() {
+				continue
+			}
+			if err := s.Compact(); err != nil {
+				logrus.WithField("err", err).Error("Error compacting json store")
+			}
+		}
+	}
+}
 
+// syncLog сбрасывает на диск записи журнала, дописанные с последнего сброса
+func (s *JSONStore) syncLog() {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	for _, req := range urls {
-		shortURL := s.gen.Next()
+	if !s.dirty || s.closed {
+		return
+	}
+	if err := s.file.Sync(); err != nil {
+		logrus.WithField("err", err).Error("Error syncing json store")
+		return
+	}
+	if err := s.clicksFile.Sync(); err != nil {
+		logrus.WithField("err", err).Error("Error syncing json clicks file")
+		return
+	}
+	s.dirty = false
+}
 
-		record := JSONRecord{
-			ShortURL:    shortURL,
-			OriginalURL: req.OriginalURL,
-			UUID:        req.CorrelationID,
-			UserID:      userID,
+// needsCompaction проверяет, накопилось ли в журнале достаточно устаревших записей
+func (s *JSONStore) needsCompaction() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.logEntries-len(s.storage) >= s.compactThreshold
+}
+
+// Compact сжимает журнал в снимок текущего состояния.
+// Снимок пишется во временный файл без блокировки стора, записи, добавленные
+// за время его формирования, дописываются следом, после чего файл атомарно заменяет журнал.
+func (s *JSONStore) Compact() error {
+	s.mutex.Lock()
+	if s.compacting || s.closed {
+		s.mutex.Unlock()
+		return nil
+	}
+	records := make([]JSONRecord, 0, len(s.storage))
+	for _, links := range s.userStorage {
+		for _, shortURL := range links {
+			records = append(records, s.storage[shortURL])
 		}
+	}
+	s.compacting = true
+	s.pending = nil
+	s.mutex.Unlock()
 
-		s.storage[shortURL] = record
-		s.fullStorage[req.OriginalURL] = record
+	tmpPath := s.filePath + ".tmp"
+	tmpFile, errSnapshot := writeSnapshot(tmpPath, records)
 
-		err := s.saveStorage()
-		if err != nil {
-			logrus.WithField("err", err).Error("Error saving json store")
-		} else {
-			responses = append(responses, models.ItemResponse{
-				CorrelationID: req.CorrelationID,
-				ShortURL:      shortURL,
-			})
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	defer func() {
+		s.compacting = false
+		s.pending = nil
+	}()
+
+	if errSnapshot != nil {
+		return errSnapshot
+	}
+	if s.closed {
+		utils.CloseWithLog(tmpFile, "Error closing JSON snapshot")
+		return os.Remove(tmpPath)
+	}
+
+	if err := finishSnapshot(tmpFile, s.pending); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, s.filePath); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := syncDir(filepath.Dir(s.filePath)); err != nil {
+		logrus.WithField("err", err).Warning("Error syncing json store directory")
+	}
+
+	utils.CloseWithLog(s.file, "Error closing JSON-file")
+	if err := s.openLog(); err != nil {
+		return err
+	}
+	s.logEntries = len(records) + len(s.pending)
+	s.dirty = false
+
+	logrus.WithField("records", s.logEntries).Info("JSON store compacted")
+	return nil
+}
+
+// writeSnapshot создает временный файл снимка и записывает в него записи
+func writeSnapshot(path string, records []JSONRecord) (*os.File, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			utils.CloseWithLog(file, "Error closing JSON snapshot")
+			_ = os.Remove(path)
+			return nil, err
 		}
 	}
+	if err := writer.Flush(); err != nil {
+		utils.CloseWithLog(file, "Error closing JSON snapshot")
+		_ = os.Remove(path)
+		return nil, err
+	}
 
-	return responses, nil
+	return file, nil
 }
 
-// GetOriginalURL осуществляет поиск оригинальной ссылки по переданной короткой
-func (s *JSONStore) GetOriginalURL(ctx context.Context, shortURL string, userID string) (string, bool, bool) {
-	record, exists := s.storage[shortURL]
-	return record.OriginalURL, exists, false
+// finishSnapshot дописывает в снимок записи, появившиеся за время его формирования, и закрывает файл
+func finishSnapshot(file *os.File, pending []logEntry) error {
+	encoder := json.NewEncoder(file)
+	for _, entry := range pending {
+		if err := encoder.Encode(entry); err != nil {
+			utils.CloseWithLog(file, "Error closing JSON snapshot")
+			return err
+		}
+	}
+	if err := file.Sync(); err != nil {
+		utils.CloseWithLog(file, "Error closing JSON snapshot")
+		return err
+	}
+	return file.Close()
 }
 
-// Ping эмулирует проверку доступности стора
-func (s *JSONStore) Ping(ctx context.Context) error {
-	return nil
+// syncDir сбрасывает на диск содержимое директории после переименования файла
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer utils.CloseWithLog(dir, "Error closing JSON store directory")
+
+	return dir.Sync()
 }
 
-// DeleteURL удаляет ссылки пользователя
-func (s *JSONStore) DeleteURL(ctx context.Context, batch []store.URLPair) error {
-	if len(batch) == 0 {
+// Close останавливает фоновые задачи, сбрасывает журнал на диск и закрывает файл
+func (s *JSONStore) Close() error {
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
 		return nil
 	}
+	s.closed = true
+	s.mutex.Unlock()
 
-	for _, pair := range batch {
-		userLink, exists := s.storage[pair.ShortURL]
-		if exists && userLink.UserID == pair.UserID {
-			delete(s.fullStorage, userLink.OriginalURL)
-			delete(s.storage, pair.ShortURL)
-		}
+	close(s.done)
+	s.wg.Wait()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.clicksFile.Sync(); err != nil {
+		logrus.WithField("err", err).Error("Error syncing json clicks file")
 	}
+	utils.CloseWithLog(s.clicksFile, "Error closing JSON clicks file")
 
+	if err := s.file.Sync(); err != nil {
+		utils.CloseWithLog(s.file, "Error closing JSON-file")
+		return err
+	}
+	return s.file.Close()
+}
+
+// AddURL осуществляет добавление с генерацией короткой ссылки для пользователя
+func (s *JSONStore) AddURL(ctx context.Context, originalURL string, userID string) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if record, exists := s.fullStorage[originalURL]; exists {
+		return record.ShortURL, store.ErrLinkExist
+	}
+
+	record := JSONRecord{
+		ShortURL:    s.nextShortURL(originalURL, nil),
+		OriginalURL: originalURL,
+		UUID:        uuid.New().String(),
+		UserID:      userID,
+	}
+
+	if err := s.appendEntries(logEntry{Op: opAdd, JSONRecord: record}); err != nil {
+		logrus.WithField("err", err).Error("Error saving json store")
+		return "", err
+	}
+	s.putRecord(record)
+
+	return record.ShortURL, nil
+}
+
+// AddLink осуществляет добавление ссылки с параметрами для пользователя
+func (s *JSONStore) AddLink(ctx context.Context, originalURL string, opts store.LinkOptions, userID string) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.storage[opts.Alias]; exists && opts.Alias != "" {
+		return "", store.ErrAliasTaken
+	}
+
+	record := s.newLinkRecord(originalURL, opts, userID, nil)
+	if err := s.appendEntries(logEntry{Op: opAdd, JSONRecord: record}); err != nil {
+		logrus.WithField("err", err).Error("Error saving json store")
+		return "", err
+	}
+	s.putRecord(record)
+
+	return record.ShortURL, nil
+}
+
+// newLinkRecord создает запись ссылки с параметрами, для ссылки без пользовательской генерирует свободную
+func (s *JSONStore) newLinkRecord(originalURL string, opts store.LinkOptions, userID string, reserved map[string]struct{}) JSONRecord {
+	record := JSONRecord{
+		ShortURL:     opts.Alias,
+		OriginalURL:  originalURL,
+		UUID:         uuid.New().String(),
+		UserID:       userID,
+		IsAlias:      opts.Alias != "",
+		PasswordHash: opts.PasswordHash,
+	}
+	if record.ShortURL == "" {
+		record.ShortURL = s.nextShortURL(originalURL, reserved)
+	}
+	if !opts.ExpiresAt.IsZero() {
+		expiresAt := opts.ExpiresAt.UTC()
+		record.ExpiresAt = &expiresAt
+	}
+	if opts.MaxClicks > 0 {
+		clicksLeft := opts.MaxClicks
+		record.ClicksLeft = &clicksLeft
+	}
+	return record
+}
+
+// AddURLs осуществляет добавление с генерацией коротких ссылок для пользователя.
+// Для уже сохраненных ссылок возвращает их короткие ссылки, пользовательские ссылки проверяются до добавления пачки,
+// новые записи пачки дописываются в журнал одной операцией записи.
+func (s *JSONStore) AddURLs(ctx context.Context, urls models.BatchRequest, userID string) (models.BatchResponse, error) {
+	var responses models.BatchResponse
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entries := make([]logEntry, 0, len(urls))
+	reserved := make(map[string]struct{}, len(urls))
+	for _, req := range urls {
+		if req.Alias == "" {
+			continue
+		}
+		if _, exists := s.storage[req.Alias]; exists {
+			return nil, store.ErrAliasTaken
+		}
+		if _, exists := reserved[req.Alias]; exists {
+			return nil, store.ErrAliasTaken
+		}
+		reserved[req.Alias] = struct{}{}
+	}
+
+	added := make(map[string]string, len(urls))
+	for _, req := range urls {
+		if opts := store.LinkOptionsFromItem(req); !opts.IsZero() {
+			record := s.newLinkRecord(req.OriginalURL, opts, userID, reserved)
+			reserved[record.ShortURL] = struct{}{}
+			entries = append(entries, logEntry{Op: opAdd, JSONRecord: record})
+			responses = append(responses, models.ItemResponse{
+				CorrelationID: req.CorrelationID,
+				ShortURL:      record.ShortURL,
+			})
+			continue
+		}
+
+		shortURL, exists := added[req.OriginalURL]
+		if record, ok := s.fullStorage[req.OriginalURL]; ok {
+			shortURL, exists = record.ShortURL, true
+		}
+		if exists {
+			responses = append(responses, models.ItemResponse{
+				CorrelationID: req.CorrelationID,
+				ShortURL:      shortURL,
+			})
+			continue
+		}
+
+		shortURL = s.nextShortURL(req.OriginalURL, reserved)
+		reserved[shortURL] = struct{}{}
+		added[req.OriginalURL] = shortURL
+
+		entries = append(entries, logEntry{
+			Op: opAdd,
+			JSONRecord: JSONRecord{
+				ShortURL:    shortURL,
+				OriginalURL: req.OriginalURL,
+				UUID:        req.CorrelationID,
+				UserID:      userID,
+			},
+		})
+		responses = append(responses, models.ItemResponse{
+			CorrelationID: req.CorrelationID,
+			ShortURL:      shortURL,
+		})
+	}
+
+	if err := s.appendEntries(entries...); err != nil {
+		logrus.WithField("err", err).Error("Error saving json store")
+		return nil, err
+	}
+	for _, entry := range entries {
+		s.putRecord(entry.JSONRecord)
+	}
+
+	return responses, nil
+}
+
+// GetOriginalURL осуществляет поиск оригинальной ссылки по переданной короткой
+func (s *JSONStore) GetOriginalURL(ctx context.Context, shortURL string, userID string) (string, bool, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	record, exists := s.storage[shortURL]
+	return record.OriginalURL, exists, record.isInactive(time.Now())
+}
+
+// Ping эмулирует проверку доступности стора
+func (s *JSONStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+// DeleteURL помечает ссылки пользователя как удаленные и дописывает изменения в журнал
+func (s *JSONStore) DeleteURL(ctx context.Context, batch []store.URLPair) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var entries []logEntry
+	for _, pair := range batch {
+		record, exists := s.storage[pair.ShortURL]
+		if exists && record.UserID == pair.UserID && !record.IsDeleted {
+			entries = append(entries, logEntry{
+				Op:         opDelete,
+				JSONRecord: JSONRecord{ShortURL: pair.ShortURL, UserID: pair.UserID},
+			})
+		}
+	}
+
+	if len(entries) == 0 {
+		return nil
+	}
+
+	if err := s.appendEntries(entries...); err != nil {
+		logrus.WithField("err", err).Error("Error saving json store")
+		return err
+	}
+	for _, entry := range entries {
+		s.apply(entry)
+	}
+
+	return nil
+}
+
+// GetUserURLs возвращает не удаленные ссылки пользователя
+func (s *JSONStore) GetUserURLs(ctx context.Context, userID string) ([]store.UserURL, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	links := s.userStorage[userID]
+	urls := make([]store.UserURL, 0, len(links))
+	for _, shortURL := range links {
+		record := s.storage[shortURL]
+		if record.isInactive(time.Now()) {
+			continue
+		}
+		urls = append(urls, store.UserURL{
+			ShortURL:    shortURL,
+			OriginalURL: record.OriginalURL,
+		})
+	}
+
+	return urls, nil
+}
+
+// DeleteExpiredURLs помечает удаленными истекшие ссылки в порядке истечения и дописывает изменения в журнал
+func (s *JSONStore) DeleteExpiredURLs(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var expired []JSONRecord
+	for _, record := range s.storage {
+		if !record.IsDeleted && record.isExpired(now) {
+			expired = append(expired, record)
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool {
+		return expired[i].ExpiresAt.Before(*expired[j].ExpiresAt)
+	})
+	if len(expired) > limit {
+		expired = expired[:limit]
+	}
+	if len(expired) == 0 {
+		return nil, nil
+	}
+
+	entries := make([]logEntry, 0, len(expired))
+	shortURLs := make([]string, 0, len(expired))
+	for _, record := range expired {
+		entries = append(entries, logEntry{
+			Op:         opDelete,
+			JSONRecord: JSONRecord{ShortURL: record.ShortURL, UserID: record.UserID},
+		})
+		shortURLs = append(shortURLs, record.ShortURL)
+	}
+	if err := s.appendEntries(entries...); err != nil {
+		logrus.WithField("err", err).Error("Error saving json store")
+		return nil, err
+	}
+	for _, entry := range entries {
+		s.apply(entry)
+	}
+	return shortURLs, nil
+}
+
+// UseClick уменьшает оставшееся количество переходов по ссылке и дописывает изменение в журнал
+func (s *JSONStore) UseClick(ctx context.Context, shortURL string) (store.ClickResult, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	record, exists := s.storage[shortURL]
+	if !exists || record.ClicksLeft == nil {
+		return store.ClickUnlimited, nil
+	}
+	if *record.ClicksLeft <= 0 {
+		return store.ClickExhausted, nil
+	}
+
+	clicksLeft := *record.ClicksLeft - 1
+	record.ClicksLeft = &clicksLeft
+	if err := s.appendEntries(logEntry{Op: opUpdate, JSONRecord: record}); err != nil {
+		logrus.WithField("err", err).Error("Error saving json store")
+		return store.ClickUnlimited, err
+	}
+	s.putRecord(record)
+
+	return store.ClickAllowed, nil
+}
+
+// GetPasswordHash возвращает хеш пароля ссылки
+func (s *JSONStore) GetPasswordHash(ctx context.Context, shortURL string) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.storage[shortURL].PasswordHash, nil
+}
+
+// GetLinkExpiry возвращает момент истечения ссылки
+func (s *JSONStore) GetLinkExpiry(ctx context.Context, shortURL string) (time.Time, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if expiresAt := s.storage[shortURL].ExpiresAt; expiresAt != nil {
+		return *expiresAt, nil
+	}
+	return time.Time{}, nil
+}
+
+// MaxCodeLength возвращает наибольшую длину сгенерированных ссылок без учета пользовательских
+func (s *JSONStore) MaxCodeLength(ctx context.Context) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var length int
+	for shortURL, record := range s.storage {
+		if !record.IsAlias {
+			length = max(length, len(shortURL))
+		}
+	}
+	return length, nil
+}
+
+// AddClicks дописывает переходы по ссылкам с владельцами ссылок в файл переходов
+// одной операцией записи согласно политике сброса на диск
+func (s *JSONStore) AddClicks(ctx context.Context, clicks []store.Click) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	spans := make([]clickSpan, len(clicks))
+	for i, click := range clicks {
+		start := buf.Len()
+		record := ClickRecord{
+			ShortURL:  click.ShortURL,
+			OwnerID:   s.storage[click.ShortURL].UserID,
+			VisitorID: click.VisitorID,
+			ClickedAt: click.ClickedAt.UTC(),
+			Referrer:  click.Referrer,
+			UserAgent: click.UserAgent,
+			IP:        click.IP,
+		}
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+		spans[i] = clickSpan{offset: s.clicksSize + int64(start), length: buf.Len() - start}
+	}
+
+	if _, err := s.clicksFile.Write(buf.Bytes()); err != nil {
+		logrus.WithField("err", err).Error("Error saving json clicks")
+		// После частичной записи смещения следующих переходов считаются от фактического размера файла
+		if info, errStat := s.clicksFile.Stat(); errStat == nil {
+			s.clicksSize = info.Size()
+		}
+		return err
+	}
+	s.clicksSize += int64(buf.Len())
+	for i, click := range clicks {
+		s.clickIndex[click.ShortURL] = append(s.clickIndex[click.ShortURL], spans[i])
+	}
+
+	switch s.syncPolicy {
+	case SyncAlways:
+		return s.clicksFile.Sync()
+	case SyncInterval:
+		s.dirty = true
+	}
+	return nil
+}
+
+// GetLinkStats считает статистику переходов по ссылке пользователя, читая из файла переходов
+// только строки ссылки по индексу смещений. Файл читается без блокировки стора: уже записанные строки не меняются.
+func (s *JSONStore) GetLinkStats(ctx context.Context, link store.URLPair, query store.StatsQuery) (store.LinkStats, error) {
+	s.mutex.Lock()
+	record, exists := s.storage[link.ShortURL]
+	spans := s.clickIndex[link.ShortURL]
+	s.mutex.Unlock()
+	if !exists || record.UserID != link.UserID {
+		return store.LinkStats{}, store.ErrStatsNotFound
+	}
+
+	file, err := os.Open(s.filePath + clicksSuffix)
+	if err != nil {
+		return store.LinkStats{}, err
+	}
+	defer utils.CloseWithLog(file, "Error closing JSON clicks file")
+
+	collector := store.NewStatsCollector(query)
+	var line []byte
+	for _, span := range spans {
+		line = slices.Grow(line[:0], span.length)[:span.length]
+		if _, err := file.ReadAt(line, span.offset); err != nil {
+			return store.LinkStats{}, err
+		}
+
+		var click ClickRecord
+		if err := json.Unmarshal(line, &click); err != nil {
+			continue
+		}
+		collector.Add(store.Click{
+			ShortURL:  click.ShortURL,
+			OwnerID:   click.OwnerID,
+			VisitorID: click.VisitorID,
+			ClickedAt: click.ClickedAt,
+			Referrer:  click.Referrer,
+			UserAgent: click.UserAgent,
+			IP:        click.IP,
+		})
+	}
+	return collector.Stats(), nil
+}
+
+// GetServiceStats считает действующие ссылки и их владельцев
+func (s *JSONStore) GetServiceStats(ctx context.Context) (store.ServiceStats, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var stats store.ServiceStats
+	users := make(map[string]struct{})
+	now := time.Now()
+	for _, record := range s.storage {
+		if record.isInactive(now) {
+			continue
+		}
+		stats.URLs++
+		users[record.UserID] = struct{}{}
+	}
+	stats.Users = len(users)
+	return stats, nil
+}
+
+// AddAPIKey сохраняет ключ API пользователя и перезаписывает файл ключей
+func (s *JSONStore) AddAPIKey(ctx context.Context, key store.APIKey) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.apiKeys[key.ID] = key
+	if err := s.saveAPIKeys(); err != nil {
+		delete(s.apiKeys, key.ID)
+		return err
+	}
+	return nil
+}
+
+// GetAPIKeys возвращает ключи API пользователя в порядке создания
+func (s *JSONStore) GetAPIKeys(ctx context.Context, userID string) ([]store.APIKey, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var keys []store.APIKey
+	for _, key := range s.apiKeys {
+		if key.UserID == userID {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys, nil
+}
+
+// RevokeAPIKey удаляет ключ API пользователя и перезаписывает файл ключей
+func (s *JSONStore) RevokeAPIKey(ctx context.Context, userID string, keyID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key, exists := s.apiKeys[keyID]
+	if !exists || key.UserID != userID {
+		return store.ErrAPIKeyNotFound
+	}
+	delete(s.apiKeys, keyID)
+	if err := s.saveAPIKeys(); err != nil {
+		s.apiKeys[keyID] = key
+		return err
+	}
+	return nil
+}
+
+// UseAPIKey возвращает владельца ключа API и запоминает момент использования.
+// На диск момент использования сохраняется не чаще apiKeyTouchInterval, чтобы запросы не перезаписывали файл ключей.
+func (s *JSONStore) UseAPIKey(ctx context.Context, keyHash string, usedAt time.Time) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for id, key := range s.apiKeys {
+		if key.Hash != keyHash {
+			continue
+		}
+
+		persist := key.LastUsedAt == nil || usedAt.Sub(*key.LastUsedAt) >= apiKeyTouchInterval
+		key.LastUsedAt = &usedAt
+		s.apiKeys[id] = key
+		if persist {
+			if err := s.saveAPIKeys(); err != nil {
+				logrus.WithField("err", err).Warning("Error saving json api key usage")
+			}
+		}
+		return key.UserID, nil
+	}
+	return "", store.ErrAPIKeyNotFound
+}
+
+// CreateAccount сохраняет учетную запись, если логин свободен, и перезаписывает файл учетных записей
+func (s *JSONStore) CreateAccount(ctx context.Context, account store.Account) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.accounts[account.Login]; exists {
+		return store.ErrAccountExists
+	}
+	s.accounts[account.Login] = account
+	if err := s.saveAccounts(); err != nil {
+		delete(s.accounts, account.Login)
+		return err
+	}
+	return nil
+}
+
+// GetAccount возвращает учетную запись по логину
+func (s *JSONStore) GetAccount(ctx context.Context, login string) (store.Account, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	account, exists := s.accounts[login]
+	if !exists {
+		return store.Account{}, store.ErrAccountNotFound
+	}
+	return account, nil
+}
+
+// AddSession сохраняет сессию, удаляет истекшие и перезаписывает файл сессий
+func (s *JSONStore) AddSession(ctx context.Context, session store.Session) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for tokenHash, current := range s.sessions {
+		if !current.IsActive(session.CreatedAt) {
+			delete(s.sessions, tokenHash)
+		}
+	}
+	s.sessions[session.TokenHash] = session
+	if err := s.saveSessions(); err != nil {
+		delete(s.sessions, session.TokenHash)
+		return err
+	}
+	return nil
+}
+
+// GetSession возвращает активную сессию по хешу токена
+func (s *JSONStore) GetSession(ctx context.Context, tokenHash string, now time.Time) (store.Session, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	session, exists := s.sessions[tokenHash]
+	if !exists || !session.IsActive(now) {
+		return store.Session{}, store.ErrSessionNotFound
+	}
+	return session, nil
+}
+
+// DeleteSession удаляет сессию по хешу токена и перезаписывает файл сессий
+func (s *JSONStore) DeleteSession(ctx context.Context, tokenHash string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	session, exists := s.sessions[tokenHash]
+	if !exists {
+		return nil
+	}
+	delete(s.sessions, tokenHash)
+	if err := s.saveSessions(); err != nil {
+		s.sessions[tokenHash] = session
+		return err
+	}
+	return nil
+}
+
+// ClaimURLs передает не удаленные ссылки пользователя fromUserID пользователю toUserID
+// и дописывает изменения в журнал
+func (s *JSONStore) ClaimURLs(ctx context.Context, fromUserID string, toUserID string) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if fromUserID == toUserID {
+		return 0, nil
+	}
+
+	var entries []logEntry
+	for _, shortURL := range s.userStorage[fromUserID] {
+		record := s.storage[shortURL]
+		if record.IsDeleted {
+			continue
+		}
+		record.UserID = toUserID
+		entries = append(entries, logEntry{Op: opUpdate, JSONRecord: record})
+	}
+
+	if err := s.appendEntries(entries...); err != nil {
+		logrus.WithField("err", err).Error("Error saving json store")
+		return 0, err
+	}
+	for _, entry := range entries {
+		s.apply(entry)
+	}
+	return len(entries), nil
+}
+
+// SearchURLs отбирает ссылки всех пользователей по запросу и возвращает страницу в порядке коротких ссылок
+func (s *JSONStore) SearchURLs(ctx context.Context, search store.LinkSearch) ([]store.Link, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	needle := search.Needle()
+	var links []store.Link
+	for _, record := range s.storage {
+		link := store.Link{
+			ShortURL:    record.ShortURL,
+			OriginalURL: record.OriginalURL,
+			UserID:      record.UserID,
+			IsDeleted:   record.IsDeleted,
+		}
+		if link.Matches(needle) {
+			links = append(links, link)
+		}
+	}
+	sort.Slice(links, func(i, j int) bool {
+		return links[i].ShortURL < links[j].ShortURL
+	})
+	return search.Page(links), nil
+}
+
+// DisableURL помечает удаленной ссылку любого пользователя и дописывает изменение в журнал
+func (s *JSONStore) DisableURL(ctx context.Context, shortURL string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	record, exists := s.storage[shortURL]
+	if !exists {
+		return store.ErrLinkNotFound
+	}
+	if record.IsDeleted {
+		return nil
+	}
+
+	entry := logEntry{
+		Op:         opDelete,
+		JSONRecord: JSONRecord{ShortURL: shortURL, UserID: record.UserID},
+	}
+	if err := s.appendEntries(entry); err != nil {
+		logrus.WithField("err", err).Error("Error saving json store")
+		return err
+	}
+	s.apply(entry)
 	return nil
 }
@@ -0,0 +1,276 @@
+package json
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	models "github.com/TimBerk/go-link-shortener/internal/app/models/batch"
+	"github.com/TimBerk/go-link-shortener/internal/app/store"
+)
+
+func newTestStore(t *testing.T, filePath string, opts ...Option) *JSONStore {
+	testStore, err := NewJSONStore(filePath, store.NewIDGenerator(), opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, testStore.Close())
+	})
+	return testStore
+}
+
+func countLines(t *testing.T, filePath string) int {
+	content, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	return strings.Count(string(content), "\n")
+}
+
+func TestDeleteURL(t *testing.T) {
+	ctx := context.Background()
+	filePath := filepath.Join(t.TempDir(), "data.json")
+	testStore := newTestStore(t, filePath)
+
+	shortURL, err := testStore.AddURL(ctx, "https://example.com", "test")
+	assert.NoError(t, err)
+
+	err = testStore.DeleteURL(ctx, []store.URLPair{{ShortURL: shortURL, UserID: "other"}})
+	assert.NoError(t, err)
+	_, exists, isDeleted := testStore.GetOriginalURL(ctx, shortURL, "test")
+	assert.True(t, exists, "Ссылка должна существовать")
+	assert.False(t, isDeleted, "Ссылка другого пользователя не должна удаляться")
+
+	err = testStore.DeleteURL(ctx, []store.URLPair{{ShortURL: shortURL, UserID: "test"}})
+	assert.NoError(t, err)
+
+	reloadedStore := newTestStore(t, filePath)
+
+	originalURL, exists, isDeleted := reloadedStore.GetOriginalURL(ctx, shortURL, "test")
+	assert.Equal(t, "https://example.com", originalURL)
+	assert.True(t, exists, "Удаленная ссылка должна сохраниться в файле")
+	assert.True(t, isDeleted, "Признак удаления должен сохраниться в файле")
+
+	urls, err := reloadedStore.GetUserURLs(ctx, "test")
+	assert.NoError(t, err)
+	assert.Empty(t, urls, "Удаленные ссылки не должны попадать в список пользователя")
+}
+
+func TestReplayLog(t *testing.T) {
+	ctx := context.Background()
+	filePath := filepath.Join(t.TempDir(), "data.json")
+	testStore := newTestStore(t, filePath, WithSyncPolicy(SyncNever))
+
+	shortURL, err := testStore.AddURL(ctx, "https://example.com/1", "test")
+	require.NoError(t, err)
+	_, err = testStore.AddURLs(ctx, models.BatchRequest{
+		{CorrelationID: "1", OriginalURL: "https://example.com/2"},
+		{CorrelationID: "2", OriginalURL: "https://example.com/3"},
+	}, "test")
+	require.NoError(t, err)
+	assert.Equal(t, 3, countLines(t, filePath), "Каждая запись должна дописываться в журнал")
+
+	reloadedStore := newTestStore(t, filePath)
+
+	_, err = reloadedStore.AddURL(ctx, "https://example.com/1", "test")
+	assert.ErrorIs(t, err, store.ErrLinkExist, "Индекс оригинальных ссылок должен восстанавливаться")
+
+	urls, err := reloadedStore.GetUserURLs(ctx, "test")
+	assert.NoError(t, err)
+	assert.Len(t, urls, 3)
+	assert.Equal(t, store.UserURL{ShortURL: shortURL, OriginalURL: "https://example.com/1"}, urls[0])
+}
+
+func TestAPIKeysReload(t *testing.T) {
+	ctx := context.Background()
+	filePath := filepath.Join(t.TempDir(), "data.json")
+	testStore := newTestStore(t, filePath)
+
+	now := time.Now()
+	revoked, _, err := store.NewAPIKey("test", "revoked", now)
+	require.NoError(t, err)
+	key, value, err := store.NewAPIKey("test", "ci", now.Add(time.Second))
+	require.NoError(t, err)
+	require.NoError(t, testStore.AddAPIKey(ctx, revoked))
+	require.NoError(t, testStore.AddAPIKey(ctx, key))
+	require.NoError(t, testStore.RevokeAPIKey(ctx, "test", revoked.ID))
+	usedAt := now.UTC().Truncate(time.Microsecond)
+	_, err = testStore.UseAPIKey(ctx, store.HashAPIKey(value), usedAt)
+	require.NoError(t, err)
+
+	reloadedStore := newTestStore(t, filePath)
+
+	keys, err := reloadedStore.GetAPIKeys(ctx, "test")
+	require.NoError(t, err)
+	require.Len(t, keys, 1, "Отозванный ключ не должен восстанавливаться")
+	assert.Equal(t, key.ID, keys[0].ID)
+	require.NotNil(t, keys[0].LastUsedAt, "Первое использование ключа должно сохраняться на диск")
+	assert.True(t, usedAt.Equal(*keys[0].LastUsedAt))
+	userID, err := reloadedStore.UseAPIKey(ctx, store.HashAPIKey(value), time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, "test", userID)
+}
+
+func TestClicksReload(t *testing.T) {
+	ctx := context.Background()
+	filePath := filepath.Join(t.TempDir(), "data.json")
+	testStore := newTestStore(t, filePath)
+
+	first, err := testStore.AddURL(ctx, "https://example.com/1", "test")
+	require.NoError(t, err)
+	second, err := testStore.AddURL(ctx, "https://example.com/2", "test")
+	require.NoError(t, err)
+	now := time.Now()
+	require.NoError(t, testStore.AddClicks(ctx, []store.Click{
+		{ShortURL: first, VisitorID: "a", ClickedAt: now},
+		{ShortURL: second, VisitorID: "a", ClickedAt: now},
+		{ShortURL: first, VisitorID: "b", ClickedAt: now},
+	}))
+	require.NoError(t, testStore.Close())
+
+	// Неполная строка, оставшаяся после аварийного завершения, отбрасывается при загрузке
+	clicksFile, err := os.OpenFile(filePath+clicksSuffix, os.O_WRONLY|os.O_APPEND, 0644)
+	require.NoError(t, err)
+	_, err = clicksFile.WriteString(`{"short_url":"` + first + `","owner_id":"te`)
+	require.NoError(t, err)
+	require.NoError(t, clicksFile.Close())
+
+	reloadedStore := newTestStore(t, filePath)
+	assert.Len(t, reloadedStore.clickIndex[first], 2)
+	assert.Len(t, reloadedStore.clickIndex[second], 1)
+
+	require.NoError(t, reloadedStore.AddClicks(ctx, []store.Click{{ShortURL: first, VisitorID: "c", ClickedAt: now}}))
+	stats, err := reloadedStore.GetLinkStats(ctx, store.URLPair{ShortURL: first, UserID: "test"}, store.StatsQuery{})
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats.TotalClicks)
+	assert.Equal(t, 3, stats.UniqueVisitors)
+	stats, err = reloadedStore.GetLinkStats(ctx, store.URLPair{ShortURL: second, UserID: "test"}, store.StatsQuery{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.TotalClicks)
+}
+
+func TestAccountsReload(t *testing.T) {
+	ctx := context.Background()
+	filePath := filepath.Join(t.TempDir(), "data.json")
+	testStore := newTestStore(t, filePath)
+
+	now := time.Now()
+	account, err := store.NewAccount("alice", "password-1", now)
+	require.NoError(t, err)
+	require.NoError(t, testStore.CreateAccount(ctx, account))
+	session, token, err := store.NewSession(account.ID, now, time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, testStore.AddSession(ctx, session))
+	expired, expiredToken, err := store.NewSession(account.ID, now.Add(-2*time.Hour), time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, testStore.AddSession(ctx, expired))
+	closed, closedToken, err := store.NewSession(account.ID, now, time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, testStore.AddSession(ctx, closed))
+	require.NoError(t, testStore.DeleteSession(ctx, closed.TokenHash))
+
+	reloadedStore := newTestStore(t, filePath)
+
+	reloaded, err := reloadedStore.GetAccount(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, account, reloaded)
+	assert.ErrorIs(t, reloadedStore.CreateAccount(ctx, account), store.ErrAccountExists)
+
+	found, err := reloadedStore.GetSession(ctx, store.HashSessionToken(token), time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, account.ID, found.UserID)
+	_, err = reloadedStore.GetSession(ctx, store.HashSessionToken(expiredToken), time.Now())
+	assert.ErrorIs(t, err, store.ErrSessionNotFound, "Истекшая сессия не должна восстанавливаться")
+	_, err = reloadedStore.GetSession(ctx, store.HashSessionToken(closedToken), time.Now())
+	assert.ErrorIs(t, err, store.ErrSessionNotFound, "Завершенная сессия не должна восстанавливаться")
+}
+
+func TestCompact(t *testing.T) {
+	ctx := context.Background()
+	filePath := filepath.Join(t.TempDir(), "data.json")
+	testStore := newTestStore(t, filePath)
+
+	var pairs []store.URLPair
+	for _, originalURL := range []string{"https://example.com/1", "https://example.com/2", "https://example.com/3"} {
+		shortURL, err := testStore.AddURL(ctx, originalURL, "test")
+		require.NoError(t, err)
+		pairs = append(pairs, store.URLPair{ShortURL: shortURL, UserID: "test"})
+	}
+	require.NoError(t, testStore.DeleteURL(ctx, pairs[:2]))
+	assert.Equal(t, 5, countLines(t, filePath))
+
+	require.NoError(t, testStore.Compact())
+	assert.Equal(t, 3, countLines(t, filePath), "После сжатия в журнале должен остаться снимок записей")
+
+	_, err := testStore.AddURL(ctx, "https://example.com/4", "test")
+	require.NoError(t, err)
+	assert.Equal(t, 4, countLines(t, filePath), "После сжатия запись в журнал должна продолжаться")
+
+	reloadedStore := newTestStore(t, filePath)
+	_, exists, isDeleted := reloadedStore.GetOriginalURL(ctx, pairs[0].ShortURL, "test")
+	assert.True(t, exists)
+	assert.True(t, isDeleted, "Признак удаления должен сохраниться в снимке")
+
+	urls, err := reloadedStore.GetUserURLs(ctx, "test")
+	assert.NoError(t, err)
+	assert.Len(t, urls, 2)
+}
+
+func TestLoadStorage(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		records  int
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "Legacy snapshot",
+			content:  `{"uuid":"1","short_url":"abc123","original_url":"https://example.com","user_id":"test"}` + "\n",
+			records:  1,
+			expected: `{"uuid":"1","short_url":"abc123","original_url":"https://example.com","user_id":"test"}` + "\n",
+		},
+		{
+			name: "Incomplete last record",
+			content: `{"uuid":"1","short_url":"abc123","original_url":"https://example.com","user_id":"test"}` + "\n" +
+				`{"op":"add","uuid":"2","short_url":"def4`,
+			records:  1,
+			expected: `{"uuid":"1","short_url":"abc123","original_url":"https://example.com","user_id":"test"}` + "\n",
+		},
+		{
+			name:     "Last record without newline",
+			content:  `{"uuid":"1","short_url":"abc123","original_url":"https://example.com","user_id":"test"}`,
+			records:  1,
+			expected: `{"uuid":"1","short_url":"abc123","original_url":"https://example.com","user_id":"test"}` + "\n",
+		},
+		{
+			name: "Broken record in the middle",
+			content: `{"uuid":"1","short_url":"abc1` + "\n" +
+				`{"uuid":"2","short_url":"def456","original_url":"https://example.com","user_id":"test"}` + "\n",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "data.json")
+			require.NoError(t, os.WriteFile(filePath, []byte(test.content), 0644))
+
+			testStore, err := NewJSONStore(filePath, store.NewIDGenerator())
+			if test.wantErr {
+				assert.Error(t, err, "Ожидалась ошибка для теста: %s", test.name)
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, testStore.Close())
+
+			assert.Len(t, testStore.storage, test.records, "Неверное количество записей для теста: %s", test.name)
+			content, err := os.ReadFile(filePath)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, string(content), "Неверное содержимое файла для теста: %s", test.name)
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package local
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	base "github.com/TimBerk/go-link-shortener/internal/app/store"
+	"github.com/TimBerk/go-link-shortener/internal/app/store/storetest"
+)
+
+func TestConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) base.Store {
+		testStore, err := NewURLStore(base.NewIDGenerator())
+		require.NoError(t, err)
+		return testStore
+	})
+}
+
+func TestConformanceHashGenerator(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) base.Store {
+		testStore, err := NewURLStore(base.NewHashGenerator())
+		require.NoError(t, err)
+		return testStore
+	})
+}
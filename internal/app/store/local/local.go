@@ -3,23 +3,40 @@ package local
 
 import (
 	"context"
+	"sort"
 	"sync"
+	"time"
 
+	"github.com/TimBerk/go-link-shortener/internal/app/config"
 	models "github.com/TimBerk/go-link-shortener/internal/app/models/batch"
 	"github.com/TimBerk/go-link-shortener/internal/app/store"
 )
 
 // UserLink описывает структуру записи
 type UserLink struct {
-	UserID string
-	Link   string
+	UserID       string
+	Link         string
+	IsDeleted    bool
+	ExpiresAt    time.Time
+	ClickLimited bool
+	ClicksLeft   int
+	PasswordHash string
+}
+
+// isInactive сообщает, что ссылка удалена, истекла или исчерпала лимит переходов к моменту now
+func (l UserLink) isInactive(now time.Time) bool {
+	return l.IsDeleted || store.IsExpired(l.ExpiresAt, now) || (l.ClickLimited && l.ClicksLeft <= 0)
 }
 
 // URLStore описывает структуру локального стора
 type URLStore struct {
 	linksMap    map[string]UserLink
 	originalMap map[string]UserLink
-	userMap     map[string]string
+	userMap     map[string][]string
+	clicksMap   map[string][]store.Click
+	apiKeys     map[string]store.APIKey
+	accounts    map[string]store.Account
+	sessions    map[string]store.Session
 	gen         store.Generator
 	mutex       sync.Mutex
 }
@@ -29,11 +46,36 @@ func NewURLStore(gen store.Generator) (*URLStore, error) {
 	return &URLStore{
 		linksMap:    make(map[string]UserLink),
 		originalMap: make(map[string]UserLink),
-		userMap:     make(map[string]string),
+		userMap:     make(map[string][]string),
+		clicksMap:   make(map[string][]store.Click),
+		apiKeys:     make(map[string]store.APIKey),
+		accounts:    make(map[string]store.Account),
+		sessions:    make(map[string]store.Session),
 		gen:         gen,
 	}, nil
 }
 
+// init регистрирует стор для схемы memory://
+func init() {
+	store.Register("memory", func(ctx context.Context, storageURL string, gen store.Generator, cfg *config.Config) (store.Store, error) {
+		return NewURLStore(gen)
+	})
+}
+
+// nextShortURL генерирует для оригинальной ссылки короткую, отсутствующую в сторе и в списке зарезервированных
+func (s *URLStore) nextShortURL(originalURL string, reserved map[string]struct{}) string {
+	for attempt := 0; ; attempt++ {
+		shortURL := store.NextFor(s.gen, originalURL, attempt)
+		if _, exists := s.linksMap[shortURL]; exists {
+			continue
+		}
+		if _, exists := reserved[shortURL]; exists {
+			continue
+		}
+		return shortURL
+	}
+}
+
 // AddURL осуществляет добавление с генерацией короткой ссылки для пользователя
 func (s *URLStore) AddURL(ctx context.Context, originalURL string, userID string) (string, error) {
 	s.mutex.Lock()
@@ -43,36 +85,87 @@ func (s *URLStore) AddURL(ctx context.Context, originalURL string, userID string
 		return userLink.Link, store.ErrLinkExist
 	}
 
-	shortURL := s.gen.Next()
+	shortURL := s.nextShortURL(originalURL, nil)
+	s.linksMap[shortURL] = UserLink{UserID: userID, Link: originalURL}
+	s.originalMap[originalURL] = UserLink{UserID: userID, Link: shortURL}
+	s.userMap[userID] = append(s.userMap[userID], shortURL)
+	return shortURL, nil
+}
 
-	if _, exists := s.linksMap[shortURL]; exists {
-		return s.AddURL(ctx, originalURL, userID)
+// AddLink осуществляет добавление ссылки с параметрами для пользователя
+func (s *URLStore) AddLink(ctx context.Context, originalURL string, opts store.LinkOptions, userID string) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.linksMap[opts.Alias]; exists && opts.Alias != "" {
+		return "", store.ErrAliasTaken
 	}
 
-	s.linksMap[shortURL] = UserLink{userID, originalURL}
-	s.originalMap[originalURL] = UserLink{userID, shortURL}
-	return shortURL, nil
+	return s.putLink(originalURL, opts, userID, nil), nil
 }
 
-// AddURLs осуществляет добавление с генерацией коротких ссылок для пользователя
+// putLink сохраняет ссылку с параметрами, для ссылки без пользовательской генерирует свободную
+func (s *URLStore) putLink(originalURL string, opts store.LinkOptions, userID string, reserved map[string]struct{}) string {
+	shortURL := opts.Alias
+	if shortURL == "" {
+		shortURL = s.nextShortURL(originalURL, reserved)
+	}
+
+	s.linksMap[shortURL] = UserLink{
+		UserID:       userID,
+		Link:         originalURL,
+		ExpiresAt:    opts.ExpiresAt,
+		ClickLimited: opts.MaxClicks > 0,
+		ClicksLeft:   opts.MaxClicks,
+		PasswordHash: opts.PasswordHash,
+	}
+	s.userMap[userID] = append(s.userMap[userID], shortURL)
+	return shortURL
+}
+
+// AddURLs осуществляет добавление с генерацией коротких ссылок для пользователя.
+// Для уже сохраненных ссылок возвращает их короткие ссылки, пользовательские ссылки проверяются до добавления пачки.
 func (s *URLStore) AddURLs(ctx context.Context, urls models.BatchRequest, userID string) (models.BatchResponse, error) {
 	var responses models.BatchResponse
 
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
+	aliases := make(map[string]struct{})
+	for _, req := range urls {
+		if req.Alias == "" {
+			continue
+		}
+		if _, exists := s.linksMap[req.Alias]; exists {
+			return nil, store.ErrAliasTaken
+		}
+		if _, exists := aliases[req.Alias]; exists {
+			return nil, store.ErrAliasTaken
+		}
+		aliases[req.Alias] = struct{}{}
+	}
+
 	for _, req := range urls {
-		var shortURL string
+		if opts := store.LinkOptionsFromItem(req); !opts.IsZero() {
+			responses = append(responses, models.ItemResponse{
+				CorrelationID: req.CorrelationID,
+				ShortURL:      s.putLink(req.OriginalURL, opts, userID, aliases),
+			})
+			continue
+		}
 
-		for {
-			shortURL = s.gen.Next()
-			if _, exists := s.linksMap[shortURL]; !exists {
-				break
-			}
+		if userLink, exists := s.originalMap[req.OriginalURL]; exists {
+			responses = append(responses, models.ItemResponse{
+				CorrelationID: req.CorrelationID,
+				ShortURL:      userLink.Link,
+			})
+			continue
 		}
 
-		s.linksMap[shortURL] = UserLink{userID, req.OriginalURL}
-		s.originalMap[req.OriginalURL] = UserLink{userID, shortURL}
+		shortURL := s.nextShortURL(req.OriginalURL, aliases)
+		s.linksMap[shortURL] = UserLink{UserID: userID, Link: req.OriginalURL}
+		s.originalMap[req.OriginalURL] = UserLink{UserID: userID, Link: shortURL}
+		s.userMap[userID] = append(s.userMap[userID], shortURL)
 
 		responses = append(responses, models.ItemResponse{
 			CorrelationID: req.CorrelationID,
@@ -85,8 +178,11 @@ func (s *URLStore) AddURLs(ctx context.Context, urls models.BatchRequest, userID
 
 // GetOriginalURL осуществляет поиск оригинальной ссылки по переданной короткой
 func (s *URLStore) GetOriginalURL(ctx context.Context, shortURL string, userID string) (string, bool, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
 	userLink, exists := s.linksMap[shortURL]
-	return userLink.Link, exists, false
+	return userLink.Link, exists, userLink.isInactive(time.Now())
 }
 
 // Ping эмулирует проверку доступности стора
@@ -94,19 +190,338 @@ func (s *URLStore) Ping(ctx context.Context) error {
 	return nil
 }
 
-// DeleteURL удаляет ссылки пользователя
+// DeleteURL помечает ссылки пользователя как удаленные
 func (s *URLStore) DeleteURL(ctx context.Context, batch []store.URLPair) error {
 	if len(batch) == 0 {
 		return nil
 	}
 
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
 	for _, pair := range batch {
 		userLink, exists := s.linksMap[pair.ShortURL]
 		if exists && userLink.UserID == pair.UserID {
-			delete(s.originalMap, userLink.Link)
-			delete(s.linksMap, pair.ShortURL)
+			userLink.IsDeleted = true
+			s.linksMap[pair.ShortURL] = userLink
+		}
+	}
+
+	return nil
+}
+
+// GetUserURLs возвращает не удаленные ссылки пользователя в порядке их добавления
+func (s *URLStore) GetUserURLs(ctx context.Context, userID string) ([]store.UserURL, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	links := s.userMap[userID]
+	urls := make([]store.UserURL, 0, len(links))
+	for _, shortURL := range links {
+		userLink := s.linksMap[shortURL]
+		if userLink.isInactive(time.Now()) {
+			continue
+		}
+		urls = append(urls, store.UserURL{
+			ShortURL:    shortURL,
+			OriginalURL: userLink.Link,
+		})
+	}
+
+	return urls, nil
+}
+
+// DeleteExpiredURLs помечает удаленными истекшие ссылки в порядке истечения
+func (s *URLStore) DeleteExpiredURLs(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var expired []string
+	for shortURL, userLink := range s.linksMap {
+		if !userLink.IsDeleted && store.IsExpired(userLink.ExpiresAt, now) {
+			expired = append(expired, shortURL)
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool {
+		return s.linksMap[expired[i]].ExpiresAt.Before(s.linksMap[expired[j]].ExpiresAt)
+	})
+	if len(expired) > limit {
+		expired = expired[:limit]
+	}
+
+	for _, shortURL := range expired {
+		userLink := s.linksMap[shortURL]
+		userLink.IsDeleted = true
+		s.linksMap[shortURL] = userLink
+	}
+	return expired, nil
+}
+
+// UseClick уменьшает оставшееся количество переходов по ссылке
+func (s *URLStore) UseClick(ctx context.Context, shortURL string) (store.ClickResult, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	userLink, exists := s.linksMap[shortURL]
+	if !exists || !userLink.ClickLimited {
+		return store.ClickUnlimited, nil
+	}
+	if userLink.ClicksLeft <= 0 {
+		return store.ClickExhausted, nil
+	}
+
+	userLink.ClicksLeft--
+	s.linksMap[shortURL] = userLink
+	return store.ClickAllowed, nil
+}
+
+// GetPasswordHash возвращает хеш пароля ссылки
+func (s *URLStore) GetPasswordHash(ctx context.Context, shortURL string) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.linksMap[shortURL].PasswordHash, nil
+}
+
+// GetLinkExpiry возвращает момент истечения ссылки
+func (s *URLStore) GetLinkExpiry(ctx context.Context, shortURL string) (time.Time, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.linksMap[shortURL].ExpiresAt, nil
+}
+
+// AddClicks сохраняет переходы по ссылкам с владельцами ссылок
+func (s *URLStore) AddClicks(ctx context.Context, clicks []store.Click) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, click := range clicks {
+		click.OwnerID = s.linksMap[click.ShortURL].UserID
+		s.clicksMap[click.ShortURL] = append(s.clicksMap[click.ShortURL], click)
+	}
+	return nil
+}
+
+// GetLinkStats считает статистику переходов по ссылке пользователя
+func (s *URLStore) GetLinkStats(ctx context.Context, link store.URLPair, query store.StatsQuery) (store.LinkStats, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	userLink, exists := s.linksMap[link.ShortURL]
+	if !exists || userLink.UserID != link.UserID {
+		return store.LinkStats{}, store.ErrStatsNotFound
+	}
+
+	collector := store.NewStatsCollector(query)
+	for _, click := range s.clicksMap[link.ShortURL] {
+		collector.Add(click)
+	}
+	return collector.Stats(), nil
+}
+
+// GetServiceStats считает действующие ссылки и их владельцев
+func (s *URLStore) GetServiceStats(ctx context.Context) (store.ServiceStats, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var stats store.ServiceStats
+	users := make(map[string]struct{})
+	now := time.Now()
+	for _, userLink := range s.linksMap {
+		if userLink.isInactive(now) {
+			continue
+		}
+		stats.URLs++
+		users[userLink.UserID] = struct{}{}
+	}
+	stats.Users = len(users)
+	return stats, nil
+}
+
+// AddAPIKey сохраняет ключ API пользователя
+func (s *URLStore) AddAPIKey(ctx context.Context, key store.APIKey) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.apiKeys[key.ID] = key
+	return nil
+}
+
+// GetAPIKeys возвращает ключи API пользователя в порядке создания
+func (s *URLStore) GetAPIKeys(ctx context.Context, userID string) ([]store.APIKey, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var keys []store.APIKey
+	for _, key := range s.apiKeys {
+		if key.UserID == userID {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys, nil
+}
+
+// RevokeAPIKey удаляет ключ API пользователя
+func (s *URLStore) RevokeAPIKey(ctx context.Context, userID string, keyID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key, exists := s.apiKeys[keyID]
+	if !exists || key.UserID != userID {
+		return store.ErrAPIKeyNotFound
+	}
+	delete(s.apiKeys, keyID)
+	return nil
+}
+
+// UseAPIKey возвращает владельца ключа API и запоминает момент использования
+func (s *URLStore) UseAPIKey(ctx context.Context, keyHash string, usedAt time.Time) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for id, key := range s.apiKeys {
+		if key.Hash == keyHash {
+			key.LastUsedAt = &usedAt
+			s.apiKeys[id] = key
+			return key.UserID, nil
 		}
 	}
+	return "", store.ErrAPIKeyNotFound
+}
+
+// CreateAccount сохраняет учетную запись, если логин свободен
+func (s *URLStore) CreateAccount(ctx context.Context, account store.Account) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 
+	if _, exists := s.accounts[account.Login]; exists {
+		return store.ErrAccountExists
+	}
+	s.accounts[account.Login] = account
+	return nil
+}
+
+// GetAccount возвращает учетную запись по логину
+func (s *URLStore) GetAccount(ctx context.Context, login string) (store.Account, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	account, exists := s.accounts[login]
+	if !exists {
+		return store.Account{}, store.ErrAccountNotFound
+	}
+	return account, nil
+}
+
+// AddSession сохраняет сессию и удаляет истекшие
+func (s *URLStore) AddSession(ctx context.Context, session store.Session) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for tokenHash, current := range s.sessions {
+		if !current.IsActive(session.CreatedAt) {
+			delete(s.sessions, tokenHash)
+		}
+	}
+	s.sessions[session.TokenHash] = session
+	return nil
+}
+
+// GetSession возвращает активную сессию по хешу токена
+func (s *URLStore) GetSession(ctx context.Context, tokenHash string, now time.Time) (store.Session, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	session, exists := s.sessions[tokenHash]
+	if !exists || !session.IsActive(now) {
+		return store.Session{}, store.ErrSessionNotFound
+	}
+	return session, nil
+}
+
+// DeleteSession удаляет сессию по хешу токена
+func (s *URLStore) DeleteSession(ctx context.Context, tokenHash string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.sessions, tokenHash)
+	return nil
+}
+
+// ClaimURLs передает не удаленные ссылки пользователя fromUserID пользователю toUserID
+func (s *URLStore) ClaimURLs(ctx context.Context, fromUserID string, toUserID string) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if fromUserID == toUserID {
+		return 0, nil
+	}
+
+	var kept []string
+	claimed := 0
+	for _, shortURL := range s.userMap[fromUserID] {
+		userLink := s.linksMap[shortURL]
+		if userLink.IsDeleted {
+			kept = append(kept, shortURL)
+			continue
+		}
+
+		userLink.UserID = toUserID
+		s.linksMap[shortURL] = userLink
+		if original, exists := s.originalMap[userLink.Link]; exists && original.Link == shortURL {
+			original.UserID = toUserID
+			s.originalMap[userLink.Link] = original
+		}
+		s.userMap[toUserID] = append(s.userMap[toUserID], shortURL)
+		claimed++
+	}
+
+	if len(kept) == 0 {
+		delete(s.userMap, fromUserID)
+	} else {
+		s.userMap[fromUserID] = kept
+	}
+	return claimed, nil
+}
+
+// SearchURLs отбирает ссылки всех пользователей по запросу и возвращает страницу в порядке коротких ссылок
+func (s *URLStore) SearchURLs(ctx context.Context, search store.LinkSearch) ([]store.Link, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	needle := search.Needle()
+	var links []store.Link
+	for shortURL, userLink := range s.linksMap {
+		link := store.Link{
+			ShortURL:    shortURL,
+			OriginalURL: userLink.Link,
+			UserID:      userLink.UserID,
+			IsDeleted:   userLink.IsDeleted,
+		}
+		if link.Matches(needle) {
+			links = append(links, link)
+		}
+	}
+	sort.Slice(links, func(i, j int) bool {
+		return links[i].ShortURL < links[j].ShortURL
+	})
+	return search.Page(links), nil
+}
+
+// DisableURL помечает удаленной ссылку любого пользователя
+func (s *URLStore) DisableURL(ctx context.Context, shortURL string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	userLink, exists := s.linksMap[shortURL]
+	if !exists {
+		return store.ErrLinkNotFound
+	}
+	userLink.IsDeleted = true
+	s.linksMap[shortURL] = userLink
 	return nil
 }
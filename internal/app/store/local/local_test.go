@@ -9,6 +9,7 @@ import (
 	"bou.ke/monkey"
 	"github.com/stretchr/testify/assert"
 
+	models "github.com/TimBerk/go-link-shortener/internal/app/models/batch"
 	base "github.com/TimBerk/go-link-shortener/internal/app/store"
 )
 
@@ -32,6 +33,7 @@ func TestAddURL(t *testing.T) {
 			store: &URLStore{
 				linksMap:    map[string]UserLink{},
 				originalMap: map[string]UserLink{},
+				userMap:     map[string][]string{},
 				gen:         base.NewIDGenerator(),
 			},
 			originalURL: "localhost:8080",
@@ -40,8 +42,9 @@ func TestAddURL(t *testing.T) {
 		{
 			name: "Add new value in Store",
 			store: &URLStore{
-				linksMap:    map[string]UserLink{"short1": {"test", "localhost:9090"}},
-				originalMap: map[string]UserLink{"localhost:9090": {"test", "short1"}},
+				linksMap:    map[string]UserLink{"short1": {UserID: "test", Link: "localhost:9090"}},
+				originalMap: map[string]UserLink{"localhost:9090": {UserID: "test", Link: "short1"}},
+				userMap:     map[string][]string{},
 				gen:         base.NewIDGenerator(),
 			},
 			originalURL: "localhost:8080",
@@ -50,8 +53,9 @@ func TestAddURL(t *testing.T) {
 		{
 			name: "Add exist value in Store",
 			store: &URLStore{
-				linksMap:    map[string]UserLink{"short2": {"test", "localhost:8080"}},
-				originalMap: map[string]UserLink{"localhost:8080": {"test", "short2"}},
+				linksMap:    map[string]UserLink{"short2": {UserID: "test", Link: "localhost:8080"}},
+				originalMap: map[string]UserLink{"localhost:8080": {UserID: "test", Link: "short2"}},
+				userMap:     map[string][]string{},
 				gen:         base.NewIDGenerator(),
 			},
 			originalURL: "localhost:8080",
@@ -101,6 +105,7 @@ func TestGetOriginalURL(t *testing.T) {
 			store: &URLStore{
 				linksMap:    map[string]UserLink{},
 				originalMap: map[string]UserLink{},
+				userMap:     map[string][]string{},
 				gen:         base.NewIDGenerator(),
 			},
 			shortURL:    "short1",
@@ -110,8 +115,9 @@ func TestGetOriginalURL(t *testing.T) {
 		{
 			name: "Get exist value in Store",
 			store: &URLStore{
-				linksMap:    map[string]UserLink{"short1": {"test", "localhost:9090"}},
-				originalMap: map[string]UserLink{"localhost:9090": {"test", "short1"}},
+				linksMap:    map[string]UserLink{"short1": {UserID: "test", Link: "localhost:9090"}},
+				originalMap: map[string]UserLink{"localhost:9090": {UserID: "test", Link: "short1"}},
+				userMap:     map[string][]string{},
 				gen:         base.NewIDGenerator(),
 			},
 			shortURL:    "short1",
@@ -121,8 +127,9 @@ func TestGetOriginalURL(t *testing.T) {
 		{
 			name: "Get not exist value in Store",
 			store: &URLStore{
-				linksMap:    map[string]UserLink{"short2": {"test", "localhost:8080"}},
-				originalMap: map[string]UserLink{"localhost:8080": {"test", "short2"}},
+				linksMap:    map[string]UserLink{"short2": {UserID: "test", Link: "localhost:8080"}},
+				originalMap: map[string]UserLink{"localhost:8080": {UserID: "test", Link: "short2"}},
+				userMap:     map[string][]string{},
 				gen:         base.NewIDGenerator(),
 			},
 			shortURL:    "short1",
@@ -140,3 +147,49 @@ func TestGetOriginalURL(t *testing.T) {
 		})
 	}
 }
+
+func TestGetUserURLs(t *testing.T) {
+	ctx := context.Background()
+	testStore, _ := NewURLStore(base.NewIDGenerator())
+
+	shortURL, _ := testStore.AddURL(ctx, "https://example.com/1", "test")
+	_, _ = testStore.AddURLs(ctx, models.BatchRequest{
+		{CorrelationID: "1", OriginalURL: "https://example.com/2"},
+	}, "test")
+	_, _ = testStore.AddURL(ctx, "https://example.com/3", "other")
+
+	urls, err := testStore.GetUserURLs(ctx, "test")
+	assert.NoError(t, err)
+	assert.Len(t, urls, 2)
+	assert.Equal(t, base.UserURL{ShortURL: shortURL, OriginalURL: "https://example.com/1"}, urls[0])
+	assert.Equal(t, "https://example.com/2", urls[1].OriginalURL)
+
+	err = testStore.DeleteURL(ctx, []base.URLPair{{ShortURL: shortURL, UserID: "test"}})
+	assert.NoError(t, err)
+
+	urls, err = testStore.GetUserURLs(ctx, "test")
+	assert.NoError(t, err)
+	assert.Len(t, urls, 1)
+	assert.Equal(t, "https://example.com/2", urls[0].OriginalURL)
+}
+
+func TestDeleteURL(t *testing.T) {
+	ctx := context.Background()
+	testStore, _ := NewURLStore(base.NewIDGenerator())
+
+	shortURL, _ := testStore.AddURL(ctx, "https://example.com", "test")
+
+	err := testStore.DeleteURL(ctx, []base.URLPair{{ShortURL: shortURL, UserID: "other"}})
+	assert.NoError(t, err)
+	originalURL, exists, isDeleted := testStore.GetOriginalURL(ctx, shortURL, "test")
+	assert.Equal(t, "https://example.com", originalURL)
+	assert.True(t, exists, "Ссылка должна существовать")
+	assert.False(t, isDeleted, "Ссылка другого пользователя не должна удаляться")
+
+	err = testStore.DeleteURL(ctx, []base.URLPair{{ShortURL: shortURL, UserID: "test"}})
+	assert.NoError(t, err)
+	originalURL, exists, isDeleted = testStore.GetOriginalURL(ctx, shortURL, "test")
+	assert.Equal(t, "https://example.com", originalURL)
+	assert.True(t, exists, "Удаленная ссылка должна существовать")
+	assert.True(t, isDeleted, "Ссылка должна быть помечена удаленной")
+}
@@ -0,0 +1,32 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// maxPasswordLength - максимальная длина пароля в байтах, которую учитывает bcrypt
+const maxPasswordLength = 72
+
+// ErrInvalidPassword ошибка о недопустимом пароле ссылки
+var ErrInvalidPassword = errors.New("invalid password")
+
+// HashPassword проверяет пароль ссылки и возвращает его bcrypt-хеш
+func HashPassword(password string) (string, error) {
+	if len(password) > maxPasswordLength {
+		return "", fmt.Errorf("%w: password must be at most %d bytes", ErrInvalidPassword, maxPasswordLength)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword сообщает, что пароль соответствует хешу
+func CheckPassword(passwordHash string, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password)) == nil
+}
@@ -0,0 +1,38 @@
+package store_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/store"
+)
+
+func TestHashPassword(t *testing.T) {
+	hash, err := store.HashPassword("s3cret")
+	require.NoError(t, err)
+	assert.NotEqual(t, "s3cret", hash, "Пароль не должен храниться в открытом виде")
+
+	tests := []struct {
+		name     string
+		password string
+		want     bool
+	}{
+		{name: "Valid password", password: "s3cret", want: true},
+		{name: "Wrong password", password: "secret", want: false},
+		{name: "Empty password", password: "", want: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, store.CheckPassword(hash, test.password))
+		})
+	}
+}
+
+func TestHashPasswordTooLong(t *testing.T) {
+	_, err := store.HashPassword(strings.Repeat("a", 73))
+	assert.ErrorIs(t, err, store.ErrInvalidPassword)
+}
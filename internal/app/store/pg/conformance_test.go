@@ -0,0 +1,35 @@
+package pg
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/config"
+	"github.com/TimBerk/go-link-shortener/internal/app/store"
+	"github.com/TimBerk/go-link-shortener/internal/app/store/storetest"
+)
+
+// TestConformance запускается только при заданной переменной окружения TEST_DATABASE_DSN,
+// так как требует доступной БД PostgreSQL. Все таблицы стора очищаются перед каждой проверкой.
+func TestConformance(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+
+	storetest.Run(t, func(t *testing.T) store.Store {
+		cfg := config.NewConfig("localhost:8080", "http://localhost:8080", false)
+		cfg.DatabaseDSN = dsn
+
+		testStore, err := NewPgStore(store.NewIDGenerator(), cfg)
+		require.NoError(t, err)
+		t.Cleanup(testStore.Close)
+
+		_, err = testStore.db.Exec(context.Background(), `TRUNCATE short_urls, clicks, api_keys, sessions, accounts RESTART IDENTITY CASCADE`)
+		require.NoError(t, err)
+		return testStore
+	})
+}
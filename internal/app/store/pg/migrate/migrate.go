@@ -0,0 +1,318 @@
+// Package migrate предназначен для применения версионных миграций схемы PostgreSQL.
+// Миграции встраиваются в бинарный файл и применяются по порядку версий,
+// примененные версии хранятся в таблице schema_migrations.
+package migrate
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// migrationsDir - директория встроенных миграций
+	migrationsDir = "migrations"
+	// lockKey - ключ advisory-блокировки, исключающей параллельное применение миграций
+	lockKey int64 = 7245017
+)
+
+// migrationsFS - встроенные файлы миграций
+//
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// fileNamePattern - шаблон имени файла миграции: <версия>_<название>.<up|down>.sql
+var fileNamePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// ErrNoDownMigration ошибка об отсутствии шага отката для миграции
+var ErrNoDownMigration = errors.New("down migration is not defined")
+
+// ErrUnknownVersion ошибка о примененной версии, которой нет среди известных миграций
+var ErrUnknownVersion = errors.New("applied migration version is unknown")
+
+// Migration описывает шаг миграции
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status описывает состояние миграции в БД
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// querier описывает выборку, общую для пула и отдельного соединения
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// Migrator применяет и откатывает миграции
+type Migrator struct {
+	db         *pgxpool.Pool
+	migrations []Migration
+}
+
+// NewMigrator создает мигратор со встроенным набором миграций
+func NewMigrator(db *pgxpool.Pool) (*Migrator, error) {
+	migrations, err := Load(migrationsFS, migrationsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Load читает миграции из директории файловой системы и сортирует их по версии
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		matches := fileNamePattern.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", entry.Name())
+		}
+
+		version, err := strconv.ParseInt(matches[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version %s: %w", entry.Name(), err)
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		migration, exists := byVersion[version]
+		if !exists {
+			migration = &Migration{Version: version, Name: matches[2]}
+			byVersion[version] = migration
+		} else if migration.Name != matches[2] {
+			return nil, fmt.Errorf("duplicate migration version %d: %s and %s", version, migration.Name, matches[2])
+		}
+
+		if matches[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up step", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Up применяет все непримененные миграции и возвращает их список
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		versions, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, exists := versions[migration.Version]; exists {
+				continue
+			}
+
+			query := `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`
+			if err := runInTx(ctx, conn, migration.Up, query, migration.Version, migration.Name); err != nil {
+				return fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+
+			logrus.WithFields(logrus.Fields{
+				"version": migration.Version,
+				"name":    migration.Name,
+			}).Info("Migration applied")
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down откатывает заданное количество последних примененных миграций и возвращает их список
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		versions, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		known := make(map[int64]Migration, len(m.migrations))
+		for _, migration := range m.migrations {
+			known[migration.Version] = migration
+		}
+
+		ordered := make([]int64, 0, len(versions))
+		for version := range versions {
+			ordered = append(ordered, version)
+		}
+		sort.Slice(ordered, func(i, j int) bool { return ordered[i] > ordered[j] })
+
+		for i := 0; i < steps && i < len(ordered); i++ {
+			migration, exists := known[ordered[i]]
+			if !exists {
+				return fmt.Errorf("%w: %d", ErrUnknownVersion, ordered[i])
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("%w: %d_%s", ErrNoDownMigration, migration.Version, migration.Name)
+			}
+
+			query := `DELETE FROM schema_migrations WHERE version = $1`
+			if err := runInTx(ctx, conn, migration.Down, query, migration.Version); err != nil {
+				return fmt.Errorf("failed to revert migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+
+			logrus.WithFields(logrus.Fields{
+				"version": migration.Version,
+				"name":    migration.Name,
+			}).Info("Migration reverted")
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+
+	return reverted, err
+}
+
+// Status возвращает состояние всех известных миграций.
+// Состояние только читается: таблица schema_migrations не создается и блокировка не берется,
+// поэтому запрос не ждет выполняющейся миграции. Без таблицы все миграции считаются непримененными.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var exists bool
+	if err := m.db.QueryRow(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to check schema_migrations: %w", err)
+	}
+
+	versions := make(map[int64]time.Time)
+	if exists {
+		var err error
+		if versions, err = appliedVersions(ctx, m.db); err != nil {
+			return nil, err
+		}
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		appliedAt, applied := versions[migration.Version]
+		statuses = append(statuses, Status{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Applied:   applied,
+			AppliedAt: appliedAt,
+		})
+	}
+	return statuses, nil
+}
+
+// withLock выполняет функцию на отдельном соединении под advisory-блокировкой
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer func() {
+		if _, errUnlock := conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey); errUnlock != nil {
+			logrus.WithField("err", errUnlock).Error("Failed to release migration lock")
+		}
+	}()
+
+	query := `
+    CREATE TABLE IF NOT EXISTS schema_migrations (
+        version BIGINT PRIMARY KEY,
+        name TEXT NOT NULL,
+        applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+    );`
+	if _, err := conn.Exec(ctx, query); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	return fn(conn)
+}
+
+// appliedVersions возвращает примененные версии с датой применения
+func appliedVersions(ctx context.Context, conn querier) (map[int64]time.Time, error) {
+	rows, err := conn.Query(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	versions := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		versions[version] = appliedAt
+	}
+
+	return versions, rows.Err()
+}
+
+// runInTx выполняет шаг миграции и запись в schema_migrations в одной транзакции
+func runInTx(ctx context.Context, conn *pgxpool.Conn, migrationSQL string, query string, args ...any) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if errRollBack := tx.Rollback(ctx); errRollBack != nil && !errors.Is(errRollBack, pgx.ErrTxClosed) {
+			logrus.WithField("err", errRollBack).Error("Failed to rollback migration transaction")
+		}
+	}()
+
+	if _, err := tx.Exec(ctx, migrationSQL); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,81 @@
+package migrate
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    fstest.MapFS
+		versions []int64
+		wantErr  bool
+	}{
+		{
+			name: "Ordered by version",
+			files: fstest.MapFS{
+				"m/0002_second.up.sql":   {Data: []byte("SELECT 2;")},
+				"m/0001_first.up.sql":    {Data: []byte("SELECT 1;")},
+				"m/0001_first.down.sql":  {Data: []byte("SELECT -1;")},
+				"m/0010_tenth.up.sql":    {Data: []byte("SELECT 10;")},
+				"m/0010_tenth.down.sql":  {Data: []byte("SELECT -10;")},
+				"m/0002_second.down.sql": {Data: []byte("SELECT -2;")},
+			},
+			versions: []int64{1, 2, 10},
+		},
+		{
+			name: "Invalid file name",
+			files: fstest.MapFS{
+				"m/first.sql": {Data: []byte("SELECT 1;")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Missing up step",
+			files: fstest.MapFS{
+				"m/0001_first.down.sql": {Data: []byte("SELECT -1;")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Duplicate version",
+			files: fstest.MapFS{
+				"m/0001_first.up.sql":  {Data: []byte("SELECT 1;")},
+				"m/0001_second.up.sql": {Data: []byte("SELECT 2;")},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			migrations, err := Load(test.files, "m")
+			if test.wantErr {
+				assert.Error(t, err, "Ожидалась ошибка для теста: %s", test.name)
+				return
+			}
+
+			assert.NoError(t, err)
+			versions := make([]int64, 0, len(migrations))
+			for _, migration := range migrations {
+				versions = append(versions, migration.Version)
+				assert.NotEmpty(t, migration.Up)
+				assert.NotEmpty(t, migration.Down)
+			}
+			assert.Equal(t, test.versions, versions, "Неверный порядок миграций для теста: %s", test.name)
+		})
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := Load(migrationsFS, migrationsDir)
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, migrations)
+	for _, migration := range migrations {
+		assert.NotEmpty(t, migration.Down, "Миграция %d_%s должна иметь шаг отката", migration.Version, migration.Name)
+	}
+}
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS short_urls (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    original_url TEXT NOT NULL UNIQUE,
+    short_url VARCHAR(6) NOT NULL UNIQUE,
+    user_id VARCHAR(255) NULL,
+    is_deleted BOOLEAN NOT NULL DEFAULT false
+);
@@ -0,0 +1,8 @@
+-- Ссылки длиннее 6 символов не помещаются в прежний тип, поэтому при их наличии откат оставляет колонку широкой
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM short_urls WHERE length(short_url) > 6) THEN
+        ALTER TABLE short_urls ALTER COLUMN short_url TYPE VARCHAR(6);
+    END IF;
+END
+$$;
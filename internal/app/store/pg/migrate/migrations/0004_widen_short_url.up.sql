@@ -0,0 +1 @@
+ALTER TABLE short_urls ALTER COLUMN short_url TYPE VARCHAR(32);
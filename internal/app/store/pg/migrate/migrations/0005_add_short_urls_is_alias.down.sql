@@ -0,0 +1,4 @@
+DELETE FROM short_urls WHERE is_alias;
+DROP INDEX IF EXISTS short_urls_original_url_idx;
+ALTER TABLE short_urls ADD CONSTRAINT short_urls_original_url_key UNIQUE (original_url);
+ALTER TABLE short_urls DROP COLUMN IF EXISTS is_alias;
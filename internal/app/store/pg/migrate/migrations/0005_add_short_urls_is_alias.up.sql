@@ -0,0 +1,3 @@
+ALTER TABLE short_urls ADD COLUMN IF NOT EXISTS is_alias BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE short_urls DROP CONSTRAINT IF EXISTS short_urls_original_url_key;
+CREATE UNIQUE INDEX IF NOT EXISTS short_urls_original_url_idx ON short_urls (original_url) WHERE NOT is_alias;
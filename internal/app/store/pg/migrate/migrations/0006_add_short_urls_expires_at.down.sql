@@ -0,0 +1,5 @@
+DELETE FROM short_urls WHERE expires_at IS NOT NULL AND NOT is_alias;
+DROP INDEX IF EXISTS short_urls_expires_at_idx;
+DROP INDEX IF EXISTS short_urls_original_url_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS short_urls_original_url_idx ON short_urls (original_url) WHERE NOT is_alias;
+ALTER TABLE short_urls DROP COLUMN IF EXISTS expires_at;
@@ -0,0 +1,4 @@
+ALTER TABLE short_urls ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ NULL;
+DROP INDEX IF EXISTS short_urls_original_url_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS short_urls_original_url_idx ON short_urls (original_url) WHERE NOT is_alias AND expires_at IS NULL;
+CREATE INDEX IF NOT EXISTS short_urls_expires_at_idx ON short_urls (expires_at) WHERE expires_at IS NOT NULL AND NOT is_deleted;
@@ -0,0 +1,3 @@
+ALTER TABLE short_urls ADD COLUMN IF NOT EXISTS clicks_left INTEGER NULL;
+DROP INDEX IF EXISTS short_urls_original_url_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS short_urls_original_url_idx ON short_urls (original_url) WHERE NOT is_alias AND expires_at IS NULL AND clicks_left IS NULL;
@@ -0,0 +1,4 @@
+DELETE FROM short_urls WHERE password_hash IS NOT NULL AND NOT is_alias AND expires_at IS NULL AND clicks_left IS NULL;
+DROP INDEX IF EXISTS short_urls_original_url_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS short_urls_original_url_idx ON short_urls (original_url) WHERE NOT is_alias AND expires_at IS NULL AND clicks_left IS NULL;
+ALTER TABLE short_urls DROP COLUMN IF EXISTS password_hash;
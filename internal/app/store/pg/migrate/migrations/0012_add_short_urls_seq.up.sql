@@ -0,0 +1 @@
+ALTER TABLE short_urls ADD COLUMN IF NOT EXISTS seq BIGINT GENERATED ALWAYS AS IDENTITY;
@@ -3,10 +3,9 @@ package pg
 
 import (
 	"context"
-	"database/sql"
 	"errors"
-	"fmt"
 	"sync"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -15,6 +14,7 @@ import (
 	"github.com/TimBerk/go-link-shortener/internal/app/config"
 	models "github.com/TimBerk/go-link-shortener/internal/app/models/batch"
 	"github.com/TimBerk/go-link-shortener/internal/app/store"
+	"github.com/TimBerk/go-link-shortener/internal/app/store/pg/migrate"
 )
 
 // PostgresStore описывает структуру стора
@@ -59,7 +59,7 @@ func NewPgPool(ctx context.Context, connString string) (*PostgresStore, error) {
 	return pgInstance, nil
 }
 
-// NewPgStore создает новый стор
+// NewPgStore создает новый стор и применяет непримененные миграции схемы
 func NewPgStore(gen store.Generator, cfg *config.Config) (*PostgresStore, error) {
 	ctx := context.Background()
 
@@ -70,14 +70,50 @@ func NewPgStore(gen store.Generator, cfg *config.Config) (*PostgresStore, error)
 
 	pgStore.gen = gen
 	pgStore.cfg = cfg
-	errCreate := pgStore.createTable(ctx)
-	if errCreate != nil {
+
+	migrator, err := migrate.NewMigrator(pgStore.db)
+	if err != nil {
+		return pgStore, err
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		logrus.WithField("err", err).Error("Failed to apply migrations")
 		return pgStore, err
 	}
+	if cfg.Generator == store.GeneratorSequence {
+		pgStore.gen = store.NewSequenceGenerator(&sequenceCounter{db: pgStore.db}, store.FormatOptions(cfg)...)
+	}
 
 	return pgStore, nil
 }
 
+// sequenceCounter описывает счетчик на основе последовательности short_url_seq
+type sequenceCounter struct {
+	db *pgxpool.Pool
+}
+
+// Next получает следующее значение последовательности
+func (c *sequenceCounter) Next() (uint64, error) {
+	var value int64
+	err := c.db.QueryRow(context.Background(), `SELECT nextval('short_url_seq')`).Scan(&value)
+	return uint64(value), err
+}
+
+// init регистрирует стор для схем postgres:// и postgresql://
+func init() {
+	factory := func(ctx context.Context, storageURL string, gen store.Generator, cfg *config.Config) (store.Store, error) {
+		pgCfg := *cfg
+		pgCfg.DatabaseDSN = storageURL
+
+		pgStore, err := NewPgStore(gen, &pgCfg)
+		if err != nil {
+			return nil, err
+		}
+		return pgStore, nil
+	}
+	store.Register("postgres", factory)
+	store.Register("postgresql", factory)
+}
+
 // Ping проверяет доступность БД
 func (pg *PostgresStore) Ping(ctx context.Context) error {
 	connection, err := pgx.Connect(ctx, pg.cfg.DatabaseDSN)
@@ -98,58 +134,61 @@ func (pg *PostgresStore) Ping(ctx context.Context) error {
 	return err
 }
 
-// Close закрывает соединение к БД
+// Close закрывает пул соединений к БД. Пул не сообщает об ошибках закрытия, поэтому метод их не возвращает.
 func (pg *PostgresStore) Close() {
 	pg.db.Close()
 }
 
-// createTable создает таблицу ссылок, если она отсутствует в БД
-func (pg *PostgresStore) createTable(ctx context.Context) error {
-	query := `
-    CREATE TABLE IF NOT EXISTS short_urls (
-        id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-        original_url TEXT NOT NULL UNIQUE,
-        short_url VARCHAR(6) NOT NULL UNIQUE,
-        user_id VARCHAR(255) NULL,
-        is_deleted BOOLEAN NOT NULL DEFAULT false
-    );`
-	_, err := pg.db.Exec(ctx, query)
-	return err
+// querier описывает общие методы пула соединений и транзакции
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
 }
 
 // getRecordByOriginalURL получает запись из БД по оригинальной ссылке
-func (pg *PostgresStore) getRecordByOriginalURL(ctx context.Context, originalURL string, userID string) (PgRecord, error) {
+func getRecordByOriginalURL(ctx context.Context, q querier, originalURL string) (PgRecord, error) {
 	var record PgRecord
-	query := `SELECT * FROM short_urls WHERE original_url = $1`
-	err := pg.db.QueryRow(ctx, query, originalURL).Scan(&record.ID, &record.OriginalURL, &record.ShortURL, &record.UserID, &record.IsDeleted)
+	query := `SELECT id, original_url, short_url, COALESCE(user_id, ''), is_deleted FROM short_urls WHERE original_url = $1 AND NOT is_alias AND expires_at IS NULL AND clicks_left IS NULL AND password_hash IS NULL`
+	err := q.QueryRow(ctx, query, originalURL).Scan(&record.ID, &record.OriginalURL, &record.ShortURL, &record.UserID, &record.IsDeleted)
 	return record, err
 }
 
 // getRecordByShortURL получает запись из БД по короткой ссылке
 func (pg *PostgresStore) getRecordByShortURL(ctx context.Context, shortURL string, userID string) (PgRecord, error) {
 	var record PgRecord
-	query := `SELECT * FROM short_urls WHERE short_url = $1`
+	query := `
+		SELECT id, original_url, short_url, COALESCE(user_id, ''),
+			is_deleted OR COALESCE(expires_at <= now(), false) OR COALESCE(clicks_left <= 0, false)
+		FROM short_urls WHERE short_url = $1`
 	err := pg.db.QueryRow(ctx, query, shortURL).Scan(&record.ID, &record.OriginalURL, &record.ShortURL, &record.UserID, &record.IsDeleted)
 	return record, err
 }
 
-// insertRecord добавляет новую запись в БД
-func (pg *PostgresStore) insertRecord(ctx context.Context, originalURL, shortURL string, userID string) error {
-	query := `INSERT INTO short_urls (original_url, short_url, user_id) VALUES ($1, $2, $3)`
-	_, err := pg.db.Exec(ctx, query, originalURL, shortURL, userID)
-	return err
+// insertRecord добавляет запись со сгенерированной короткой ссылкой.
+// При совпадении короткой ссылки с существующей генерирует новую, а если оригинальная ссылка
+// была добавлена параллельным запросом, возвращает ее короткую ссылку с ошибкой store.ErrLinkExist.
+func (pg *PostgresStore) insertRecord(ctx context.Context, q querier, originalURL string, userID string) (string, error) {
+	query := `INSERT INTO short_urls (original_url, short_url, user_id) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING RETURNING short_url`
+	for attempt := 0; ; attempt++ {
+		var shortURL string
+		err := q.QueryRow(ctx, query, originalURL, store.NextFor(pg.gen, originalURL, attempt), userID).Scan(&shortURL)
+		if err == nil {
+			return shortURL, nil
+		} else if !errors.Is(err, pgx.ErrNoRows) {
+			return "", err
+		}
+
+		record, err := getRecordByOriginalURL(ctx, q, originalURL)
+		if err == nil {
+			return record.ShortURL, store.ErrLinkExist
+		} else if !errors.Is(err, pgx.ErrNoRows) {
+			return "", err
+		}
+	}
 }
 
 // AddURL добавляет новую ссылку в БД, если она отсутствует. Иначе возвращает существующую.
 func (pg *PostgresStore) AddURL(ctx context.Context, originalURL string, userID string) (string, error) {
-	logrus.WithField("uri", originalURL).Info("Search short URL")
-	record, err := pg.getRecordByOriginalURL(ctx, originalURL, userID)
-	logrus.WithFields(logrus.Fields{
-		"originalURL": originalURL,
-		"record":      record,
-		"err":         err,
-	}).Info("Attempt check short URL link")
-
+	record, err := getRecordByOriginalURL(ctx, pg.db, originalURL)
 	if err == nil {
 		return record.ShortURL, store.ErrLinkExist
 	} else if !errors.Is(err, pgx.ErrNoRows) {
@@ -160,39 +199,70 @@ func (pg *PostgresStore) AddURL(ctx context.Context, originalURL string, userID
 		return "", err
 	}
 
-	var shortURL string
-	for {
-		shortURL = pg.gen.Next()
-		_, err := pg.getRecordByShortURL(ctx, shortURL, userID)
+	shortURL, err := pg.insertRecord(ctx, pg.db, originalURL, userID)
+	if err != nil && !errors.Is(err, store.ErrLinkExist) {
 		logrus.WithFields(logrus.Fields{
-			"shortURL": shortURL,
-			"err":      err,
-		}).Info("Attempt generate new short URL link")
+			"err": err,
+			"uri": originalURL,
+		}).Error("Error inserting new URL")
+		return "", err
+	}
 
-		if errors.Is(err, pgx.ErrNoRows) {
-			break
-		} else if err != nil {
-			logrus.WithFields(logrus.Fields{
-				"err": err,
-				"uri": shortURL,
-			}).Error("failed to check existing short URL")
+	return shortURL, err
+}
+
+// insertLink добавляет запись ссылки с параметрами. Для ссылки без пользовательской генерирует свободную,
+// если пользовательская ссылка занята, возвращает store.ErrAliasTaken.
+func (pg *PostgresStore) insertLink(ctx context.Context, q querier, originalURL string, opts store.LinkOptions, userID string) (string, error) {
+	var expiresAt *time.Time
+	if !opts.ExpiresAt.IsZero() {
+		expiresAt = &opts.ExpiresAt
+	}
+	var clicksLeft *int
+	if opts.MaxClicks > 0 {
+		clicksLeft = &opts.MaxClicks
+	}
+	var passwordHash *string
+	if opts.PasswordHash != "" {
+		passwordHash = &opts.PasswordHash
+	}
+
+	query := `
+		INSERT INTO short_urls (original_url, short_url, user_id, is_alias, expires_at, clicks_left, password_hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT DO NOTHING RETURNING short_url`
+	for attempt := 0; ; attempt++ {
+		shortURL := opts.Alias
+		if shortURL == "" {
+			shortURL = store.NextFor(pg.gen, originalURL, attempt)
+		}
+		err := q.QueryRow(ctx, query, originalURL, shortURL, userID, opts.Alias != "", expiresAt, clicksLeft, passwordHash).Scan(&shortURL)
+		if err == nil {
+			return shortURL, nil
+		} else if !errors.Is(err, pgx.ErrNoRows) {
 			return "", err
 		}
+		if opts.Alias != "" {
+			return "", store.ErrAliasTaken
+		}
 	}
+}
 
-	if err := pg.insertRecord(ctx, originalURL, shortURL, userID); err != nil {
+// AddLink добавляет ссылку с параметрами в БД
+func (pg *PostgresStore) AddLink(ctx context.Context, originalURL string, opts store.LinkOptions, userID string) (string, error) {
+	shortURL, err := pg.insertLink(ctx, pg.db, originalURL, opts, userID)
+	if err != nil && !errors.Is(err, store.ErrAliasTaken) {
 		logrus.WithFields(logrus.Fields{
-			"err":      err,
-			"uri":      originalURL,
-			"shortUri": shortURL,
-		}).Error("Error inserting new URL")
-		return "", err
+			"err":   err,
+			"uri":   originalURL,
+			"alias": opts.Alias,
+		}).Error("Error inserting link")
 	}
-
-	return shortURL, nil
+	return shortURL, err
 }
 
-// AddURLs добавляет новые ссылки в БД, если они отсутствуют.
+// AddURLs добавляет новые ссылки в БД в одной транзакции. Для существующих ссылок возвращает их короткие ссылки.
+// Если пользовательская ссылка из пачки занята, транзакция откатывается.
 func (pg *PostgresStore) AddURLs(ctx context.Context, urls models.BatchRequest, userID string) (models.BatchResponse, error) {
 	var responses models.BatchResponse
 
@@ -202,60 +272,44 @@ func (pg *PostgresStore) AddURLs(ctx context.Context, urls models.BatchRequest,
 		return nil, err
 	}
 	defer func() {
-		if errRollBack := tx.Rollback(ctx); errRollBack != nil && !errors.Is(errRollBack, sql.ErrTxDone) {
+		if errRollBack := tx.Rollback(ctx); errRollBack != nil && !errors.Is(errRollBack, pgx.ErrTxClosed) {
 			logrus.WithField("err", errRollBack).Error("Failed to rollback transaction")
 		}
 	}()
 
-	query := `INSERT INTO short_urls (ID, original_url, short_url, user_id) VALUES ($1, $2, $3, $4) ON CONFLICT (short_url) DO NOTHING`
-	stmt, err := tx.Prepare(ctx, "insert-tx-stmt", query)
-	if err != nil {
-		return nil, fmt.Errorf("failed to prepare statement: %w", err)
-	}
-
 	for _, req := range urls {
-		record, errRecord := pg.getRecordByOriginalURL(ctx, req.OriginalURL, userID)
-		if errRecord == nil {
+		if opts := store.LinkOptionsFromItem(req); !opts.IsZero() {
+			shortURL, err := pg.insertLink(ctx, tx, req.OriginalURL, opts, userID)
+			if err != nil {
+				return nil, err
+			}
 			responses = append(responses, models.ItemResponse{
 				CorrelationID: req.CorrelationID,
-				ShortURL:      fmt.Sprintf("http://%s/%s", pg.cfg.ServerAddress, record.ShortURL),
+				ShortURL:      shortURL,
 			})
 			continue
-		} else if !errors.Is(errRecord, pgx.ErrNoRows) {
-			logrus.WithFields(logrus.Fields{
-				"err":         errRecord,
-				"originalURL": req.OriginalURL,
-			}).Error("failed to check existing original URL")
 		}
 
 		var shortURL string
-		for {
-			shortURL = pg.gen.Next()
-			_, err := pg.getRecordByShortURL(ctx, shortURL, userID)
-			if errors.Is(err, pgx.ErrNoRows) {
-				break
-			} else if err != nil {
-				logrus.WithFields(logrus.Fields{
-					"err": err,
-					"uri": shortURL,
-				}).Error("failed to check existing short URL")
-			}
+		record, err := getRecordByOriginalURL(ctx, tx, req.OriginalURL)
+		if err == nil {
+			shortURL = record.ShortURL
+		} else if errors.Is(err, pgx.ErrNoRows) {
+			shortURL, err = pg.insertRecord(ctx, tx, req.OriginalURL, userID)
 		}
-
-		_, err := tx.Exec(ctx, stmt.SQL, req.CorrelationID, req.OriginalURL, shortURL, userID)
-		if err != nil {
+		if err != nil && !errors.Is(err, store.ErrLinkExist) {
 			logrus.WithFields(logrus.Fields{
-				"err":      err,
-				"ID":       req.CorrelationID,
-				"uri":      req.OriginalURL,
-				"shortUri": shortURL,
+				"err": err,
+				"ID":  req.CorrelationID,
+				"uri": req.OriginalURL,
 			}).Error("Error inserting URL")
-		} else {
-			responses = append(responses, models.ItemResponse{
-				CorrelationID: req.CorrelationID,
-				ShortURL:      fmt.Sprintf("http://%s/%s", pg.cfg.ServerAddress, shortURL),
-			})
+			return nil, err
 		}
+
+		responses = append(responses, models.ItemResponse{
+			CorrelationID: req.CorrelationID,
+			ShortURL:      shortURL,
+		})
 	}
 
 	if err := tx.Commit(ctx); err != nil {
@@ -311,3 +365,486 @@ func (pg *PostgresStore) DeleteURL(ctx context.Context, batch []store.URLPair) e
 	_, err := pg.db.Exec(ctx, query, shortURLs, userIDs)
 	return err
 }
+
+// GetUserURLs получает не удаленные ссылки пользователя в порядке добавления.
+// Идентификатор ссылки - случайный UUID, поэтому порядок задает счетчик seq.
+func (pg *PostgresStore) GetUserURLs(ctx context.Context, userID string) ([]store.UserURL, error) {
+	query := `
+		SELECT short_url, original_url FROM short_urls
+		WHERE user_id = $1 AND is_deleted = false AND (expires_at IS NULL OR expires_at > now())
+		AND (clicks_left IS NULL OR clicks_left > 0)
+		ORDER BY seq`
+	rows, err := pg.db.Query(ctx, query, userID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"err":    err,
+			"userID": userID,
+		}).Error("Error selecting user URLs")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var urls []store.UserURL
+	for rows.Next() {
+		var url store.UserURL
+		if err := rows.Scan(&url.ShortURL, &url.OriginalURL); err != nil {
+			return nil, err
+		}
+		urls = append(urls, url)
+	}
+
+	return urls, rows.Err()
+}
+
+// DeleteExpiredURLs помечает удаленными истекшие ссылки в порядке истечения одним обновлением.
+// Ссылки отбираются по короткой ссылке, поэтому удаляются и записи без владельца.
+// Строки, заблокированные параллельной очисткой, пропускаются.
+func (pg *PostgresStore) DeleteExpiredURLs(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	query := `
+		UPDATE short_urls SET is_deleted = true
+		WHERE id IN (
+			SELECT id FROM short_urls
+			WHERE expires_at IS NOT NULL AND NOT is_deleted AND expires_at <= $1
+			ORDER BY expires_at LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING short_url`
+	rows, err := pg.db.Query(ctx, query, now, limit)
+	if err != nil {
+		logrus.WithField("err", err).Error("Error deleting expired URLs")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var shortURLs []string
+	for rows.Next() {
+		var shortURL string
+		if err := rows.Scan(&shortURL); err != nil {
+			return nil, err
+		}
+		shortURLs = append(shortURLs, shortURL)
+	}
+
+	return shortURLs, rows.Err()
+}
+
+// UseClick уменьшает оставшееся количество переходов по ссылке одним условным обновлением.
+// Запрос возвращает признак обновления и лимит записи до обновления, по которым различаются
+// учтенный переход, исчерпанный лимит и ссылка без лимита.
+func (pg *PostgresStore) UseClick(ctx context.Context, shortURL string) (store.ClickResult, error) {
+	query := `
+		WITH used AS (
+			UPDATE short_urls SET clicks_left = clicks_left - 1
+			WHERE short_url = $1 AND clicks_left > 0
+			RETURNING clicks_left
+		)
+		SELECT EXISTS (SELECT 1 FROM used), (SELECT clicks_left FROM short_urls WHERE short_url = $1)`
+	var used bool
+	var clicksLeft *int
+	if err := pg.db.QueryRow(ctx, query, shortURL).Scan(&used, &clicksLeft); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"err": err,
+			"uri": shortURL,
+		}).Error("Error using link click")
+		return store.ClickUnlimited, err
+	}
+
+	switch {
+	case used:
+		return store.ClickAllowed, nil
+	case clicksLeft != nil:
+		return store.ClickExhausted, nil
+	default:
+		return store.ClickUnlimited, nil
+	}
+}
+
+// GetPasswordHash получает хеш пароля ссылки
+func (pg *PostgresStore) GetPasswordHash(ctx context.Context, shortURL string) (string, error) {
+	var passwordHash *string
+	query := `SELECT password_hash FROM short_urls WHERE short_url = $1`
+	err := pg.db.QueryRow(ctx, query, shortURL).Scan(&passwordHash)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		logrus.WithFields(logrus.Fields{
+			"err": err,
+			"uri": shortURL,
+		}).Error("Error selecting link password")
+		return "", err
+	}
+	if passwordHash == nil {
+		return "", nil
+	}
+	return *passwordHash, nil
+}
+
+// GetLinkExpiry возвращает момент истечения ссылки
+func (pg *PostgresStore) GetLinkExpiry(ctx context.Context, shortURL string) (time.Time, error) {
+	var expiresAt *time.Time
+	query := `SELECT expires_at FROM short_urls WHERE short_url = $1`
+	err := pg.db.QueryRow(ctx, query, shortURL).Scan(&expiresAt)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		logrus.WithFields(logrus.Fields{
+			"err": err,
+			"uri": shortURL,
+		}).Error("Error selecting link expiration")
+		return time.Time{}, err
+	}
+	if expiresAt == nil {
+		return time.Time{}, nil
+	}
+	return *expiresAt, nil
+}
+
+// MaxCodeLength возвращает наибольшую длину сгенерированных ссылок без учета пользовательских
+func (pg *PostgresStore) MaxCodeLength(ctx context.Context) (int, error) {
+	var length int
+	query := `SELECT COALESCE(MAX(length(short_url)), 0) FROM short_urls WHERE NOT is_alias`
+	if err := pg.db.QueryRow(ctx, query).Scan(&length); err != nil {
+		logrus.WithField("err", err).Error("Error selecting max short URL length")
+		return 0, err
+	}
+	return length, nil
+}
+
+// AddClicks сохраняет переходы по ссылкам с владельцами ссылок одним запросом
+func (pg *PostgresStore) AddClicks(ctx context.Context, clicks []store.Click) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+
+	shortURLs := make([]string, 0, len(clicks))
+	visitorIDs := make([]string, 0, len(clicks))
+	clickedAt := make([]time.Time, 0, len(clicks))
+	referrers := make([]string, 0, len(clicks))
+	userAgents := make([]string, 0, len(clicks))
+	ips := make([]string, 0, len(clicks))
+	for _, click := range clicks {
+		shortURLs = append(shortURLs, click.ShortURL)
+		visitorIDs = append(visitorIDs, click.VisitorID)
+		clickedAt = append(clickedAt, click.ClickedAt)
+		referrers = append(referrers, click.Referrer)
+		userAgents = append(userAgents, click.UserAgent)
+		ips = append(ips, click.IP)
+	}
+
+	query := `
+		INSERT INTO clicks (short_url, owner_id, visitor_id, clicked_at, referrer, user_agent, ip)
+		SELECT c.short_url, s.user_id, c.visitor_id, c.clicked_at, c.referrer, c.user_agent, c.ip
+		FROM unnest($1::text[], $2::text[], $3::timestamptz[], $4::text[], $5::text[], $6::text[])
+			AS c (short_url, visitor_id, clicked_at, referrer, user_agent, ip)
+		LEFT JOIN short_urls s ON s.short_url = c.short_url`
+	_, err := pg.db.Exec(ctx, query, shortURLs, visitorIDs, clickedAt, referrers, userAgents, ips)
+	if err != nil {
+		logrus.WithField("err", err).Error("Error inserting clicks")
+	}
+	return err
+}
+
+// clicksRange - условие выборки переходов по ссылке за период, пустые границы не ограничивают период
+const clicksRange = `short_url = $1 AND ($2::timestamptz IS NULL OR clicked_at >= $2) AND ($3::timestamptz IS NULL OR clicked_at < $3)`
+
+// statsBounds возвращает границы периода статистики, нулевые границы передаются как NULL
+func statsBounds(query store.StatsQuery) (*time.Time, *time.Time) {
+	var from, to *time.Time
+	if !query.From.IsZero() {
+		from = &query.From
+	}
+	if !query.To.IsZero() {
+		to = &query.To
+	}
+	return from, to
+}
+
+// GetLinkStats считает статистику переходов по ссылке пользователя агрегирующими запросами
+func (pg *PostgresStore) GetLinkStats(ctx context.Context, link store.URLPair, query store.StatsQuery) (store.LinkStats, error) {
+	var ownerID string
+	err := pg.db.QueryRow(ctx, `SELECT COALESCE(user_id, '') FROM short_urls WHERE short_url = $1`, link.ShortURL).Scan(&ownerID)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && ownerID != link.UserID) {
+		return store.LinkStats{}, store.ErrStatsNotFound
+	} else if err != nil {
+		return store.LinkStats{}, err
+	}
+
+	var stats store.LinkStats
+	from, to := statsBounds(query)
+	err = pg.db.QueryRow(ctx, `SELECT COUNT(*), COUNT(DISTINCT visitor_id) FROM clicks WHERE `+clicksRange,
+		link.ShortURL, from, to).Scan(&stats.TotalClicks, &stats.UniqueVisitors)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"err": err,
+			"uri": link.ShortURL,
+		}).Error("Error selecting link stats")
+		return store.LinkStats{}, err
+	}
+
+	if stats.ByDay, err = pg.periodCounts(ctx, "day", link.ShortURL, from, to); err != nil {
+		return store.LinkStats{}, err
+	}
+	if stats.ByHour, err = pg.periodCounts(ctx, "hour", link.ShortURL, from, to); err != nil {
+		return store.LinkStats{}, err
+	}
+	if stats.TopReferrers, err = pg.topValues(ctx, "referrer", link.ShortURL, from, to, query.Limit()); err != nil {
+		return store.LinkStats{}, err
+	}
+	if stats.TopUserAgents, err = pg.topValues(ctx, "user_agent", link.ShortURL, from, to, query.Limit()); err != nil {
+		return store.LinkStats{}, err
+	}
+	return stats, nil
+}
+
+// periodCounts считает переходы по ссылке за периоды date_trunc в UTC в порядке их начала
+func (pg *PostgresStore) periodCounts(ctx context.Context, period string, shortURL string, from *time.Time, to *time.Time) ([]store.PeriodCount, error) {
+	query := `
+		SELECT date_trunc('` + period + `', clicked_at AT TIME ZONE 'UTC') AS period, COUNT(*) FROM clicks
+		WHERE ` + clicksRange + `
+		GROUP BY period ORDER BY period`
+	rows, err := pg.db.Query(ctx, query, shortURL, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []store.PeriodCount{}
+	for rows.Next() {
+		var count store.PeriodCount
+		if err := rows.Scan(&count.Period, &count.Clicks); err != nil {
+			return nil, err
+		}
+		count.Period = count.Period.UTC()
+		counts = append(counts, count)
+	}
+	return counts, rows.Err()
+}
+
+// topValues возвращает limit непустых значений колонки column с наибольшим количеством переходов
+func (pg *PostgresStore) topValues(ctx context.Context, column string, shortURL string, from *time.Time, to *time.Time, limit int) ([]store.ValueCount, error) {
+	query := `
+		SELECT ` + column + `, COUNT(*) AS clicks FROM clicks
+		WHERE ` + clicksRange + ` AND ` + column + ` <> ''
+		GROUP BY ` + column + ` ORDER BY clicks DESC, ` + column + ` LIMIT $4`
+	rows, err := pg.db.Query(ctx, query, shortURL, from, to, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := []store.ValueCount{}
+	for rows.Next() {
+		var value store.ValueCount
+		if err := rows.Scan(&value.Value, &value.Clicks); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+// GetServiceStats считает действующие ссылки и их владельцев
+func (pg *PostgresStore) GetServiceStats(ctx context.Context) (store.ServiceStats, error) {
+	var stats store.ServiceStats
+	query := `
+		SELECT COUNT(*), COUNT(DISTINCT user_id) FROM short_urls
+		WHERE NOT is_deleted AND (expires_at IS NULL OR expires_at > now())
+		AND (clicks_left IS NULL OR clicks_left > 0)`
+	if err := pg.db.QueryRow(ctx, query).Scan(&stats.URLs, &stats.Users); err != nil {
+		logrus.WithField("err", err).Error("Error selecting service stats")
+		return store.ServiceStats{}, err
+	}
+	return stats, nil
+}
+
+// AddAPIKey сохраняет ключ API пользователя
+func (pg *PostgresStore) AddAPIKey(ctx context.Context, key store.APIKey) error {
+	query := `INSERT INTO api_keys (id, user_id, name, prefix, key_hash, created_at) VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err := pg.db.Exec(ctx, query, key.ID, key.UserID, key.Name, key.Prefix, key.Hash, key.CreatedAt)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"err":    err,
+			"userID": key.UserID,
+		}).Error("Error inserting api key")
+	}
+	return err
+}
+
+// GetAPIKeys возвращает ключи API пользователя в порядке создания
+func (pg *PostgresStore) GetAPIKeys(ctx context.Context, userID string) ([]store.APIKey, error) {
+	query := `SELECT id::text, name, prefix, key_hash, created_at, last_used_at FROM api_keys WHERE user_id = $1 ORDER BY created_at, id`
+	rows, err := pg.db.Query(ctx, query, userID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"err":    err,
+			"userID": userID,
+		}).Error("Error selecting api keys")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []store.APIKey
+	for rows.Next() {
+		key := store.APIKey{UserID: userID}
+		if err := rows.Scan(&key.ID, &key.Name, &key.Prefix, &key.Hash, &key.CreatedAt, &key.LastUsedAt); err != nil {
+			return nil, err
+		}
+		key.CreatedAt = key.CreatedAt.UTC()
+		if key.LastUsedAt != nil {
+			usedAt := key.LastUsedAt.UTC()
+			key.LastUsedAt = &usedAt
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// RevokeAPIKey удаляет ключ API пользователя
+func (pg *PostgresStore) RevokeAPIKey(ctx context.Context, userID string, keyID string) error {
+	tag, err := pg.db.Exec(ctx, `DELETE FROM api_keys WHERE id::text = $1 AND user_id = $2`, keyID, userID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"err":    err,
+			"userID": userID,
+		}).Error("Error deleting api key")
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return store.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// UseAPIKey возвращает владельца ключа API и обновляет момент его использования
+func (pg *PostgresStore) UseAPIKey(ctx context.Context, keyHash string, usedAt time.Time) (string, error) {
+	var userID string
+	query := `UPDATE api_keys SET last_used_at = $1 WHERE key_hash = $2 RETURNING user_id`
+	err := pg.db.QueryRow(ctx, query, usedAt, keyHash).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", store.ErrAPIKeyNotFound
+	} else if err != nil {
+		logrus.WithField("err", err).Error("Error using api key")
+		return "", err
+	}
+	return userID, nil
+}
+
+// CreateAccount сохраняет учетную запись, если логин свободен
+func (pg *PostgresStore) CreateAccount(ctx context.Context, account store.Account) error {
+	query := `INSERT INTO accounts (id, login, password_hash, created_at) VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING`
+	tag, err := pg.db.Exec(ctx, query, account.ID, account.Login, account.PasswordHash, account.CreatedAt)
+	if err != nil {
+		logrus.WithField("err", err).Error("Error inserting account")
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return store.ErrAccountExists
+	}
+	return nil
+}
+
+// GetAccount возвращает учетную запись по логину
+func (pg *PostgresStore) GetAccount(ctx context.Context, login string) (store.Account, error) {
+	account := store.Account{Login: login}
+	query := `SELECT id, password_hash, created_at FROM accounts WHERE login = $1`
+	err := pg.db.QueryRow(ctx, query, login).Scan(&account.ID, &account.PasswordHash, &account.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return store.Account{}, store.ErrAccountNotFound
+	} else if err != nil {
+		logrus.WithField("err", err).Error("Error selecting account")
+		return store.Account{}, err
+	}
+	account.CreatedAt = account.CreatedAt.UTC()
+	return account, nil
+}
+
+// AddSession сохраняет сессию и удаляет истекшие одним запросом
+func (pg *PostgresStore) AddSession(ctx context.Context, session store.Session) error {
+	query := `
+		WITH purged AS (DELETE FROM sessions WHERE expires_at <= $3)
+		INSERT INTO sessions (token_hash, user_id, created_at, expires_at) VALUES ($1, $2, $3, $4)`
+	_, err := pg.db.Exec(ctx, query, session.TokenHash, session.UserID, session.CreatedAt, session.ExpiresAt)
+	if err != nil {
+		logrus.WithField("err", err).Error("Error inserting session")
+	}
+	return err
+}
+
+// GetSession возвращает активную сессию по хешу токена
+func (pg *PostgresStore) GetSession(ctx context.Context, tokenHash string, now time.Time) (store.Session, error) {
+	session := store.Session{TokenHash: tokenHash}
+	query := `SELECT user_id, created_at, expires_at FROM sessions WHERE token_hash = $1 AND expires_at > $2`
+	err := pg.db.QueryRow(ctx, query, tokenHash, now).Scan(&session.UserID, &session.CreatedAt, &session.ExpiresAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return store.Session{}, store.ErrSessionNotFound
+	} else if err != nil {
+		logrus.WithField("err", err).Error("Error selecting session")
+		return store.Session{}, err
+	}
+	session.CreatedAt = session.CreatedAt.UTC()
+	session.ExpiresAt = session.ExpiresAt.UTC()
+	return session, nil
+}
+
+// DeleteSession удаляет сессию по хешу токена
+func (pg *PostgresStore) DeleteSession(ctx context.Context, tokenHash string) error {
+	_, err := pg.db.Exec(ctx, `DELETE FROM sessions WHERE token_hash = $1`, tokenHash)
+	if err != nil {
+		logrus.WithField("err", err).Error("Error deleting session")
+	}
+	return err
+}
+
+// ClaimURLs передает не удаленные ссылки пользователя fromUserID пользователю toUserID одним обновлением
+func (pg *PostgresStore) ClaimURLs(ctx context.Context, fromUserID string, toUserID string) (int, error) {
+	if fromUserID == toUserID {
+		return 0, nil
+	}
+
+	tag, err := pg.db.Exec(ctx, `UPDATE short_urls SET user_id = $1 WHERE user_id = $2 AND NOT is_deleted`, toUserID, fromUserID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"err":    err,
+			"userID": fromUserID,
+		}).Error("Error claiming user URLs")
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// SearchURLs отбирает ссылки всех пользователей по подстроке и возвращает страницу в порядке коротких ссылок
+func (pg *PostgresStore) SearchURLs(ctx context.Context, search store.LinkSearch) ([]store.Link, error) {
+	query := `
+		SELECT short_url, original_url, COALESCE(user_id, ''), is_deleted FROM short_urls
+		WHERE $1 = '' OR strpos(lower(short_url), $1) > 0 OR strpos(lower(original_url), $1) > 0
+		ORDER BY short_url COLLATE "C"
+		LIMIT $2 OFFSET $3`
+	rows, err := pg.db.Query(ctx, query, search.Needle(), search.Limit, search.Offset)
+	if err != nil {
+		logrus.WithField("err", err).Error("Error searching URLs")
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := []store.Link{}
+	for rows.Next() {
+		var link store.Link
+		if err := rows.Scan(&link.ShortURL, &link.OriginalURL, &link.UserID, &link.IsDeleted); err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+// DisableURL помечает удаленной ссылку любого пользователя
+func (pg *PostgresStore) DisableURL(ctx context.Context, shortURL string) error {
+	tag, err := pg.db.Exec(ctx, `UPDATE short_urls SET is_deleted = true WHERE short_url = $1`, shortURL)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"err":      err,
+			"shortURL": shortURL,
+		}).Error("Error disabling URL")
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return store.ErrLinkNotFound
+	}
+	return nil
+}
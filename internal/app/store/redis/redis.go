@@ -0,0 +1,1196 @@
+// Package redis предназначен для организации хранения данных в key-value хранилище,
+// поддерживающем протокол Redis. Несколько экземпляров сервиса могут работать с одним хранилищем.
+//
+// Схема ключей:
+//   - link:<код> - хеш записи (original_url, user_id, uuid, is_deleted, expires_at, clicks_left, password_hash);
+//   - original:<ссылка> - код короткой ссылки для оригинальной ссылки;
+//   - user:<пользователь> - упорядоченное по времени добавления множество кодов пользователя;
+//   - expiry - упорядоченное по моменту истечения множество кодов не удаленных истекающих ссылок;
+//   - clicks:<код> - список переходов по ссылке в виде JSON-записей в порядке сохранения;
+//   - apikey:<идентификатор> - хеш ключа API (user_id, name, prefix, key_hash, created_at, last_used_at);
+//   - apikeyhash:<хеш ключа> - идентификатор ключа API по хешу значения;
+//   - apikeys:<пользователь> - упорядоченное по времени создания множество идентификаторов ключей API пользователя;
+//   - account:<логин> - хеш учетной записи (id, password_hash, created_at);
+//   - session:<хеш токена> - хеш сессии (user_id, created_at, expires_at), истекающий вместе с сессией;
+//   - counter - счетчик последовательного генератора ссылок.
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/config"
+	models "github.com/TimBerk/go-link-shortener/internal/app/models/batch"
+	"github.com/TimBerk/go-link-shortener/internal/app/store"
+)
+
+const (
+	// keyPrefix - общий префикс ключей сервиса
+	keyPrefix = "shortener:"
+	// counterKey - ключ счетчика последовательного генератора ссылок
+	counterKey = keyPrefix + "counter"
+	// expiryKey - ключ множества истекающих ссылок
+	expiryKey = keyPrefix + "expiry"
+	// orderKey - ключ общего счетчика порядка добавления ссылок. Номер из счетчика служит весом ссылки
+	// в множестве ссылок пользователя: в отличие от времени, он точно представим в float64 и не повторяется,
+	// а общий для всех пользователей счетчик сохраняет порядок и при передаче ссылок другому пользователю.
+	orderKey = keyPrefix + "order"
+	// disableAttempts - количество попыток отключить ссылку, владелец которой меняется одновременно с отключением
+	disableAttempts = 3
+
+	// статусы результата скрипта добавления ссылки
+	addStatusExist    = 0
+	addStatusAdded    = 1
+	addStatusConflict = 2
+)
+
+// addScript атомарно резервирует код и добавляет запись, если оригинальная ссылка еще не сохранена.
+// KEYS: original:<ссылка>, link:<код>, user:<пользователь>, order
+// ARGV: оригинальная ссылка, пользователь, код, uuid
+var addScript = goredis.NewScript(`
+local existing = redis.call('GET', KEYS[1])
+if existing then
+	return {0, existing}
+end
+if redis.call('EXISTS', KEYS[2]) == 1 then
+	return {2, ''}
+end
+redis.call('HSET', KEYS[2], 'original_url', ARGV[1], 'user_id', ARGV[2], 'uuid', ARGV[4], 'is_deleted', '0')
+redis.call('SET', KEYS[1], ARGV[3])
+redis.call('ZADD', KEYS[3], redis.call('INCR', KEYS[4]), ARGV[3])
+return {1, ARGV[3]}
+`)
+
+// linkScript атомарно добавляет записи ссылок с параметрами, если все их коды свободны.
+// Ссылки с параметрами не сохраняются в индексе оригинальных ссылок.
+// Возвращает 0 или номер первой записи, код которой занят.
+// KEYS: user:<пользователь>, expiry, order, link:<код>...
+// ARGV: пользователь, затем для каждой ссылки: код, оригинальная ссылка, uuid,
+// момент истечения в наносекундах и в микросекундах, лимит переходов, хеш пароля или пустые строки,
+// признак пользовательской ссылки
+var linkScript = goredis.NewScript(`
+for i = 4, #KEYS do
+	if redis.call('EXISTS', KEYS[i]) == 1 then
+		return i - 3
+	end
+end
+for i = 4, #KEYS do
+	local arg = (i - 4) * 8 + 2
+	redis.call('HSET', KEYS[i], 'original_url', ARGV[arg + 1], 'user_id', ARGV[1], 'uuid', ARGV[arg + 2], 'is_deleted', '0')
+	redis.call('ZADD', KEYS[1], redis.call('INCR', KEYS[3]), ARGV[arg])
+	if ARGV[arg + 3] ~= '' then
+		redis.call('HSET', KEYS[i], 'expires_at', ARGV[arg + 3])
+		redis.call('ZADD', KEYS[2], ARGV[arg + 4], ARGV[arg])
+	end
+	if ARGV[arg + 5] ~= '' then
+		redis.call('HSET', KEYS[i], 'clicks_left', ARGV[arg + 5])
+	end
+	if ARGV[arg + 6] ~= '' then
+		redis.call('HSET', KEYS[i], 'password_hash', ARGV[arg + 6])
+	end
+	if ARGV[arg + 7] ~= '' then
+		redis.call('HSET', KEYS[i], 'is_alias', ARGV[arg + 7])
+	end
+end
+return 0
+`)
+
+// clickScript уменьшает оставшееся количество переходов по ссылке, если оно задано и положительно.
+// Возвращает ClickUnlimited, ClickAllowed или ClickExhausted.
+// KEYS: link:<код>
+var clickScript = goredis.NewScript(`
+local left = redis.call('HGET', KEYS[1], 'clicks_left')
+if not left then
+	return 0
+end
+if tonumber(left) <= 0 then
+	return 2
+end
+redis.call('HINCRBY', KEYS[1], 'clicks_left', -1)
+return 1
+`)
+
+// expireScript помечает истекшую запись удаленной и убирает ее из множества истекающих ссылок
+// и множества ссылок владельца. Владелец читается до запуска скрипта, чтобы все ключи передавались в KEYS.
+// Возвращает 1, если запись помечена, 0, если записи нет, и 2, если владелец сменился после чтения:
+// тогда ссылка остается в множестве истекающих и удаляется при следующей очистке.
+// KEYS: link:<код>, expiry, user:<владелец>
+// ARGV: код, владелец
+var expireScript = goredis.NewScript(`
+local userID = redis.call('HGET', KEYS[1], 'user_id')
+if not userID then
+	redis.call('ZREM', KEYS[2], ARGV[1])
+	return 0
+end
+if userID ~= ARGV[2] then
+	return 2
+end
+redis.call('HSET', KEYS[1], 'is_deleted', '1')
+redis.call('ZREM', KEYS[2], ARGV[1])
+redis.call('ZREM', KEYS[3], ARGV[1])
+return 1
+`)
+
+// deleteScript помечает запись удаленной, если она принадлежит пользователю.
+// KEYS: link:<код>, user:<пользователь>, expiry
+// ARGV: пользователь, код
+var deleteScript = goredis.NewScript(`
+if redis.call('HGET', KEYS[1], 'user_id') == ARGV[1] then
+	redis.call('HSET', KEYS[1], 'is_deleted', '1')
+	redis.call('ZREM', KEYS[2], ARGV[2])
+	redis.call('ZREM', KEYS[3], ARGV[2])
+	return 1
+end
+return 0
+`)
+
+// useAPIKeyScript запоминает момент использования ключа API, если ключ не отозван.
+// Возвращает владельца ключа или nil.
+// KEYS: apikey:<идентификатор>
+// ARGV: момент использования в наносекундах
+var useAPIKeyScript = goredis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return false
+end
+redis.call('HSET', KEYS[1], 'last_used_at', ARGV[1])
+return redis.call('HGET', KEYS[1], 'user_id')
+`)
+
+// accountScript сохраняет учетную запись, если логин свободен.
+// Возвращает 1 при сохранении и 0, если логин занят.
+// KEYS: account:<логин>
+// ARGV: id, хеш пароля, момент создания в наносекундах
+var accountScript = goredis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'id', ARGV[1], 'password_hash', ARGV[2], 'created_at', ARGV[3])
+return 1
+`)
+
+// claimScript передает не удаленную ссылку другому пользователю с сохранением порядка добавления.
+// Возвращает 1, если ссылка передана.
+// KEYS: link:<код>, user:<прежний пользователь>, user:<новый пользователь>
+// ARGV: прежний пользователь, новый пользователь, код, порядок добавления
+var claimScript = goredis.NewScript(`
+if redis.call('HGET', KEYS[1], 'user_id') ~= ARGV[1] or redis.call('HGET', KEYS[1], 'is_deleted') == '1' then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'user_id', ARGV[2])
+redis.call('ZREM', KEYS[2], ARGV[3])
+redis.call('ZADD', KEYS[3], ARGV[4], ARGV[3])
+return 1
+`)
+
+// RedisStore описывает структуру стора
+type RedisStore struct {
+	client *goredis.Client
+	gen    store.Generator
+}
+
+// NewRedisStore подключается к хранилищу по переданному URL вида redis://host:port/db
+func NewRedisStore(redisURL string, gen store.Generator) (*RedisStore, error) {
+	options, err := goredis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis url: %w", err)
+	}
+
+	redisStore := &RedisStore{client: goredis.NewClient(options), gen: gen}
+	if err := redisStore.Ping(context.Background()); err != nil {
+		logrus.WithField("err", err).Error("Unable to connect to redis")
+		if errClose := redisStore.Close(); errClose != nil {
+			logrus.WithField("err", errClose).Error("Failed to close redis client")
+		}
+		return nil, err
+	}
+
+	return redisStore, nil
+}
+
+// init регистрирует стор для схем redis:// и rediss://
+func init() {
+	factory := func(ctx context.Context, storageURL string, gen store.Generator, cfg *config.Config) (store.Store, error) {
+		redisStore, err := NewRedisStore(storageURL, gen)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Generator == store.GeneratorSequence {
+			redisStore.gen = store.NewSequenceGenerator(&counter{client: redisStore.client}, store.FormatOptions(cfg)...)
+		}
+		return redisStore, nil
+	}
+	store.Register("redis", factory)
+	store.Register("rediss", factory)
+}
+
+// counter описывает счетчик, общий для всех экземпляров сервиса
+type counter struct {
+	client *goredis.Client
+}
+
+// Next получает следующее значение счетчика
+func (c *counter) Next() (uint64, error) {
+	value, err := c.client.Incr(context.Background(), counterKey).Uint64()
+	return value, err
+}
+
+// linkKey возвращает ключ записи короткой ссылки
+func linkKey(shortURL string) string {
+	return keyPrefix + "link:" + shortURL
+}
+
+// originalKey возвращает ключ индекса оригинальной ссылки
+func originalKey(originalURL string) string {
+	return keyPrefix + "original:" + originalURL
+}
+
+// clicksKey возвращает ключ списка переходов по ссылке
+func clicksKey(shortURL string) string {
+	return keyPrefix + "clicks:" + shortURL
+}
+
+// userKey возвращает ключ множества ссылок пользователя
+func userKey(userID string) string {
+	return keyPrefix + "user:" + userID
+}
+
+// apiKeyKey возвращает ключ хеша ключа API
+func apiKeyKey(keyID string) string {
+	return keyPrefix + "apikey:" + keyID
+}
+
+// apiKeyHashKey возвращает ключ идентификатора ключа API по хешу его значения
+func apiKeyHashKey(keyHash string) string {
+	return keyPrefix + "apikeyhash:" + keyHash
+}
+
+// userAPIKeysKey возвращает ключ множества ключей API пользователя
+func userAPIKeysKey(userID string) string {
+	return keyPrefix + "apikeys:" + userID
+}
+
+// accountKey возвращает ключ хеша учетной записи
+func accountKey(login string) string {
+	return keyPrefix + "account:" + login
+}
+
+// sessionKey возвращает ключ хеша сессии
+func sessionKey(tokenHash string) string {
+	return keyPrefix + "session:" + tokenHash
+}
+
+// Ping проверяет доступность хранилища
+func (s *RedisStore) Ping(ctx context.Context) error {
+	return s.client.Ping(ctx).Err()
+}
+
+// Close закрывает соединения к хранилищу
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
+
+// addScriptParams возвращает ключи и аргументы скрипта добавления ссылки с новым кодом для номера попытки
+func (s *RedisStore) addScriptParams(originalURL string, userID string, attempt int) ([]string, []any) {
+	shortURL := store.NextFor(s.gen, originalURL, attempt)
+	keys := []string{originalKey(originalURL), linkKey(shortURL), userKey(userID), orderKey}
+	args := []any{originalURL, userID, shortURL, uuid.New().String()}
+	return keys, args
+}
+
+// parseAddResult разбирает результат скрипта добавления на статус и код ссылки
+func parseAddResult(cmd *goredis.Cmd) (int64, string, error) {
+	values, err := cmd.Slice()
+	if err != nil {
+		return 0, "", err
+	}
+	if len(values) != 2 {
+		return 0, "", fmt.Errorf("unexpected add script result: %v", values)
+	}
+
+	status, ok := values[0].(int64)
+	if !ok {
+		return 0, "", fmt.Errorf("unexpected add script status: %v", values[0])
+	}
+	shortURL, _ := values[1].(string)
+	return status, shortURL, nil
+}
+
+// AddURL добавляет новую ссылку, если она отсутствует. Иначе возвращает существующую.
+func (s *RedisStore) AddURL(ctx context.Context, originalURL string, userID string) (string, error) {
+	for attempt := 0; ; attempt++ {
+		keys, args := s.addScriptParams(originalURL, userID, attempt)
+		status, shortURL, err := parseAddResult(addScript.Run(ctx, s.client, keys, args...))
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"err": err,
+				"uri": originalURL,
+			}).Error("Error adding URL")
+			return "", err
+		}
+
+		switch status {
+		case addStatusExist:
+			return shortURL, store.ErrLinkExist
+		case addStatusAdded:
+			return shortURL, nil
+		}
+	}
+}
+
+// addLinks атомарно добавляет ссылки с параметрами и возвращает их коды.
+// Если занята пользовательская ссылка, возвращает store.ErrAliasTaken, занятые сгенерированные коды генерируются заново.
+func (s *RedisStore) addLinks(ctx context.Context, urls models.BatchRequest, userID string) ([]string, error) {
+	codes := make([]string, len(urls))
+	seen := make(map[string]struct{}, len(urls))
+	for i, req := range urls {
+		if req.Alias == "" {
+			continue
+		}
+		if _, exists := seen[req.Alias]; exists {
+			return nil, store.ErrAliasTaken
+		}
+		seen[req.Alias] = struct{}{}
+		codes[i] = req.Alias
+	}
+
+	for attempt := 0; ; attempt++ {
+		keys := []string{userKey(userID), expiryKey, orderKey}
+		args := []any{userID}
+		for i, req := range urls {
+			opts := store.LinkOptionsFromItem(req)
+			if req.Alias == "" {
+				codes[i] = store.NextFor(s.gen, req.OriginalURL, attempt)
+			}
+			var expiresAt, score, clicksLeft, isAlias any = "", "", "", ""
+			if !opts.ExpiresAt.IsZero() {
+				expiresAt, score = opts.ExpiresAt.UnixNano(), opts.ExpiresAt.UnixMicro()
+			}
+			if opts.MaxClicks > 0 {
+				clicksLeft = opts.MaxClicks
+			}
+			if req.Alias != "" {
+				isAlias = "1"
+			}
+			keys = append(keys, linkKey(codes[i]))
+			args = append(args, codes[i], req.OriginalURL, uuid.New().String(), expiresAt, score, clicksLeft, opts.PasswordHash, isAlias)
+		}
+
+		conflict, err := linkScript.Run(ctx, s.client, keys, args...).Int()
+		if err != nil {
+			logrus.WithField("err", err).Error("Error adding links")
+			return nil, err
+		}
+		if conflict == 0 {
+			return codes, nil
+		}
+		if urls[conflict-1].Alias != "" {
+			return nil, store.ErrAliasTaken
+		}
+	}
+}
+
+// AddLink добавляет ссылку с параметрами
+func (s *RedisStore) AddLink(ctx context.Context, originalURL string, opts store.LinkOptions, userID string) (string, error) {
+	req := models.ItemRequest{
+		OriginalURL:  originalURL,
+		Alias:        opts.Alias,
+		MaxClicks:    opts.MaxClicks,
+		PasswordHash: opts.PasswordHash,
+	}
+	if !opts.ExpiresAt.IsZero() {
+		req.ExpiresAt = &opts.ExpiresAt
+	}
+
+	codes, err := s.addLinks(ctx, models.BatchRequest{req}, userID)
+	if err != nil {
+		return "", err
+	}
+	return codes[0], nil
+}
+
+// AddURLs добавляет новые ссылки пачкой запросов. Ссылки с параметрами добавляются первыми одним скриптом,
+// ссылки, код которых оказался занят, повторяются следующей пачкой.
+func (s *RedisStore) AddURLs(ctx context.Context, urls models.BatchRequest, userID string) (models.BatchResponse, error) {
+	responses := make(models.BatchResponse, len(urls))
+	remaining := make([]int, 0, len(urls))
+	var links models.BatchRequest
+	var linkIndexes []int
+	for i, req := range urls {
+		if store.LinkOptionsFromItem(req).IsZero() {
+			remaining = append(remaining, i)
+			continue
+		}
+		links = append(links, req)
+		linkIndexes = append(linkIndexes, i)
+	}
+	if len(links) > 0 {
+		codes, err := s.addLinks(ctx, links, userID)
+		if err != nil {
+			return nil, err
+		}
+		for i, index := range linkIndexes {
+			responses[index] = models.ItemResponse{
+				CorrelationID: urls[index].CorrelationID,
+				ShortURL:      codes[i],
+			}
+		}
+	}
+
+	for attempt := 0; len(remaining) > 0; attempt++ {
+		pipe := s.client.Pipeline()
+		cmds := make([]*goredis.Cmd, len(remaining))
+		for i, index := range remaining {
+			keys, args := s.addScriptParams(urls[index].OriginalURL, userID, attempt)
+			// EVALSHA не может перейти на EVAL внутри пайплайна, поэтому скрипт передается целиком
+			cmds[i] = addScript.Eval(ctx, pipe, keys, args...)
+		}
+		if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, goredis.Nil) {
+			logrus.WithField("err", err).Error("Error executing add pipeline")
+			return nil, err
+		}
+
+		var conflicts []int
+		for i, index := range remaining {
+			status, shortURL, err := parseAddResult(cmds[i])
+			if err != nil {
+				return nil, err
+			}
+			if status == addStatusConflict {
+				conflicts = append(conflicts, index)
+				continue
+			}
+			responses[index] = models.ItemResponse{
+				CorrelationID: urls[index].CorrelationID,
+				ShortURL:      shortURL,
+			}
+		}
+		remaining = conflicts
+	}
+
+	return responses, nil
+}
+
+// GetOriginalURL получает оригинальную ссылку по короткой
+func (s *RedisStore) GetOriginalURL(ctx context.Context, shortURL string, userID string) (string, bool, bool) {
+	values, err := s.client.HMGet(ctx, linkKey(shortURL), "original_url", "is_deleted", "expires_at", "clicks_left").Result()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"uri": shortURL,
+			"err": err,
+		}).Error("Error getting short URL")
+		return "", false, false
+	}
+
+	originalURL, ok := values[0].(string)
+	if !ok {
+		return "", false, false
+	}
+	isDeleted, _ := values[1].(string)
+	expiresAt, _ := values[2].(string)
+	clicksLeft, _ := values[3].(string)
+	return originalURL, true, isDeleted == "1" || isExpired(expiresAt, time.Now()) || isExhausted(clicksLeft)
+}
+
+// isExhausted сообщает, что лимит переходов из поля clicks_left исчерпан
+func isExhausted(clicksLeft string) bool {
+	if clicksLeft == "" {
+		return false
+	}
+	left, err := strconv.Atoi(clicksLeft)
+	return err == nil && left <= 0
+}
+
+// isExpired сообщает, что ссылка с моментом истечения из поля expires_at истекла к моменту now
+func isExpired(expiresAt string, now time.Time) bool {
+	if expiresAt == "" {
+		return false
+	}
+	nanos, err := strconv.ParseInt(expiresAt, 10, 64)
+	if err != nil {
+		logrus.WithField("expires_at", expiresAt).Warning("Invalid link expiration")
+		return false
+	}
+	return store.IsExpired(time.Unix(0, nanos), now)
+}
+
+// DeleteURL помечает ссылки пользователя как удаленные пачкой запросов
+func (s *RedisStore) DeleteURL(ctx context.Context, batch []store.URLPair) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	pipe := s.client.Pipeline()
+	for _, pair := range batch {
+		keys := []string{linkKey(pair.ShortURL), userKey(pair.UserID), expiryKey}
+		deleteScript.Eval(ctx, pipe, keys, pair.UserID, pair.ShortURL)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// GetUserURLs получает не удаленные ссылки пользователя в порядке их добавления
+func (s *RedisStore) GetUserURLs(ctx context.Context, userID string) ([]store.UserURL, error) {
+	shortURLs, err := s.client.ZRange(ctx, userKey(userID), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(shortURLs) == 0 {
+		return nil, nil
+	}
+
+	pipe := s.client.Pipeline()
+	cmds := make([]*goredis.SliceCmd, len(shortURLs))
+	for i, shortURL := range shortURLs {
+		cmds[i] = pipe.HMGet(ctx, linkKey(shortURL), "original_url", "expires_at", "clicks_left")
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	urls := make([]store.UserURL, 0, len(shortURLs))
+	for i, shortURL := range shortURLs {
+		values, err := cmds[i].Result()
+		if err != nil {
+			return nil, err
+		}
+		originalURL, ok := values[0].(string)
+		if !ok {
+			continue
+		}
+		expiresAt, _ := values[1].(string)
+		clicksLeft, _ := values[2].(string)
+		if isExpired(expiresAt, now) || isExhausted(clicksLeft) {
+			continue
+		}
+		urls = append(urls, store.UserURL{ShortURL: shortURL, OriginalURL: originalURL})
+	}
+
+	return urls, nil
+}
+
+// DeleteExpiredURLs помечает удаленными истекшие ссылки в порядке истечения.
+// Ссылки без записи убираются из множества истекающих ссылок и не возвращаются.
+func (s *RedisStore) DeleteExpiredURLs(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	shortURLs, err := s.client.ZRangeByScore(ctx, expiryKey, &goredis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(now.UnixMicro(), 10),
+		Count: int64(limit),
+	}).Result()
+	if err != nil {
+		logrus.WithField("err", err).Error("Error selecting expired URLs")
+		return nil, err
+	}
+	if len(shortURLs) == 0 {
+		return nil, nil
+	}
+
+	owners := s.client.Pipeline()
+	ownerCmds := make([]*goredis.StringCmd, len(shortURLs))
+	for i, shortURL := range shortURLs {
+		ownerCmds[i] = owners.HGet(ctx, linkKey(shortURL), "user_id")
+	}
+	if _, err := owners.Exec(ctx); err != nil && !errors.Is(err, goredis.Nil) {
+		logrus.WithField("err", err).Error("Error selecting expired URLs owners")
+		return nil, err
+	}
+
+	pipe := s.client.Pipeline()
+	cmds := make([]*goredis.Cmd, len(shortURLs))
+	for i, shortURL := range shortURLs {
+		userID := ownerCmds[i].Val()
+		keys := []string{linkKey(shortURL), expiryKey, userKey(userID)}
+		cmds[i] = expireScript.Eval(ctx, pipe, keys, shortURL, userID)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		logrus.WithField("err", err).Error("Error deleting expired URLs")
+		return nil, err
+	}
+
+	deleted := make([]string, 0, len(shortURLs))
+	for i, shortURL := range shortURLs {
+		if result, _ := cmds[i].Int(); result == 1 {
+			deleted = append(deleted, shortURL)
+		}
+	}
+	return deleted, nil
+}
+
+// UseClick атомарно уменьшает оставшееся количество переходов по ссылке
+func (s *RedisStore) UseClick(ctx context.Context, shortURL string) (store.ClickResult, error) {
+	result, err := clickScript.Run(ctx, s.client, []string{linkKey(shortURL)}).Int()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"err": err,
+			"uri": shortURL,
+		}).Error("Error using link click")
+		return store.ClickUnlimited, err
+	}
+	return store.ClickResult(result), nil
+}
+
+// GetPasswordHash получает хеш пароля ссылки
+func (s *RedisStore) GetPasswordHash(ctx context.Context, shortURL string) (string, error) {
+	passwordHash, err := s.client.HGet(ctx, linkKey(shortURL), "password_hash").Result()
+	if err != nil && !errors.Is(err, goredis.Nil) {
+		logrus.WithFields(logrus.Fields{
+			"err": err,
+			"uri": shortURL,
+		}).Error("Error getting link password")
+		return "", err
+	}
+	return passwordHash, nil
+}
+
+// GetLinkExpiry возвращает момент истечения ссылки
+func (s *RedisStore) GetLinkExpiry(ctx context.Context, shortURL string) (time.Time, error) {
+	expiresAt, err := s.client.HGet(ctx, linkKey(shortURL), "expires_at").Result()
+	if errors.Is(err, goredis.Nil) || (err == nil && expiresAt == "") {
+		return time.Time{}, nil
+	} else if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"err": err,
+			"uri": shortURL,
+		}).Error("Error getting link expiration")
+		return time.Time{}, err
+	}
+	return parseUnixNano(expiresAt)
+}
+
+// clickRecord описывает JSON-запись перехода по ссылке
+type clickRecord struct {
+	OwnerID   string    `json:"owner_id"`
+	VisitorID string    `json:"visitor_id,omitempty"`
+	ClickedAt time.Time `json:"clicked_at"`
+	Referrer  string    `json:"referrer,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	IP        string    `json:"ip,omitempty"`
+}
+
+// MaxCodeLength возвращает наибольшую длину сгенерированных ссылок без учета пользовательских.
+// Записи ссылок перебираются через SCAN. Пользовательские ссылки, сохраненные до появления признака is_alias,
+// учитываются как сгенерированные, поэтому длина может оказаться больше, но не превысит максимальную длину генератора.
+func (s *RedisStore) MaxCodeLength(ctx context.Context) (int, error) {
+	prefix := linkKey("")
+	iter := s.client.Scan(ctx, 0, linkKey("*"), 1000).Iterator()
+	var shortURLs []string
+	for iter.Next(ctx) {
+		shortURLs = append(shortURLs, strings.TrimPrefix(iter.Val(), prefix))
+	}
+	if err := iter.Err(); err != nil {
+		logrus.WithField("err", err).Error("Error scanning link keys")
+		return 0, err
+	}
+	if len(shortURLs) == 0 {
+		return 0, nil
+	}
+
+	pipe := s.client.Pipeline()
+	cmds := make([]*goredis.StringCmd, len(shortURLs))
+	for i, shortURL := range shortURLs {
+		cmds[i] = pipe.HGet(ctx, linkKey(shortURL), "is_alias")
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, goredis.Nil) {
+		logrus.WithField("err", err).Error("Error selecting link aliases")
+		return 0, err
+	}
+
+	var length int
+	for i, shortURL := range shortURLs {
+		if cmds[i].Val() != "1" {
+			length = max(length, len(shortURL))
+		}
+	}
+	return length, nil
+}
+
+// AddClicks сохраняет переходы по ссылкам пачкой запросов: сначала получает владельцев ссылок,
+// затем дописывает записи в списки переходов
+func (s *RedisStore) AddClicks(ctx context.Context, clicks []store.Click) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+
+	pipe := s.client.Pipeline()
+	owners := make([]*goredis.StringCmd, len(clicks))
+	for i, click := range clicks {
+		owners[i] = pipe.HGet(ctx, linkKey(click.ShortURL), "user_id")
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, goredis.Nil) {
+		logrus.WithField("err", err).Error("Error getting click owners")
+		return err
+	}
+
+	pipe = s.client.Pipeline()
+	for i, click := range clicks {
+		ownerID, err := owners[i].Result()
+		if err != nil && !errors.Is(err, goredis.Nil) {
+			return err
+		}
+		record, err := json.Marshal(clickRecord{
+			OwnerID:   ownerID,
+			VisitorID: click.VisitorID,
+			ClickedAt: click.ClickedAt.UTC(),
+			Referrer:  click.Referrer,
+			UserAgent: click.UserAgent,
+			IP:        click.IP,
+		})
+		if err != nil {
+			return err
+		}
+		pipe.RPush(ctx, clicksKey(click.ShortURL), record)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		logrus.WithField("err", err).Error("Error saving clicks")
+		return err
+	}
+	return nil
+}
+
+// GetLinkStats считает статистику переходов по ссылке пользователя из списка переходов
+func (s *RedisStore) GetLinkStats(ctx context.Context, link store.URLPair, query store.StatsQuery) (store.LinkStats, error) {
+	ownerID, err := s.client.HGet(ctx, linkKey(link.ShortURL), "user_id").Result()
+	if errors.Is(err, goredis.Nil) || (err == nil && ownerID != link.UserID) {
+		return store.LinkStats{}, store.ErrStatsNotFound
+	} else if err != nil {
+		return store.LinkStats{}, err
+	}
+
+	records, err := s.client.LRange(ctx, clicksKey(link.ShortURL), 0, -1).Result()
+	if err != nil {
+		logrus.WithField("err", err).Error("Error getting clicks")
+		return store.LinkStats{}, err
+	}
+
+	collector := store.NewStatsCollector(query)
+	for _, value := range records {
+		var record clickRecord
+		if err := json.Unmarshal([]byte(value), &record); err != nil {
+			logrus.WithField("err", err).Warning("Skipping invalid click record")
+			continue
+		}
+		collector.Add(store.Click{
+			ShortURL:  link.ShortURL,
+			OwnerID:   record.OwnerID,
+			VisitorID: record.VisitorID,
+			ClickedAt: record.ClickedAt,
+			Referrer:  record.Referrer,
+			UserAgent: record.UserAgent,
+			IP:        record.IP,
+		})
+	}
+	return collector.Stats(), nil
+}
+
+// GetServiceStats считает действующие ссылки и их владельцев по множествам ссылок пользователей.
+// Удаленные ссылки исключаются из множеств, истекшие и исчерпавшие лимит переходов
+// отбираются по записям ссылок, как в списке ссылок пользователя.
+func (s *RedisStore) GetServiceStats(ctx context.Context) (store.ServiceStats, error) {
+	var stats store.ServiceStats
+	// SCAN может вернуть ключ несколько раз, поэтому ключи собираются без повторов
+	iter := s.client.Scan(ctx, 0, userKey("*"), 1000).Iterator()
+	var keys []string
+	seen := make(map[string]struct{})
+	for iter.Next(ctx) {
+		if _, exists := seen[iter.Val()]; exists {
+			continue
+		}
+		seen[iter.Val()] = struct{}{}
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		logrus.WithField("err", err).Error("Error scanning user keys")
+		return store.ServiceStats{}, err
+	}
+	if len(keys) == 0 {
+		return stats, nil
+	}
+
+	pipe := s.client.Pipeline()
+	members := make([]*goredis.StringSliceCmd, len(keys))
+	for i, key := range keys {
+		members[i] = pipe.ZRange(ctx, key, 0, -1)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return store.ServiceStats{}, err
+	}
+
+	pipe = s.client.Pipeline()
+	cmds := make([][]*goredis.SliceCmd, len(keys))
+	for i, member := range members {
+		for _, shortURL := range member.Val() {
+			cmds[i] = append(cmds[i], pipe.HMGet(ctx, linkKey(shortURL), "expires_at", "clicks_left"))
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return store.ServiceStats{}, err
+	}
+
+	now := time.Now()
+	for _, userCmds := range cmds {
+		count := 0
+		for _, cmd := range userCmds {
+			values := cmd.Val()
+			expiresAt, _ := values[0].(string)
+			clicksLeft, _ := values[1].(string)
+			if !isExpired(expiresAt, now) && !isExhausted(clicksLeft) {
+				count++
+			}
+		}
+		if count > 0 {
+			stats.URLs += count
+			stats.Users++
+		}
+	}
+	return stats, nil
+}
+
+// AddAPIKey сохраняет ключ API пользователя в одной транзакции
+func (s *RedisStore) AddAPIKey(ctx context.Context, key store.APIKey) error {
+	_, err := s.client.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.HSet(ctx, apiKeyKey(key.ID),
+			"user_id", key.UserID,
+			"name", key.Name,
+			"prefix", key.Prefix,
+			"key_hash", key.Hash,
+			"created_at", strconv.FormatInt(key.CreatedAt.UnixNano(), 10),
+		)
+		pipe.Set(ctx, apiKeyHashKey(key.Hash), key.ID, 0)
+		pipe.ZAdd(ctx, userAPIKeysKey(key.UserID), goredis.Z{Score: float64(key.CreatedAt.UnixMicro()), Member: key.ID})
+		return nil
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"err":  err,
+			"user": key.UserID,
+		}).Error("Error adding api key")
+	}
+	return err
+}
+
+// parseUnixNano разбирает момент времени в наносекундах
+func parseUnixNano(value string) (time.Time, error) {
+	nanos, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, nanos).UTC(), nil
+}
+
+// GetAPIKeys возвращает ключи API пользователя в порядке создания
+func (s *RedisStore) GetAPIKeys(ctx context.Context, userID string) ([]store.APIKey, error) {
+	keyIDs, err := s.client.ZRange(ctx, userAPIKeysKey(userID), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(keyIDs) == 0 {
+		return nil, nil
+	}
+
+	pipe := s.client.Pipeline()
+	cmds := make([]*goredis.MapStringStringCmd, len(keyIDs))
+	for i, keyID := range keyIDs {
+		cmds[i] = pipe.HGetAll(ctx, apiKeyKey(keyID))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	keys := make([]store.APIKey, 0, len(keyIDs))
+	for i, keyID := range keyIDs {
+		fields := cmds[i].Val()
+		if len(fields) == 0 {
+			continue
+		}
+		createdAt, err := parseUnixNano(fields["created_at"])
+		if err != nil {
+			return nil, fmt.Errorf("invalid api key %s: %w", keyID, err)
+		}
+		key := store.APIKey{
+			ID:        keyID,
+			UserID:    fields["user_id"],
+			Name:      fields["name"],
+			Prefix:    fields["prefix"],
+			Hash:      fields["key_hash"],
+			CreatedAt: createdAt,
+		}
+		if value, exists := fields["last_used_at"]; exists {
+			usedAt, err := parseUnixNano(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid api key %s: %w", keyID, err)
+			}
+			key.LastUsedAt = &usedAt
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey удаляет ключ API пользователя вместе с индексами
+func (s *RedisStore) RevokeAPIKey(ctx context.Context, userID string, keyID string) error {
+	values, err := s.client.HMGet(ctx, apiKeyKey(keyID), "user_id", "key_hash").Result()
+	if err != nil {
+		return err
+	}
+	owner, _ := values[0].(string)
+	keyHash, _ := values[1].(string)
+	if owner == "" || owner != userID {
+		return store.ErrAPIKeyNotFound
+	}
+
+	_, err = s.client.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.Del(ctx, apiKeyKey(keyID), apiKeyHashKey(keyHash))
+		pipe.ZRem(ctx, userAPIKeysKey(userID), keyID)
+		return nil
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"err":  err,
+			"user": userID,
+		}).Error("Error revoking api key")
+	}
+	return err
+}
+
+// UseAPIKey возвращает владельца ключа API и обновляет момент его использования
+func (s *RedisStore) UseAPIKey(ctx context.Context, keyHash string, usedAt time.Time) (string, error) {
+	keyID, err := s.client.Get(ctx, apiKeyHashKey(keyHash)).Result()
+	if errors.Is(err, goredis.Nil) {
+		return "", store.ErrAPIKeyNotFound
+	} else if err != nil {
+		logrus.WithField("err", err).Error("Error selecting api key")
+		return "", err
+	}
+
+	userID, err := useAPIKeyScript.Run(ctx, s.client, []string{apiKeyKey(keyID)}, strconv.FormatInt(usedAt.UnixNano(), 10)).Text()
+	if errors.Is(err, goredis.Nil) {
+		return "", store.ErrAPIKeyNotFound
+	} else if err != nil {
+		logrus.WithField("err", err).Error("Error using api key")
+		return "", err
+	}
+	return userID, nil
+}
+
+// CreateAccount сохраняет учетную запись, если логин свободен
+func (s *RedisStore) CreateAccount(ctx context.Context, account store.Account) error {
+	created, err := accountScript.Run(ctx, s.client, []string{accountKey(account.Login)},
+		account.ID, account.PasswordHash, strconv.FormatInt(account.CreatedAt.UnixNano(), 10)).Int()
+	if err != nil {
+		logrus.WithField("err", err).Error("Error adding account")
+		return err
+	}
+	if created == 0 {
+		return store.ErrAccountExists
+	}
+	return nil
+}
+
+// GetAccount возвращает учетную запись по логину
+func (s *RedisStore) GetAccount(ctx context.Context, login string) (store.Account, error) {
+	fields, err := s.client.HGetAll(ctx, accountKey(login)).Result()
+	if err != nil {
+		logrus.WithField("err", err).Error("Error selecting account")
+		return store.Account{}, err
+	}
+	if len(fields) == 0 {
+		return store.Account{}, store.ErrAccountNotFound
+	}
+	createdAt, err := parseUnixNano(fields["created_at"])
+	if err != nil {
+		return store.Account{}, fmt.Errorf("invalid account %s: %w", login, err)
+	}
+	return store.Account{
+		ID:           fields["id"],
+		Login:        login,
+		PasswordHash: fields["password_hash"],
+		CreatedAt:    createdAt,
+	}, nil
+}
+
+// AddSession сохраняет сессию, которая удаляется хранилищем в момент истечения
+func (s *RedisStore) AddSession(ctx context.Context, session store.Session) error {
+	key := sessionKey(session.TokenHash)
+	_, err := s.client.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.HSet(ctx, key,
+			"user_id", session.UserID,
+			"created_at", strconv.FormatInt(session.CreatedAt.UnixNano(), 10),
+			"expires_at", strconv.FormatInt(session.ExpiresAt.UnixNano(), 10),
+		)
+		pipe.ExpireAt(ctx, key, session.ExpiresAt)
+		return nil
+	})
+	if err != nil {
+		logrus.WithField("err", err).Error("Error adding session")
+	}
+	return err
+}
+
+// GetSession возвращает активную сессию по хешу токена
+func (s *RedisStore) GetSession(ctx context.Context, tokenHash string, now time.Time) (store.Session, error) {
+	fields, err := s.client.HGetAll(ctx, sessionKey(tokenHash)).Result()
+	if err != nil {
+		logrus.WithField("err", err).Error("Error selecting session")
+		return store.Session{}, err
+	}
+	if len(fields) == 0 {
+		return store.Session{}, store.ErrSessionNotFound
+	}
+
+	createdAt, err := parseUnixNano(fields["created_at"])
+	if err != nil {
+		return store.Session{}, fmt.Errorf("invalid session: %w", err)
+	}
+	expiresAt, err := parseUnixNano(fields["expires_at"])
+	if err != nil {
+		return store.Session{}, fmt.Errorf("invalid session: %w", err)
+	}
+	session := store.Session{
+		TokenHash: tokenHash,
+		UserID:    fields["user_id"],
+		CreatedAt: createdAt,
+		ExpiresAt: expiresAt,
+	}
+	if !session.IsActive(now) {
+		return store.Session{}, store.ErrSessionNotFound
+	}
+	return session, nil
+}
+
+// DeleteSession удаляет сессию по хешу токена
+func (s *RedisStore) DeleteSession(ctx context.Context, tokenHash string) error {
+	err := s.client.Del(ctx, sessionKey(tokenHash)).Err()
+	if err != nil {
+		logrus.WithField("err", err).Error("Error deleting session")
+	}
+	return err
+}
+
+// ClaimURLs передает не удаленные ссылки пользователя fromUserID пользователю toUserID.
+// Каждая ссылка передается атомарно, порядок добавления ссылок сохраняется.
+func (s *RedisStore) ClaimURLs(ctx context.Context, fromUserID string, toUserID string) (int, error) {
+	if fromUserID == toUserID {
+		return 0, nil
+	}
+
+	links, err := s.client.ZRangeWithScores(ctx, userKey(fromUserID), 0, -1).Result()
+	if err != nil {
+		return 0, err
+	}
+	if len(links) == 0 {
+		return 0, nil
+	}
+
+	pipe := s.client.Pipeline()
+	cmds := make([]*goredis.Cmd, len(links))
+	for i, link := range links {
+		shortURL, _ := link.Member.(string)
+		keys := []string{linkKey(shortURL), userKey(fromUserID), userKey(toUserID)}
+		cmds[i] = claimScript.Eval(ctx, pipe, keys, fromUserID, toUserID, shortURL, link.Score)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"err":  err,
+			"user": fromUserID,
+		}).Error("Error claiming user URLs")
+		return 0, err
+	}
+
+	claimed := 0
+	for _, cmd := range cmds {
+		if result, _ := cmd.Int(); result == 1 {
+			claimed++
+		}
+	}
+	return claimed, nil
+}
+
+// SearchURLs отбирает ссылки всех пользователей по запросу и возвращает страницу в порядке коротких ссылок.
+// Записи ссылок перебираются через SCAN, поэтому поиск проходит по всему хранилищу.
+func (s *RedisStore) SearchURLs(ctx context.Context, search store.LinkSearch) ([]store.Link, error) {
+	// SCAN может вернуть ключ несколько раз, поэтому коды собираются без повторов
+	prefix := linkKey("")
+	iter := s.client.Scan(ctx, 0, linkKey("*"), 1000).Iterator()
+	var shortURLs []string
+	seen := make(map[string]struct{})
+	for iter.Next(ctx) {
+		shortURL := strings.TrimPrefix(iter.Val(), prefix)
+		if _, exists := seen[shortURL]; exists {
+			continue
+		}
+		seen[shortURL] = struct{}{}
+		shortURLs = append(shortURLs, shortURL)
+	}
+	if err := iter.Err(); err != nil {
+		logrus.WithField("err", err).Error("Error scanning link keys")
+		return nil, err
+	}
+	if len(shortURLs) == 0 {
+		return []store.Link{}, nil
+	}
+
+	pipe := s.client.Pipeline()
+	cmds := make([]*goredis.SliceCmd, len(shortURLs))
+	for i, shortURL := range shortURLs {
+		cmds[i] = pipe.HMGet(ctx, linkKey(shortURL), "original_url", "user_id", "is_deleted")
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	needle := search.Needle()
+	links := make([]store.Link, 0, len(shortURLs))
+	for i, shortURL := range shortURLs {
+		values, err := cmds[i].Result()
+		if err != nil {
+			return nil, err
+		}
+		originalURL, ok := values[0].(string)
+		if !ok {
+			continue
+		}
+		userID, _ := values[1].(string)
+		isDeleted, _ := values[2].(string)
+		link := store.Link{ShortURL: shortURL, OriginalURL: originalURL, UserID: userID, IsDeleted: isDeleted == "1"}
+		if link.Matches(needle) {
+			links = append(links, link)
+		}
+	}
+	sort.Slice(links, func(i, j int) bool {
+		return links[i].ShortURL < links[j].ShortURL
+	})
+	return search.Page(links), nil
+}
+
+// DisableURL помечает удаленной ссылку любого пользователя.
+// Если владелец ссылки сменился между чтением и удалением, удаление повторяется с новым владельцем.
+func (s *RedisStore) DisableURL(ctx context.Context, shortURL string) error {
+	for attempt := 0; attempt < disableAttempts; attempt++ {
+		userID, err := s.client.HGet(ctx, linkKey(shortURL), "user_id").Result()
+		if errors.Is(err, goredis.Nil) {
+			return store.ErrLinkNotFound
+		} else if err != nil {
+			return err
+		}
+
+		keys := []string{linkKey(shortURL), userKey(userID), expiryKey}
+		deleted, err := deleteScript.Run(ctx, s.client, keys, userID, shortURL).Int()
+		if err != nil {
+			return err
+		}
+		if deleted == 1 {
+			return nil
+		}
+	}
+	return fmt.Errorf("link %s owner keeps changing", shortURL)
+}
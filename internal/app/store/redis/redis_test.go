@@ -0,0 +1,176 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/config"
+	models "github.com/TimBerk/go-link-shortener/internal/app/models/batch"
+	"github.com/TimBerk/go-link-shortener/internal/app/store"
+)
+
+type sequenceGenerator struct {
+	codes []string
+	index int
+}
+
+func (g *sequenceGenerator) Next() string {
+	code := g.codes[g.index%len(g.codes)]
+	g.index++
+	return code
+}
+
+func newTestStore(t *testing.T, gen store.Generator) *RedisStore {
+	server := miniredis.RunT(t)
+	testStore, err := NewRedisStore("redis://"+server.Addr(), gen)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, testStore.Close())
+	})
+	return testStore
+}
+
+func TestAddURL(t *testing.T) {
+	ctx := context.Background()
+	testStore := newTestStore(t, &sequenceGenerator{codes: []string{"aaaaaa", "aaaaaa", "bbbbbb"}})
+
+	shortURL, err := testStore.AddURL(ctx, "https://example.com/1", "test")
+	require.NoError(t, err)
+	assert.Equal(t, "aaaaaa", shortURL)
+
+	shortURL, err = testStore.AddURL(ctx, "https://example.com/2", "test")
+	require.NoError(t, err)
+	assert.Equal(t, "bbbbbb", shortURL, "Занятый код должен генерироваться заново")
+
+	existURL, err := testStore.AddURL(ctx, "https://example.com/1", "other")
+	assert.ErrorIs(t, err, store.ErrLinkExist)
+	assert.Equal(t, "aaaaaa", existURL)
+
+	originalURL, exists, isDeleted := testStore.GetOriginalURL(ctx, "aaaaaa", "test")
+	assert.Equal(t, "https://example.com/1", originalURL)
+	assert.True(t, exists)
+	assert.False(t, isDeleted)
+
+	_, exists, _ = testStore.GetOriginalURL(ctx, "unknown", "test")
+	assert.False(t, exists)
+}
+
+func TestAddURLs(t *testing.T) {
+	ctx := context.Background()
+	testStore := newTestStore(t, &sequenceGenerator{codes: []string{"aaaaaa", "aaaaaa", "bbbbbb", "cccccc"}})
+
+	responses, err := testStore.AddURLs(ctx, models.BatchRequest{
+		{CorrelationID: "1", OriginalURL: "https://example.com/1"},
+		{CorrelationID: "2", OriginalURL: "https://example.com/2"},
+	}, "test")
+	require.NoError(t, err)
+	assert.Equal(t, models.BatchResponse{
+		{CorrelationID: "1", ShortURL: "aaaaaa"},
+		{CorrelationID: "2", ShortURL: "bbbbbb"},
+	}, responses, "Конфликт кода внутри пачки должен разрешаться повтором")
+
+	urls, err := testStore.GetUserURLs(ctx, "test")
+	assert.NoError(t, err)
+	assert.Equal(t, []store.UserURL{
+		{ShortURL: "aaaaaa", OriginalURL: "https://example.com/1"},
+		{ShortURL: "bbbbbb", OriginalURL: "https://example.com/2"},
+	}, urls)
+}
+
+func TestUserURLsOrder(t *testing.T) {
+	ctx := context.Background()
+	testStore := newTestStore(t, &sequenceGenerator{codes: []string{"cccccc", "bbbbbb", "aaaaaa", "dddddd"}})
+
+	_, err := testStore.AddURL(ctx, "https://example.com/1", "test")
+	require.NoError(t, err)
+	expiresAt := time.Now().Add(time.Hour)
+	_, err = testStore.AddURLs(ctx, models.BatchRequest{
+		{CorrelationID: "2", OriginalURL: "https://example.com/2", ExpiresAt: &expiresAt},
+		{CorrelationID: "3", OriginalURL: "https://example.com/3", MaxClicks: 5},
+	}, "test")
+	require.NoError(t, err)
+	_, err = testStore.AddURL(ctx, "https://example.com/4", "test")
+	require.NoError(t, err)
+
+	links, err := testStore.client.ZRangeWithScores(ctx, userKey("test"), 0, -1).Result()
+	require.NoError(t, err)
+	require.Len(t, links, 4)
+	for i, expected := range []string{"cccccc", "bbbbbb", "aaaaaa", "dddddd"} {
+		assert.Equal(t, expected, links[i].Member, "Ссылки должны идти в порядке добавления, а не в порядке кодов")
+		if i > 0 {
+			assert.Greater(t, links[i].Score, links[i-1].Score, "Порядок добавления не должен повторяться внутри пачки")
+		}
+	}
+}
+
+func TestDeleteExpiredURLsOwnerChanged(t *testing.T) {
+	ctx := context.Background()
+	testStore := newTestStore(t, store.NewIDGenerator())
+
+	shortURL, err := testStore.AddLink(ctx, "https://example.com", store.LinkOptions{ExpiresAt: time.Now().Add(time.Minute)}, "test")
+	require.NoError(t, err)
+
+	// Владелец, прочитанный до смены, не совпадает с текущим: ссылка остается до следующей очистки
+	keys := []string{linkKey(shortURL), expiryKey, userKey("previous")}
+	result, err := expireScript.Run(ctx, testStore.client, keys, shortURL, "previous").Int()
+	require.NoError(t, err)
+	assert.Equal(t, 2, result)
+	_, _, isDeleted := testStore.GetOriginalURL(ctx, shortURL, "test")
+	assert.False(t, isDeleted)
+
+	deleted, err := testStore.DeleteExpiredURLs(ctx, time.Now().Add(time.Hour), 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{shortURL}, deleted)
+	_, _, isDeleted = testStore.GetOriginalURL(ctx, shortURL, "test")
+	assert.True(t, isDeleted)
+	count, err := testStore.client.ZCard(ctx, userKey("test")).Result()
+	require.NoError(t, err)
+	assert.Zero(t, count, "Истекшая ссылка должна убираться из множества ссылок владельца")
+}
+
+func TestDeleteURL(t *testing.T) {
+	ctx := context.Background()
+	testStore := newTestStore(t, store.NewIDGenerator())
+
+	shortURL, err := testStore.AddURL(ctx, "https://example.com", "test")
+	require.NoError(t, err)
+
+	require.NoError(t, testStore.DeleteURL(ctx, []store.URLPair{{ShortURL: shortURL, UserID: "other"}}))
+	_, _, isDeleted := testStore.GetOriginalURL(ctx, shortURL, "test")
+	assert.False(t, isDeleted, "Ссылка другого пользователя не должна удаляться")
+
+	require.NoError(t, testStore.DeleteURL(ctx, []store.URLPair{{ShortURL: shortURL, UserID: "test"}}))
+	originalURL, exists, isDeleted := testStore.GetOriginalURL(ctx, shortURL, "test")
+	assert.Equal(t, "https://example.com", originalURL)
+	assert.True(t, exists)
+	assert.True(t, isDeleted)
+
+	urls, err := testStore.GetUserURLs(ctx, "test")
+	assert.NoError(t, err)
+	assert.Empty(t, urls)
+}
+
+func TestSequenceGenerator(t *testing.T) {
+	server := miniredis.RunT(t)
+	cfg := config.NewConfig("localhost:8080", "http://localhost:8080", false)
+	cfg.Generator = store.GeneratorSequence
+
+	testStore, err := store.Open(context.Background(), "redis://"+server.Addr(), store.NewIDGenerator(), cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, testStore.(*RedisStore).Close())
+	})
+
+	first, err := testStore.AddURL(context.Background(), "https://example.com/1", "user-1")
+	require.NoError(t, err)
+	second, err := testStore.AddURL(context.Background(), "https://example.com/2", "user-1")
+	require.NoError(t, err)
+
+	assert.Equal(t, "AAAAAB", first, "Ссылка должна дополняться до заданной длины")
+	assert.Equal(t, "AAAAAC", second)
+}
@@ -0,0 +1,91 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/config"
+)
+
+// ErrUnknownBackend ошибка об отсутствии стора для схемы URL хранилища
+var ErrUnknownBackend = errors.New("unknown storage backend")
+
+// Factory создает стор по URL хранилища
+type Factory func(ctx context.Context, storageURL string, gen Generator, cfg *config.Config) (Store, error)
+
+var (
+	// registryMutex защищает реестр сторов
+	registryMutex sync.RWMutex
+	// registry - фабрики сторов по схеме URL хранилища
+	registry = make(map[string]Factory)
+)
+
+// Register регистрирует фабрику стора для схемы URL хранилища.
+// Вызывается из init пакета стора, повторная регистрация схемы приводит к панике.
+func Register(scheme string, factory Factory) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	if factory == nil {
+		panic("store: Register factory is nil")
+	}
+	if _, exists := registry[scheme]; exists {
+		panic("store: Register called twice for scheme " + scheme)
+	}
+	registry[scheme] = factory
+}
+
+// Backends возвращает отсортированный список зарегистрированных схем
+func Backends() []string {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	schemes := make([]string, 0, len(registry))
+	for scheme := range registry {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+
+	return schemes
+}
+
+// Open создает стор фабрикой, зарегистрированной для схемы переданного URL хранилища
+func Open(ctx context.Context, storageURL string, gen Generator, cfg *config.Config) (Store, error) {
+	scheme, _, found := strings.Cut(storageURL, "://")
+	if !found || scheme == "" {
+		return nil, fmt.Errorf("%w: storage url %q has no scheme, available backends: %s",
+			ErrUnknownBackend, storageURL, strings.Join(Backends(), ", "))
+	}
+
+	registryMutex.RLock()
+	factory, exists := registry[strings.ToLower(scheme)]
+	registryMutex.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("%w %q, available backends: %s",
+			ErrUnknownBackend, scheme, strings.Join(Backends(), ", "))
+	}
+
+	return factory(ctx, storageURL, gen, cfg)
+}
+
+// PathFromURL возвращает путь к файлу из URL хранилища.
+// Поддерживаются абсолютные (file:///data/links.json) и относительные (file://files/data.json) пути.
+func PathFromURL(storageURL string) (string, error) {
+	u, err := url.Parse(storageURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid storage url: %w", err)
+	}
+
+	path := u.Host + u.Path
+	if path == "" {
+		return "", fmt.Errorf("storage url %q has no path", storageURL)
+	}
+
+	return path, nil
+}
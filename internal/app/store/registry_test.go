@@ -0,0 +1,95 @@
+package store_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/config"
+	"github.com/TimBerk/go-link-shortener/internal/app/store"
+	"github.com/TimBerk/go-link-shortener/internal/app/store/local"
+)
+
+func TestOpen(t *testing.T) {
+	var openedURL string
+	store.Register("registry-test", func(ctx context.Context, storageURL string, gen store.Generator, cfg *config.Config) (store.Store, error) {
+		openedURL = storageURL
+		return local.NewURLStore(gen)
+	})
+
+	tests := []struct {
+		name       string
+		storageURL string
+		wantErr    error
+	}{
+		{
+			name:       "Registered scheme",
+			storageURL: "registry-test://localhost/db",
+		},
+		{
+			name:       "Scheme in upper case",
+			storageURL: "REGISTRY-TEST://localhost/db",
+		},
+		{
+			name:       "Unknown scheme",
+			storageURL: "unknown://localhost",
+			wantErr:    store.ErrUnknownBackend,
+		},
+		{
+			name:       "URL without scheme",
+			storageURL: "host=localhost user=shortener",
+			wantErr:    store.ErrUnknownBackend,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			openedURL = ""
+			dataStore, err := store.Open(context.Background(), test.storageURL, store.NewIDGenerator(), &config.Config{})
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+				assert.Contains(t, err.Error(), "memory", "Ошибка должна перечислять доступные сторы")
+				return
+			}
+
+			require.NoError(t, err)
+			assert.NotNil(t, dataStore)
+			assert.Equal(t, test.storageURL, openedURL, "Фабрика должна получать исходный URL хранилища")
+		})
+	}
+}
+
+func TestRegisterTwice(t *testing.T) {
+	assert.Panics(t, func() {
+		store.Register("memory", func(ctx context.Context, storageURL string, gen store.Generator, cfg *config.Config) (store.Store, error) {
+			return nil, nil
+		})
+	})
+}
+
+func TestPathFromURL(t *testing.T) {
+	tests := []struct {
+		name       string
+		storageURL string
+		want       string
+		wantErr    bool
+	}{
+		{name: "Absolute path", storageURL: "file:///data/links.json", want: "/data/links.json"},
+		{name: "Relative path", storageURL: "file://files/data.json", want: "files/data.json"},
+		{name: "Empty path", storageURL: "file://", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path, err := store.PathFromURL(test.storageURL)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, path)
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/store/pg/migrate"
+)
+
+// migrationsFS - встроенные миграции схемы SQLite, применяемые при открытии БД.
+// Формат файлов совпадает с миграциями PostgreSQL, шаги отката не используются.
+//
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// legacyColumns - колонки таблицы ссылок в порядке их появления и версии миграций, которые их добавили.
+// По ним определяется версия схемы БД, созданной до появления таблицы schema_migrations.
+var legacyColumns = []struct {
+	name    string
+	version int64
+}{
+	{name: "is_alias", version: 2},
+	{name: "expires_at", version: 3},
+	{name: "clicks_left", version: 4},
+	{name: "password_hash", version: 5},
+}
+
+// applyMigrations применяет непримененные миграции в одной транзакции.
+// Примененные версии хранятся в таблице schema_migrations. Для БД без этой таблицы,
+// но с таблицей ссылок, примененными считаются миграции, колонки которых уже есть в таблице.
+// Момент истечения и момент перехода хранятся в наносекундах Unix.
+func (s *SQLiteStore) applyMigrations(ctx context.Context) error {
+	migrations, err := migrate.Load(migrationsFS, "migrations")
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if errRollBack := tx.Rollback(); errRollBack != nil && !errors.Is(errRollBack, sql.ErrTxDone) {
+			logrus.WithField("err", errRollBack).Error("Failed to rollback transaction")
+		}
+	}()
+
+	versions, err := appliedVersions(ctx, tx)
+	if err != nil {
+		return err
+	}
+	var legacy int64
+	if len(versions) == 0 {
+		if legacy, err = legacyVersion(ctx, tx); err != nil {
+			return err
+		}
+	}
+
+	for _, migration := range migrations {
+		if _, exists := versions[migration.Version]; exists {
+			continue
+		}
+		if migration.Version > legacy {
+			if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+				return fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+		}
+		if err := markApplied(ctx, tx, migration); err != nil {
+			return err
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"version": migration.Version,
+			"name":    migration.Name,
+			"legacy":  migration.Version <= legacy,
+		}).Info("SQLite migration applied")
+	}
+
+	return tx.Commit()
+}
+
+// appliedVersions создает таблицу schema_migrations при ее отсутствии и возвращает примененные версии
+func appliedVersions(ctx context.Context, tx *sql.Tx) (map[int64]struct{}, error) {
+	query := `
+    CREATE TABLE IF NOT EXISTS schema_migrations (
+        version INTEGER PRIMARY KEY,
+        name TEXT NOT NULL,
+        applied_at INTEGER NOT NULL
+    )`
+	if _, err := tx.ExecContext(ctx, query); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if errClose := rows.Close(); errClose != nil {
+			logrus.WithField("err", errClose).Error("Failed to close rows")
+		}
+	}()
+
+	versions := make(map[int64]struct{})
+	for rows.Next() {
+		var version int64
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		versions[version] = struct{}{}
+	}
+	return versions, rows.Err()
+}
+
+// legacyVersion определяет по колонкам таблицы ссылок версию схемы БД, созданной без schema_migrations.
+// Для новой БД возвращает 0. Следующие миграции создают таблицы с IF NOT EXISTS
+// и применяются к такой БД без ошибок.
+func legacyVersion(ctx context.Context, tx *sql.Tx) (int64, error) {
+	columns, err := tableColumns(ctx, tx, "short_urls")
+	if err != nil || len(columns) == 0 {
+		return 0, err
+	}
+
+	version := int64(1)
+	for _, column := range legacyColumns {
+		if _, exists := columns[column.name]; !exists {
+			break
+		}
+		version = column.version
+	}
+	return version, nil
+}
+
+// markApplied записывает версию примененной миграции
+func markApplied(ctx context.Context, tx *sql.Tx, migration migrate.Migration) error {
+	query := `INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`
+	_, err := tx.ExecContext(ctx, query, migration.Version, migration.Name, time.Now().UnixNano())
+	return err
+}
+
+// tableColumns возвращает имена колонок таблицы, для отсутствующей таблицы - пустое множество
+func tableColumns(ctx context.Context, tx *sql.Tx, table string) (map[string]struct{}, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if errClose := rows.Close(); errClose != nil {
+			logrus.WithField("err", errClose).Error("Failed to close rows")
+		}
+	}()
+
+	columns := make(map[string]struct{})
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		columns[name] = struct{}{}
+	}
+	return columns, rows.Err()
+}
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS short_urls (
+    id TEXT PRIMARY KEY,
+    original_url TEXT NOT NULL UNIQUE,
+    short_url TEXT NOT NULL UNIQUE,
+    user_id TEXT NULL,
+    is_deleted INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS short_urls_user_id_idx ON short_urls (user_id);
@@ -0,0 +1,16 @@
+-- Ограничение UNIQUE колонки нельзя удалить в SQLite, поэтому таблица пересоздается.
+-- Индексы создаются после переименования новой таблицы, чтобы они не удалились вместе со старой.
+CREATE TABLE short_urls_new (
+    id TEXT PRIMARY KEY,
+    original_url TEXT NOT NULL,
+    short_url TEXT NOT NULL UNIQUE,
+    user_id TEXT NULL,
+    is_deleted INTEGER NOT NULL DEFAULT 0,
+    is_alias INTEGER NOT NULL DEFAULT 0
+);
+INSERT INTO short_urls_new (id, original_url, short_url, user_id, is_deleted)
+SELECT id, original_url, short_url, user_id, is_deleted FROM short_urls;
+DROP TABLE short_urls;
+ALTER TABLE short_urls_new RENAME TO short_urls;
+CREATE UNIQUE INDEX short_urls_original_url_idx ON short_urls (original_url) WHERE is_alias = 0;
+CREATE INDEX short_urls_user_id_idx ON short_urls (user_id);
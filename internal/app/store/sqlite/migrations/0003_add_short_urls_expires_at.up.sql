@@ -0,0 +1,6 @@
+ALTER TABLE short_urls ADD COLUMN expires_at INTEGER NULL;
+DROP INDEX IF EXISTS short_urls_original_url_idx;
+CREATE UNIQUE INDEX short_urls_original_url_idx ON short_urls (original_url)
+    WHERE is_alias = 0 AND expires_at IS NULL;
+CREATE INDEX IF NOT EXISTS short_urls_expires_at_idx ON short_urls (expires_at)
+    WHERE expires_at IS NOT NULL AND is_deleted = 0;
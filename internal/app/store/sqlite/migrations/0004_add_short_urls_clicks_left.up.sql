@@ -0,0 +1,4 @@
+ALTER TABLE short_urls ADD COLUMN clicks_left INTEGER NULL;
+DROP INDEX IF EXISTS short_urls_original_url_idx;
+CREATE UNIQUE INDEX short_urls_original_url_idx ON short_urls (original_url)
+    WHERE is_alias = 0 AND expires_at IS NULL AND clicks_left IS NULL;
@@ -0,0 +1,2 @@
+-- Прежнее обновление таблицы без is_alias удаляло индекс по пользователю вместе со старой таблицей
+CREATE INDEX IF NOT EXISTS short_urls_user_id_idx ON short_urls (user_id);
@@ -0,0 +1,840 @@
+// Package sqlite предназначен для организации хранения данных во встроенной БД SQLite
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	_ "modernc.org/sqlite"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/config"
+	models "github.com/TimBerk/go-link-shortener/internal/app/models/batch"
+	"github.com/TimBerk/go-link-shortener/internal/app/store"
+)
+
+// SQLiteStore описывает структуру стора
+type SQLiteStore struct {
+	db  *sql.DB
+	gen store.Generator
+}
+
+// NewSQLiteStore открывает файл БД по переданному пути и применяет к ней непримененные миграции
+func NewSQLiteStore(path string, gen store.Generator) (*SQLiteStore, error) {
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)", path)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite допускает только одного писателя, поэтому все запросы идут через одно соединение
+	db.SetMaxOpenConns(1)
+
+	sqliteStore := &SQLiteStore{db: db, gen: gen}
+	if err := sqliteStore.applyMigrations(context.Background()); err != nil {
+		logrus.WithField("err", err).Error("Failed to migrate sqlite database")
+		if errClose := db.Close(); errClose != nil {
+			logrus.WithField("err", errClose).Error("Failed to close sqlite database")
+		}
+		return nil, err
+	}
+
+	return sqliteStore, nil
+}
+
+// init регистрирует стор для схемы sqlite://
+func init() {
+	store.Register("sqlite", func(ctx context.Context, storageURL string, gen store.Generator, cfg *config.Config) (store.Store, error) {
+		path, err := store.PathFromURL(storageURL)
+		if err != nil {
+			return nil, err
+		}
+
+		sqliteStore, err := NewSQLiteStore(path, gen)
+		if err != nil {
+			return nil, err
+		}
+		return sqliteStore, nil
+	})
+}
+
+// Ping проверяет доступность БД
+func (s *SQLiteStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// Close закрывает соединение к БД
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// querier описывает общие методы БД и транзакции
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// getShortURLByOriginalURL получает короткую ссылку по оригинальной
+func getShortURLByOriginalURL(ctx context.Context, q querier, originalURL string) (string, error) {
+	var shortURL string
+	query := `SELECT short_url FROM short_urls WHERE original_url = ? AND is_alias = 0 AND expires_at IS NULL AND clicks_left IS NULL AND password_hash IS NULL`
+	err := q.QueryRowContext(ctx, query, originalURL).Scan(&shortURL)
+	return shortURL, err
+}
+
+// insertRecord добавляет запись со сгенерированной короткой ссылкой.
+// При совпадении короткой ссылки с существующей генерирует новую, а если оригинальная ссылка
+// была добавлена параллельным запросом, возвращает ее короткую ссылку с ошибкой store.ErrLinkExist.
+func (s *SQLiteStore) insertRecord(ctx context.Context, q querier, originalURL string, userID string) (string, error) {
+	query := `INSERT INTO short_urls (id, original_url, short_url, user_id) VALUES (?, ?, ?, ?) ON CONFLICT DO NOTHING`
+	for attempt := 0; ; attempt++ {
+		shortURL := store.NextFor(s.gen, originalURL, attempt)
+		result, err := q.ExecContext(ctx, query, uuid.New().String(), originalURL, shortURL, userID)
+		if err != nil {
+			return "", err
+		}
+
+		inserted, err := result.RowsAffected()
+		if err != nil {
+			return "", err
+		}
+		if inserted > 0 {
+			return shortURL, nil
+		}
+
+		existURL, err := getShortURLByOriginalURL(ctx, q, originalURL)
+		if err == nil {
+			return existURL, store.ErrLinkExist
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return "", err
+		}
+	}
+}
+
+// AddURL добавляет новую ссылку в БД, если она отсутствует. Иначе возвращает существующую.
+func (s *SQLiteStore) AddURL(ctx context.Context, originalURL string, userID string) (string, error) {
+	shortURL, err := getShortURLByOriginalURL(ctx, s.db, originalURL)
+	if err == nil {
+		return shortURL, store.ErrLinkExist
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		logrus.WithFields(logrus.Fields{
+			"err": err,
+			"uri": originalURL,
+		}).Error("Error checking existing URL")
+		return "", err
+	}
+
+	shortURL, err = s.insertRecord(ctx, s.db, originalURL, userID)
+	if err != nil && !errors.Is(err, store.ErrLinkExist) {
+		logrus.WithFields(logrus.Fields{
+			"err": err,
+			"uri": originalURL,
+		}).Error("Error inserting new URL")
+		return "", err
+	}
+
+	return shortURL, err
+}
+
+// expiresAtValue возвращает значение колонки expires_at для момента истечения
+func expiresAtValue(expiresAt time.Time) sql.NullInt64 {
+	if expiresAt.IsZero() {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: expiresAt.UnixNano(), Valid: true}
+}
+
+// clicksLeftValue возвращает значение колонки clicks_left для лимита переходов
+func clicksLeftValue(maxClicks int) sql.NullInt64 {
+	if maxClicks <= 0 {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(maxClicks), Valid: true}
+}
+
+// passwordHashValue возвращает значение колонки password_hash для хеша пароля
+func passwordHashValue(passwordHash string) sql.NullString {
+	return sql.NullString{String: passwordHash, Valid: passwordHash != ""}
+}
+
+// insertLink добавляет запись ссылки с параметрами. Для ссылки без пользовательской генерирует свободную,
+// если пользовательская ссылка занята, возвращает store.ErrAliasTaken.
+func (s *SQLiteStore) insertLink(ctx context.Context, q querier, originalURL string, opts store.LinkOptions, userID string) (string, error) {
+	query := `
+    INSERT INTO short_urls (id, original_url, short_url, user_id, is_alias, expires_at, clicks_left, password_hash)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+    ON CONFLICT DO NOTHING`
+	for attempt := 0; ; attempt++ {
+		shortURL := opts.Alias
+		if shortURL == "" {
+			shortURL = store.NextFor(s.gen, originalURL, attempt)
+		}
+		result, err := q.ExecContext(ctx, query, uuid.New().String(), originalURL, shortURL, userID,
+			opts.Alias != "", expiresAtValue(opts.ExpiresAt), clicksLeftValue(opts.MaxClicks), passwordHashValue(opts.PasswordHash))
+		if err != nil {
+			return "", err
+		}
+
+		inserted, err := result.RowsAffected()
+		if err != nil {
+			return "", err
+		}
+		if inserted > 0 {
+			return shortURL, nil
+		}
+		if opts.Alias != "" {
+			return "", store.ErrAliasTaken
+		}
+	}
+}
+
+// AddLink добавляет ссылку с параметрами в БД
+func (s *SQLiteStore) AddLink(ctx context.Context, originalURL string, opts store.LinkOptions, userID string) (string, error) {
+	return s.insertLink(ctx, s.db, originalURL, opts, userID)
+}
+
+// AddURLs добавляет новые ссылки в БД в одной транзакции. Для существующих ссылок возвращает их короткие ссылки.
+// Если пользовательская ссылка из пачки занята, транзакция откатывается.
+func (s *SQLiteStore) AddURLs(ctx context.Context, urls models.BatchRequest, userID string) (models.BatchResponse, error) {
+	var responses models.BatchResponse
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		logrus.WithField("err", err).Error("Error starting transaction")
+		return nil, err
+	}
+	defer func() {
+		if errRollBack := tx.Rollback(); errRollBack != nil && !errors.Is(errRollBack, sql.ErrTxDone) {
+			logrus.WithField("err", errRollBack).Error("Failed to rollback transaction")
+		}
+	}()
+
+	for _, req := range urls {
+		if opts := store.LinkOptionsFromItem(req); !opts.IsZero() {
+			shortURL, err := s.insertLink(ctx, tx, req.OriginalURL, opts, userID)
+			if err != nil {
+				return nil, err
+			}
+			responses = append(responses, models.ItemResponse{
+				CorrelationID: req.CorrelationID,
+				ShortURL:      shortURL,
+			})
+			continue
+		}
+
+		shortURL, err := getShortURLByOriginalURL(ctx, tx, req.OriginalURL)
+		if errors.Is(err, sql.ErrNoRows) {
+			shortURL, err = s.insertRecord(ctx, tx, req.OriginalURL, userID)
+		}
+		if err != nil && !errors.Is(err, store.ErrLinkExist) {
+			logrus.WithFields(logrus.Fields{
+				"err": err,
+				"ID":  req.CorrelationID,
+				"uri": req.OriginalURL,
+			}).Error("Error inserting URL")
+			return nil, err
+		}
+
+		responses = append(responses, models.ItemResponse{
+			CorrelationID: req.CorrelationID,
+			ShortURL:      shortURL,
+		})
+	}
+
+	if err := tx.Commit(); err != nil {
+		logrus.WithField("err", err).Error("Error committing transaction")
+		return nil, err
+	}
+
+	return responses, nil
+}
+
+// GetOriginalURL получает оригинальную ссылку по короткой
+func (s *SQLiteStore) GetOriginalURL(ctx context.Context, shortURL string, userID string) (string, bool, bool) {
+	var originalURL string
+	var isDeleted bool
+	query := `
+    SELECT original_url, is_deleted OR COALESCE(expires_at <= ?, 0) OR COALESCE(clicks_left <= 0, 0)
+    FROM short_urls WHERE short_url = ?`
+	err := s.db.QueryRowContext(ctx, query, time.Now().UnixNano(), shortURL).Scan(&originalURL, &isDeleted)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			logrus.WithFields(logrus.Fields{
+				"uri": shortURL,
+				"err": err,
+			}).Error("Error selecting short URL")
+		}
+		return "", false, false
+	}
+
+	return originalURL, true, isDeleted
+}
+
+// DeleteURL помечает ссылки пользователя как удаленные в одной транзакции
+func (s *SQLiteStore) DeleteURL(ctx context.Context, batch []store.URLPair) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if errRollBack := tx.Rollback(); errRollBack != nil && !errors.Is(errRollBack, sql.ErrTxDone) {
+			logrus.WithField("err", errRollBack).Error("Failed to rollback transaction")
+		}
+	}()
+
+	stmt, err := tx.PrepareContext(ctx, `UPDATE short_urls SET is_deleted = 1 WHERE short_url = ? AND user_id = ?`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer func() {
+		if errClose := stmt.Close(); errClose != nil {
+			logrus.WithField("err", errClose).Error("Failed to close statement")
+		}
+	}()
+
+	for _, pair := range batch {
+		if _, err := stmt.ExecContext(ctx, pair.ShortURL, pair.UserID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetUserURLs получает не удаленные ссылки пользователя в порядке их добавления
+func (s *SQLiteStore) GetUserURLs(ctx context.Context, userID string) ([]store.UserURL, error) {
+	query := `
+    SELECT short_url, original_url FROM short_urls
+    WHERE user_id = ? AND is_deleted = 0 AND (expires_at IS NULL OR expires_at > ?) AND (clicks_left IS NULL OR clicks_left > 0)
+    ORDER BY rowid`
+	rows, err := s.db.QueryContext(ctx, query, userID, time.Now().UnixNano())
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"err":    err,
+			"userID": userID,
+		}).Error("Error selecting user URLs")
+		return nil, err
+	}
+	defer func() {
+		if errClose := rows.Close(); errClose != nil {
+			logrus.WithField("err", errClose).Error("Failed to close rows")
+		}
+	}()
+
+	var urls []store.UserURL
+	for rows.Next() {
+		var url store.UserURL
+		if err := rows.Scan(&url.ShortURL, &url.OriginalURL); err != nil {
+			return nil, err
+		}
+		urls = append(urls, url)
+	}
+
+	return urls, rows.Err()
+}
+
+// DeleteExpiredURLs помечает удаленными истекшие ссылки в порядке истечения одним обновлением
+func (s *SQLiteStore) DeleteExpiredURLs(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	query := `
+    UPDATE short_urls SET is_deleted = 1
+    WHERE id IN (
+        SELECT id FROM short_urls
+        WHERE expires_at IS NOT NULL AND is_deleted = 0 AND expires_at <= ?
+        ORDER BY expires_at LIMIT ?
+    )
+    RETURNING short_url`
+	rows, err := s.db.QueryContext(ctx, query, now.UnixNano(), limit)
+	if err != nil {
+		logrus.WithField("err", err).Error("Error deleting expired URLs")
+		return nil, err
+	}
+	defer func() {
+		if errClose := rows.Close(); errClose != nil {
+			logrus.WithField("err", errClose).Error("Failed to close rows")
+		}
+	}()
+
+	var shortURLs []string
+	for rows.Next() {
+		var shortURL string
+		if err := rows.Scan(&shortURL); err != nil {
+			return nil, err
+		}
+		shortURLs = append(shortURLs, shortURL)
+	}
+
+	return shortURLs, rows.Err()
+}
+
+// UseClick уменьшает оставшееся количество переходов по ссылке одним условным обновлением.
+// Если обновление не затронуло запись, лимит исчерпан либо отсутствует.
+func (s *SQLiteStore) UseClick(ctx context.Context, shortURL string) (store.ClickResult, error) {
+	var clicksLeft sql.NullInt64
+	query := `UPDATE short_urls SET clicks_left = clicks_left - 1 WHERE short_url = ? AND clicks_left > 0 RETURNING clicks_left`
+	err := s.db.QueryRowContext(ctx, query, shortURL).Scan(&clicksLeft)
+	if err == nil {
+		return store.ClickAllowed, nil
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		logrus.WithFields(logrus.Fields{
+			"err": err,
+			"uri": shortURL,
+		}).Error("Error using link click")
+		return store.ClickUnlimited, err
+	}
+
+	err = s.db.QueryRowContext(ctx, `SELECT clicks_left FROM short_urls WHERE short_url = ?`, shortURL).Scan(&clicksLeft)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return store.ClickUnlimited, err
+	}
+	if clicksLeft.Valid {
+		return store.ClickExhausted, nil
+	}
+	return store.ClickUnlimited, nil
+}
+
+// GetPasswordHash получает хеш пароля ссылки
+func (s *SQLiteStore) GetPasswordHash(ctx context.Context, shortURL string) (string, error) {
+	var passwordHash sql.NullString
+	query := `SELECT password_hash FROM short_urls WHERE short_url = ?`
+	err := s.db.QueryRowContext(ctx, query, shortURL).Scan(&passwordHash)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		logrus.WithFields(logrus.Fields{
+			"err": err,
+			"uri": shortURL,
+		}).Error("Error selecting link password")
+		return "", err
+	}
+	return passwordHash.String, nil
+}
+
+// GetLinkExpiry возвращает момент истечения ссылки
+func (s *SQLiteStore) GetLinkExpiry(ctx context.Context, shortURL string) (time.Time, error) {
+	var expiresAt sql.NullInt64
+	query := `SELECT expires_at FROM short_urls WHERE short_url = ?`
+	err := s.db.QueryRowContext(ctx, query, shortURL).Scan(&expiresAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		logrus.WithFields(logrus.Fields{
+			"err": err,
+			"uri": shortURL,
+		}).Error("Error selecting link expiration")
+		return time.Time{}, err
+	}
+	if !expiresAt.Valid {
+		return time.Time{}, nil
+	}
+	return time.Unix(0, expiresAt.Int64).UTC(), nil
+}
+
+// MaxCodeLength возвращает наибольшую длину сгенерированных ссылок без учета пользовательских
+func (s *SQLiteStore) MaxCodeLength(ctx context.Context) (int, error) {
+	var length int
+	query := `SELECT COALESCE(MAX(length(short_url)), 0) FROM short_urls WHERE is_alias = 0`
+	if err := s.db.QueryRowContext(ctx, query).Scan(&length); err != nil {
+		logrus.WithField("err", err).Error("Error selecting max short URL length")
+		return 0, err
+	}
+	return length, nil
+}
+
+// AddClicks сохраняет переходы по ссылкам с владельцами ссылок в одной транзакции
+func (s *SQLiteStore) AddClicks(ctx context.Context, clicks []store.Click) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if errRollBack := tx.Rollback(); errRollBack != nil && !errors.Is(errRollBack, sql.ErrTxDone) {
+			logrus.WithField("err", errRollBack).Error("Failed to rollback transaction")
+		}
+	}()
+
+	query := `
+    INSERT INTO clicks (short_url, owner_id, visitor_id, clicked_at, referrer, user_agent, ip)
+    VALUES (?, (SELECT user_id FROM short_urls WHERE short_url = ?), ?, ?, ?, ?, ?)`
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer func() {
+		if errClose := stmt.Close(); errClose != nil {
+			logrus.WithField("err", errClose).Error("Failed to close statement")
+		}
+	}()
+
+	for _, click := range clicks {
+		_, err := stmt.ExecContext(ctx, click.ShortURL, click.ShortURL, click.VisitorID,
+			click.ClickedAt.UnixNano(), click.Referrer, click.UserAgent, click.IP)
+		if err != nil {
+			logrus.WithField("err", err).Error("Error inserting clicks")
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// clicksRange - условие выборки переходов по ссылке за период в наносекундах Unix
+const clicksRange = `short_url = ? AND clicked_at >= ? AND clicked_at < ?`
+
+// statsBounds возвращает границы периода статистики в наносекундах Unix
+func statsBounds(query store.StatsQuery) (int64, int64) {
+	from, to := int64(math.MinInt64), int64(math.MaxInt64)
+	if !query.From.IsZero() {
+		from = query.From.UnixNano()
+	}
+	if !query.To.IsZero() {
+		to = query.To.UnixNano()
+	}
+	return from, to
+}
+
+// GetLinkStats считает статистику переходов по ссылке пользователя агрегирующими запросами
+func (s *SQLiteStore) GetLinkStats(ctx context.Context, link store.URLPair, query store.StatsQuery) (store.LinkStats, error) {
+	var ownerID string
+	err := s.db.QueryRowContext(ctx, `SELECT COALESCE(user_id, '') FROM short_urls WHERE short_url = ?`, link.ShortURL).Scan(&ownerID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && ownerID != link.UserID) {
+		return store.LinkStats{}, store.ErrStatsNotFound
+	} else if err != nil {
+		return store.LinkStats{}, err
+	}
+
+	var stats store.LinkStats
+	from, to := statsBounds(query)
+	err = s.db.QueryRowContext(ctx, `SELECT COUNT(*), COUNT(DISTINCT visitor_id) FROM clicks WHERE `+clicksRange,
+		link.ShortURL, from, to).Scan(&stats.TotalClicks, &stats.UniqueVisitors)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"err": err,
+			"uri": link.ShortURL,
+		}).Error("Error selecting link stats")
+		return store.LinkStats{}, err
+	}
+
+	if stats.ByDay, err = s.periodCounts(ctx, link.ShortURL, from, to, 24*time.Hour); err != nil {
+		return store.LinkStats{}, err
+	}
+	if stats.ByHour, err = s.periodCounts(ctx, link.ShortURL, from, to, time.Hour); err != nil {
+		return store.LinkStats{}, err
+	}
+	if stats.TopReferrers, err = s.topValues(ctx, "referrer", link.ShortURL, from, to, query.Limit()); err != nil {
+		return store.LinkStats{}, err
+	}
+	if stats.TopUserAgents, err = s.topValues(ctx, "user_agent", link.ShortURL, from, to, query.Limit()); err != nil {
+		return store.LinkStats{}, err
+	}
+	return stats, nil
+}
+
+// periodCounts считает переходы по ссылке за периоды длиной period в порядке их начала
+func (s *SQLiteStore) periodCounts(ctx context.Context, shortURL string, from int64, to int64, period time.Duration) ([]store.PeriodCount, error) {
+	query := `
+    SELECT clicked_at / ? * ? AS period, COUNT(*) FROM clicks
+    WHERE ` + clicksRange + `
+    GROUP BY period ORDER BY period`
+	rows, err := s.db.QueryContext(ctx, query, int64(period), int64(period), shortURL, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if errClose := rows.Close(); errClose != nil {
+			logrus.WithField("err", errClose).Error("Failed to close rows")
+		}
+	}()
+
+	counts := []store.PeriodCount{}
+	for rows.Next() {
+		var nanos int64
+		var count store.PeriodCount
+		if err := rows.Scan(&nanos, &count.Clicks); err != nil {
+			return nil, err
+		}
+		count.Period = time.Unix(0, nanos).UTC()
+		counts = append(counts, count)
+	}
+	return counts, rows.Err()
+}
+
+// topValues возвращает limit непустых значений колонки column с наибольшим количеством переходов
+func (s *SQLiteStore) topValues(ctx context.Context, column string, shortURL string, from int64, to int64, limit int) ([]store.ValueCount, error) {
+	query := `
+    SELECT ` + column + `, COUNT(*) AS clicks FROM clicks
+    WHERE ` + clicksRange + ` AND ` + column + ` <> ''
+    GROUP BY ` + column + ` ORDER BY clicks DESC, ` + column + ` LIMIT ?`
+	rows, err := s.db.QueryContext(ctx, query, shortURL, from, to, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if errClose := rows.Close(); errClose != nil {
+			logrus.WithField("err", errClose).Error("Failed to close rows")
+		}
+	}()
+
+	values := []store.ValueCount{}
+	for rows.Next() {
+		var value store.ValueCount
+		if err := rows.Scan(&value.Value, &value.Clicks); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+// GetServiceStats считает действующие ссылки и их владельцев
+func (s *SQLiteStore) GetServiceStats(ctx context.Context) (store.ServiceStats, error) {
+	var stats store.ServiceStats
+	query := `
+    SELECT COUNT(*), COUNT(DISTINCT user_id) FROM short_urls
+    WHERE is_deleted = 0 AND (expires_at IS NULL OR expires_at > ?) AND (clicks_left IS NULL OR clicks_left > 0)`
+	if err := s.db.QueryRowContext(ctx, query, time.Now().UnixNano()).Scan(&stats.URLs, &stats.Users); err != nil {
+		logrus.WithField("err", err).Error("Error selecting service stats")
+		return store.ServiceStats{}, err
+	}
+	return stats, nil
+}
+
+// AddAPIKey сохраняет ключ API пользователя
+func (s *SQLiteStore) AddAPIKey(ctx context.Context, key store.APIKey) error {
+	query := `INSERT INTO api_keys (id, user_id, name, prefix, key_hash, created_at) VALUES (?, ?, ?, ?, ?, ?)`
+	_, err := s.db.ExecContext(ctx, query, key.ID, key.UserID, key.Name, key.Prefix, key.Hash, key.CreatedAt.UnixNano())
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"err":  err,
+			"user": key.UserID,
+		}).Error("Error inserting api key")
+	}
+	return err
+}
+
+// GetAPIKeys возвращает ключи API пользователя в порядке создания
+func (s *SQLiteStore) GetAPIKeys(ctx context.Context, userID string) ([]store.APIKey, error) {
+	query := `SELECT id, name, prefix, key_hash, created_at, last_used_at FROM api_keys WHERE user_id = ? ORDER BY created_at, id`
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"err":  err,
+			"user": userID,
+		}).Error("Error selecting api keys")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []store.APIKey
+	for rows.Next() {
+		var createdAt int64
+		var lastUsedAt sql.NullInt64
+		key := store.APIKey{UserID: userID}
+		if err := rows.Scan(&key.ID, &key.Name, &key.Prefix, &key.Hash, &createdAt, &lastUsedAt); err != nil {
+			return nil, err
+		}
+		key.CreatedAt = time.Unix(0, createdAt).UTC()
+		if lastUsedAt.Valid {
+			usedAt := time.Unix(0, lastUsedAt.Int64).UTC()
+			key.LastUsedAt = &usedAt
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// RevokeAPIKey удаляет ключ API пользователя
+func (s *SQLiteStore) RevokeAPIKey(ctx context.Context, userID string, keyID string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM api_keys WHERE id = ? AND user_id = ?`, keyID, userID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"err":  err,
+			"user": userID,
+		}).Error("Error deleting api key")
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return store.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// UseAPIKey возвращает владельца ключа API и обновляет момент его использования
+func (s *SQLiteStore) UseAPIKey(ctx context.Context, keyHash string, usedAt time.Time) (string, error) {
+	var userID string
+	query := `UPDATE api_keys SET last_used_at = ? WHERE key_hash = ? RETURNING user_id`
+	err := s.db.QueryRowContext(ctx, query, usedAt.UnixNano(), keyHash).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", store.ErrAPIKeyNotFound
+	} else if err != nil {
+		logrus.WithField("err", err).Error("Error using api key")
+		return "", err
+	}
+	return userID, nil
+}
+
+// CreateAccount сохраняет учетную запись, если логин свободен
+func (s *SQLiteStore) CreateAccount(ctx context.Context, account store.Account) error {
+	query := `INSERT INTO accounts (id, login, password_hash, created_at) VALUES (?, ?, ?, ?) ON CONFLICT DO NOTHING`
+	result, err := s.db.ExecContext(ctx, query, account.ID, account.Login, account.PasswordHash, account.CreatedAt.UnixNano())
+	if err != nil {
+		logrus.WithField("err", err).Error("Error inserting account")
+		return err
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if inserted == 0 {
+		return store.ErrAccountExists
+	}
+	return nil
+}
+
+// GetAccount возвращает учетную запись по логину
+func (s *SQLiteStore) GetAccount(ctx context.Context, login string) (store.Account, error) {
+	account := store.Account{Login: login}
+	var createdAt int64
+	query := `SELECT id, password_hash, created_at FROM accounts WHERE login = ?`
+	err := s.db.QueryRowContext(ctx, query, login).Scan(&account.ID, &account.PasswordHash, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return store.Account{}, store.ErrAccountNotFound
+	} else if err != nil {
+		logrus.WithField("err", err).Error("Error selecting account")
+		return store.Account{}, err
+	}
+	account.CreatedAt = time.Unix(0, createdAt).UTC()
+	return account, nil
+}
+
+// AddSession сохраняет сессию и удаляет истекшие в одной транзакции
+func (s *SQLiteStore) AddSession(ctx context.Context, session store.Session) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if errRollBack := tx.Rollback(); errRollBack != nil && !errors.Is(errRollBack, sql.ErrTxDone) {
+			logrus.WithField("err", errRollBack).Error("Failed to rollback transaction")
+		}
+	}()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM sessions WHERE expires_at <= ?`, session.CreatedAt.UnixNano()); err != nil {
+		return err
+	}
+	query := `INSERT INTO sessions (token_hash, user_id, created_at, expires_at) VALUES (?, ?, ?, ?)`
+	_, err = tx.ExecContext(ctx, query, session.TokenHash, session.UserID, session.CreatedAt.UnixNano(), session.ExpiresAt.UnixNano())
+	if err != nil {
+		logrus.WithField("err", err).Error("Error inserting session")
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetSession возвращает активную сессию по хешу токена
+func (s *SQLiteStore) GetSession(ctx context.Context, tokenHash string, now time.Time) (store.Session, error) {
+	session := store.Session{TokenHash: tokenHash}
+	var createdAt, expiresAt int64
+	query := `SELECT user_id, created_at, expires_at FROM sessions WHERE token_hash = ? AND expires_at > ?`
+	err := s.db.QueryRowContext(ctx, query, tokenHash, now.UnixNano()).Scan(&session.UserID, &createdAt, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return store.Session{}, store.ErrSessionNotFound
+	} else if err != nil {
+		logrus.WithField("err", err).Error("Error selecting session")
+		return store.Session{}, err
+	}
+	session.CreatedAt = time.Unix(0, createdAt).UTC()
+	session.ExpiresAt = time.Unix(0, expiresAt).UTC()
+	return session, nil
+}
+
+// DeleteSession удаляет сессию по хешу токена
+func (s *SQLiteStore) DeleteSession(ctx context.Context, tokenHash string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM sessions WHERE token_hash = ?`, tokenHash)
+	if err != nil {
+		logrus.WithField("err", err).Error("Error deleting session")
+	}
+	return err
+}
+
+// ClaimURLs передает не удаленные ссылки пользователя fromUserID пользователю toUserID одним обновлением
+func (s *SQLiteStore) ClaimURLs(ctx context.Context, fromUserID string, toUserID string) (int, error) {
+	if fromUserID == toUserID {
+		return 0, nil
+	}
+
+	query := `UPDATE short_urls SET user_id = ? WHERE user_id = ? AND is_deleted = 0`
+	result, err := s.db.ExecContext(ctx, query, toUserID, fromUserID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"err":  err,
+			"user": fromUserID,
+		}).Error("Error claiming user URLs")
+		return 0, err
+	}
+	claimed, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(claimed), nil
+}
+
+// SearchURLs отбирает ссылки всех пользователей по подстроке и возвращает страницу в порядке коротких ссылок
+func (s *SQLiteStore) SearchURLs(ctx context.Context, search store.LinkSearch) ([]store.Link, error) {
+	query := `
+    SELECT short_url, original_url, COALESCE(user_id, ''), is_deleted FROM short_urls
+    WHERE ?1 = '' OR instr(lower(short_url), ?1) > 0 OR instr(lower(original_url), ?1) > 0
+    ORDER BY short_url
+    LIMIT ?2 OFFSET ?3`
+	rows, err := s.db.QueryContext(ctx, query, search.Needle(), search.Limit, search.Offset)
+	if err != nil {
+		logrus.WithField("err", err).Error("Error searching URLs")
+		return nil, err
+	}
+	defer func() {
+		if errClose := rows.Close(); errClose != nil {
+			logrus.WithField("err", errClose).Error("Failed to close rows")
+		}
+	}()
+
+	links := []store.Link{}
+	for rows.Next() {
+		var link store.Link
+		if err := rows.Scan(&link.ShortURL, &link.OriginalURL, &link.UserID, &link.IsDeleted); err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+// DisableURL помечает удаленной ссылку любого пользователя
+func (s *SQLiteStore) DisableURL(ctx context.Context, shortURL string) error {
+	result, err := s.db.ExecContext(ctx, `UPDATE short_urls SET is_deleted = 1 WHERE short_url = ?`, shortURL)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"err":      err,
+			"shortURL": shortURL,
+		}).Error("Error disabling URL")
+		return err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return store.ErrLinkNotFound
+	}
+	return nil
+}
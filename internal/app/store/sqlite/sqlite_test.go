@@ -0,0 +1,199 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	models "github.com/TimBerk/go-link-shortener/internal/app/models/batch"
+	"github.com/TimBerk/go-link-shortener/internal/app/store"
+)
+
+func newTestStore(t *testing.T) *SQLiteStore {
+	testStore, err := NewSQLiteStore(filepath.Join(t.TempDir(), "data.db"), store.NewIDGenerator())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, testStore.Close())
+	})
+	return testStore
+}
+
+// shortURLsIndexes возвращает имена созданных индексов таблицы ссылок
+func shortURLsIndexes(t *testing.T, testStore *SQLiteStore) []string {
+	rows, err := testStore.db.QueryContext(context.Background(),
+		`SELECT name FROM sqlite_master WHERE type = 'index' AND tbl_name = 'short_urls' AND sql IS NOT NULL ORDER BY name`)
+	require.NoError(t, err)
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		require.NoError(t, rows.Scan(&name))
+		names = append(names, name)
+	}
+	require.NoError(t, rows.Err())
+	return names
+}
+
+// expectedIndexes - индексы таблицы ссылок после применения всех миграций
+var expectedIndexes = []string{"short_urls_expires_at_idx", "short_urls_original_url_idx", "short_urls_user_id_idx"}
+
+func TestMigrations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.db")
+	testStore, err := NewSQLiteStore(path, store.NewIDGenerator())
+	require.NoError(t, err)
+	assert.Equal(t, expectedIndexes, shortURLsIndexes(t, testStore))
+	_, err = testStore.AddURL(context.Background(), "https://example.com", "test")
+	require.NoError(t, err)
+	require.NoError(t, testStore.Close())
+
+	testStore, err = NewSQLiteStore(path, store.NewIDGenerator())
+	require.NoError(t, err, "Повторное открытие БД не должно применять миграции заново")
+	t.Cleanup(func() {
+		assert.NoError(t, testStore.Close())
+	})
+	var applied int
+	require.NoError(t, testStore.db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&applied))
+	assert.Equal(t, 9, applied)
+}
+
+func TestAddURL(t *testing.T) {
+	ctx := context.Background()
+	testStore := newTestStore(t)
+
+	shortURL, err := testStore.AddURL(ctx, "https://example.com", "test")
+	require.NoError(t, err)
+
+	existURL, err := testStore.AddURL(ctx, "https://example.com", "other")
+	assert.ErrorIs(t, err, store.ErrLinkExist)
+	assert.Equal(t, shortURL, existURL)
+
+	originalURL, exists, isDeleted := testStore.GetOriginalURL(ctx, shortURL, "test")
+	assert.Equal(t, "https://example.com", originalURL)
+	assert.True(t, exists)
+	assert.False(t, isDeleted)
+
+	_, exists, _ = testStore.GetOriginalURL(ctx, "unknown", "test")
+	assert.False(t, exists)
+}
+
+func TestAddURLs(t *testing.T) {
+	ctx := context.Background()
+	testStore := newTestStore(t)
+
+	shortURL, err := testStore.AddURL(ctx, "https://example.com/1", "test")
+	require.NoError(t, err)
+
+	responses, err := testStore.AddURLs(ctx, models.BatchRequest{
+		{CorrelationID: "1", OriginalURL: "https://example.com/1"},
+		{CorrelationID: "2", OriginalURL: "https://example.com/2"},
+	}, "test")
+	require.NoError(t, err)
+	require.Len(t, responses, 2)
+	assert.Equal(t, models.ItemResponse{CorrelationID: "1", ShortURL: shortURL}, responses[0])
+	assert.Equal(t, "2", responses[1].CorrelationID)
+
+	urls, err := testStore.GetUserURLs(ctx, "test")
+	assert.NoError(t, err)
+	assert.Equal(t, []store.UserURL{
+		{ShortURL: shortURL, OriginalURL: "https://example.com/1"},
+		{ShortURL: responses[1].ShortURL, OriginalURL: "https://example.com/2"},
+	}, urls)
+}
+
+func TestDeleteURL(t *testing.T) {
+	ctx := context.Background()
+	testStore := newTestStore(t)
+
+	shortURL, err := testStore.AddURL(ctx, "https://example.com", "test")
+	require.NoError(t, err)
+
+	require.NoError(t, testStore.DeleteURL(ctx, []store.URLPair{{ShortURL: shortURL, UserID: "other"}}))
+	_, _, isDeleted := testStore.GetOriginalURL(ctx, shortURL, "test")
+	assert.False(t, isDeleted, "Ссылка другого пользователя не должна удаляться")
+
+	require.NoError(t, testStore.DeleteURL(ctx, []store.URLPair{{ShortURL: shortURL, UserID: "test"}}))
+	originalURL, exists, isDeleted := testStore.GetOriginalURL(ctx, shortURL, "test")
+	assert.Equal(t, "https://example.com", originalURL)
+	assert.True(t, exists)
+	assert.True(t, isDeleted)
+
+	urls, err := testStore.GetUserURLs(ctx, "test")
+	assert.NoError(t, err)
+	assert.Empty(t, urls)
+}
+
+func TestUpgradeTables(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "data.db")
+
+	db, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, `
+    CREATE TABLE short_urls (
+        id TEXT PRIMARY KEY,
+        original_url TEXT NOT NULL UNIQUE,
+        short_url TEXT NOT NULL UNIQUE,
+        user_id TEXT NULL,
+        is_deleted INTEGER NOT NULL DEFAULT 0
+    );
+    INSERT INTO short_urls (id, original_url, short_url, user_id) VALUES ('1', 'https://example.com', 'abc123', 'test');`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	testStore, err := NewSQLiteStore(path, store.NewIDGenerator())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, testStore.Close())
+	})
+
+	originalURL, exists, _ := testStore.GetOriginalURL(ctx, "abc123", "test")
+	assert.True(t, exists, "Записи должны сохраняться при обновлении таблицы")
+	assert.Equal(t, "https://example.com", originalURL)
+
+	_, err = testStore.AddLink(ctx, "https://example.com", store.LinkOptions{Alias: "spring-sale"}, "test")
+	assert.NoError(t, err)
+	assert.Equal(t, expectedIndexes, shortURLsIndexes(t, testStore), "Индексы должны сохраняться при пересоздании таблицы")
+}
+
+func TestUpgradeTablesExpiresAt(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "data.db")
+
+	db, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, `
+    CREATE TABLE short_urls (
+        id TEXT PRIMARY KEY,
+        original_url TEXT NOT NULL,
+        short_url TEXT NOT NULL UNIQUE,
+        user_id TEXT NULL,
+        is_deleted INTEGER NOT NULL DEFAULT 0,
+        is_alias INTEGER NOT NULL DEFAULT 0
+    );
+    CREATE UNIQUE INDEX short_urls_original_url_idx ON short_urls (original_url) WHERE is_alias = 0;
+    INSERT INTO short_urls (id, original_url, short_url, user_id) VALUES ('1', 'https://example.com', 'abc123', 'test');`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	testStore, err := NewSQLiteStore(path, store.NewIDGenerator())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, testStore.Close())
+	})
+
+	_, exists, isDeleted := testStore.GetOriginalURL(ctx, "abc123", "test")
+	assert.True(t, exists, "Записи должны сохраняться при обновлении таблицы")
+	assert.False(t, isDeleted)
+
+	opts := store.LinkOptions{ExpiresAt: time.Now().Add(time.Hour)}
+	shortURL, err := testStore.AddLink(ctx, "https://example.com", opts, "test")
+	require.NoError(t, err, "Истекающая ссылка не должна нарушать уникальность оригинальной ссылки")
+	assert.NotEqual(t, "abc123", shortURL)
+	assert.Equal(t, expectedIndexes, shortURLsIndexes(t, testStore), "Индекс по пользователю должен восстанавливаться")
+}
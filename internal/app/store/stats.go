@@ -0,0 +1,178 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DefaultStatsTopLimit - количество записей в топах источников перехода и клиентов по умолчанию
+const DefaultStatsTopLimit = 10
+
+var (
+	// ErrStatsNotFound ошибка об отсутствии ссылки пользователя для статистики
+	ErrStatsNotFound = errors.New("link not found for user")
+	// ErrInvalidStatsRange ошибка о недопустимом периоде статистики
+	ErrInvalidStatsRange = errors.New("invalid stats range")
+)
+
+// StatsQuery параметры статистики переходов по ссылке
+type StatsQuery struct {
+	// From - начало периода включительно, нулевое значение - без ограничения
+	From time.Time
+	// To - конец периода не включительно, нулевое значение - без ограничения
+	To time.Time
+	// TopLimit - количество записей в топах, 0 - DefaultStatsTopLimit
+	TopLimit int
+}
+
+// ParseStatsQuery разбирает период статистики в формате RFC 3339 или даты 2006-01-02
+func ParseStatsQuery(from string, to string) (StatsQuery, error) {
+	var query StatsQuery
+	var err error
+	if query.From, err = parseStatsTime(from); err != nil {
+		return StatsQuery{}, fmt.Errorf("%w: from %s", ErrInvalidStatsRange, err)
+	}
+	if query.To, err = parseStatsTime(to); err != nil {
+		return StatsQuery{}, fmt.Errorf("%w: to %s", ErrInvalidStatsRange, err)
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && !query.From.Before(query.To) {
+		return StatsQuery{}, fmt.Errorf("%w: from must be before to", ErrInvalidStatsRange)
+	}
+	return query, nil
+}
+
+// parseStatsTime разбирает границу периода, пустая строка соответствует нулевому значению
+func parseStatsTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, errors.New("must be a RFC 3339 time or a date like 2006-01-02")
+	}
+	return t, nil
+}
+
+// Contains сообщает, что момент t попадает в период
+func (q StatsQuery) Contains(t time.Time) bool {
+	return (q.From.IsZero() || !t.Before(q.From)) && (q.To.IsZero() || t.Before(q.To))
+}
+
+// Limit возвращает количество записей в топах
+func (q StatsQuery) Limit() int {
+	if q.TopLimit > 0 {
+		return q.TopLimit
+	}
+	return DefaultStatsTopLimit
+}
+
+// PeriodCount количество переходов за период, начинающийся в Period
+type PeriodCount struct {
+	Period time.Time `json:"period"`
+	Clicks int       `json:"clicks"`
+}
+
+// ValueCount количество переходов для значения
+type ValueCount struct {
+	Value  string `json:"value"`
+	Clicks int    `json:"clicks"`
+}
+
+// LinkStats статистика переходов по ссылке. Периоды по дням и часам считаются в UTC.
+type LinkStats struct {
+	TotalClicks    int           `json:"total_clicks"`
+	UniqueVisitors int           `json:"unique_visitors"`
+	ByDay          []PeriodCount `json:"by_day"`
+	ByHour         []PeriodCount `json:"by_hour"`
+	TopReferrers   []ValueCount  `json:"top_referrers"`
+	TopUserAgents  []ValueCount  `json:"top_user_agents"`
+}
+
+// StatsCollector считает статистику по переходам для хранилищ без агрегирующих запросов
+type StatsCollector struct {
+	query      StatsQuery
+	total      int
+	visitors   map[string]struct{}
+	days       map[time.Time]int
+	hours      map[time.Time]int
+	referrers  map[string]int
+	userAgents map[string]int
+}
+
+// NewStatsCollector создает сборщик статистики для переходов из периода запроса
+func NewStatsCollector(query StatsQuery) *StatsCollector {
+	return &StatsCollector{
+		query:      query,
+		visitors:   make(map[string]struct{}),
+		days:       make(map[time.Time]int),
+		hours:      make(map[time.Time]int),
+		referrers:  make(map[string]int),
+		userAgents: make(map[string]int),
+	}
+}
+
+// Add учитывает переход, если он попадает в период запроса
+func (c *StatsCollector) Add(click Click) {
+	if !c.query.Contains(click.ClickedAt) {
+		return
+	}
+
+	clickedAt := click.ClickedAt.UTC()
+	c.total++
+	c.visitors[click.VisitorID] = struct{}{}
+	c.days[clickedAt.Truncate(24*time.Hour)]++
+	c.hours[clickedAt.Truncate(time.Hour)]++
+	if click.Referrer != "" {
+		c.referrers[click.Referrer]++
+	}
+	if click.UserAgent != "" {
+		c.userAgents[click.UserAgent]++
+	}
+}
+
+// Stats возвращает статистику по учтенным переходам
+func (c *StatsCollector) Stats() LinkStats {
+	return LinkStats{
+		TotalClicks:    c.total,
+		UniqueVisitors: len(c.visitors),
+		ByDay:          sortedPeriods(c.days),
+		ByHour:         sortedPeriods(c.hours),
+		TopReferrers:   topValues(c.referrers, c.query.Limit()),
+		TopUserAgents:  topValues(c.userAgents, c.query.Limit()),
+	}
+}
+
+// sortedPeriods возвращает количество переходов по периодам в порядке их начала
+func sortedPeriods(counts map[time.Time]int) []PeriodCount {
+	periods := make([]PeriodCount, 0, len(counts))
+	for period, clicks := range counts {
+		periods = append(periods, PeriodCount{Period: period, Clicks: clicks})
+	}
+	sort.Slice(periods, func(i, j int) bool {
+		return periods[i].Period.Before(periods[j].Period)
+	})
+	return periods
+}
+
+// topValues возвращает limit значений с наибольшим количеством переходов, при равенстве - по алфавиту
+func topValues(counts map[string]int, limit int) []ValueCount {
+	values := make([]ValueCount, 0, len(counts))
+	for value, clicks := range counts {
+		values = append(values, ValueCount{Value: value, Clicks: clicks})
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if values[i].Clicks != values[j].Clicks {
+			return values[i].Clicks > values[j].Clicks
+		}
+		return values[i].Value < values[j].Value
+	})
+	if len(values) > limit {
+		values = values[:limit]
+	}
+	return values
+}
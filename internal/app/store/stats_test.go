@@ -0,0 +1,76 @@
+package store_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/store"
+)
+
+func TestParseStatsQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    string
+		to      string
+		want    store.StatsQuery
+		wantErr bool
+	}{
+		{name: "Without range"},
+		{
+			name: "RFC 3339",
+			from: "2024-05-01T10:00:00Z",
+			to:   "2024-05-01T12:00:00Z",
+			want: store.StatsQuery{
+				From: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+				To:   time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+			},
+		},
+		{name: "Date", from: "2024-05-01", want: store.StatsQuery{From: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)}},
+		{name: "Invalid from", from: "yesterday", wantErr: true},
+		{name: "Invalid to", to: "2024-13-01", wantErr: true},
+		{name: "From after to", from: "2024-05-02", to: "2024-05-01", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := store.ParseStatsQuery(test.from, test.to)
+			if test.wantErr {
+				assert.ErrorIs(t, err, store.ErrInvalidStatsRange)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestStatsCollector(t *testing.T) {
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	query := store.StatsQuery{From: day, To: day.Add(48 * time.Hour), TopLimit: 1}
+	collector := store.NewStatsCollector(query)
+	for _, click := range []store.Click{
+		{VisitorID: "v1", ClickedAt: day.Add(10*time.Hour + 15*time.Minute), Referrer: "https://a.example.com", UserAgent: "agent"},
+		{VisitorID: "v1", ClickedAt: day.Add(10*time.Hour + 45*time.Minute), Referrer: "https://b.example.com"},
+		{VisitorID: "v2", ClickedAt: day.Add(33 * time.Hour), Referrer: "https://b.example.com"},
+		{VisitorID: "v3", ClickedAt: day.Add(-time.Minute)},
+	} {
+		collector.Add(click)
+	}
+
+	assert.Equal(t, store.LinkStats{
+		TotalClicks:    3,
+		UniqueVisitors: 2,
+		ByDay: []store.PeriodCount{
+			{Period: day, Clicks: 2},
+			{Period: day.Add(24 * time.Hour), Clicks: 1},
+		},
+		ByHour: []store.PeriodCount{
+			{Period: day.Add(10 * time.Hour), Clicks: 2},
+			{Period: day.Add(33 * time.Hour), Clicks: 1},
+		},
+		TopReferrers:  []store.ValueCount{{Value: "https://b.example.com", Clicks: 2}},
+		TopUserAgents: []store.ValueCount{{Value: "agent", Clicks: 1}},
+	}, collector.Stats())
+}
@@ -1,22 +1,17 @@
 // Package store работает с настройками и обработчиками для хранения/извлечения данных.
-// Дополнительно предоставляет генератор случайных значений.
+// Дополнительно предоставляет генераторы коротких ссылок.
 package store
 
 import (
 	"context"
 	"errors"
+	"io"
 	"math/rand"
+	"time"
 
 	"github.com/TimBerk/go-link-shortener/internal/app/models/batch"
 )
 
-const (
-	// возмозжные символы для генерации ссылки
-	chars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
-	// длина ссылки
-	length = 6
-)
-
 // ErrLinkExist ошибка о наличии ссылки для исходного адреса
 var ErrLinkExist = errors.New("short link exist for original url")
 
@@ -26,11 +21,22 @@ type URLPair struct {
 	UserID   string
 }
 
+// UserURL параметры ссылки в списке пользователя
+type UserURL struct {
+	ShortURL    string `json:"short_url"`
+	OriginalURL string `json:"original_url"`
+}
+
 // Store интерфейс для обработки основных методов хранилища данных
 type Store interface {
 	// AddURL генерирует сокращенную ссылку для переданного URL от пользователя
 	AddURL(ctx context.Context, originalURL string, userID string) (string, error)
-	// AddURLs генерирует сокращенные ссылку для переданных URL от пользователя
+	// AddLink сохраняет ссылку с параметрами для URL от пользователя и возвращает ее.
+	// Такие ссылки не участвуют в дедупликации: оригинальная ссылка может иметь несколько ссылок с параметрами.
+	// Если пользовательская ссылка занята, возвращает ErrAliasTaken.
+	AddLink(ctx context.Context, originalURL string, opts LinkOptions, userID string) (string, error)
+	// AddURLs генерирует сокращенные ссылку для переданных URL от пользователя.
+	// Записи с параметрами сохраняются как в AddLink, если хотя бы одна пользовательская ссылка занята, возвращает ErrAliasTaken.
 	AddURLs(ctx context.Context, urls batch.BatchRequest, userID string) (batch.BatchResponse, error)
 	// GetOriginalURL на основании сокращенной ссылки возвращает оригинальную ссылку пользователя
 	GetOriginalURL(ctx context.Context, shortURL string, userID string) (string, bool, bool)
@@ -38,10 +44,115 @@ type Store interface {
 	Ping(ctx context.Context) error
 	// DeleteURL удаляет ссылку пользователя
 	DeleteURL(ctx context.Context, batch []URLPair) error
+	// GetUserURLs возвращает не удаленные ссылки, созданные пользователем, в порядке добавления.
+	// Оригинальная ссылка сокращается один раз для всех пользователей: повторное сокращение
+	// возвращает ErrLinkExist с существующей ссылкой и не добавляет ее в список повторившего пользователя.
+	GetUserURLs(ctx context.Context, userID string) ([]UserURL, error)
+	// DeleteExpiredURLs помечает удаленными до limit ссылок, истекших к моменту now, в порядке истечения
+	// независимо от владельца и возвращает их короткие ссылки
+	DeleteExpiredURLs(ctx context.Context, now time.Time, limit int) ([]string, error)
+	// UseClick атомарно учитывает переход по ссылке, уменьшая оставшееся количество переходов.
+	// Для ссылок без лимита и отсутствующих ссылок возвращает ClickUnlimited.
+	UseClick(ctx context.Context, shortURL string) (ClickResult, error)
+	// GetPasswordHash возвращает хеш пароля ссылки.
+	// Для ссылок без пароля и отсутствующих ссылок возвращает пустую строку.
+	GetPasswordHash(ctx context.Context, shortURL string) (string, error)
+	// GetLinkExpiry возвращает момент истечения ссылки.
+	// Для бессрочных и отсутствующих ссылок возвращает нулевое время.
+	GetLinkExpiry(ctx context.Context, shortURL string) (time.Time, error)
+	// AddClicks сохраняет пачку переходов по ссылкам вместе с владельцами ссылок
+	AddClicks(ctx context.Context, clicks []Click) error
+	// GetLinkStats возвращает статистику переходов по ссылке пользователя за период запроса.
+	// Если ссылка отсутствует или принадлежит другому пользователю, возвращает ErrStatsNotFound.
+	GetLinkStats(ctx context.Context, link URLPair, query StatsQuery) (LinkStats, error)
+	// GetServiceStats возвращает количество действующих ссылок и различных пользователей, которым они принадлежат.
+	// Удаленные, истекшие и исчерпавшие лимит переходов ссылки не учитываются.
+	GetServiceStats(ctx context.Context) (ServiceStats, error)
+	// AddAPIKey сохраняет ключ API пользователя вместе с хешем его значения
+	AddAPIKey(ctx context.Context, key APIKey) error
+	// GetAPIKeys возвращает ключи API пользователя в порядке создания
+	GetAPIKeys(ctx context.Context, userID string) ([]APIKey, error)
+	// RevokeAPIKey удаляет ключ API пользователя.
+	// Если ключ отсутствует или принадлежит другому пользователю, возвращает ErrAPIKeyNotFound.
+	RevokeAPIKey(ctx context.Context, userID string, keyID string) error
+	// UseAPIKey возвращает владельца ключа API по хешу значения и запоминает момент использования usedAt.
+	// Если ключ отсутствует, возвращает ErrAPIKeyNotFound.
+	UseAPIKey(ctx context.Context, keyHash string, usedAt time.Time) (string, error)
+	// CreateAccount сохраняет учетную запись. Если логин занят, возвращает ErrAccountExists.
+	CreateAccount(ctx context.Context, account Account) error
+	// GetAccount возвращает учетную запись по нормализованному логину.
+	// Если учетная запись отсутствует, возвращает ErrAccountNotFound.
+	GetAccount(ctx context.Context, login string) (Account, error)
+	// AddSession сохраняет сессию учетной записи
+	AddSession(ctx context.Context, session Session) error
+	// GetSession возвращает сессию по хешу токена, активную к моменту now.
+	// Если сессия отсутствует или истекла, возвращает ErrSessionNotFound.
+	GetSession(ctx context.Context, tokenHash string, now time.Time) (Session, error)
+	// DeleteSession удаляет сессию по хешу токена, отсутствие сессии не считается ошибкой
+	DeleteSession(ctx context.Context, tokenHash string) error
+	// ClaimURLs передает пользователю toUserID все не удаленные ссылки пользователя fromUserID
+	// и возвращает их количество
+	ClaimURLs(ctx context.Context, fromUserID string, toUserID string) (int, error)
+	// SearchURLs возвращает страницу ссылок всех пользователей, включая удаленные, в порядке коротких ссылок
+	SearchURLs(ctx context.Context, search LinkSearch) ([]Link, error)
+	// DisableURL помечает удаленной ссылку любого пользователя.
+	// Если ссылка отсутствует, возвращает ErrLinkNotFound.
+	DisableURL(ctx context.Context, shortURL string) error
+}
+
+// Close закрывает стор, если он держит ресурсы. Сторы закрываются методом Close с ошибкой,
+// как io.Closer, или без нее, как пул соединений PostgreSQL, который не сообщает об ошибках закрытия.
+func Close(s Store) error {
+	switch closer := s.(type) {
+	case io.Closer:
+		return closer.Close()
+	case interface{ Close() }:
+		closer.Close()
+	}
+	return nil
+}
+
+// ServiceStats статистика сервиса
+type ServiceStats struct {
+	URLs  int `json:"urls"`
+	Users int `json:"users"`
+}
+
+// LinkOptions параметры ссылки, сохраняемой отдельно от других ссылок на тот же адрес
+type LinkOptions struct {
+	// Alias - пользовательская ссылка, при пустом значении ссылка генерируется
+	Alias string
+	// ExpiresAt - момент истечения ссылки, нулевое значение соответствует бессрочной ссылке
+	ExpiresAt time.Time
+	// MaxClicks - количество переходов, после которого ссылка перестает работать, 0 - без ограничения
+	MaxClicks int
+	// PasswordHash - хеш пароля, который нужно ввести перед переходом, пустое значение - ссылка без пароля
+	PasswordHash string
+}
+
+// LinkOptionsFromItem возвращает параметры ссылки из записи пакетного запроса
+func LinkOptionsFromItem(item batch.ItemRequest) LinkOptions {
+	opts := LinkOptions{Alias: item.Alias, MaxClicks: item.MaxClicks, PasswordHash: item.PasswordHash}
+	if item.ExpiresAt != nil {
+		opts.ExpiresAt = *item.ExpiresAt
+	}
+	return opts
+}
+
+// IsZero сообщает, что параметры не заданы и ссылка участвует в дедупликации
+func (o LinkOptions) IsZero() bool {
+	return o.Alias == "" && o.ExpiresAt.IsZero() && o.MaxClicks == 0 && o.PasswordHash == ""
+}
+
+// IsExpired сообщает, что ссылка с моментом истечения expiresAt истекла к моменту now
+func IsExpired(expiresAt time.Time, now time.Time) bool {
+	return !expiresAt.IsZero() && !now.Before(expiresAt)
 }
 
 // IDGenerator генератор ссылок
-type IDGenerator struct{}
+type IDGenerator struct {
+	format *codeFormat
+}
 
 // Generator интерфейс для генерации ссылок
 type Generator interface {
@@ -50,16 +161,27 @@ type Generator interface {
 }
 
 // NewIDGenerator возвращает новый генератор ссылок
-func NewIDGenerator() Generator {
-	return &IDGenerator{}
+func NewIDGenerator(opts ...GeneratorOption) Generator {
+	return &IDGenerator{format: newCodeFormat(opts...)}
 }
 
 // Next генерирует ссылку
 func (g *IDGenerator) Next() string {
-	id := make([]byte, length)
+	symbols := g.format.symbols()
+	id := make([]byte, g.format.currentLength())
 	for i := range id {
-		id[i] = chars[rand.Intn(len(chars))]
+		id[i] = symbols[rand.Intn(len(symbols))]
 	}
 
 	return string(id)
 }
+
+// observe учитывает попытку генерации ссылки для подбора ее длины
+func (g *IDGenerator) observe(_ string, attempt int) {
+	g.format.observe("", attempt)
+}
+
+// restoreLength восстанавливает длину ссылок, увеличенную до перезапуска
+func (g *IDGenerator) restoreLength(length int) {
+	g.format.restore(length)
+}
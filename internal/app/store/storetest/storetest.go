@@ -0,0 +1,981 @@
+// Package storetest содержит набор проверок поведения для реализаций store.Store.
+// Каждая реализация стора запускает его из своих тестов через Run,
+// чтобы подтвердить единые правила дедупликации, пакетного добавления и удаления ссылок.
+//
+// Пример использования:
+//
+//	func TestConformance(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T) store.Store {
+//			s, err := NewMyStore(store.NewIDGenerator())
+//			require.NoError(t, err)
+//			return s
+//		})
+//	}
+package storetest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	models "github.com/TimBerk/go-link-shortener/internal/app/models/batch"
+	"github.com/TimBerk/go-link-shortener/internal/app/store"
+)
+
+const (
+	// concurrency - количество параллельных запросов в проверках конкурентности
+	concurrency = 20
+)
+
+// Factory создает пустой стор для одной проверки.
+// Освобождение ресурсов стора регистрируется фабрикой через t.Cleanup.
+type Factory func(t *testing.T) store.Store
+
+// Run запускает полный набор проверок поведения стора
+func Run(t *testing.T, newStore Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s store.Store)
+	}{
+		{"AddURL returns new short URL", testAddURL},
+		{"AddURL dedupes original URL across users", testAddURLDedupe},
+		{"AddURLs keeps correlation IDs order", testAddURLsCorrelation},
+		{"AddURLs dedupes existing and repeated URLs", testAddURLsDedupe},
+		{"AddLink stores alias", testAddAlias},
+		{"AddLink rejects taken alias", testAddAliasTaken},
+		{"AddLink stores expiring link", testAddLinkExpiring},
+		{"AddURLs stores expiring links", testAddURLsExpiring},
+		{"DeleteExpiredURLs respects limit and expiration order", testDeleteExpiredURLsLimit},
+		{"UseClick counts down click limit", testUseClick},
+		{"Concurrent UseClick never exceeds limit", testConcurrentUseClick},
+		{"GetPasswordHash returns link password", testGetPasswordHash},
+		{"GetLinkExpiry returns link expiration", testGetLinkExpiry},
+		{"AddClicks stores clicks", testAddClicks},
+		{"GetLinkStats aggregates clicks", testGetLinkStats},
+		{"GetServiceStats counts links and users", testGetServiceStats},
+		{"MaxCodeLength skips aliases", testMaxCodeLength},
+		{"GetAPIKeys lists own keys in creation order", testGetAPIKeys},
+		{"RevokeAPIKey checks ownership", testRevokeAPIKey},
+		{"UseAPIKey resolves owner and records usage", testUseAPIKey},
+		{"CreateAccount rejects taken login", testCreateAccount},
+		{"GetSession skips expired and deleted sessions", testSessions},
+		{"ClaimURLs moves active links", testClaimURLs},
+		{"SearchURLs pages links of all users", testSearchURLs},
+		{"DisableURL deletes link of any user", testDisableURL},
+		{"AddURLs stores aliases", testAddURLsAlias},
+		{"AddURLs rejects taken alias", testAddURLsAliasTaken},
+		{"GetOriginalURL unknown short URL", testGetOriginalURLUnknown},
+		{"DeleteURL checks ownership", testDeleteURLOwnership},
+		{"DeleteURL is idempotent", testDeleteURLIdempotent},
+		{"GetUserURLs lists only own links", testGetUserURLs},
+		{"Ping", testPing},
+		{"Concurrent AddURL of same URL", testConcurrentAddURLSame},
+		{"Concurrent AddURL of distinct URLs", testConcurrentAddURLDistinct},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fn(t, newStore(t))
+		})
+	}
+}
+
+// testAddURL проверяет добавление и получение ссылки
+func testAddURL(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	shortURL, err := s.AddURL(ctx, "https://example.com/add", "user-1")
+	require.NoError(t, err)
+	require.NotEmpty(t, shortURL)
+
+	originalURL, exists, isDeleted := s.GetOriginalURL(ctx, shortURL, "user-2")
+	assert.Equal(t, "https://example.com/add", originalURL)
+	assert.True(t, exists, "Добавленная ссылка должна находиться")
+	assert.False(t, isDeleted, "Добавленная ссылка не должна быть удаленной")
+}
+
+// testAddURLDedupe проверяет, что повторное добавление оригинальной ссылки любым пользователем возвращает ErrLinkExist
+func testAddURLDedupe(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	shortURL, err := s.AddURL(ctx, "https://example.com/dedupe", "user-1")
+	require.NoError(t, err)
+
+	sameUserURL, err := s.AddURL(ctx, "https://example.com/dedupe", "user-1")
+	assert.ErrorIs(t, err, store.ErrLinkExist)
+	assert.Equal(t, shortURL, sameUserURL, "Для того же пользователя должна возвращаться существующая ссылка")
+
+	otherUserURL, err := s.AddURL(ctx, "https://example.com/dedupe", "user-2")
+	assert.ErrorIs(t, err, store.ErrLinkExist)
+	assert.Equal(t, shortURL, otherUserURL, "Для другого пользователя должна возвращаться существующая ссылка")
+}
+
+// testAddURLsCorrelation проверяет, что ответ пакетного добавления сохраняет порядок и идентификаторы запроса
+func testAddURLsCorrelation(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	request := models.BatchRequest{
+		{CorrelationID: "c-1", OriginalURL: "https://example.com/batch/1"},
+		{CorrelationID: "c-2", OriginalURL: "https://example.com/batch/2"},
+		{CorrelationID: "c-3", OriginalURL: "https://example.com/batch/3"},
+	}
+	responses, err := s.AddURLs(ctx, request, "user-1")
+	require.NoError(t, err)
+	require.Len(t, responses, len(request))
+
+	seen := make(map[string]struct{}, len(responses))
+	for i, response := range responses {
+		assert.Equal(t, request[i].CorrelationID, response.CorrelationID, "Порядок ответа должен совпадать с запросом")
+		assert.NotContains(t, response.ShortURL, "/", "Стор должен возвращать код ссылки, а не полный URL")
+		seen[response.ShortURL] = struct{}{}
+
+		originalURL, exists, _ := s.GetOriginalURL(ctx, response.ShortURL, "user-1")
+		assert.True(t, exists)
+		assert.Equal(t, request[i].OriginalURL, originalURL)
+	}
+	assert.Len(t, seen, len(request), "Короткие ссылки пачки должны быть уникальными")
+}
+
+// testAddURLsDedupe проверяет, что пакетное добавление возвращает существующие ссылки без ошибки
+func testAddURLsDedupe(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	existURL, err := s.AddURL(ctx, "https://example.com/batch/exist", "user-2")
+	require.NoError(t, err)
+
+	responses, err := s.AddURLs(ctx, models.BatchRequest{
+		{CorrelationID: "c-1", OriginalURL: "https://example.com/batch/exist"},
+		{CorrelationID: "c-2", OriginalURL: "https://example.com/batch/new"},
+		{CorrelationID: "c-3", OriginalURL: "https://example.com/batch/new"},
+	}, "user-1")
+	require.NoError(t, err)
+	require.Len(t, responses, 3)
+
+	assert.Equal(t, existURL, responses[0].ShortURL, "Для существующей ссылки должен возвращаться ее код")
+	assert.Equal(t, responses[1].ShortURL, responses[2].ShortURL, "Повтор ссылки в пачке должен получать тот же код")
+
+	_, err = s.AddURL(ctx, "https://example.com/batch/new", "user-1")
+	assert.ErrorIs(t, err, store.ErrLinkExist, "Ссылки из пачки должны участвовать в дедупликации")
+}
+
+// testAddAlias проверяет, что пользовательская ссылка находится, отображается у пользователя
+// и не мешает дедупликации сгенерированных ссылок
+func testAddAlias(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	shortURL, err := s.AddURL(ctx, "https://example.com/sale", "user-1")
+	require.NoError(t, err)
+	require.NoError(t, addAlias(ctx, s, "https://example.com/sale", "spring-sale", "user-1"))
+	require.NoError(t, addAlias(ctx, s, "https://example.com/sale", "summer-sale", "user-2"))
+
+	originalURL, exists, isDeleted := s.GetOriginalURL(ctx, "spring-sale", "user-2")
+	assert.Equal(t, "https://example.com/sale", originalURL)
+	assert.True(t, exists)
+	assert.False(t, isDeleted)
+
+	existURL, err := s.AddURL(ctx, "https://example.com/sale", "user-2")
+	assert.ErrorIs(t, err, store.ErrLinkExist)
+	assert.Equal(t, shortURL, existURL, "Пользовательская ссылка не должна заменять сгенерированную")
+
+	urls, err := s.GetUserURLs(ctx, "user-1")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []store.UserURL{
+		{ShortURL: shortURL, OriginalURL: "https://example.com/sale"},
+		{ShortURL: "spring-sale", OriginalURL: "https://example.com/sale"},
+	}, urls)
+}
+
+// testAddAliasTaken проверяет, что занятая пользовательская или сгенерированная ссылка не перезаписывается
+func testAddAliasTaken(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	require.NoError(t, addAlias(ctx, s, "https://example.com/first", "promo", "user-1"))
+	assert.ErrorIs(t, addAlias(ctx, s, "https://example.com/second", "promo", "user-2"), store.ErrAliasTaken)
+
+	shortURL, err := s.AddURL(ctx, "https://example.com/generated", "user-1")
+	require.NoError(t, err)
+	assert.ErrorIs(t, addAlias(ctx, s, "https://example.com/second", shortURL, "user-2"), store.ErrAliasTaken)
+
+	originalURL, _, _ := s.GetOriginalURL(ctx, "promo", "user-1")
+	assert.Equal(t, "https://example.com/first", originalURL)
+	originalURL, _, _ = s.GetOriginalURL(ctx, shortURL, "user-1")
+	assert.Equal(t, "https://example.com/generated", originalURL)
+}
+
+// testAddURLsAlias проверяет пакетное добавление пользовательских и сгенерированных ссылок
+func testAddURLsAlias(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	responses, err := s.AddURLs(ctx, models.BatchRequest{
+		{CorrelationID: "c-1", OriginalURL: "https://example.com/batch/alias", Alias: "batch-alias"},
+		{CorrelationID: "c-2", OriginalURL: "https://example.com/batch/generated"},
+	}, "user-1")
+	require.NoError(t, err)
+	require.Len(t, responses, 2)
+
+	assert.Equal(t, models.ItemResponse{CorrelationID: "c-1", ShortURL: "batch-alias"}, responses[0])
+	assert.Equal(t, "c-2", responses[1].CorrelationID)
+	originalURL, exists, _ := s.GetOriginalURL(ctx, "batch-alias", "user-1")
+	assert.True(t, exists)
+	assert.Equal(t, "https://example.com/batch/alias", originalURL)
+}
+
+// testAddURLsAliasTaken проверяет, что пачка с занятой или повторяющейся пользовательской ссылкой не добавляется
+func testAddURLsAliasTaken(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	require.NoError(t, addAlias(ctx, s, "https://example.com/taken", "taken", "user-2"))
+
+	_, err := s.AddURLs(ctx, models.BatchRequest{
+		{CorrelationID: "c-1", OriginalURL: "https://example.com/batch/free", Alias: "free"},
+		{CorrelationID: "c-2", OriginalURL: "https://example.com/batch/taken", Alias: "taken"},
+	}, "user-1")
+	assert.ErrorIs(t, err, store.ErrAliasTaken)
+
+	_, err = s.AddURLs(ctx, models.BatchRequest{
+		{CorrelationID: "c-1", OriginalURL: "https://example.com/batch/1", Alias: "twice"},
+		{CorrelationID: "c-2", OriginalURL: "https://example.com/batch/2", Alias: "twice"},
+	}, "user-1")
+	assert.ErrorIs(t, err, store.ErrAliasTaken)
+
+	_, exists, _ := s.GetOriginalURL(ctx, "free", "user-1")
+	assert.False(t, exists, "Пачка с занятой ссылкой не должна добавляться частично")
+}
+
+// testAddLinkExpiring проверяет, что истекшая ссылка считается удаленной, скрывается из списка пользователя
+// и удаляется очисткой, а истекающие ссылки не участвуют в дедупликации
+func testAddLinkExpiring(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	now := time.Now()
+
+	expiredURL, err := s.AddLink(ctx, "https://example.com/expiring", store.LinkOptions{ExpiresAt: now.Add(-time.Hour)}, "user-1")
+	require.NoError(t, err)
+	activeURL, err := s.AddLink(ctx, "https://example.com/expiring", store.LinkOptions{ExpiresAt: now.Add(time.Hour)}, "user-1")
+	require.NoError(t, err)
+	assert.NotEqual(t, expiredURL, activeURL, "Истекающие ссылки не должны дедуплицироваться")
+
+	originalURL, exists, isDeleted := s.GetOriginalURL(ctx, expiredURL, "user-1")
+	assert.Equal(t, "https://example.com/expiring", originalURL)
+	assert.True(t, exists)
+	assert.True(t, isDeleted, "Истекшая ссылка должна считаться удаленной")
+	_, _, isDeleted = s.GetOriginalURL(ctx, activeURL, "user-1")
+	assert.False(t, isDeleted, "Не истекшая ссылка не должна считаться удаленной")
+
+	shortURL, err := s.AddURL(ctx, "https://example.com/expiring", "user-1")
+	require.NoError(t, err, "Истекающая ссылка не должна заменять бессрочную")
+	assert.NotEqual(t, activeURL, shortURL)
+
+	urls, err := s.GetUserURLs(ctx, "user-1")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []store.UserURL{
+		{ShortURL: activeURL, OriginalURL: "https://example.com/expiring"},
+		{ShortURL: shortURL, OriginalURL: "https://example.com/expiring"},
+	}, urls, "Список не должен содержать истекшие ссылки")
+
+	deleted, err := s.DeleteExpiredURLs(ctx, now, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{expiredURL}, deleted)
+	_, exists, isDeleted = s.GetOriginalURL(ctx, expiredURL, "user-1")
+	assert.True(t, exists)
+	assert.True(t, isDeleted)
+
+	deleted, err = s.DeleteExpiredURLs(ctx, now, 10)
+	require.NoError(t, err)
+	assert.Empty(t, deleted, "Удаленные ссылки не должны возвращаться для очистки")
+}
+
+// testAddURLsExpiring проверяет пакетное добавление истекающих ссылок
+func testAddURLsExpiring(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	expiresAt := time.Now().Add(-time.Minute)
+
+	responses, err := s.AddURLs(ctx, models.BatchRequest{
+		{CorrelationID: "c-1", OriginalURL: "https://example.com/batch/expiring", ExpiresAt: &expiresAt},
+		{CorrelationID: "c-2", OriginalURL: "https://example.com/batch/expiring"},
+		{CorrelationID: "c-3", OriginalURL: "https://example.com/batch/expiring", Alias: "expiring-alias", ExpiresAt: &expiresAt},
+	}, "user-1")
+	require.NoError(t, err)
+	require.Len(t, responses, 3)
+	assert.NotEqual(t, responses[0].ShortURL, responses[1].ShortURL)
+	assert.Equal(t, "expiring-alias", responses[2].ShortURL)
+
+	for i, wantDeleted := range []bool{true, false, true} {
+		_, exists, isDeleted := s.GetOriginalURL(ctx, responses[i].ShortURL, "user-1")
+		assert.True(t, exists)
+		assert.Equal(t, wantDeleted, isDeleted, responses[i].CorrelationID)
+	}
+}
+
+// testDeleteExpiredURLsLimit проверяет, что очистка удаляет не больше limit ссылок, начиная с истекших раньше
+func testDeleteExpiredURLsLimit(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	now := time.Now()
+
+	shortURLs := make([]string, 3)
+	for i := range shortURLs {
+		opts := store.LinkOptions{ExpiresAt: now.Add(-time.Duration(i+1) * time.Minute)}
+		shortURL, err := s.AddLink(ctx, fmt.Sprintf("https://example.com/expired/%d", i), opts, "user-1")
+		require.NoError(t, err)
+		shortURLs[i] = shortURL
+	}
+
+	deleted, err := s.DeleteExpiredURLs(ctx, now.Add(-time.Hour), 10)
+	require.NoError(t, err)
+	assert.Empty(t, deleted, "Ссылки, истекающие позже момента очистки, не должны удаляться")
+
+	deleted, err = s.DeleteExpiredURLs(ctx, now, 2)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{shortURLs[2], shortURLs[1]}, deleted, "Первыми должны удаляться ссылки, истекшие раньше")
+
+	deleted, err = s.DeleteExpiredURLs(ctx, now, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{shortURLs[0]}, deleted)
+}
+
+// testUseClick проверяет учет переходов по ссылкам с лимитом, без лимита и по отсутствующей ссылке
+func testUseClick(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	limitedURL, err := s.AddLink(ctx, "https://example.com/limited", store.LinkOptions{MaxClicks: 2}, "user-1")
+	require.NoError(t, err)
+	responses, err := s.AddURLs(ctx, models.BatchRequest{
+		{CorrelationID: "c-1", OriginalURL: "https://example.com/limited", MaxClicks: 1},
+	}, "user-1")
+	require.NoError(t, err)
+	oneTimeURL := responses[0].ShortURL
+	assert.NotEqual(t, limitedURL, oneTimeURL, "Ссылки с лимитом не должны дедуплицироваться")
+
+	for i, want := range []store.ClickResult{store.ClickAllowed, store.ClickAllowed, store.ClickExhausted, store.ClickExhausted} {
+		_, _, isDeleted := s.GetOriginalURL(ctx, limitedURL, "user-1")
+		assert.Equal(t, i >= 2, isDeleted, "Ссылка должна перестать работать после исчерпания лимита")
+
+		result, err := s.UseClick(ctx, limitedURL)
+		require.NoError(t, err)
+		assert.Equal(t, want, result, "Переход %d", i+1)
+	}
+
+	result, err := s.UseClick(ctx, oneTimeURL)
+	require.NoError(t, err)
+	assert.Equal(t, store.ClickAllowed, result)
+
+	urls, err := s.GetUserURLs(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Empty(t, urls, "Список не должен содержать ссылки с исчерпанным лимитом")
+
+	unlimitedURL, err := s.AddURL(ctx, "https://example.com/limited", "user-1")
+	require.NoError(t, err, "Ссылка с лимитом не должна заменять ссылку без лимита")
+	result, err = s.UseClick(ctx, unlimitedURL)
+	require.NoError(t, err)
+	assert.Equal(t, store.ClickUnlimited, result)
+
+	result, err = s.UseClick(ctx, "missing")
+	require.NoError(t, err)
+	assert.Equal(t, store.ClickUnlimited, result)
+}
+
+// testConcurrentUseClick проверяет, что параллельные переходы не превышают лимит
+func testConcurrentUseClick(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	const maxClicks = 5
+
+	shortURL, err := s.AddLink(ctx, "https://example.com/concurrent/clicks", store.LinkOptions{MaxClicks: maxClicks}, "user-1")
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	results := make([]store.ClickResult, concurrency)
+	errs := make([]error, concurrency)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = s.UseClick(ctx, shortURL)
+		}(i)
+	}
+	wg.Wait()
+
+	allowed := 0
+	for i := 0; i < concurrency; i++ {
+		require.NoError(t, errs[i])
+		if results[i] == store.ClickAllowed {
+			allowed++
+		} else {
+			assert.Equal(t, store.ClickExhausted, results[i])
+		}
+	}
+	assert.Equal(t, maxClicks, allowed, "Переходов должно быть учтено ровно столько, сколько разрешено")
+}
+
+// testGetPasswordHash проверяет хранение хеша пароля ссылки
+func testGetPasswordHash(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	protectedURL, err := s.AddLink(ctx, "https://example.com/secret", store.LinkOptions{PasswordHash: "hash-1"}, "user-1")
+	require.NoError(t, err)
+	responses, err := s.AddURLs(ctx, models.BatchRequest{
+		{CorrelationID: "c-1", OriginalURL: "https://example.com/secret", PasswordHash: "hash-2"},
+		{CorrelationID: "c-2", OriginalURL: "https://example.com/secret"},
+	}, "user-1")
+	require.NoError(t, err)
+	assert.NotEqual(t, protectedURL, responses[0].ShortURL, "Ссылки с паролем не должны дедуплицироваться")
+	assert.NotEqual(t, protectedURL, responses[1].ShortURL, "Ссылка с паролем не должна заменять ссылку без пароля")
+
+	tests := []struct {
+		shortURL string
+		want     string
+	}{
+		{shortURL: protectedURL, want: "hash-1"},
+		{shortURL: responses[0].ShortURL, want: "hash-2"},
+		{shortURL: responses[1].ShortURL, want: ""},
+		{shortURL: "missing", want: ""},
+	}
+	for _, test := range tests {
+		passwordHash, err := s.GetPasswordHash(ctx, test.shortURL)
+		require.NoError(t, err)
+		assert.Equal(t, test.want, passwordHash, "Хеш пароля ссылки %s", test.shortURL)
+	}
+
+	originalURL, exists, isDeleted := s.GetOriginalURL(ctx, protectedURL, "user-1")
+	assert.Equal(t, "https://example.com/secret", originalURL)
+	assert.True(t, exists)
+	assert.False(t, isDeleted)
+}
+
+// testGetLinkExpiry проверяет получение момента истечения ссылки
+func testGetLinkExpiry(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	expiringURL, err := s.AddLink(ctx, "https://example.com/expiry", store.LinkOptions{ExpiresAt: expiresAt}, "user-1")
+	require.NoError(t, err)
+	permanentURL, err := s.AddURL(ctx, "https://example.com/expiry", "user-1")
+	require.NoError(t, err)
+
+	tests := []struct {
+		shortURL string
+		want     time.Time
+	}{
+		{shortURL: expiringURL, want: expiresAt},
+		{shortURL: permanentURL},
+		{shortURL: "missing"},
+	}
+	for _, test := range tests {
+		got, err := s.GetLinkExpiry(ctx, test.shortURL)
+		require.NoError(t, err)
+		assert.True(t, test.want.Equal(got), "Момент истечения ссылки %s: ожидался %v, получен %v", test.shortURL, test.want, got)
+	}
+}
+
+// testAddClicks проверяет сохранение пачки переходов, в том числе по отсутствующей ссылке
+func testAddClicks(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	shortURL, err := s.AddURL(ctx, "https://example.com/clicks", "user-1")
+	require.NoError(t, err)
+
+	now := time.Now()
+	clicks := []store.Click{
+		{ShortURL: shortURL, VisitorID: "visitor-1", ClickedAt: now, Referrer: "https://ref.example.com", UserAgent: "test-agent", IP: "192.0.2.1"},
+		{ShortURL: shortURL, VisitorID: "visitor-2", ClickedAt: now.Add(time.Second)},
+		{ShortURL: "missing", ClickedAt: now},
+	}
+	require.NoError(t, s.AddClicks(ctx, clicks))
+	require.NoError(t, s.AddClicks(ctx, nil), "Пустая пачка не должна приводить к ошибке")
+}
+
+// testGetLinkStats проверяет статистику переходов по ссылке за период и проверку владельца
+func testGetLinkStats(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	shortURL, err := s.AddURL(ctx, "https://example.com/stats", "user-1")
+	require.NoError(t, err)
+	otherURL, err := s.AddURL(ctx, "https://example.com/other", "user-1")
+	require.NoError(t, err)
+
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, s.AddClicks(ctx, []store.Click{
+		{ShortURL: shortURL, VisitorID: "v1", ClickedAt: day.Add(10*time.Hour + 15*time.Minute), Referrer: "https://a.example.com", UserAgent: "agent-1"},
+		{ShortURL: shortURL, VisitorID: "v1", ClickedAt: day.Add(10*time.Hour + 45*time.Minute), Referrer: "https://b.example.com", UserAgent: "agent-1"},
+		{ShortURL: shortURL, VisitorID: "v2", ClickedAt: day.Add(33 * time.Hour), Referrer: "https://b.example.com", UserAgent: "agent-2"},
+		{ShortURL: otherURL, VisitorID: "v3", ClickedAt: day.Add(11 * time.Hour)},
+	}))
+
+	stats, err := s.GetLinkStats(ctx, store.URLPair{ShortURL: shortURL, UserID: "user-1"}, store.StatsQuery{})
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats.TotalClicks)
+	assert.Equal(t, 2, stats.UniqueVisitors)
+	assert.Equal(t, []store.PeriodCount{
+		{Period: day, Clicks: 2},
+		{Period: day.Add(24 * time.Hour), Clicks: 1},
+	}, stats.ByDay)
+	assert.Equal(t, []store.PeriodCount{
+		{Period: day.Add(10 * time.Hour), Clicks: 2},
+		{Period: day.Add(33 * time.Hour), Clicks: 1},
+	}, stats.ByHour)
+	assert.Equal(t, []store.ValueCount{
+		{Value: "https://b.example.com", Clicks: 2},
+		{Value: "https://a.example.com", Clicks: 1},
+	}, stats.TopReferrers)
+	assert.Equal(t, []store.ValueCount{
+		{Value: "agent-1", Clicks: 2},
+		{Value: "agent-2", Clicks: 1},
+	}, stats.TopUserAgents)
+
+	query := store.StatsQuery{From: day.Add(10*time.Hour + 30*time.Minute), To: day.Add(24 * time.Hour), TopLimit: 1}
+	stats, err = s.GetLinkStats(ctx, store.URLPair{ShortURL: shortURL, UserID: "user-1"}, query)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.TotalClicks, "Статистика должна учитывать только переходы за период")
+	assert.Equal(t, []store.ValueCount{{Value: "https://b.example.com", Clicks: 1}}, stats.TopReferrers)
+
+	_, err = s.GetLinkStats(ctx, store.URLPair{ShortURL: shortURL, UserID: "user-2"}, store.StatsQuery{})
+	assert.ErrorIs(t, err, store.ErrStatsNotFound, "Статистика чужой ссылки не должна быть доступна")
+	_, err = s.GetLinkStats(ctx, store.URLPair{ShortURL: "missing", UserID: "user-1"}, store.StatsQuery{})
+	assert.ErrorIs(t, err, store.ErrStatsNotFound)
+
+	emptyURL, err := s.AddURL(ctx, "https://example.com/empty", "user-1")
+	require.NoError(t, err)
+	stats, err = s.GetLinkStats(ctx, store.URLPair{ShortURL: emptyURL, UserID: "user-1"}, store.StatsQuery{})
+	require.NoError(t, err)
+	assert.Zero(t, stats.TotalClicks)
+	assert.Empty(t, stats.ByDay)
+}
+
+// testGetServiceStats проверяет подсчет действующих ссылок и их владельцев
+func testGetServiceStats(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	stats, err := s.GetServiceStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, store.ServiceStats{}, stats)
+
+	_, err = s.AddURL(ctx, "https://example.com/1", "user-1")
+	require.NoError(t, err)
+	_, err = s.AddLink(ctx, "https://example.com/1", store.LinkOptions{Alias: "first-alias"}, "user-1")
+	require.NoError(t, err)
+	_, err = s.AddURL(ctx, "https://example.com/2", "user-2")
+	require.NoError(t, err)
+	deletedURL, err := s.AddURL(ctx, "https://example.com/3", "user-3")
+	require.NoError(t, err)
+	require.NoError(t, s.DeleteURL(ctx, []store.URLPair{{ShortURL: deletedURL, UserID: "user-3"}}))
+	expiringURL, err := s.AddLink(ctx, "https://example.com/4", store.LinkOptions{ExpiresAt: time.Now().Add(50 * time.Millisecond)}, "user-4")
+	require.NoError(t, err)
+	limitedURL, err := s.AddLink(ctx, "https://example.com/5", store.LinkOptions{MaxClicks: 1}, "user-5")
+	require.NoError(t, err)
+
+	stats, err = s.GetServiceStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, store.ServiceStats{URLs: 5, Users: 4}, stats, "Действующие ссылки с ограничениями должны учитываться")
+
+	result, err := s.UseClick(ctx, limitedURL)
+	require.NoError(t, err)
+	require.Equal(t, store.ClickAllowed, result)
+	require.Eventually(t, func() bool {
+		expiresAt, err := s.GetLinkExpiry(ctx, expiringURL)
+		return err == nil && time.Now().After(expiresAt)
+	}, time.Second, 10*time.Millisecond)
+
+	stats, err = s.GetServiceStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, store.ServiceStats{URLs: 3, Users: 2}, stats,
+		"Удаленные, истекшие и исчерпавшие лимит переходов ссылки и их владельцы не должны учитываться")
+}
+
+// addAPIKey создает и сохраняет ключ API пользователя
+func addAPIKey(t *testing.T, s store.Store, userID string, name string, now time.Time) (store.APIKey, string) {
+	key, value, err := store.NewAPIKey(userID, name, now)
+	require.NoError(t, err)
+	require.NoError(t, s.AddAPIKey(context.Background(), key))
+	return key, value
+}
+
+// testGetAPIKeys проверяет список ключей API пользователя
+func testGetAPIKeys(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	now := time.Now()
+
+	keys, err := s.GetAPIKeys(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	second, _ := addAPIKey(t, s, "user-1", "second", now.Add(time.Second))
+	first, _ := addAPIKey(t, s, "user-1", "first", now)
+	addAPIKey(t, s, "user-2", "other", now)
+
+	keys, err = s.GetAPIKeys(ctx, "user-1")
+	require.NoError(t, err)
+	require.Len(t, keys, 2, "Должны возвращаться только ключи пользователя")
+	assert.Equal(t, []string{first.ID, second.ID}, []string{keys[0].ID, keys[1].ID}, "Ключи должны идти в порядке создания")
+	assert.Equal(t, "first", keys[0].Name)
+	assert.Equal(t, first.Prefix, keys[0].Prefix)
+	assert.Equal(t, first.Hash, keys[0].Hash)
+	assert.True(t, first.CreatedAt.Equal(keys[0].CreatedAt))
+	assert.Nil(t, keys[0].LastUsedAt)
+}
+
+// testRevokeAPIKey проверяет, что отозвать ключ API может только его владелец
+func testRevokeAPIKey(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	key, value := addAPIKey(t, s, "user-1", "ci", time.Now())
+
+	assert.ErrorIs(t, s.RevokeAPIKey(ctx, "user-2", key.ID), store.ErrAPIKeyNotFound, "Чужой ключ не должен отзываться")
+	assert.ErrorIs(t, s.RevokeAPIKey(ctx, "user-1", "missing"), store.ErrAPIKeyNotFound)
+
+	require.NoError(t, s.RevokeAPIKey(ctx, "user-1", key.ID))
+	keys, err := s.GetAPIKeys(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	_, err = s.UseAPIKey(ctx, store.HashAPIKey(value), time.Now())
+	assert.ErrorIs(t, err, store.ErrAPIKeyNotFound, "Отозванный ключ не должен приниматься")
+	assert.ErrorIs(t, s.RevokeAPIKey(ctx, "user-1", key.ID), store.ErrAPIKeyNotFound)
+}
+
+// testUseAPIKey проверяет поиск владельца ключа API и сохранение момента использования
+func testUseAPIKey(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	_, value := addAPIKey(t, s, "user-1", "ci", time.Now())
+
+	_, err := s.UseAPIKey(ctx, store.HashAPIKey("sk_unknown"), time.Now())
+	assert.ErrorIs(t, err, store.ErrAPIKeyNotFound)
+
+	usedAt := time.Now().UTC().Truncate(time.Microsecond)
+	userID, err := s.UseAPIKey(ctx, store.HashAPIKey(value), usedAt)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", userID)
+
+	keys, err := s.GetAPIKeys(ctx, "user-1")
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	require.NotNil(t, keys[0].LastUsedAt, "Момент использования ключа должен сохраняться")
+	assert.True(t, usedAt.Equal(*keys[0].LastUsedAt))
+}
+
+// testCreateAccount проверяет создание и поиск учетной записи
+func testCreateAccount(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	_, err := s.GetAccount(ctx, "alice")
+	assert.ErrorIs(t, err, store.ErrAccountNotFound)
+
+	account, err := store.NewAccount("Alice", "password-1", time.Now())
+	require.NoError(t, err)
+	require.NoError(t, s.CreateAccount(ctx, account))
+
+	other, err := store.NewAccount("alice", "password-2", time.Now())
+	require.NoError(t, err)
+	assert.ErrorIs(t, s.CreateAccount(ctx, other), store.ErrAccountExists, "Логин должен быть уникальным")
+
+	found, err := s.GetAccount(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, account.ID, found.ID)
+	assert.Equal(t, "alice", found.Login)
+	assert.True(t, store.CheckPassword(found.PasswordHash, "password-1"))
+	assert.True(t, account.CreatedAt.Equal(found.CreatedAt))
+}
+
+// testSessions проверяет поиск, истечение и удаление сессий
+func testSessions(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	now := time.Now()
+
+	session, token, err := store.NewSession("user-1", now, time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, s.AddSession(ctx, session))
+	expired, expiredToken, err := store.NewSession("user-2", now.Add(-2*time.Hour), time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, s.AddSession(ctx, expired))
+
+	found, err := s.GetSession(ctx, store.HashSessionToken(token), now)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", found.UserID)
+	assert.True(t, session.ExpiresAt.Equal(found.ExpiresAt))
+
+	_, err = s.GetSession(ctx, store.HashSessionToken(token), now.Add(2*time.Hour))
+	assert.ErrorIs(t, err, store.ErrSessionNotFound, "Истекшая сессия не должна находиться")
+	_, err = s.GetSession(ctx, store.HashSessionToken(expiredToken), now)
+	assert.ErrorIs(t, err, store.ErrSessionNotFound, "Истекшая сессия не должна находиться")
+
+	require.NoError(t, s.DeleteSession(ctx, store.HashSessionToken(token)))
+	_, err = s.GetSession(ctx, store.HashSessionToken(token), now)
+	assert.ErrorIs(t, err, store.ErrSessionNotFound)
+	assert.NoError(t, s.DeleteSession(ctx, store.HashSessionToken(token)), "Повторное удаление сессии не должно быть ошибкой")
+}
+
+// testClaimURLs проверяет передачу не удаленных ссылок анонимного пользователя учетной записи
+func testClaimURLs(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	ownURL, err := s.AddURL(ctx, "https://example.com/own", "account")
+	require.NoError(t, err)
+	firstURL, err := s.AddURL(ctx, "https://example.com/1", "anonymous")
+	require.NoError(t, err)
+	require.NoError(t, addAlias(ctx, s, "https://example.com/2", "claimed-alias", "anonymous"))
+	deletedURL, err := s.AddURL(ctx, "https://example.com/3", "anonymous")
+	require.NoError(t, err)
+	require.NoError(t, s.DeleteURL(ctx, []store.URLPair{{ShortURL: deletedURL, UserID: "anonymous"}}))
+	otherURL, err := s.AddURL(ctx, "https://example.com/other", "other")
+	require.NoError(t, err)
+
+	claimed, err := s.ClaimURLs(ctx, "anonymous", "account")
+	require.NoError(t, err)
+	assert.Equal(t, 2, claimed, "Удаленные ссылки не должны передаваться")
+
+	urls, err := s.GetUserURLs(ctx, "account")
+	require.NoError(t, err)
+	assert.Equal(t, []store.UserURL{
+		{ShortURL: ownURL, OriginalURL: "https://example.com/own"},
+		{ShortURL: firstURL, OriginalURL: "https://example.com/1"},
+		{ShortURL: "claimed-alias", OriginalURL: "https://example.com/2"},
+	}, urls)
+	urls, err = s.GetUserURLs(ctx, "anonymous")
+	require.NoError(t, err)
+	assert.Empty(t, urls)
+
+	_, err = s.GetLinkStats(ctx, store.URLPair{ShortURL: firstURL, UserID: "account"}, store.StatsQuery{})
+	assert.NoError(t, err, "Статистика переданной ссылки должна быть доступна новому владельцу")
+	require.NoError(t, s.DeleteURL(ctx, []store.URLPair{{ShortURL: firstURL, UserID: "account"}}))
+	_, _, isDeleted := s.GetOriginalURL(ctx, firstURL, "account")
+	assert.True(t, isDeleted, "Новый владелец должен удалять переданные ссылки")
+	_, _, isDeleted = s.GetOriginalURL(ctx, otherURL, "other")
+	assert.False(t, isDeleted)
+
+	claimed, err = s.ClaimURLs(ctx, "anonymous", "account")
+	require.NoError(t, err)
+	assert.Zero(t, claimed, "Повторная передача не должна находить ссылок")
+	claimed, err = s.ClaimURLs(ctx, "account", "account")
+	require.NoError(t, err)
+	assert.Zero(t, claimed)
+}
+
+func testSearchURLs(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	require.NoError(t, addAlias(ctx, s, "https://example.com/Docs", "search-b", "alice"))
+	require.NoError(t, addAlias(ctx, s, "https://example.org/blog", "search-a", "bob"))
+	require.NoError(t, addAlias(ctx, s, "https://example.com/news", "search-c", "bob"))
+	require.NoError(t, s.DeleteURL(ctx, []store.URLPair{{ShortURL: "search-c", UserID: "bob"}}))
+
+	links, err := s.SearchURLs(ctx, store.LinkSearch{Limit: store.DefaultSearchLimit})
+	require.NoError(t, err)
+	assert.Equal(t, []store.Link{
+		{ShortURL: "search-a", OriginalURL: "https://example.org/blog", UserID: "bob"},
+		{ShortURL: "search-b", OriginalURL: "https://example.com/Docs", UserID: "alice"},
+		{ShortURL: "search-c", OriginalURL: "https://example.com/news", UserID: "bob", IsDeleted: true},
+	}, links, "Поиск без запроса должен возвращать все ссылки, включая удаленные")
+
+	tests := []struct {
+		name     string
+		search   store.LinkSearch
+		expected []string
+	}{
+		{name: "Original URL ignoring case", search: store.LinkSearch{Query: "EXAMPLE.COM", Limit: 10}, expected: []string{"search-b", "search-c"}},
+		{name: "Short URL", search: store.LinkSearch{Query: "search-a", Limit: 10}, expected: []string{"search-a"}},
+		{name: "Page", search: store.LinkSearch{Limit: 1, Offset: 1}, expected: []string{"search-b"}},
+		{name: "Offset after last link", search: store.LinkSearch{Limit: 10, Offset: 3}, expected: []string{}},
+		{name: "No matches", search: store.LinkSearch{Query: "missing", Limit: 10}, expected: []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			links, err := s.SearchURLs(ctx, test.search)
+			require.NoError(t, err)
+			shortURLs := make([]string, 0, len(links))
+			for _, link := range links {
+				shortURLs = append(shortURLs, link.ShortURL)
+			}
+			assert.Equal(t, test.expected, shortURLs)
+		})
+	}
+}
+
+func testDisableURL(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	shortURL, err := s.AddURL(ctx, "https://example.com/spam", "spammer")
+	require.NoError(t, err)
+	otherURL, err := s.AddURL(ctx, "https://example.com/ok", "spammer")
+	require.NoError(t, err)
+
+	require.NoError(t, s.DisableURL(ctx, shortURL))
+	_, exists, isDeleted := s.GetOriginalURL(ctx, shortURL, "")
+	assert.True(t, exists)
+	assert.True(t, isDeleted, "Отключенная ссылка должна считаться удаленной")
+	urls, err := s.GetUserURLs(ctx, "spammer")
+	require.NoError(t, err)
+	assert.Equal(t, []store.UserURL{{ShortURL: otherURL, OriginalURL: "https://example.com/ok"}}, urls)
+
+	assert.NoError(t, s.DisableURL(ctx, shortURL), "Повторное отключение не должно быть ошибкой")
+	assert.ErrorIs(t, s.DisableURL(ctx, "missing-link"), store.ErrLinkNotFound)
+}
+
+// testMaxCodeLength проверяет, что длина ссылок считается только по сгенерированным ссылкам
+func testMaxCodeLength(t *testing.T, s store.Store) {
+	lengthStore, ok := s.(store.CodeLengthStore)
+	if !ok {
+		t.Skip("Стор не хранит ссылки между запусками")
+	}
+	ctx := context.Background()
+
+	length, err := lengthStore.MaxCodeLength(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, length, "Пустой стор не должен влиять на длину ссылок")
+
+	shortURL, err := s.AddURL(ctx, "https://example.com/length", "user-1")
+	require.NoError(t, err)
+	require.NoError(t, addAlias(ctx, s, "https://example.com/length", "a-very-long-custom-alias", "user-1"))
+
+	length, err = lengthStore.MaxCodeLength(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, len(shortURL), length)
+}
+
+// addAlias добавляет пользовательскую ссылку без срока действия
+func addAlias(ctx context.Context, s store.Store, originalURL string, alias string, userID string) error {
+	_, err := s.AddLink(ctx, originalURL, store.LinkOptions{Alias: alias}, userID)
+	return err
+}
+
+// testGetOriginalURLUnknown проверяет поиск отсутствующей ссылки
+func testGetOriginalURLUnknown(t *testing.T, s store.Store) {
+	originalURL, exists, isDeleted := s.GetOriginalURL(context.Background(), "missing", "user-1")
+	assert.Empty(t, originalURL)
+	assert.False(t, exists)
+	assert.False(t, isDeleted)
+}
+
+// testDeleteURLOwnership проверяет, что удалить ссылку может только ее владелец
+func testDeleteURLOwnership(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	shortURL, err := s.AddURL(ctx, "https://example.com/delete", "user-1")
+	require.NoError(t, err)
+
+	require.NoError(t, s.DeleteURL(ctx, []store.URLPair{{ShortURL: shortURL, UserID: "user-2"}}))
+	_, exists, isDeleted := s.GetOriginalURL(ctx, shortURL, "user-1")
+	assert.True(t, exists)
+	assert.False(t, isDeleted, "Чужая ссылка не должна удаляться")
+
+	require.NoError(t, s.DeleteURL(ctx, []store.URLPair{{ShortURL: shortURL, UserID: "user-1"}}))
+	originalURL, exists, isDeleted := s.GetOriginalURL(ctx, shortURL, "user-1")
+	assert.Equal(t, "https://example.com/delete", originalURL, "Удаленная ссылка должна сохранять оригинальный адрес")
+	assert.True(t, exists, "Удаленная ссылка должна находиться")
+	assert.True(t, isDeleted, "Ссылка владельца должна помечаться удаленной")
+}
+
+// testDeleteURLIdempotent проверяет повторное удаление, удаление пустой пачки и неизвестной ссылки
+func testDeleteURLIdempotent(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	shortURL, err := s.AddURL(ctx, "https://example.com/delete/twice", "user-1")
+	require.NoError(t, err)
+
+	pairs := []store.URLPair{{ShortURL: shortURL, UserID: "user-1"}, {ShortURL: "missing", UserID: "user-1"}}
+	assert.NoError(t, s.DeleteURL(ctx, pairs))
+	assert.NoError(t, s.DeleteURL(ctx, pairs))
+	assert.NoError(t, s.DeleteURL(ctx, nil))
+
+	_, _, isDeleted := s.GetOriginalURL(ctx, shortURL, "user-1")
+	assert.True(t, isDeleted)
+}
+
+// testGetUserURLs проверяет список ссылок пользователя
+func testGetUserURLs(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	urls, err := s.GetUserURLs(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Empty(t, urls, "У нового пользователя не должно быть ссылок")
+
+	firstURL, err := s.AddURL(ctx, "https://example.com/user/1", "user-1")
+	require.NoError(t, err)
+	responses, err := s.AddURLs(ctx, models.BatchRequest{
+		{CorrelationID: "c-1", OriginalURL: "https://example.com/user/2"},
+	}, "user-1")
+	require.NoError(t, err)
+	deletedURL, err := s.AddURL(ctx, "https://example.com/user/3", "user-1")
+	require.NoError(t, err)
+	otherURL, err := s.AddURL(ctx, "https://example.com/user/other", "user-2")
+	require.NoError(t, err)
+	require.NoError(t, s.DeleteURL(ctx, []store.URLPair{{ShortURL: deletedURL, UserID: "user-1"}}))
+
+	urls, err = s.GetUserURLs(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, []store.UserURL{
+		{ShortURL: firstURL, OriginalURL: "https://example.com/user/1"},
+		{ShortURL: responses[0].ShortURL, OriginalURL: "https://example.com/user/2"},
+	}, urls, "Список должен содержать только не удаленные ссылки пользователя в порядке добавления")
+
+	existURL, err := s.AddURL(ctx, "https://example.com/user/1", "user-2")
+	require.ErrorIs(t, err, store.ErrLinkExist)
+	assert.Equal(t, firstURL, existURL)
+
+	urls, err = s.GetUserURLs(ctx, "user-2")
+	require.NoError(t, err)
+	assert.Equal(t, []store.UserURL{{ShortURL: otherURL, OriginalURL: "https://example.com/user/other"}}, urls,
+		"Повторное сокращение чужой ссылки не должно добавлять ее в список пользователя")
+}
+
+// testPing проверяет доступность стора
+func testPing(t *testing.T, s store.Store) {
+	assert.NoError(t, s.Ping(context.Background()))
+}
+
+// testConcurrentAddURLSame проверяет, что параллельное добавление одной ссылки создает ровно одну запись
+func testConcurrentAddURLSame(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	results := make([]string, concurrency)
+	errs := make([]error, concurrency)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = s.AddURL(ctx, "https://example.com/concurrent", fmt.Sprintf("user-%d", i))
+		}(i)
+	}
+	wg.Wait()
+
+	created := 0
+	for i := 0; i < concurrency; i++ {
+		if errs[i] == nil {
+			created++
+		} else {
+			assert.ErrorIs(t, errs[i], store.ErrLinkExist)
+		}
+		assert.Equal(t, results[0], results[i], "Все запросы должны получить одну и ту же ссылку")
+	}
+	assert.Equal(t, 1, created, "Ссылка должна создаваться ровно один раз")
+}
+
+// testConcurrentAddURLDistinct проверяет, что параллельное добавление разных ссылок выдает уникальные коды
+func testConcurrentAddURLDistinct(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	results := make([]string, concurrency)
+	errs := make([]error, concurrency)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = s.AddURL(ctx, fmt.Sprintf("https://example.com/concurrent/%d", i), "user-1")
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[string]struct{}, concurrency)
+	for i := 0; i < concurrency; i++ {
+		require.NoError(t, errs[i])
+		seen[results[i]] = struct{}{}
+	}
+	assert.Len(t, seen, concurrency, "Разные ссылки должны получать разные коды")
+
+	urls, err := s.GetUserURLs(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Len(t, urls, concurrency)
+}
@@ -14,6 +14,10 @@ import (
 const (
 	// batchLimit - лимит пачки для удаления записей
 	batchLimit = 100
+	// clickBatchLimit - лимит пачки для сохранения переходов
+	clickBatchLimit = 500
+	// flushInterval - интервал сохранения неполной пачки
+	flushInterval = 5 * time.Second
 )
 
 // Worker в фоне получает пачку записей, где сущность представляет идентификатор и короткую ссылку пользователя.
@@ -23,7 +27,7 @@ func Worker(ctx context.Context, dataStore store.Store, urlChan <-chan store.URL
 	defer wg.Done()
 
 	var batch []store.URLPair
-	ticker := time.NewTicker(5 * time.Second)
+	ticker := time.NewTicker(flushInterval)
 	defer ticker.Stop()
 
 	for {
@@ -57,10 +61,77 @@ func Worker(ctx context.Context, dataStore store.Store, urlChan <-chan store.URL
 	}
 }
 
+// ClickWorker в фоне получает переходы по ссылкам и копит их в пачку, когда она достигает clickBatchLimit
+// или проходит flushInterval, переходы сохраняются в flushClicks. Воркер завершается после закрытия канала,
+// сохранив оставшиеся переходы.
+func ClickWorker(ctx context.Context, dataStore store.Store, clickChan <-chan store.Click, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	var batch []store.Click
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case click, ok := <-clickChan:
+			if !ok {
+				flushClicks(ctx, batch, dataStore)
+				return
+			}
+			batch = append(batch, click)
+			if len(batch) >= clickBatchLimit {
+				flushClicks(ctx, batch, dataStore)
+				batch = nil
+			}
+		case <-ticker.C:
+			flushClicks(ctx, batch, dataStore)
+			batch = nil
+		case <-ctx.Done():
+			// переходы запросов, завершающихся при остановке сервера, сохраняются до закрытия канала
+			ctx = context.WithoutCancel(ctx)
+		}
+	}
+}
+
+// Reaper в фоне с интервалом interval помечает удаленными истекшие ссылки пачками по batchLimit записей
+func Reaper(ctx context.Context, dataStore store.Store, interval time.Duration, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			reapExpired(ctx, dataStore, time.Now())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// reapExpired удаляет ссылки, истекшие к моменту now, пачками по batchLimit, пока пачка не окажется неполной
+// или не произойдет ошибка. Неполная, в том числе пустая, пачка означает, что истекших ссылок не осталось.
+func reapExpired(ctx context.Context, dataStore store.Store, now time.Time) {
+	for ctx.Err() == nil {
+		deleted, err := dataStore.DeleteExpiredURLs(ctx, now, batchLimit)
+		if err != nil {
+			logrus.WithField("error", err).Error("Failed to delete expired urls")
+			return
+		}
+		if len(deleted) > 0 {
+			logrus.WithField("count", len(deleted)).Info("Deleted expired URLs")
+		}
+		if len(deleted) < batchLimit {
+			return
+		}
+	}
+}
+
 // flushBatch удаляет переданные записи из БД
-func flushBatch(ctx context.Context, batch []store.URLPair, dataStore store.Store) {
+func flushBatch(ctx context.Context, batch []store.URLPair, dataStore store.Store) error {
 	if len(batch) == 0 {
-		return
+		return nil
 	}
 
 	logrus.WithField("count", len(batch)).Info("Flush batch URLs")
@@ -69,4 +140,21 @@ func flushBatch(ctx context.Context, batch []store.URLPair, dataStore store.Stor
 	if errDelete != nil {
 		logrus.WithField("error", errDelete).Error("Failed to delete urls")
 	}
+	return errDelete
+}
+
+// flushClicks сохраняет переданные переходы в БД
+func flushClicks(ctx context.Context, batch []store.Click, dataStore store.Store) {
+	if len(batch) == 0 {
+		return
+	}
+
+	logrus.WithField("count", len(batch)).Debug("Flush batch clicks")
+
+	if err := dataStore.AddClicks(ctx, batch); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+			"count": len(batch),
+		}).Error("Failed to save clicks")
+	}
 }
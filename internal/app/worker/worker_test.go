@@ -0,0 +1,148 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TimBerk/go-link-shortener/internal/app/store"
+	"github.com/TimBerk/go-link-shortener/internal/app/store/local"
+)
+
+func TestReapExpired(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	dataStore, err := local.NewURLStore(store.NewIDGenerator())
+	require.NoError(t, err)
+
+	var expired []string
+	for i := 0; i < batchLimit+5; i++ {
+		opts := store.LinkOptions{ExpiresAt: now.Add(-time.Minute)}
+		shortURL, err := dataStore.AddLink(ctx, fmt.Sprintf("https://example.com/%d", i), opts, "user-1")
+		require.NoError(t, err)
+		expired = append(expired, shortURL)
+	}
+	active, err := dataStore.AddLink(ctx, "https://example.com/active", store.LinkOptions{ExpiresAt: now.Add(time.Hour)}, "user-1")
+	require.NoError(t, err)
+
+	reapExpired(ctx, dataStore, now)
+
+	deleted, err := dataStore.DeleteExpiredURLs(ctx, now, batchLimit)
+	require.NoError(t, err)
+	assert.Empty(t, deleted, "Все истекшие ссылки должны быть удалены, в том числе сверх одной пачки")
+	for _, shortURL := range expired {
+		_, exists, isDeleted := dataStore.GetOriginalURL(ctx, shortURL, "user-1")
+		assert.True(t, exists)
+		assert.True(t, isDeleted)
+	}
+	_, _, isDeleted := dataStore.GetOriginalURL(ctx, active, "user-1")
+	assert.False(t, isDeleted, "Не истекшая ссылка не должна удаляться")
+}
+
+// reapStore возвращает заданные пачки удаленных ссылок и считает вызовы очистки
+type reapStore struct {
+	store.Store
+	batches [][]string
+	calls   int
+}
+
+func (s *reapStore) DeleteExpiredURLs(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	s.calls++
+	if len(s.batches) == 0 {
+		return nil, nil
+	}
+	batch := s.batches[0]
+	s.batches = s.batches[1:]
+	return batch, nil
+}
+
+func TestReapExpired_StopsWithoutProgress(t *testing.T) {
+	full := make([]string, batchLimit)
+	for i := range full {
+		full[i] = fmt.Sprintf("code-%d", i)
+	}
+
+	tests := []struct {
+		name      string
+		batches   [][]string
+		wantCalls int
+	}{
+		{name: "Nothing expired", wantCalls: 1},
+		{name: "Partial batch", batches: [][]string{{"code-1"}}, wantCalls: 1},
+		{name: "Full batches then empty", batches: [][]string{full, full}, wantCalls: 3},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dataStore := &reapStore{batches: test.batches}
+			reapExpired(context.Background(), dataStore, time.Now())
+			assert.Equal(t, test.wantCalls, dataStore.calls)
+		})
+	}
+}
+
+// clickStore запоминает пачки сохраненных переходов
+type clickStore struct {
+	store.Store
+	mutex   sync.Mutex
+	batches [][]store.Click
+}
+
+func (s *clickStore) AddClicks(ctx context.Context, clicks []store.Click) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.batches = append(s.batches, clicks)
+	return nil
+}
+
+func TestClickWorker(t *testing.T) {
+	tests := []struct {
+		name        string
+		clicks      int
+		cancelFirst bool
+		wantBatches int
+	}{
+		{name: "Flush on close", clicks: 3, wantBatches: 1},
+		{name: "Flush full batches", clicks: clickBatchLimit*2 + 1, wantBatches: 3},
+		{name: "Keep saving after context cancel", clicks: 2, cancelFirst: true, wantBatches: 1},
+		{name: "No clicks", clicks: 0, wantBatches: 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			localStore, err := local.NewURLStore(store.NewIDGenerator())
+			require.NoError(t, err)
+			dataStore := &clickStore{Store: localStore}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			clickChan := make(chan store.Click, test.clicks)
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go ClickWorker(ctx, dataStore, clickChan, &wg)
+
+			if test.cancelFirst {
+				cancel()
+				time.Sleep(10 * time.Millisecond)
+			}
+			for i := 0; i < test.clicks; i++ {
+				clickChan <- store.Click{ShortURL: fmt.Sprintf("code-%d", i), ClickedAt: time.Now()}
+			}
+			close(clickChan)
+			wg.Wait()
+
+			assert.Len(t, dataStore.batches, test.wantBatches)
+			var saved int
+			for _, batch := range dataStore.batches {
+				saved += len(batch)
+			}
+			assert.Equal(t, test.clicks, saved, "Все переходы должны быть сохранены")
+		})
+	}
+}
@@ -5,22 +5,29 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/sirupsen/logrus"
 
 	"github.com/gorilla/securecookie"
-)
 
-var (
-	// hash ключ для работы с cookie
-	hashKey = securecookie.GenerateRandomKey(64)
-	// blockKey - дополнительный ключ для шифрования cookie
-	blockKey = securecookie.GenerateRandomKey(32)
-	// s - SecureCookie для работы с пользовательскими cookie в приложении
-	s = securecookie.New(hashKey, blockKey)
+	"github.com/TimBerk/go-link-shortener/internal/pkg/oidc"
 )
 
+// codecSet - кодеки пользовательских cookie, первый кодек используется для кодирования
+type codecSet struct {
+	codecs []securecookie.Codec
+}
+
+// codecs - кодеки для работы с пользовательскими cookie в приложении.
+// До вызова SetKeys используются случайные ключи, и cookie перестают расшифровываться после перезапуска.
+var codecs atomic.Pointer[codecSet]
+
+func init() {
+	_ = SetKeys([]KeyPair{GenerateKeyPair()})
+}
+
 // GenerateUserID генерирует значение для ID пользователя
 func GenerateUserID() string {
 	b := make([]byte, 16)
@@ -36,7 +43,7 @@ func GetEncodedValue(userID string) (string, error) {
 	value := map[string]string{
 		"user_id": userID,
 	}
-	return s.Encode("user", value)
+	return securecookie.EncodeMulti("user", value, codecs.Load().codecs...)
 }
 
 // SetUserCookie устанавливает значение ID пользователя в securecookie
@@ -54,13 +61,113 @@ func SetUserCookie(w http.ResponseWriter, userID string) {
 	}
 }
 
+// SessionCookieName - имя cookie с токеном сессии учетной записи
+const SessionCookieName = "session"
+
+// SetSessionCookie устанавливает cookie с токеном сессии, истекающую вместе с сессией.
+// Токен случайный и проверяется по хранилищу, поэтому не подписывается.
+func SetSessionCookie(w http.ResponseWriter, token string, expiresAt time.Time, secure bool) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  expiresAt,
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// ClearSessionCookie удаляет cookie с токеном сессии
+func ClearSessionCookie(w http.ResponseWriter, secure bool) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// OIDCCookieName - имя cookie с одноразовыми значениями входа через OpenID Connect
+const OIDCCookieName = "oidc"
+
+// oidcCookieTTL - время, за которое пользователь должен вернуться от провайдера OpenID Connect
+const oidcCookieTTL = 10 * time.Minute
+
+// oidcCookieValue - содержимое cookie входа через OpenID Connect
+type oidcCookieValue struct {
+	Request  oidc.AuthRequest
+	IssuedAt int64
+}
+
+// SetOIDCCookie сохраняет подписанные и зашифрованные state, nonce и code verifier входа до возврата от провайдера.
+// Cookie передается при возврате от провайдера благодаря SameSite Lax.
+func SetOIDCCookie(w http.ResponseWriter, req oidc.AuthRequest, secure bool) error {
+	value := oidcCookieValue{Request: req, IssuedAt: time.Now().Unix()}
+	encoded, err := securecookie.EncodeMulti(OIDCCookieName, value, codecs.Load().codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     OIDCCookieName,
+		Value:    encoded,
+		Path:     "/",
+		MaxAge:   int(oidcCookieTTL / time.Second),
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// GetOIDCCookie получает значения входа через OpenID Connect, cookie старше oidcCookieTTL отклоняется
+func GetOIDCCookie(r *http.Request) (oidc.AuthRequest, error) {
+	cookie, err := r.Cookie(OIDCCookieName)
+	if err != nil {
+		return oidc.AuthRequest{}, err
+	}
+
+	var value oidcCookieValue
+	if err := securecookie.DecodeMulti(OIDCCookieName, cookie.Value, &value, codecs.Load().codecs...); err != nil {
+		return oidc.AuthRequest{}, err
+	}
+	if time.Since(time.Unix(value.IssuedAt, 0)) > oidcCookieTTL {
+		return oidc.AuthRequest{}, http.ErrNoCookie
+	}
+	return value.Request, nil
+}
+
+// ClearOIDCCookie удаляет cookie входа через OpenID Connect
+func ClearOIDCCookie(w http.ResponseWriter, secure bool) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     OIDCCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
 // GetUserID получает значение ID пользователя из securecookie
 func GetUserID(r *http.Request) (string, error) {
 	if cookie, err := r.Cookie("user"); err == nil {
-		value := make(map[string]string)
-		if err = s.Decode("user", cookie.Value, &value); err == nil {
-			return value["user_id"], nil
+		if userID, err := DecodeUserID(cookie.Value); err == nil {
+			return userID, nil
 		}
 	}
 	return "", http.ErrNoCookie
 }
+
+// DecodeUserID получает значение ID пользователя из закодированного securecookie значения
+func DecodeUserID(encoded string) (string, error) {
+	value := make(map[string]string)
+	if err := securecookie.DecodeMulti("user", encoded, &value, codecs.Load().codecs...); err != nil {
+		return "", err
+	}
+	return value["user_id"], nil
+}
@@ -0,0 +1,130 @@
+package cookies
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gorilla/securecookie"
+)
+
+const (
+	// hashKeyLength - длина генерируемого ключа подписи
+	hashKeyLength = 64
+	// blockKeyLength - длина генерируемого ключа шифрования, соответствует AES-256
+	blockKeyLength = 32
+	// minHashKeyLength - минимальная длина ключа подписи
+	minHashKeyLength = 32
+)
+
+// ErrInvalidKey ошибка о недопустимом ключе cookie
+var ErrInvalidKey = errors.New("invalid cookie key")
+
+// KeyPair - ключи подписи и шифрования cookie
+type KeyPair struct {
+	HashKey  []byte
+	BlockKey []byte
+}
+
+// GenerateKeyPair генерирует случайную пару ключей
+func GenerateKeyPair() KeyPair {
+	return KeyPair{
+		HashKey:  securecookie.GenerateRandomKey(hashKeyLength),
+		BlockKey: securecookie.GenerateRandomKey(blockKeyLength),
+	}
+}
+
+// String возвращает пару ключей в формате base64(hash):base64(block)
+func (k KeyPair) String() string {
+	return base64.StdEncoding.EncodeToString(k.HashKey) + ":" + base64.StdEncoding.EncodeToString(k.BlockKey)
+}
+
+// ParseKeyPair разбирает пару ключей в формате base64(hash):base64(block).
+// Ключ подписи должен быть не короче 32 байт, ключ шифрования - 16, 24 или 32 байта.
+func ParseKeyPair(value string) (KeyPair, error) {
+	hashPart, blockPart, ok := strings.Cut(strings.TrimSpace(value), ":")
+	if !ok {
+		return KeyPair{}, fmt.Errorf("%w: expected base64 hash and block keys separated by a colon", ErrInvalidKey)
+	}
+
+	hashKey, err := base64.StdEncoding.DecodeString(hashPart)
+	if err != nil {
+		return KeyPair{}, fmt.Errorf("%w: hash key is not base64: %v", ErrInvalidKey, err)
+	}
+	if len(hashKey) < minHashKeyLength {
+		return KeyPair{}, fmt.Errorf("%w: hash key must be at least %d bytes", ErrInvalidKey, minHashKeyLength)
+	}
+
+	blockKey, err := base64.StdEncoding.DecodeString(blockPart)
+	if err != nil {
+		return KeyPair{}, fmt.Errorf("%w: block key is not base64: %v", ErrInvalidKey, err)
+	}
+	if n := len(blockKey); n != 16 && n != 24 && n != 32 {
+		return KeyPair{}, fmt.Errorf("%w: block key must be 16, 24 or 32 bytes", ErrInvalidKey)
+	}
+
+	return KeyPair{HashKey: hashKey, BlockKey: blockKey}, nil
+}
+
+// ParseKeyPairs разбирает пары ключей, разделенные запятыми или переводами строк.
+// Пустые строки и строки, начинающиеся с #, пропускаются. Первая пара подписывает cookie, остальные только проверяют.
+func ParseKeyPairs(value string) ([]KeyPair, error) {
+	var keys []KeyPair
+	for _, line := range strings.Split(value, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		for _, field := range strings.Split(line, ",") {
+			if strings.TrimSpace(field) == "" {
+				continue
+			}
+			key, err := ParseKeyPair(field)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// LoadKeys возвращает пары ключей из строки keys, а если она пуста - из файла keysFile.
+// Если не заданы ни ключи, ни файл, возвращает пустой список.
+func LoadKeys(keys string, keysFile string) ([]KeyPair, error) {
+	if keys != "" {
+		return ParseKeyPairs(keys)
+	}
+	if keysFile == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(keysFile)
+	if err != nil {
+		return nil, fmt.Errorf("read cookie keys file: %w", err)
+	}
+	parsed, err := ParseKeyPairs(string(data))
+	if err != nil {
+		return nil, err
+	}
+	if len(parsed) == 0 {
+		return nil, fmt.Errorf("%w: cookie keys file %s has no keys", ErrInvalidKey, keysFile)
+	}
+	return parsed, nil
+}
+
+// SetKeys задает ключи cookie: первая пара подписывает и шифрует новые cookie,
+// все пары используются для проверки, что позволяет менять ключи без потери пользователей
+func SetKeys(keys []KeyPair) error {
+	if len(keys) == 0 {
+		return fmt.Errorf("%w: at least one key pair is required", ErrInvalidKey)
+	}
+
+	pairs := make([][]byte, 0, 2*len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key.HashKey, key.BlockKey)
+	}
+	codecs.Store(&codecSet{codecs: securecookie.CodecsFromPairs(pairs...)})
+	return nil
+}
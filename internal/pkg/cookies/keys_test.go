@@ -0,0 +1,111 @@
+package cookies
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useKeys задает ключи на время теста и возвращает случайные ключи после него
+func useKeys(t *testing.T, keys ...KeyPair) {
+	t.Helper()
+
+	require.NoError(t, SetKeys(keys))
+	t.Cleanup(func() {
+		_ = SetKeys([]KeyPair{GenerateKeyPair()})
+	})
+}
+
+func TestSetKeys_Rotation(t *testing.T) {
+	oldKey, newKey := GenerateKeyPair(), GenerateKeyPair()
+
+	useKeys(t, oldKey)
+	encoded, err := GetEncodedValue("user-1")
+	require.NoError(t, err)
+
+	useKeys(t, newKey, oldKey)
+	userID, err := DecodeUserID(encoded)
+	require.NoError(t, err, "Старый ключ должен проверять выданные cookie")
+	assert.Equal(t, "user-1", userID)
+
+	reencoded, err := GetEncodedValue("user-1")
+	require.NoError(t, err)
+	useKeys(t, newKey)
+	_, err = DecodeUserID(reencoded)
+	assert.NoError(t, err, "Новые cookie должны подписываться первым ключом")
+	_, err = DecodeUserID(encoded)
+	assert.Error(t, err, "Удаленный ключ не должен проверять cookie")
+}
+
+func TestParseKeyPair(t *testing.T) {
+	hashKey := base64.StdEncoding.EncodeToString(make([]byte, 64))
+	blockKey := base64.StdEncoding.EncodeToString(make([]byte, 32))
+
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{name: "Valid", value: hashKey + ":" + blockKey},
+		{name: "Generated", value: GenerateKeyPair().String()},
+		{name: "No separator", value: hashKey, wantErr: true},
+		{name: "Not base64", value: "hash:" + blockKey, wantErr: true},
+		{name: "Short hash key", value: base64.StdEncoding.EncodeToString(make([]byte, 16)) + ":" + blockKey, wantErr: true},
+		{name: "Invalid block key length", value: hashKey + ":" + base64.StdEncoding.EncodeToString(make([]byte, 20)), wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseKeyPair(test.value)
+
+			if test.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidKey)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestLoadKeys(t *testing.T) {
+	first, second := GenerateKeyPair(), GenerateKeyPair()
+	dir := t.TempDir()
+	keysFile := filepath.Join(dir, "cookie_keys")
+	content := "# signing key, then previous key\n" + first.String() + "\n\n" + second.String() + "\n"
+	require.NoError(t, os.WriteFile(keysFile, []byte(content), 0o600))
+	emptyFile := filepath.Join(dir, "empty")
+	require.NoError(t, os.WriteFile(emptyFile, []byte("# no keys\n"), 0o600))
+
+	tests := []struct {
+		name     string
+		keys     string
+		keysFile string
+		expected []KeyPair
+		wantErr  bool
+	}{
+		{name: "Not configured"},
+		{name: "Keys", keys: strings.Join([]string{first.String(), second.String()}, ","), expected: []KeyPair{first, second}},
+		{name: "Keys override file", keys: second.String(), keysFile: keysFile, expected: []KeyPair{second}},
+		{name: "File", keysFile: keysFile, expected: []KeyPair{first, second}},
+		{name: "Missing file", keysFile: filepath.Join(dir, "missing"), wantErr: true},
+		{name: "Empty file", keysFile: emptyFile, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			keys, err := LoadKeys(test.keys, test.keysFile)
+
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, keys)
+		})
+	}
+}
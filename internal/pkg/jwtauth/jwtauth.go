@@ -0,0 +1,185 @@
+// Package jwtauth выпускает и проверяет JWT с ID пользователя в claim sub.
+// Поддерживаются алгоритмы HS256 с общим секретом и RS256 с парой ключей RSA.
+package jwtauth
+
+import (
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// HS256 - подпись HMAC SHA-256 общим секретом
+	HS256 = "HS256"
+	// RS256 - подпись RSA SHA-256 закрытым ключом
+	RS256 = "RS256"
+	// DefaultTTL - время жизни токена по умолчанию
+	DefaultTTL = 24 * time.Hour
+	// minSecretLength - минимальная длина секрета HS256
+	minSecretLength = 32
+)
+
+var (
+	// ErrInvalidToken ошибка о недействительном токене
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrIssueDisabled ошибка о том, что токены проверяются, но не выпускаются: для RS256 не задан закрытый ключ
+	ErrIssueDisabled = errors.New("token issuing is disabled")
+)
+
+// Options параметры выпуска и проверки токенов
+type Options struct {
+	// Algorithm - HS256 или RS256, пустое значение соответствует HS256
+	Algorithm string
+	// Secret - секрет HS256
+	Secret string
+	// PrivateKeyFile - путь к закрытому ключу RS256 в формате PEM, открытый ключ вычисляется из него
+	PrivateKeyFile string
+	// PublicKeyFile - путь к открытому ключу RS256 в формате PEM для проверки токенов, выпущенных другим сервисом
+	PublicKeyFile string
+	// Issuer - значение claim iss, при непустом значении проверяется
+	Issuer string
+	// Audience - значение claim aud, при непустом значении проверяется
+	Audience string
+	// TTL - время жизни выпускаемых токенов, 0 - DefaultTTL
+	TTL time.Duration
+}
+
+// Enabled сообщает, что заданы ключи для работы с токенами
+func (o Options) Enabled() bool {
+	return o.Secret != "" || o.PrivateKeyFile != "" || o.PublicKeyFile != ""
+}
+
+// Manager выпускает и проверяет токены
+type Manager struct {
+	method    jwt.SigningMethod
+	signKey   any
+	verifyKey any
+	issuer    string
+	audience  string
+	ttl       time.Duration
+	nowFunc   func() time.Time
+}
+
+// NewManager создает менеджер токенов по параметрам
+func NewManager(opts Options) (*Manager, error) {
+	m := &Manager{
+		issuer:   opts.Issuer,
+		audience: opts.Audience,
+		ttl:      opts.TTL,
+		nowFunc:  time.Now,
+	}
+	if m.ttl <= 0 {
+		m.ttl = DefaultTTL
+	}
+
+	switch opts.Algorithm {
+	case HS256, "":
+		if len(opts.Secret) < minSecretLength {
+			return nil, fmt.Errorf("jwt secret must be at least %d bytes for %s", minSecretLength, HS256)
+		}
+		m.method = jwt.SigningMethodHS256
+		m.signKey = []byte(opts.Secret)
+		m.verifyKey = m.signKey
+	case RS256:
+		m.method = jwt.SigningMethodRS256
+		if err := m.loadRSAKeys(opts.PrivateKeyFile, opts.PublicKeyFile); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown jwt algorithm %q, expected %s or %s", opts.Algorithm, HS256, RS256)
+	}
+	return m, nil
+}
+
+// loadRSAKeys читает ключи RS256, для проверки используется открытый ключ из файла или закрытого ключа
+func (m *Manager) loadRSAKeys(privateKeyFile string, publicKeyFile string) error {
+	if privateKeyFile == "" && publicKeyFile == "" {
+		return fmt.Errorf("jwt private or public key file is required for %s", RS256)
+	}
+
+	if privateKeyFile != "" {
+		data, err := os.ReadFile(privateKeyFile)
+		if err != nil {
+			return fmt.Errorf("read jwt private key: %w", err)
+		}
+		privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+		if err != nil {
+			return fmt.Errorf("parse jwt private key: %w", err)
+		}
+		m.signKey = privateKey
+		m.verifyKey = &privateKey.PublicKey
+	}
+
+	if publicKeyFile != "" {
+		data, err := os.ReadFile(publicKeyFile)
+		if err != nil {
+			return fmt.Errorf("read jwt public key: %w", err)
+		}
+		publicKey, err := jwt.ParseRSAPublicKeyFromPEM(data)
+		if err != nil {
+			return fmt.Errorf("parse jwt public key: %w", err)
+		}
+		if privateKey, ok := m.signKey.(*rsa.PrivateKey); ok && !privateKey.PublicKey.Equal(publicKey) {
+			return errors.New("jwt public key does not match private key")
+		}
+		m.verifyKey = publicKey
+	}
+	return nil
+}
+
+// Issue выпускает токен для пользователя и возвращает его вместе с моментом истечения
+func (m *Manager) Issue(userID string) (string, time.Time, error) {
+	if m.signKey == nil {
+		return "", time.Time{}, ErrIssueDisabled
+	}
+
+	now := m.nowFunc()
+	expiresAt := now.Add(m.ttl).Truncate(time.Second)
+	claims := jwt.RegisteredClaims{
+		Subject:   userID,
+		Issuer:    m.issuer,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	}
+	if m.audience != "" {
+		claims.Audience = jwt.ClaimStrings{m.audience}
+	}
+
+	token, err := jwt.NewWithClaims(m.method, claims).SignedString(m.signKey)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// Parse проверяет подпись, алгоритм, срок действия, издателя и аудиторию токена и возвращает ID пользователя
+func (m *Manager) Parse(token string) (string, error) {
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{m.method.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithTimeFunc(m.nowFunc),
+	}
+	if m.issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(m.issuer))
+	}
+	if m.audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(m.audience))
+	}
+
+	var claims jwt.RegisteredClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (any, error) {
+		return m.verifyKey, nil
+	}, parserOpts...)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if claims.Subject == "" {
+		return "", fmt.Errorf("%w: subject is required", ErrInvalidToken)
+	}
+	return claims.Subject, nil
+}
@@ -0,0 +1,146 @@
+package jwtauth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+// writeRSAKeys сохраняет закрытый и открытый ключи RSA в PEM-файлы и возвращает пути к ним
+func writeRSAKeys(t *testing.T) (string, string) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	publicDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	privateFile := filepath.Join(dir, "jwt.key")
+	publicFile := filepath.Join(dir, "jwt.pub")
+	privatePEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+	require.NoError(t, os.WriteFile(privateFile, privatePEM, 0o600))
+	require.NoError(t, os.WriteFile(publicFile, publicPEM, 0o600))
+	return privateFile, publicFile
+}
+
+func TestManager_IssueParse(t *testing.T) {
+	privateFile, publicFile := writeRSAKeys(t)
+
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{name: "HS256", opts: Options{Secret: testSecret}},
+		{name: "HS256 with issuer and audience", opts: Options{Algorithm: HS256, Secret: testSecret, Issuer: "shortener", Audience: "cli"}},
+		{name: "RS256", opts: Options{Algorithm: RS256, PrivateKeyFile: privateFile}},
+		{name: "RS256 with public key", opts: Options{Algorithm: RS256, PrivateKeyFile: privateFile, PublicKeyFile: publicFile}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			manager, err := NewManager(test.opts)
+			require.NoError(t, err)
+
+			token, expiresAt, err := manager.Issue("user-1")
+			require.NoError(t, err)
+			assert.WithinDuration(t, time.Now().Add(DefaultTTL), expiresAt, time.Minute)
+
+			userID, err := manager.Parse(token)
+			require.NoError(t, err)
+			assert.Equal(t, "user-1", userID)
+		})
+	}
+}
+
+func TestManager_ParseInvalid(t *testing.T) {
+	privateFile, publicFile := writeRSAKeys(t)
+	issuer, err := NewManager(Options{Secret: testSecret, Issuer: "shortener", Audience: "cli", TTL: time.Hour})
+	require.NoError(t, err)
+	rsaIssuer, err := NewManager(Options{Algorithm: RS256, PrivateKeyFile: privateFile})
+	require.NoError(t, err)
+
+	token, _, err := issuer.Issue("user-1")
+	require.NoError(t, err)
+	rsaToken, _, err := rsaIssuer.Issue("user-1")
+	require.NoError(t, err)
+	expired, err := NewManager(Options{Secret: testSecret, Issuer: "shortener", Audience: "cli", TTL: time.Hour})
+	require.NoError(t, err)
+	expired.nowFunc = func() time.Time { return time.Now().Add(-2 * time.Hour) }
+	expiredToken, _, err := expired.Issue("user-1")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		opts  Options
+		token string
+	}{
+		{name: "Malformed", opts: Options{Secret: testSecret}, token: "not-a-token"},
+		{name: "Other secret", opts: Options{Secret: strings.Repeat("x", 32)}, token: token},
+		{name: "Other issuer", opts: Options{Secret: testSecret, Issuer: "other"}, token: token},
+		{name: "Other audience", opts: Options{Secret: testSecret, Audience: "other"}, token: token},
+		{name: "Expired", opts: Options{Secret: testSecret}, token: expiredToken},
+		{name: "Unexpected algorithm", opts: Options{Secret: testSecret}, token: rsaToken},
+		{name: "HS256 token signed with public key", opts: Options{Algorithm: RS256, PublicKeyFile: publicFile}, token: token},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			manager, err := NewManager(test.opts)
+			require.NoError(t, err)
+
+			_, err = manager.Parse(test.token)
+
+			assert.ErrorIs(t, err, ErrInvalidToken)
+		})
+	}
+}
+
+func TestManager_VerifyOnly(t *testing.T) {
+	privateFile, publicFile := writeRSAKeys(t)
+	issuer, err := NewManager(Options{Algorithm: RS256, PrivateKeyFile: privateFile})
+	require.NoError(t, err)
+	verifier, err := NewManager(Options{Algorithm: RS256, PublicKeyFile: publicFile})
+	require.NoError(t, err)
+
+	token, _, err := issuer.Issue("user-1")
+	require.NoError(t, err)
+	userID, err := verifier.Parse(token)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", userID)
+
+	_, _, err = verifier.Issue("user-1")
+	assert.ErrorIs(t, err, ErrIssueDisabled)
+}
+
+func TestNewManager_InvalidOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{name: "Short secret", opts: Options{Secret: "secret"}},
+		{name: "Unknown algorithm", opts: Options{Algorithm: "none", Secret: testSecret}},
+		{name: "RS256 without keys", opts: Options{Algorithm: RS256}},
+		{name: "Missing key file", opts: Options{Algorithm: RS256, PrivateKeyFile: filepath.Join(t.TempDir(), "missing")}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewManager(test.opts)
+
+			assert.Error(t, err)
+		})
+	}
+}
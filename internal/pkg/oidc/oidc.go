@@ -0,0 +1,402 @@
+// Package oidc реализует вход через OpenID Connect по коду авторизации с PKCE.
+// Настройки провайдера загружаются из документа discovery при первом обращении,
+// ID token проверяется ключами из jwks_uri, а ID пользователя берется из настраиваемых claims.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// DiscoveryPath - путь документа discovery относительно издателя
+	DiscoveryPath = "/.well-known/openid-configuration"
+	// DefaultUserPrefix - префикс ID пользователей, вошедших через OpenID Connect
+	DefaultUserPrefix = "oidc:"
+	// challengeMethod - единственный поддерживаемый метод PKCE
+	challengeMethod = "S256"
+	// randomBytes - количество случайных байт в state, nonce и code verifier
+	randomBytes = 32
+	// jwksRefreshInterval - минимальный интервал между загрузками ключей при неизвестном kid
+	jwksRefreshInterval = time.Minute
+	// clockSkew - допустимое расхождение часов с провайдером при проверке ID token
+	clockSkew = time.Minute
+	// maxResponseSize - максимальный размер ответа провайдера
+	maxResponseSize = 1 << 20
+	// requestTimeout - таймаут запросов к провайдеру по умолчанию
+	requestTimeout = 10 * time.Second
+)
+
+var (
+	// ErrDiscovery ошибка загрузки настроек или ключей провайдера
+	ErrDiscovery = errors.New("oidc discovery failed")
+	// ErrExchange ошибка обмена кода авторизации на токены
+	ErrExchange = errors.New("oidc code exchange failed")
+	// ErrInvalidIDToken ошибка о недействительном ID token
+	ErrInvalidIDToken = errors.New("invalid id token")
+	// ErrNoUserClaim ошибка об отсутствии в ID token claims для ID пользователя
+	ErrNoUserClaim = errors.New("id token has no user claim")
+
+	// defaultScopes - запрашиваемые scope по умолчанию
+	defaultScopes = []string{"openid", "profile", "email"}
+	// signingMethods - асимметричные алгоритмы подписи, которые принимаются у ID token
+	signingMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+)
+
+// Options параметры клиента OpenID Connect
+type Options struct {
+	// Issuer - издатель, из которого загружается документ discovery и который проверяется в ID token
+	Issuer string
+	// ClientID - идентификатор клиента, проверяется в claim aud
+	ClientID string
+	// ClientSecret - секрет клиента, без него клиент считается публичным и защищается только PKCE
+	ClientSecret string
+	// RedirectURL - адрес обработчика, на который провайдер возвращает код авторизации
+	RedirectURL string
+	// Scopes - запрашиваемые scope, openid добавляется всегда
+	Scopes []string
+	// UserClaims - claims, из первого непустого строкового значения которых берется ID пользователя
+	UserClaims []string
+	// UserPrefix - префикс ID пользователя, отделяющий пользователей провайдера от остальных
+	UserPrefix string
+	// HTTPClient - клиент запросов к провайдеру, по умолчанию клиент с таймаутом
+	HTTPClient *http.Client
+}
+
+// Enabled сообщает, что вход через OpenID Connect настроен
+func (o Options) Enabled() bool {
+	return o.Issuer != ""
+}
+
+// metadata - используемые поля документа discovery
+type metadata struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	ChallengeMethods      []string `json:"code_challenge_methods_supported"`
+}
+
+// AuthRequest одноразовые значения запроса авторизации, которые хранятся у клиента до возврата от провайдера
+type AuthRequest struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+}
+
+// Provider клиент провайдера OpenID Connect
+type Provider struct {
+	opts    Options
+	client  *http.Client
+	nowFunc func() time.Time
+
+	mu            sync.Mutex
+	meta          *metadata
+	keys          map[string]any
+	keysFetchedAt time.Time
+}
+
+// NewProvider проверяет параметры и создает клиент провайдера. Документ discovery загружается при первом входе,
+// чтобы недоступность провайдера не мешала запуску сервиса.
+func NewProvider(opts Options) (*Provider, error) {
+	issuer, err := url.Parse(opts.Issuer)
+	if err != nil || (issuer.Scheme != "https" && issuer.Scheme != "http") || issuer.Host == "" {
+		return nil, fmt.Errorf("oidc issuer must be an absolute http(s) URL, got %q", opts.Issuer)
+	}
+	if opts.ClientID == "" {
+		return nil, errors.New("oidc client id is required")
+	}
+	if redirect, err := url.Parse(opts.RedirectURL); err != nil || !redirect.IsAbs() {
+		return nil, fmt.Errorf("oidc redirect url must be absolute, got %q", opts.RedirectURL)
+	}
+
+	if len(opts.Scopes) == 0 {
+		opts.Scopes = defaultScopes
+	}
+	if !slices.Contains(opts.Scopes, "openid") {
+		opts.Scopes = append([]string{"openid"}, opts.Scopes...)
+	}
+	if len(opts.UserClaims) == 0 {
+		opts.UserClaims = []string{"sub"}
+	}
+
+	p := &Provider{opts: opts, client: opts.HTTPClient, nowFunc: time.Now}
+	if p.client == nil {
+		p.client = &http.Client{Timeout: requestTimeout}
+	}
+	return p, nil
+}
+
+// randomString возвращает случайную строку в base64 без дополнения
+func randomString() (string, error) {
+	b := make([]byte, randomBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// NewAuthRequest генерирует state, nonce и code verifier для нового входа
+func NewAuthRequest() (AuthRequest, error) {
+	var req AuthRequest
+	var err error
+	if req.State, err = randomString(); err != nil {
+		return AuthRequest{}, err
+	}
+	if req.Nonce, err = randomString(); err != nil {
+		return AuthRequest{}, err
+	}
+	if req.Verifier, err = randomString(); err != nil {
+		return AuthRequest{}, err
+	}
+	return req, nil
+}
+
+// CodeChallenge возвращает code challenge S256 для code verifier
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// getJSON загружает JSON-документ провайдера
+func (p *Provider) getJSON(ctx context.Context, target string, value any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, target)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(value)
+}
+
+// discover возвращает настройки провайдера, загружая их при первом обращении
+func (p *Provider) discover(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.meta != nil {
+		return p.meta, nil
+	}
+
+	var meta metadata
+	if err := p.getJSON(ctx, strings.TrimSuffix(p.opts.Issuer, "/")+DiscoveryPath, &meta); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDiscovery, err)
+	}
+	if meta.Issuer != p.opts.Issuer {
+		return nil, fmt.Errorf("%w: issuer %q does not match configured %q", ErrDiscovery, meta.Issuer, p.opts.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: authorization, token and jwks endpoints are required", ErrDiscovery)
+	}
+	if len(meta.ChallengeMethods) > 0 && !slices.Contains(meta.ChallengeMethods, challengeMethod) {
+		return nil, fmt.Errorf("%w: provider does not support PKCE %s", ErrDiscovery, challengeMethod)
+	}
+
+	p.meta = &meta
+	return p.meta, nil
+}
+
+// AuthCodeURL возвращает адрес провайдера, на который перенаправляется пользователь для входа
+func (p *Provider) AuthCodeURL(ctx context.Context, req AuthRequest) (string, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	endpoint, err := url.Parse(meta.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrDiscovery, err)
+	}
+	query := endpoint.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.opts.ClientID)
+	query.Set("redirect_uri", p.opts.RedirectURL)
+	query.Set("scope", strings.Join(p.opts.Scopes, " "))
+	query.Set("state", req.State)
+	query.Set("nonce", req.Nonce)
+	query.Set("code_challenge", CodeChallenge(req.Verifier))
+	query.Set("code_challenge_method", challengeMethod)
+	endpoint.RawQuery = query.Encode()
+	return endpoint.String(), nil
+}
+
+// tokenResponse - используемые поля ответа token endpoint
+type tokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Exchange обменивает код авторизации на ID token, передавая code verifier запроса
+func (p *Provider) Exchange(ctx context.Context, code string, verifier string) (string, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.opts.RedirectURL},
+		"client_id":     {p.opts.ClientID},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.opts.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.opts.ClientID), url.QueryEscape(p.opts.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrExchange, err)
+	}
+	defer resp.Body.Close()
+
+	var token tokenResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&token); err != nil {
+		return "", fmt.Errorf("%w: status %d: %v", ErrExchange, resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || token.Error != "" {
+		return "", fmt.Errorf("%w: status %d: %s %s", ErrExchange, resp.StatusCode, token.Error, token.ErrorDescription)
+	}
+	if token.IDToken == "" {
+		return "", fmt.Errorf("%w: response has no id_token", ErrExchange)
+	}
+	return token.IDToken, nil
+}
+
+// key возвращает ключ проверки подписи по kid, перезагружая ключи провайдера при неизвестном kid
+func (p *Provider) key(ctx context.Context, kid string) (any, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	if !p.keysFetchedAt.IsZero() && p.nowFunc().Sub(p.keysFetchedAt) < jwksRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	var set jsonWebKeySet
+	if err := p.getJSON(ctx, meta.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDiscovery, err)
+	}
+	p.keys = set.publicKeys()
+	p.keysFetchedAt = p.nowFunc()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookupKey находит загруженный ключ по kid. Без kid подходит только единственный ключ провайдера.
+func (p *Provider) lookupKey(kid string) (any, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+// Verify проверяет подпись, издателя, аудиторию, срок действия и nonce ID token и возвращает его claims
+func (p *Provider) Verify(ctx context.Context, rawIDToken string, nonce string) (jwt.MapClaims, error) {
+	if _, err := p.discover(ctx); err != nil {
+		return nil, err
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.key(ctx, kid)
+	},
+		jwt.WithValidMethods(signingMethods),
+		jwt.WithIssuer(p.opts.Issuer),
+		jwt.WithAudience(p.opts.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(clockSkew),
+		jwt.WithTimeFunc(p.nowFunc),
+	)
+	if err != nil {
+		if errors.Is(err, ErrDiscovery) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	tokenNonce, _ := claims["nonce"].(string)
+	if nonce == "" || subtle.ConstantTimeCompare([]byte(tokenNonce), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("%w: nonce does not match", ErrInvalidIDToken)
+	}
+	audience, _ := claims.GetAudience()
+	if azp, ok := claims["azp"].(string); (ok || len(audience) > 1) && azp != p.opts.ClientID {
+		return nil, fmt.Errorf("%w: authorized party %q is not the client", ErrInvalidIDToken, azp)
+	}
+	return claims, nil
+}
+
+// UserID возвращает ID пользователя из первого настроенного claim с непустым строковым значением.
+// Claim email используется, только если провайдер не отметил адрес как неподтвержденный.
+func (p *Provider) UserID(claims jwt.MapClaims) (string, error) {
+	for _, name := range p.opts.UserClaims {
+		value, _ := claims[name].(string)
+		if value == "" {
+			continue
+		}
+		if verified, ok := claims["email_verified"].(bool); name == "email" && ok && !verified {
+			continue
+		}
+		return p.opts.UserPrefix + value, nil
+	}
+	return "", fmt.Errorf("%w: expected one of %s", ErrNoUserClaim, strings.Join(p.opts.UserClaims, ", "))
+}
+
+// Authenticate завершает вход: обменивает код на ID token, проверяет его и возвращает ID пользователя
+func (p *Provider) Authenticate(ctx context.Context, code string, req AuthRequest) (string, error) {
+	rawIDToken, err := p.Exchange(ctx, code, req.Verifier)
+	if err != nil {
+		return "", err
+	}
+	claims, err := p.Verify(ctx, rawIDToken, req.Nonce)
+	if err != nil {
+		return "", err
+	}
+	return p.UserID(claims)
+}
@@ -0,0 +1,221 @@
+package oidc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TimBerk/go-link-shortener/internal/pkg/oidc/oidctest"
+)
+
+const testRedirectURL = "http://localhost:8080/api/user/oidc/callback"
+
+// newTestProvider возвращает клиент локального провайдера
+func newTestProvider(t *testing.T, idp *oidctest.Server, opts Options) *Provider {
+	t.Helper()
+
+	opts.Issuer = idp.URL
+	opts.ClientID = idp.ClientID
+	opts.ClientSecret = idp.ClientSecret
+	opts.RedirectURL = testRedirectURL
+	provider, err := NewProvider(opts)
+	require.NoError(t, err)
+	return provider
+}
+
+func TestNewProvider(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr bool
+	}{
+		{name: "Valid options", opts: Options{Issuer: "https://sso.example.com", ClientID: "shortener", RedirectURL: testRedirectURL}},
+		{name: "Relative issuer", opts: Options{Issuer: "sso.example.com", ClientID: "shortener", RedirectURL: testRedirectURL}, wantErr: true},
+		{name: "Without client id", opts: Options{Issuer: "https://sso.example.com", RedirectURL: testRedirectURL}, wantErr: true},
+		{name: "Relative redirect url", opts: Options{Issuer: "https://sso.example.com", ClientID: "shortener", RedirectURL: "/api/user/oidc/callback"}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			provider, err := NewProvider(test.opts)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, defaultScopes, provider.opts.Scopes)
+			assert.Equal(t, []string{"sub"}, provider.opts.UserClaims)
+		})
+	}
+}
+
+func TestProvider_AuthCodeURL(t *testing.T) {
+	idp := oidctest.NewServer(t, "shortener", "")
+	provider := newTestProvider(t, idp, Options{Scopes: []string{"email"}})
+	req, err := NewAuthRequest()
+	require.NoError(t, err)
+
+	authURL, err := provider.AuthCodeURL(context.Background(), req)
+	require.NoError(t, err)
+
+	parsed, err := url.Parse(authURL)
+	require.NoError(t, err)
+	query := parsed.Query()
+	assert.Equal(t, idp.URL+"/authorize", parsed.Scheme+"://"+parsed.Host+parsed.Path)
+	assert.Equal(t, "code", query.Get("response_type"))
+	assert.Equal(t, "openid email", query.Get("scope"), "Scope openid должен добавляться всегда")
+	assert.Equal(t, req.State, query.Get("state"))
+	assert.Equal(t, req.Nonce, query.Get("nonce"))
+	assert.Equal(t, CodeChallenge(req.Verifier), query.Get("code_challenge"))
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+	assert.NotContains(t, authURL, req.Verifier, "Code verifier не должен передаваться в адресе авторизации")
+}
+
+func TestProvider_Authenticate(t *testing.T) {
+	tests := []struct {
+		name         string
+		clientSecret string
+		opts         Options
+		claims       map[string]any
+		verifier     string
+		nonce        string
+		expectedUser string
+		expectedErr  error
+	}{
+		{name: "Confidential client", clientSecret: "secret", opts: Options{UserPrefix: "oidc:"}, expectedUser: "oidc:user-1"},
+		{name: "Public client", expectedUser: "user-1"},
+		{name: "Email claim", opts: Options{UserClaims: []string{"email", "sub"}}, claims: map[string]any{"email": "alice@example.com", "email_verified": true}, expectedUser: "alice@example.com"},
+		{name: "Unverified email falls back to sub", opts: Options{UserClaims: []string{"email", "sub"}}, claims: map[string]any{"email": "alice@example.com", "email_verified": false}, expectedUser: "user-1"},
+		{name: "Missing user claim", opts: Options{UserClaims: []string{"preferred_username"}}, expectedErr: ErrNoUserClaim},
+		{name: "Wrong code verifier", verifier: "wrong-verifier", expectedErr: ErrExchange},
+		{name: "Wrong nonce", nonce: "wrong-nonce", expectedErr: ErrInvalidIDToken},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			idp := oidctest.NewServer(t, "shortener", test.clientSecret)
+			idp.Claims = test.claims
+			provider := newTestProvider(t, idp, test.opts)
+			req, err := NewAuthRequest()
+			require.NoError(t, err)
+			authURL, err := provider.AuthCodeURL(context.Background(), req)
+			require.NoError(t, err)
+
+			callback := idp.Authorize(t, authURL)
+			assert.Equal(t, req.State, callback.Query().Get("state"))
+			if test.verifier != "" {
+				req.Verifier = test.verifier
+			}
+			if test.nonce != "" {
+				req.Nonce = test.nonce
+			}
+
+			userID, err := provider.Authenticate(context.Background(), callback.Query().Get("code"), req)
+			if test.expectedErr != nil {
+				assert.ErrorIs(t, err, test.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedUser, userID)
+		})
+	}
+}
+
+func TestProvider_Verify(t *testing.T) {
+	idp := oidctest.NewServer(t, "shortener", "")
+	provider := newTestProvider(t, idp, Options{})
+	now := time.Now()
+	validClaims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":   idp.URL,
+			"sub":   "user-1",
+			"aud":   "shortener",
+			"iat":   now.Unix(),
+			"exp":   now.Add(time.Hour).Unix(),
+			"nonce": "nonce-1",
+		}
+	}
+
+	tests := []struct {
+		name    string
+		token   func() string
+		wantErr bool
+	}{
+		{name: "Valid token", token: func() string { return idp.Sign(validClaims()) }},
+		{name: "Expired token", token: func() string {
+			claims := validClaims()
+			claims["exp"] = now.Add(-time.Hour).Unix()
+			return idp.Sign(claims)
+		}, wantErr: true},
+		{name: "Another issuer", token: func() string {
+			claims := validClaims()
+			claims["iss"] = "https://evil.example.com"
+			return idp.Sign(claims)
+		}, wantErr: true},
+		{name: "Another audience", token: func() string {
+			claims := validClaims()
+			claims["aud"] = "another-client"
+			return idp.Sign(claims)
+		}, wantErr: true},
+		{name: "Several audiences without azp", token: func() string {
+			claims := validClaims()
+			claims["aud"] = []string{"shortener", "another-client"}
+			return idp.Sign(claims)
+		}, wantErr: true},
+		{name: "Without nonce", token: func() string {
+			claims := validClaims()
+			delete(claims, "nonce")
+			return idp.Sign(claims)
+		}, wantErr: true},
+		{name: "HMAC signature", token: func() string {
+			token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims()).SignedString([]byte("0123456789abcdef0123456789abcdef"))
+			return token
+		}, wantErr: true},
+		{name: "Unsigned token", token: func() string {
+			token, _ := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+			return token
+		}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			claims, err := provider.Verify(context.Background(), test.token(), "nonce-1")
+			if test.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidIDToken)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "user-1", claims["sub"])
+		})
+	}
+}
+
+func TestProvider_DiscoveryIssuerMismatch(t *testing.T) {
+	idp := oidctest.NewServer(t, "shortener", "")
+	proxy := httptest.NewServer(idp.Config.Handler)
+	t.Cleanup(proxy.Close)
+
+	provider, err := NewProvider(Options{Issuer: proxy.URL, ClientID: "shortener", RedirectURL: testRedirectURL})
+	require.NoError(t, err)
+
+	_, err = provider.AuthCodeURL(context.Background(), AuthRequest{})
+	assert.ErrorIs(t, err, ErrDiscovery, "Издатель из discovery должен совпадать с настроенным")
+}
+
+func TestProvider_DiscoveryUnavailable(t *testing.T) {
+	idp := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(idp.Close)
+
+	provider, err := NewProvider(Options{Issuer: idp.URL, ClientID: "shortener", RedirectURL: testRedirectURL})
+	require.NoError(t, err, "Недоступный провайдер не должен мешать созданию клиента")
+
+	_, err = provider.AuthCodeURL(context.Background(), AuthRequest{})
+	assert.ErrorIs(t, err, ErrDiscovery)
+}
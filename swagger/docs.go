@@ -158,7 +158,7 @@ const docTemplate = `{
         },
         "/api/user/urls": {
             "get": {
-                "description": "Возвращает все сокращенные URL текущего пользователя",
+                "description": "Возвращает созданные текущим пользователем сокращенные URL в порядке добавления.\nСсылка, уже сокращенная другим пользователем, возвращается с кодом 409 и в список не попадает.",
                 "produces": [
                     "application/json"
                 ],